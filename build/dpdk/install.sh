@@ -88,6 +88,9 @@ pushd "$DPDK_HOME" > /dev/null || exit
 	sed -e 's/RTE_CPUFLAG_AVX512VL\,//' -i rte_build_config.h
   ninja
   sudo ninja install
+  # the pipeline packet capture (pkg/dpdkswx/pipeline/capture.c) needs the pipeline internals, it only compiles
+  # against the internals of DPDK 22.07 (checked with RTE_VERSION)
+  sudo install --mode=644 ../lib/pipeline/rte_swx_pipeline_internal.h /usr/local/include/
   sudo ldconfig
 popd > /dev/null || exit
 
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	dpdkiConfig "github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+)
+
+func initConfig(parents ...*cobra.Command) *cobra.Command {
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Base command for all configuration actions",
+	}
+
+	initConfigShow(configCmd)
+	initConfigSave(configCmd)
+	initConfigDiff(configCmd)
+	initConfigApply(configCmd)
+	return cli.AddCommand(parents, configCmd)
+}
+
+// the running configuration in the config file format
+func marshalRunningConfig() ([]byte, error) {
+	conf, err := runningConfig()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(conf, "", "  ")
+}
+
+func initConfigShow(parents ...*cobra.Command) *cobra.Command {
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the running configuration, including the resources created through the CLI",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := marshalRunningConfig()
+			if err != nil {
+				cmd.PrintErrf("Configuration export err: %v\n", err)
+				return
+			}
+
+			cmd.Println(string(data))
+		},
+	}
+
+	return cli.AddCommand(parents, showCmd)
+}
+
+func initConfigSave(parents ...*cobra.Command) *cobra.Command {
+	saveCmd := &cobra.Command{
+		Use:   "save [file]",
+		Short: "Save the running configuration, including the resources created through the CLI, to a config file",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must specify the file to save the configuration to"),
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := marshalRunningConfig()
+			if err != nil {
+				cmd.PrintErrf("Configuration export err: %v\n", err)
+				return
+			}
+
+			if err = os.WriteFile(args[0], append(data, '\n'), 0644); err != nil {
+				cmd.PrintErrf("Configuration save err: %v\n", err)
+				return
+			}
+			cmd.Printf("Configuration saved to %s\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, saveCmd)
+}
+
+func initConfigDiff(parents ...*cobra.Command) *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff [file]",
+		Short: "Show the changes needed to get from the running chassis configuration to the given configuration file",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must specify the configuration file to compare with"),
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			conf, err := loadChassisConfig(args[0])
+			if err != nil {
+				cmd.PrintErrf("Configuration err: %v\n", err)
+				return
+			}
+
+			cmd.Println(dpdkiConfig.Diff(conf))
+		},
+	}
+
+	return cli.AddCommand(parents, diffCmd)
+}
+
+func initConfigApply(parents ...*cobra.Command) *cobra.Command {
+	applyCmd := &cobra.Command{
+		Use:   "apply [file]",
+		Short: "Change the running chassis configuration into the given configuration file",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must specify the configuration file to apply"),
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			conf, err := loadChassisConfig(args[0])
+			if err != nil {
+				cmd.PrintErrf("Configuration err: %v\n", err)
+				return
+			}
+
+			plan, err := dpdkiConfig.Reconcile(conf)
+			if plan != nil {
+				cmd.Println(plan)
+			}
+			if err != nil {
+				cmd.PrintErrf("Apply err: %v\n", err)
+				return
+			}
+			cmd.Println("Configuration applied")
+		},
+	}
+
+	return cli.AddCommand(parents, applyCmd)
+}
@@ -5,13 +5,18 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
 	dpdkiConfig "github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/script"
 	"github.com/stolsma/go-p4pack/pkg/flowtest"
+	"github.com/stolsma/go-p4pack/pkg/hugepages"
 	"github.com/stolsma/go-p4pack/pkg/logging"
 	"github.com/stolsma/go-p4pack/pkg/signals"
 	"github.com/stolsma/go-p4pack/pkg/sshshell"
@@ -26,11 +31,121 @@ func init() {
 	})
 }
 
+// the configuration the application is started with
+var appConf *Config
+
 type Config struct {
 	*dpdkiConfig.Config `json:"chassis"`
-	FlowTest            *flowtest.Config `json:"flowtest"`
-	Logging             *logging.Config  `json:"logging"`
-	SSHShell            *sshshell.Config `json:"sshshell"`
+	FlowTest            *flowtest.Config  `json:"flowtest"`
+	Hugepages           *hugepages.Config `json:"hugepages"`
+	Logging             *logging.Config   `json:"logging"`
+	SSHShell            *sshshell.Config  `json:"sshshell"`
+}
+
+// Validate checks the complete configuration without applying anything and returns all errors found with their JSON
+// path. The given EAL settings are checked when not nil.
+func (c *Config) Validate(ealConf *dpdkiConfig.EalConfig) error {
+	var errs validation.Errors
+
+	if ealConf != nil {
+		if err := ealConf.Validate(); err != nil {
+			errs.Add("chassis", err)
+		}
+	}
+	if c.Config != nil {
+		c.Config.Validate("chassis", &errs)
+	}
+	if c.FlowTest != nil {
+		c.FlowTest.Validate("flowtest", &errs)
+	}
+	if c.Hugepages != nil {
+		if _, err := c.Hugepages.GetPageSize(); err != nil {
+			errs.Add("hugepages.pagesize", err)
+		}
+	}
+
+	return errs.Err()
+}
+
+// mount the hugetlbfs and reserve the hugepages and check if the pktmbufs will fit in the free hugepages
+func setupHugepages(
+	hpConf *hugepages.Config, ealConf *dpdkiConfig.EalConfig, pktmbufs dpdkiConfig.PktmbufsConfig,
+) error {
+	system := hugepages.New("")
+	if err := hpConf.Apply(system); err != nil {
+		return err
+	}
+
+	// let DPDK use the configured hugetlbfs
+	if ealConf.HugeDir == "" && hpConf.Mount != "" {
+		ealConf.HugeDir = hpConf.Mount
+	}
+
+	if !hpConf.Check {
+		return nil
+	}
+	pageSize, err := hpConf.GetPageSize()
+	if err != nil {
+		return err
+	}
+	nodes, err := system.Nodes()
+	if err != nil {
+		return err
+	}
+	return system.Fits(pageSize, pktmbufs.HugepageDemand(nodes))
+}
+
+// load and validate a configuration file for changing the running chassis configuration. The EAL settings can't be
+// changed at runtime and are not validated.
+func loadChassisConfig(file string) (*dpdkiConfig.Config, error) {
+	conf := &Config{Config: dpdkiConfig.Create()}
+	if err := config.LoadConfigStrict(file, conf); err != nil {
+		return nil, err
+	}
+	if err := conf.Validate(nil); err != nil {
+		return nil, err
+	}
+	return conf.Config, nil
+}
+
+// load and validate a DPDK pipeline CLI script
+func loadScript(file string) (*script.Script, error) {
+	s, err := script.Load(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// change the running chassis configuration into the chassis configuration of the given file
+func reloadConfig(file string) {
+	conf, err := loadChassisConfig(file)
+	if err != nil {
+		log.Errorf("Reloading %s failed: %v", file, err)
+		return
+	}
+
+	plan, err := dpdkiConfig.Reconcile(conf)
+	if err != nil {
+		log.Errorf("Reconciling %s failed: %v\nPlan:\n%s", file, err, plan)
+		return
+	}
+	log.Infof("Reloaded %s, executed plan:\n%s", file, plan)
+}
+
+// the configuration of the application with the chassis configuration exported from the running state
+func runningConfig() (*Config, error) {
+	chassis, err := dpdkiConfig.Export()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := *appConf
+	conf.Config = chassis
+	return &conf, nil
 }
 
 func main() {
@@ -42,10 +157,12 @@ func main() {
 	cmd.Execute()
 	dpdkArgs, _ := cmd.Flags().GetString("dpdkargs")
 	configFile, _ := cmd.Flags().GetString("config")
+	check, _ := cmd.Flags().GetBool("check")
+	scriptFile, _ := cmd.Flags().GetString("script")
 
 	// get configuration
 	conf := &Config{Config: dpdkiConfig.Create()}
-	err := config.LoadConfig(configFile, conf)
+	err := config.LoadConfigStrict(configFile, conf)
 	if err != nil {
 		log.Fatalf("Configuration load failed: %v", err)
 	}
@@ -58,8 +175,74 @@ func main() {
 		}
 	}
 
+	// get the EAL settings from the config file and command line
+	ealConf := conf.Config.Eal
+	if ealConf == nil {
+		ealConf = dpdkiConfig.DefaultEalConfig()
+	}
+	applyEalFlags(cmd, ealConf)
+	conf.Config.Eal = ealConf
+
+	// validate the configuration before anything is created, the EAL settings are not used when dpdkargs is given
+	validateEal := ealConf
+	if cmd.Flags().Changed("dpdkargs") {
+		validateEal = nil
+	}
+	err = conf.Validate(validateEal)
+
+	// load and validate the startup script, the script errors contain the script file name and line
+	var startScript *script.Script
+	var scriptErr error
+	if scriptFile != "" {
+		startScript, scriptErr = loadScript(scriptFile)
+	}
+
+	if check {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", configFile, err)
+		}
+		if scriptErr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", scriptErr)
+		}
+		if err != nil || scriptErr != nil {
+			os.Exit(1)
+		}
+		fmt.Printf("%s: configuration is valid\n", configFile)
+		if scriptFile != "" {
+			fmt.Printf("%s: script is valid\n", scriptFile)
+		}
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Configuration invalid: %v", err)
+	}
+	if scriptErr != nil {
+		log.Fatalf("Startup script invalid: %v", scriptErr)
+	}
+	appConf = conf
+
+	// setup hugepages and check if the configured pktmbufs fit before the EAL is initialized
+	if conf.Hugepages != nil && !ealConf.NoHuge {
+		err = setupHugepages(conf.Hugepages, ealConf, conf.Config.Pktmbufs)
+		if err != nil {
+			log.Fatalf("Hugepages setup failed: %v", err)
+		}
+	}
+
+	// get the EAL arguments from the EAL settings or the deprecated dpdkargs flag
+	var ealArgs []string
+	if cmd.Flags().Changed("dpdkargs") {
+		ealArgs = strings.Fields(dpdkArgs)
+	} else {
+		ealArgs, err = ealConf.Args(os.Args[0])
+		if err != nil {
+			log.Fatalf("EAL configuration invalid: %v", err)
+		}
+	}
+	log.Infof("EAL arguments: %q", ealArgs)
+
 	// initialize the dpdkinfra singleton
-	dpdki, err := dpdkinfra.CreateAndInit(strings.Split(dpdkArgs, " "))
+	dpdki, err := dpdkinfra.CreateAndInit(ealArgs)
 	if err != nil {
 		log.Fatalf("DPDKInfraInit failed: %v", err)
 	}
@@ -72,6 +255,14 @@ func main() {
 		}
 	}
 
+	// run the startup script on top of the chassis config
+	if startScript != nil {
+		err = startScript.Apply()
+		if err != nil {
+			log.Fatalf("Running startup script failed: %v", err)
+		}
+	}
+
 	// initialize the flowtest singleton
 	_, err = flowtest.CreateAndInit(appCtx)
 	if err != nil {
@@ -94,9 +285,18 @@ func main() {
 	// initialize wait for signals to react on during packet processing
 	log.Info("p4vswitch pipeline and SSH CLI server running!")
 	stopCh := signals.RegisterSignalHandlers()
+	reloadCh := signals.RegisterReloadHandler()
 
-	// wait for stop signal CTRL-C or forced termination
-	<-stopCh
+	// reload the configuration file on SIGHUP until stop signal CTRL-C or forced termination
+	for running := true; running; {
+		select {
+		case <-reloadCh:
+			log.Infof("Reload of %s requested", configFile)
+			reloadConfig(configFile)
+		case <-stopCh:
+			running = false
+		}
+	}
 
 	// Cancel the App context to let all running SSH sessions and Flow tests close in a neat way
 	cancelAppCtx()
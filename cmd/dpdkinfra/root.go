@@ -4,7 +4,15 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
+
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+	dpdkiConfig "github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/script"
 )
 
 // Create CLI handler
@@ -17,9 +25,142 @@ func CreateCmd() *cobra.Command {
 	}
 	var config, dpdkargs string
 	root.Flags().StringVarP(&config, "config", "c", "./examples/default/config.json", "The config file to use.")
+	root.Flags().Bool("check", false, "Validate the config file and exit without initializing DPDK.")
+	root.Flags().String("script", "", "A DPDK pipeline CLI script to run after the config file is applied.")
 	// "dummy -c 3 -n 4"
 	// "dummy -c 3 --log-level .*,8"
-	root.Flags().StringVarP(&dpdkargs, "dpdkargs", "d", "dummy -c 3 -n 4", "The DPDK arguments to use.")
+	root.Flags().StringVarP(&dpdkargs, "dpdkargs", "d", "", "The DPDK arguments to use, replaces all EAL settings.")
+	root.Flags().MarkDeprecated("dpdkargs", "use the eal config section or the --eal-* flags")
 
+	// EAL settings overriding the eal section of the config file
+	root.Flags().String("eal-lcores", "", "EAL lcore list or mapping.")
+	root.Flags().String("eal-coremask", "", "EAL hexadecimal core mask.")
+	root.Flags().Uint("eal-main-lcore", 0, "EAL main lcore.")
+	root.Flags().Uint("eal-memory-channels", 0, "EAL number of memory channels.")
+	root.Flags().Uint("eal-memory", 0, "EAL memory to preallocate in MB.")
+	root.Flags().UintSlice("eal-socket-mem", nil, "EAL memory to preallocate per NUMA node in MB.")
+	root.Flags().String("eal-huge-dir", "", "EAL hugetlbfs directory.")
+	root.Flags().String("eal-file-prefix", "", "EAL hugepage and runtime file prefix.")
+	root.Flags().Bool("eal-no-huge", false, "EAL use anonymous memory instead of hugepages.")
+	root.Flags().Bool("eal-in-memory", false, "EAL don't create shared runtime files.")
+	root.Flags().StringArray("eal-allow", nil, "EAL PCI device to use exclusively (repeatable).")
+	root.Flags().StringArray("eal-block", nil, "EAL PCI device not to use (repeatable).")
+	root.Flags().StringArray("eal-vdev", nil, "EAL virtual device to create (repeatable).")
+	root.Flags().StringArray("eal-log-level", nil, "EAL log level as <level> or <pattern>:<level> (repeatable).")
+	root.Flags().String("eal-iova-mode", "", "EAL IO virtual address mode (pa or va).")
+	root.Flags().StringArray("eal-arg", nil, "Additional EAL argument (repeatable).")
+
+	root.AddCommand(schemaCmd())
+	root.AddCommand(convertCmd())
 	return root
 }
+
+// print the JSON Schema of the config file and exit without initializing DPDK
+func schemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file and exit.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := json.MarshalIndent(schema.Document(&Config{}, "DPDKInfra configuration"), "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "schema err: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			os.Exit(0)
+		},
+	}
+}
+
+// print a DPDK pipeline CLI script converted into a config file and exit without initializing DPDK
+func convertCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "convert [script]",
+		Short: "Print the given DPDK pipeline CLI script converted into a config file and exit.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := script.Load(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "convert err: %v\n", err)
+				os.Exit(1)
+			}
+			// the config is converted also when it is not valid on this system, i.e. when files are missing
+			if err = s.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+
+			data, err := json.MarshalIndent(map[string]any{"chassis": s.Config()}, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "convert err: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			os.Exit(0)
+		},
+	}
+}
+
+// apply the EAL flags given on the command line to the EAL settings
+func applyEalFlags(cmd *cobra.Command, ec *dpdkiConfig.EalConfig) {
+	flags := cmd.Flags()
+	changed := func(name string) bool {
+		return flags.Changed("eal-" + name)
+	}
+
+	if changed("lcores") {
+		ec.Lcores, _ = flags.GetString("eal-lcores")
+		ec.CoreMask = ""
+	}
+	if changed("coremask") {
+		ec.CoreMask, _ = flags.GetString("eal-coremask")
+		ec.Lcores = ""
+	}
+	if changed("main-lcore") {
+		mainLcore, _ := flags.GetUint("eal-main-lcore")
+		ec.MainLcore = &mainLcore
+	}
+	if changed("memory-channels") {
+		ec.MemoryChannels, _ = flags.GetUint("eal-memory-channels")
+	}
+	if changed("memory") {
+		ec.Memory, _ = flags.GetUint("eal-memory")
+		ec.SocketMem = nil
+	}
+	if changed("socket-mem") {
+		ec.SocketMem, _ = flags.GetUintSlice("eal-socket-mem")
+		ec.Memory = 0
+	}
+	if changed("huge-dir") {
+		ec.HugeDir, _ = flags.GetString("eal-huge-dir")
+	}
+	if changed("file-prefix") {
+		ec.FilePrefix, _ = flags.GetString("eal-file-prefix")
+	}
+	if changed("no-huge") {
+		ec.NoHuge, _ = flags.GetBool("eal-no-huge")
+	}
+	if changed("in-memory") {
+		ec.InMemory, _ = flags.GetBool("eal-in-memory")
+	}
+	if changed("allow") {
+		ec.Allow, _ = flags.GetStringArray("eal-allow")
+		ec.Block = nil
+	}
+	if changed("block") {
+		ec.Block, _ = flags.GetStringArray("eal-block")
+		ec.Allow = nil
+	}
+	if changed("vdev") {
+		ec.Vdevs, _ = flags.GetStringArray("eal-vdev")
+	}
+	if changed("log-level") {
+		ec.LogLevels, _ = flags.GetStringArray("eal-log-level")
+	}
+	if changed("iova-mode") {
+		ec.IovaMode, _ = flags.GetString("eal-iova-mode")
+	}
+	if changed("arg") {
+		ec.ExtraArgs, _ = flags.GetStringArray("eal-arg")
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	dpdkiConfig "github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+)
+
+func TestApplyEalFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		args  []string
+	}{
+		{"no flags", nil, []string{"dpdkinfra", "-c", "0x3", "-n", "4"}},
+		{"lcores replace coremask", []string{"--eal-lcores", "0-3", "--eal-main-lcore", "1"},
+			[]string{"dpdkinfra", "--lcores", "0-3", "--main-lcore", "1", "-n", "4"}},
+		{"coremask", []string{"--eal-coremask", "0xf", "--eal-memory-channels", "2"},
+			[]string{"dpdkinfra", "-c", "0xf", "-n", "2"}},
+		{"memory", []string{"--eal-memory", "512", "--eal-no-huge", "--eal-in-memory"},
+			[]string{"dpdkinfra", "-c", "0x3", "-n", "4", "-m", "512", "--no-huge", "--in-memory"}},
+		{"socket mem", []string{"--eal-socket-mem", "1024,512", "--eal-huge-dir", "/mnt/huge", "--eal-file-prefix", "p4"},
+			[]string{"dpdkinfra", "-c", "0x3", "-n", "4", "--socket-mem", "1024,512", "--huge-dir", "/mnt/huge",
+				"--file-prefix", "p4"}},
+		{"devices", []string{"--eal-allow", "0000:00:04.0", "--eal-allow", "0000:00:05.0", "--eal-vdev", "net_tap0"},
+			[]string{"dpdkinfra", "-c", "0x3", "-n", "4", "-a", "0000:00:04.0", "-a", "0000:00:05.0", "--vdev",
+				"net_tap0"}},
+		{"block", []string{"--eal-block", "0000:00:04.0"},
+			[]string{"dpdkinfra", "-c", "0x3", "-n", "4", "-b", "0000:00:04.0"}},
+		{"log levels and extra args", []string{"--eal-log-level", "lib.eal:debug", "--eal-iova-mode", "va",
+			"--eal-arg", "--no-telemetry"}, []string{"dpdkinfra", "-c", "0x3", "-n", "4", "--log-level", "lib.eal:debug",
+			"--iova-mode", "va", "--no-telemetry"}},
+	}
+
+	for _, test := range tests {
+		cmd := CreateCmd()
+		if !assert.NoError(t, cmd.ParseFlags(test.flags), test.name) {
+			continue
+		}
+		ec := dpdkiConfig.DefaultEalConfig()
+		applyEalFlags(cmd, ec)
+		args, err := ec.Args("dpdkinfra")
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.args, args, test.name)
+		}
+	}
+}
+
+func TestApplyEalFlagsOverride(t *testing.T) {
+	// a flag replaces the conflicting setting of the config file
+	cmd := CreateCmd()
+	assert.NoError(t, cmd.ParseFlags([]string{"--eal-memory", "512", "--eal-block", "0000:00:05.0"}))
+	ec := &dpdkiConfig.EalConfig{Lcores: "0-1", SocketMem: []uint{1024}, Allow: []string{"0000:00:04.0"}}
+	applyEalFlags(cmd, ec)
+	assert.Equal(t, &dpdkiConfig.EalConfig{Lcores: "0-1", Memory: 512, Block: []string{"0000:00:05.0"}}, ec)
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+)
+
+func initSource(parents ...*cobra.Command) *cobra.Command {
+	sourceCmd := &cobra.Command{
+		Use:   "source [file]",
+		Short: "Run the DPDK pipeline CLI script (i.e. examples/vxlan/vxlan.cli) in the given file",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must specify the script file to run"),
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := loadScript(args[0])
+			if err != nil {
+				cmd.PrintErrf("Script err: %v\n", err)
+				return
+			}
+
+			if err = s.Apply(); err != nil {
+				cmd.PrintErrf("Script err: %v\n", err)
+				return
+			}
+			cmd.Printf("Script %s applied\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, sourceCmd)
+}
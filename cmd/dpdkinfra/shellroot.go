@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 	dpdkinfracli "github.com/stolsma/go-p4pack/pkg/dpdkinfra/cli"
 	flowtestcli "github.com/stolsma/go-p4pack/pkg/flowtest/cli"
+	hugepagescli "github.com/stolsma/go-p4pack/pkg/hugepages/cli"
 	loggingcli "github.com/stolsma/go-p4pack/pkg/logging/cli"
 	pcidevicescli "github.com/stolsma/go-p4pack/pkg/pcidevices/cli"
 )
@@ -22,7 +23,10 @@ func createShellRoot() *cobra.Command {
 	cliRoot.CompletionOptions.DisableDefaultCmd = true // no completion create command
 	initExit(cliRoot)
 	initVersion(cliRoot)
+	initConfig(cliRoot)
+	initSource(cliRoot)
 	pcidevicescli.GetCommand(cliRoot)
+	hugepagescli.GetCommand(cliRoot)
 	dpdkinfracli.GetCommand(cliRoot)
 	loggingcli.GetCommand(cliRoot)
 	flowtestcli.GetCommand(cliRoot)
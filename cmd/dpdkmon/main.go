@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// dpdkmon is a read-only monitor attaching as DPDK secondary process to a running dpdkinfra (primary) process. It
+// shows the ethdev port, mempool and ring statistics and captures the packets of ethdev ports without going through
+// the CLI of the running dpdkinfra instance.
+package main
+
+import (
+	"os"
+
+	"github.com/stolsma/go-p4pack/pkg/logging"
+)
+
+var log logging.Logger
+
+func init() {
+	// keep the logger up to date, also after new log config
+	logging.Register("main", func(logger logging.Logger) {
+		log = logger
+	})
+}
+
+func main() {
+	if err := CreateCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
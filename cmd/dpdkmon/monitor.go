@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pdump"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ring"
+	"github.com/stolsma/go-p4pack/pkg/pcapng"
+	"github.com/stolsma/go-p4pack/pkg/signals"
+)
+
+// call the given function once or every interval until stopped by a signal
+func repeat(interval time.Duration, fn func() error) error {
+	if interval == 0 {
+		return fn()
+	}
+
+	stopCh := signals.RegisterSignalHandlers()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return nil
+		}
+	}
+}
+
+// get the attached ethdev ports of the primary process, sorted on name
+func attachedPorts() ([]*ethdev.Ethdev, error) {
+	ports, err := ethdev.GetAttachedPorts()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name() < ports[j].Name() })
+	return ports, nil
+}
+
+func PortsCmd(parent *cobra.Command) *cobra.Command {
+	var interval time.Duration
+	portsCmd := &cobra.Command{
+		Use:   "ports",
+		Short: "Show the link state and statistics of the ethdev ports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return repeat(interval, func() error {
+				ports, err := attachedPorts()
+				if err != nil {
+					return err
+				}
+
+				cmd.Printf("%-24s %4s %-10s %-5s %10s %16s %16s %12s %12s %12s\n", "Port", "ID", "Driver", "Link",
+					"Speed", "RX packets", "TX packets", "RX errors", "RX missed", "TX errors")
+				for _, port := range ports {
+					driver := ""
+					if info, err := port.InfoGet(); err == nil {
+						driver = info.DriverName()
+					}
+					link, speed := "down", ""
+					if up, s, _, err := port.LinkGet(); err == nil && up {
+						link, speed = "up", ethdev.RteEthLinkSpeedToString(s)
+					}
+					stats, err := port.GetPortStats()
+					if err != nil {
+						cmd.PrintErrf("port %s stats err: %v\n", port.Name(), err)
+						continue
+					}
+					stat := func(name string) string {
+						return strings.TrimSpace(stats[name])
+					}
+					cmd.Printf("%-24s %4d %-10s %-5s %10s %16s %16s %12s %12s %12s\n", port.Name(), port.PortID(), driver,
+						link, speed, stat("ipackets"), stat("opackets"), stat("ierrors"), stat("imissed"), stat("oerrors"))
+				}
+				cmd.Println()
+				return nil
+			})
+		},
+	}
+	portsCmd.Flags().DurationVarP(&interval, "interval", "i", 0, "Repeat every interval until interrupted.")
+
+	parent.AddCommand(portsCmd)
+	return portsCmd
+}
+
+func MempoolsCmd(parent *cobra.Command) *cobra.Command {
+	var interval time.Duration
+	mempoolsCmd := &cobra.Command{
+		Use:   "mempools [name...]",
+		Short: "Show the utilisation statistics of all or the given pktmbuf mempools",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := args
+			if len(names) == 0 {
+				names = pktmbuf.Names()
+				sort.Strings(names)
+			}
+
+			pools := make([]*pktmbuf.Pktmbuf, 0, len(names))
+			for _, name := range names {
+				pm, err := pktmbuf.Lookup(name)
+				if err != nil {
+					return fmt.Errorf("mempool %s: %w", name, err)
+				}
+				defer pm.Free()
+				pools = append(pools, pm)
+			}
+
+			return repeat(interval, func() error {
+				for _, pm := range pools {
+					stats := pm.Stats()
+					cmd.Printf("%-24s %s\n", pm.Name(), stats.String())
+				}
+				cmd.Println()
+				return nil
+			})
+		},
+	}
+	mempoolsCmd.Flags().DurationVarP(&interval, "interval", "i", 0, "Repeat every interval until interrupted.")
+
+	parent.AddCommand(mempoolsCmd)
+	return mempoolsCmd
+}
+
+func RingsCmd(parent *cobra.Command) *cobra.Command {
+	var interval time.Duration
+	ringsCmd := &cobra.Command{
+		Use:   "rings [name...]",
+		Short: "Show the number of used and free entries of the given rings",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rings := make([]*ring.Ring, 0, len(args))
+			for _, name := range args {
+				r, err := ring.Lookup(name, nil)
+				if err != nil {
+					return fmt.Errorf("ring %s not found: %w", name, err)
+				}
+				rings = append(rings, r)
+			}
+
+			return repeat(interval, func() error {
+				cmd.Printf("%-24s %10s %10s %10s\n", "Ring", "Capacity", "Used", "Free")
+				for _, r := range rings {
+					cmd.Printf("%-24s %10d %10d %10d\n", r.Name(), r.Capacity(), r.Count(), r.FreeCount())
+				}
+				cmd.Println()
+				return nil
+			})
+		},
+	}
+	ringsCmd.Flags().DurationVarP(&interval, "interval", "i", 0, "Repeat every interval until interrupted.")
+
+	parent.AddCommand(ringsCmd)
+	return ringsCmd
+}
+
+func CaptureCmd(parent *cobra.Command) *cobra.Command {
+	var (
+		count    uint64
+		duration time.Duration
+		snapLen  uint32
+		queue    int
+		size     uint32
+	)
+	captureCmd := &cobra.Command{
+		Use:   "capture [port] [rx|tx|both] [file]",
+		Short: "Capture the packets of an ethdev port into a pcapng file until interrupted",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var dirs int
+			switch args[1] {
+			case "rx":
+				dirs = pdump.Rx
+			case "tx":
+				dirs = pdump.Tx
+			case "both":
+				dirs = pdump.Rx | pdump.Tx
+			default:
+				return fmt.Errorf("unknown direction %s, should be rx, tx or both", args[1])
+			}
+
+			portID, err := ethdev.GetPortID(args[0])
+			if err != nil {
+				return fmt.Errorf("port %s not found: %w", args[0], err)
+			}
+			q := uint16(pdump.AllQueues)
+			if queue >= 0 {
+				q = uint16(queue)
+			}
+
+			f, err := os.Create(args[2])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w, err := pcapng.NewWriter(f, "dpdkmon", fmt.Sprintf("capture of port %s", args[0]))
+			if err != nil {
+				return err
+			}
+			iface := pcapng.Interface{Name: args[0], LinkType: pcapng.LinkTypeEthernet, SnapLen: snapLen}
+			ifID, err := w.AddInterface(iface)
+			if err != nil {
+				return err
+			}
+
+			c, err := pdump.NewCapture(fmt.Sprintf("MON%d_%d", os.Getpid(), portID), portID, q, dirs, size)
+			if err != nil {
+				return err
+			}
+			defer c.Free()
+
+			cmd.Printf("Capturing %s packets of port %s into %s, interrupt to stop\n", args[1], args[0], args[2])
+			stopCh := signals.RegisterSignalHandlers()
+			var end <-chan time.Time
+			if duration > 0 {
+				end = time.After(duration)
+			}
+
+			var written uint64
+			var writeErr error
+			write := func(dir int, ts time.Time, origLen uint32, data []byte) {
+				if writeErr != nil || (count > 0 && written >= count) {
+					return
+				}
+				pdir := pcapng.DirectionInbound
+				if dir == pdump.Tx {
+					pdir = pcapng.DirectionOutbound
+				}
+				writeErr = w.WritePacket(ifID, ts, origLen, data, pdir)
+				written++
+			}
+
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+		loop:
+			for writeErr == nil && (count == 0 || written < count) {
+				select {
+				case <-stopCh:
+					break loop
+				case <-end:
+					break loop
+				case <-ticker.C:
+				}
+
+				// read until the capture buffers are empty
+				for c.Read(snapLen, write) > 0 && writeErr == nil {
+					continue
+				}
+			}
+
+			cmd.Printf("%d packets captured\n", written)
+			return writeErr
+		},
+	}
+	captureCmd.Flags().Uint64VarP(&count, "count", "n", 0, "Stop after the given number of packets.")
+	captureCmd.Flags().DurationVarP(&duration, "duration", "d", 0, "Stop after the given duration.")
+	captureCmd.Flags().Uint32Var(&snapLen, "snaplen", 0, "Maximum number of bytes captured per packet.")
+	captureCmd.Flags().IntVarP(&queue, "queue", "q", -1, "Capture only the given queue instead of all queues.")
+	captureCmd.Flags().Uint32Var(&size, "size", 8192, "Number of packets buffered per direction.")
+
+	parent.AddCommand(captureCmd)
+	return captureCmd
+}
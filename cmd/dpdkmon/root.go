@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2022-present Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	dpdkiConfig "github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+)
+
+// Create CLI handler
+func CreateCmd() *cobra.Command {
+	ealConf := &dpdkiConfig.EalConfig{ProcType: "secondary"}
+
+	root := &cobra.Command{
+		Use:   "dpdkmon",
+		Short: "DPDKMon is a read-only monitor for a running DPDKInfra instance",
+		Long: `DPDKMon attaches as DPDK secondary process to a running DPDKInfra instance (started with the same file
+prefix) and shows port, mempool and ring statistics or captures port packets. Complete documentation is available at
+https://github.com/stolsma/go-p4pack/`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return ealInit(ealConf)
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return eal.RteEalCleanup()
+		},
+	}
+
+	// EAL settings, the file prefix and hugepage directory must be the same as the ones of the primary process
+	flags := root.PersistentFlags()
+	flags.StringVar(&ealConf.FilePrefix, "file-prefix", "", "File prefix of the DPDKInfra instance to attach to.")
+	flags.StringVar(&ealConf.HugeDir, "huge-dir", "", "Hugetlbfs directory used by the DPDKInfra instance.")
+	flags.StringVar(&ealConf.Lcores, "lcores", "", "EAL lcore list, should not overlap the lcores of the instance.")
+	flags.StringArrayVar(&ealConf.LogLevels, "log-level", nil, "EAL log level as <level> or <pattern>:<level>.")
+	flags.StringArrayVar(&ealConf.ExtraArgs, "eal-arg", nil, "Additional EAL argument (repeatable).")
+
+	PortsCmd(root)
+	MempoolsCmd(root)
+	RingsCmd(root)
+	CaptureCmd(root)
+
+	return root
+}
+
+// initialize EAL as secondary process
+func ealInit(ealConf *dpdkiConfig.EalConfig) error {
+	args, err := ealConf.Args(os.Args[0])
+	if err != nil {
+		return err
+	}
+
+	log.Infof("EAL arguments: %q", args)
+	if err := eal.StartLogBridge(); err != nil {
+		log.Warnf("DPDK log output not forwarded: %v", err)
+	}
+	if _, err := eal.RteEalInit(args); err != nil {
+		return fmt.Errorf("attaching to DPDK primary process with file prefix %q failed: %w", ealConf.FilePrefix, err)
+	}
+
+	return nil
+}
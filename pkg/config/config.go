@@ -4,10 +4,13 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
 type Type interface {
@@ -27,13 +30,37 @@ func (b *Base) GetBasePath() string {
 	return b.basePath
 }
 
+// LoadConfig reads the given config file into c. JSON, YAML (.yaml or .yml) and TOML (.toml) config files are
+// supported, selected by the file extension. Before decoding into c the $include directives, $for loops and ${VAR} or
+// ${VAR:-default} environment variable references in the config file are processed. Included files are relative to
+// the directory of the file including them. The directory of the given config file is set as the base path of c. Keys
+// that don't match a field of c are ignored.
 func LoadConfig(filename string, c Type) error {
-	data, err := os.ReadFile(filename)
+	return loadConfig(filename, c, unmarshalLenient)
+}
+
+// LoadConfigStrict reads the given config file into c like LoadConfig, but keys that don't match a field of c are
+// reported as error.
+func LoadConfigStrict(filename string, c Type) error {
+	return loadConfig(filename, c, UnmarshalStrict)
+}
+
+func loadConfig(filename string, c Type, unmarshal func(data []byte, v any) error) error {
+	l := &loader{}
+	value, err := l.load(filename, nil)
 	if err != nil {
-		return fmt.Errorf("error when opening file: %s", err)
+		return fmt.Errorf("error when loading file: %s", err)
+	}
+	// the reference to the JSON Schema of the file is only used by editors
+	if m, ok := value.(map[string]any); ok {
+		delete(m, "$schema")
 	}
 
-	if err := json.Unmarshal(data, c); err != nil {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("config conversion failed: %s", err)
+	}
+	if err := unmarshal(data, c); err != nil {
 		return fmt.Errorf("JSON unmarshaling failed: %s", err)
 	}
 
@@ -42,3 +69,52 @@ func LoadConfig(filename string, c Type) error {
 
 	return nil
 }
+
+// the strictness of the config decode in progress, the decodes are serialized so it applies to one decode at a time
+var (
+	decodeLock sync.Mutex
+	strict     int32 // 1 if the decode in progress is strict
+)
+
+func unmarshalLenient(data []byte, v any) error {
+	decodeLock.Lock()
+	defer decodeLock.Unlock()
+	return json.Unmarshal(data, v)
+}
+
+// UnmarshalStrict decodes the JSON data into v like json.Unmarshal but returns an error for object keys that don't
+// match a field of v. Types with a custom UnmarshalJSON method must decode with Unmarshal to be decoded strictly.
+func UnmarshalStrict(data []byte, v any) error {
+	decodeLock.Lock()
+	defer decodeLock.Unlock()
+	atomic.StoreInt32(&strict, 1)
+	defer atomic.StoreInt32(&strict, 0)
+	return decodeStrict(data, v)
+}
+
+// Unmarshal decodes the JSON data into v, to be used in the UnmarshalJSON method of config types. Unknown object keys
+// are reported as error when the config is decoded by UnmarshalStrict or LoadConfigStrict and are ignored otherwise.
+func Unmarshal(data []byte, v any) error {
+	if Strict() {
+		return decodeStrict(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Strict returns true if the config is decoded by UnmarshalStrict or LoadConfigStrict, for UnmarshalJSON methods
+// checking the object keys themselves.
+func Strict() bool {
+	return atomic.LoadInt32(&strict) == 1
+}
+
+func decodeStrict(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if d.More() {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+}
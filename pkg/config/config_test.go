@@ -4,6 +4,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stolsma/go-p4pack/pkg/flowtest"
@@ -30,3 +32,216 @@ func TestLoad(t *testing.T) {
 	assert.Equal(t, "../../examples/default", c.GetBasePath())
 	assert.NotEqual(t, empty{}, c)
 }
+
+type loopConfig struct {
+	*Base
+	Name       string           `json:"name"`
+	Size       int              `json:"size"`
+	Enabled    bool             `json:"enabled"`
+	Logging    *logging.Config  `json:"logging"`
+	Interfaces []map[string]any `json:"interfaces"`
+}
+
+func writeFile(t *testing.T, dir string, name string, data string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"name": "test", "size": 10, "enabled": true}`,
+		"config.yaml": "name: test\nsize: 10\nenabled: true\n",
+		"config.toml": "name = \"test\"\nsize = 10\nenabled = true\n",
+	}
+	for name, data := range files {
+		var c = loopConfig{Base: &Base{}}
+		err := LoadConfig(writeFile(t, dir, name, data), &c)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "test", c.Name, name)
+		assert.Equal(t, 10, c.Size, name)
+		assert.True(t, c.Enabled, name)
+		assert.Equal(t, dir, c.GetBasePath(), name)
+	}
+}
+
+func TestLoadStrict(t *testing.T) {
+	dir := t.TempDir()
+	filename := writeFile(t, dir, "config.json", `{"$schema": "schema.json", "name": "test"}`)
+	assert.NoError(t, LoadConfigStrict(filename, &loopConfig{Base: &Base{}}))
+
+	unknown := writeFile(t, dir, "unknown.json", `{"name": "test", "nmae": "typo"}`)
+	err := LoadConfigStrict(unknown, &loopConfig{Base: &Base{}})
+	assert.ErrorContains(t, err, `unknown field "nmae"`)
+
+	nested := writeFile(t, dir, "nested.yaml", "logging:\n  loggers:\n    root:\n      lvl: info\n")
+	err = LoadConfigStrict(nested, &loopConfig{Base: &Base{}})
+	assert.ErrorContains(t, err, `unknown field "lvl"`)
+
+	// not strict by default
+	var c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(unknown, &c))
+	assert.Equal(t, "test", c.Name)
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.yaml", "name: base\nsize: 5\nlogging:\n  loggers:\n    root:\n      level: info\n")
+	writeFile(t, dir, "ifaces.json", `[{"name": "tap0"}, {"name": "tap1"}]`)
+	filename := writeFile(t, dir, "config.json", `{
+		"$include": "base.yaml",
+		"size": 20,
+		"interfaces": [{"$include": "ifaces.json"}, {"name": "tap2"}]
+	}`)
+
+	var c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(filename, &c))
+	assert.Equal(t, "base", c.Name)
+	assert.Equal(t, 20, c.Size)
+	assert.NotNil(t, c.Logging)
+	assert.Equal(t, []map[string]any{{"name": "tap0"}, {"name": "tap1"}, {"name": "tap2"}}, c.Interfaces)
+
+	cycle := writeFile(t, dir, "cycle.json", `{"$include": "cycle.json"}`)
+	assert.Error(t, LoadConfig(cycle, &loopConfig{Base: &Base{}}))
+
+	// without the $ prefix include is an ordinary key
+	plain := writeFile(t, dir, "plain.json", `{"interfaces": [{"include": "ifaces.json"}]}`)
+	c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(plain, &c))
+	assert.Equal(t, []map[string]any{{"include": "ifaces.json"}}, c.Interfaces)
+}
+
+func TestLoadNestedInclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "ifaces"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "sub/ifaces/tap.json", `[{"name": "tap0"}]`)
+	writeFile(t, dir, "sub/base.json", `{"name": "base", "interfaces": [{"$include": "ifaces/tap.json"}]}`)
+	filename := writeFile(t, dir, "config.json", `{"$include": "sub/base.json"}`)
+
+	// included files are relative to the directory of the including file, the base path is the main file directory
+	var c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(filename, &c))
+	assert.Equal(t, "base", c.Name)
+	assert.Equal(t, []map[string]any{{"name": "tap0"}}, c.Interfaces)
+	assert.Equal(t, dir, c.GetBasePath())
+}
+
+func TestLoadVariables(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("P4PACK_TEST_NAME", "env")
+	t.Setenv("P4PACK_TEST_SIZE", "42")
+	filename := writeFile(t, dir, "config.yaml", `
+name: ${P4PACK_TEST_NAME}-$${X}
+size: ${P4PACK_TEST_SIZE}
+enabled: ${P4PACK_TEST_UNSET:-true}
+`)
+
+	// unquoted YAML values that are only a variable reference get the type of the variable value
+	var c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(filename, &c))
+	assert.Equal(t, "env-${X}", c.Name)
+	assert.Equal(t, 42, c.Size)
+	assert.True(t, c.Enabled)
+
+	// quoted values (and all JSON values) stay strings
+	quoted := map[string]string{
+		"quoted.yaml": "name: \"${P4PACK_TEST_SIZE}\"\n",
+		"quoted.json": `{"name": "${P4PACK_TEST_SIZE}"}`,
+		"quoted.toml": "name = \"${P4PACK_TEST_SIZE}\"\n",
+	}
+	for name, data := range quoted {
+		c = loopConfig{Base: &Base{}}
+		assert.NoError(t, LoadConfig(writeFile(t, dir, name, data), &c), name)
+		assert.Equal(t, "42", c.Name, name)
+	}
+
+	// a typed reference that is the whole value is converted in all formats
+	typed := map[string]string{
+		"typed.yaml": "size: \"${P4PACK_TEST_SIZE:number}\"\nenabled: \"${P4PACK_TEST_UNSET:bool:-true}\"\n",
+		"typed.json": `{"size": "${P4PACK_TEST_SIZE:number}", "enabled": "${P4PACK_TEST_UNSET:bool:-true}"}`,
+		"typed.toml": "size = \"${P4PACK_TEST_SIZE:number}\"\nenabled = \"${P4PACK_TEST_UNSET:bool:-true}\"\n",
+	}
+	for name, data := range typed {
+		c = loopConfig{Base: &Base{}}
+		assert.NoError(t, LoadConfig(writeFile(t, dir, name, data), &c), name)
+		assert.Equal(t, 42, c.Size, name)
+		assert.True(t, c.Enabled, name)
+	}
+
+	// a dollar sign that doesn't start a variable reference is kept
+	c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(writeFile(t, dir, "dollar.json", `{"name": "a$$b$c"}`), &c))
+	assert.Equal(t, "a$$b$c", c.Name)
+
+	errors := map[string]string{
+		"unset.json":     `{"name": "${P4PACK_TEST_UNSET}"}`,
+		"notnumber.json": `{"size": "${P4PACK_TEST_NAME:number}"}`,
+		"notwhole.json":  `{"name": "tap${P4PACK_TEST_SIZE:number}"}`,
+	}
+	for name, data := range errors {
+		assert.Error(t, LoadConfig(writeFile(t, dir, name, data), &loopConfig{Base: &Base{}}), name)
+	}
+}
+
+func TestLoadFor(t *testing.T) {
+	dir := t.TempDir()
+	filename := writeFile(t, dir, "config.yaml", `
+interfaces:
+  - $for: port
+    $range: [0, 1]
+    $template:
+      name: tap${port}
+      queue: ${port}
+  - $for: name
+    $in: [a, b]
+    $template:
+      name: ring_${name}
+  - for: ordinary key
+`)
+
+	var c = loopConfig{Base: &Base{}}
+	assert.NoError(t, LoadConfig(filename, &c))
+	assert.Equal(t, []map[string]any{
+		{"name": "tap0", "queue": float64(0)},
+		{"name": "tap1", "queue": float64(1)},
+		{"name": "ring_a"},
+		{"name": "ring_b"},
+		{"for": "ordinary key"},
+	}, c.Interfaces)
+}
+
+func TestLoadForFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"interfaces": [
+			{"$for": "port", "$range": [0, 1], "$template": {"name": "tap${port}", "queue": "${port:number}"}}
+		]}`,
+		"config.toml": `
+[[interfaces]]
+"$for" = "port"
+"$range" = [0, 1]
+"$template" = { name = "tap${port}", queue = "${port:number}" }
+`,
+		"config.yaml": `
+interfaces:
+  - $for: port
+    $range: [0, 1]
+    $template: { name: "tap${port}", queue: "${port:number}" }
+`,
+	}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		var c = loopConfig{Base: &Base{}}
+		assert.NoError(t, LoadConfig(writeFile(t, dir, name, data), &c), name)
+		assert.Equal(t, []map[string]any{
+			{"name": "tap0", "queue": float64(0)},
+			{"name": "tap1", "queue": float64(1)},
+		}, c.Interfaces, name)
+	}
+}
@@ -0,0 +1,450 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// The keys of the config file directives, the $ prefix keeps them apart from the keys of the configuration itself
+const (
+	includeKey  = "$include"  // file name or list of file names to include
+	forKey      = "$for"      // loop variable name
+	inKey       = "$in"       // loop values
+	rangeKey    = "$range"    // loop range [from, to], both inclusive
+	templateKey = "$template" // loop template
+)
+
+// loads a config file and processes the config file directives and variables into a generic value
+type loader struct {
+	basePath string   // directory of the file being processed, included files are relative to it
+	files    []string // files being loaded, to detect include cycles
+}
+
+// an unquoted YAML string value with variable references, it becomes a number or boolean when it is only one variable
+// reference with a number or boolean value
+type plainString string
+
+// variable reference ${VAR}, ${VAR:-default}, the typed ${VAR:number} or ${VAR:bool} (also with :-default) or an
+// escaped reference $${
+var varRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::(number|bool))?(?::-([^}]*))?\}`)
+
+// decode the file data into a generic value with the decoder selected by the file extension, JSON is the default
+func decode(filename string, data []byte) (any, error) {
+	var value any
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err == nil {
+			value, err = yamlValue(&node)
+		}
+	case ".toml":
+		err = toml.Unmarshal(data, &value)
+	default:
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		err = d.Decode(&value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normalize(value), nil
+}
+
+// convert a YAML node into a generic value like yaml.Unmarshal, but with the unquoted strings that contain variable
+// references as plainString
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(node.Content))
+		for _, n := range node.Content {
+			item, err := yamlValue(n)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case yaml.MappingNode:
+		m := make(map[string]any)
+		var merged []map[string]any
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := yamlValue(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if node.Content[i].ShortTag() == "!!merge" {
+				merged = append(merged, yamlMerged(value)...)
+				continue
+			}
+			m[fmt.Sprint(key)] = value
+		}
+		// the keys of the map itself take precedence over merged keys (<<: *alias)
+		for _, mm := range merged {
+			for key, value := range mm {
+				if _, ok := m[key]; !ok {
+					m[key] = value
+				}
+			}
+		}
+		return m, nil
+	}
+
+	if node.Style == 0 && node.ShortTag() == "!!str" && varRegexp.MatchString(node.Value) {
+		return plainString(node.Value), nil
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalize(value), nil
+}
+
+// the maps of a YAML merge key value, a map or a list of maps
+func yamlMerged(value any) []map[string]any {
+	if m, ok := value.(map[string]any); ok {
+		return []map[string]any{m}
+	}
+	var result []map[string]any
+	list, _ := value.([]any)
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// convert the maps with non string keys (i.e. YAML integer keys) into maps with string keys
+func normalize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	}
+	return value
+}
+
+// load the given file and process its contents
+func (l *loader) load(filename string, vars map[string]string) (any, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range l.files {
+		if f == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(l.files, abs), " -> "))
+		}
+	}
+	l.files = append(l.files, abs)
+	basePath := l.basePath
+	l.basePath = filepath.Dir(filename)
+	defer func() {
+		l.files = l.files[:len(l.files)-1]
+		l.basePath = basePath
+	}()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decode(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return l.process(value, vars)
+}
+
+// load the files of an include directive, the file names are relative to the directory of the including file
+func (l *loader) include(value any, vars map[string]string) ([]any, error) {
+	var names []any
+	switch v := value.(type) {
+	case string:
+		names = []any{v}
+	case []any:
+		names = v
+	default:
+		return nil, fmt.Errorf("include must be a file name or a list of file names")
+	}
+
+	var result []any
+	for _, n := range names {
+		name, err := l.process(n, vars)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("include file name %v is not a string", name)
+		}
+		if !filepath.IsAbs(s) {
+			s = filepath.Join(l.basePath, s)
+		}
+		included, err := l.load(s, vars)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, included)
+	}
+	return result, nil
+}
+
+// process the directives and variables of a generic config value
+func (l *loader) process(value any, vars map[string]string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return l.processMap(v, vars)
+	case []any:
+		return l.processList(v, vars)
+	case string:
+		return substitute(v, vars, false)
+	case plainString:
+		return substitute(string(v), vars, true)
+	}
+	return value, nil
+}
+
+// process a map, the included maps are merged first and the map values are merged into them
+func (l *loader) processMap(m map[string]any, vars map[string]string) (any, error) {
+	result := make(map[string]any)
+	if inc, ok := m[includeKey]; ok {
+		included, err := l.include(inc, vars)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range included {
+			im, ok := i.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("included file must contain a map to include in a map")
+			}
+			merge(result, im)
+		}
+	}
+
+	for key, item := range m {
+		if key == includeKey {
+			continue
+		}
+		processed, err := l.process(item, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		merge(result, map[string]any{key: processed})
+	}
+	return result, nil
+}
+
+// process a list, the items of included lists and loops are added in place
+func (l *loader) processList(list []any, vars map[string]string) (any, error) {
+	result := make([]any, 0, len(list))
+	for i, item := range list {
+		m, _ := item.(map[string]any)
+		var items []any
+		var err error
+		switch {
+		case m != nil && m[forKey] != nil:
+			items, err = l.loop(m, vars)
+		case m != nil && m[includeKey] != nil && len(m) == 1:
+			if items, err = l.include(m[includeKey], vars); err == nil {
+				items = flatten(items)
+			}
+		default:
+			var processed any
+			if processed, err = l.process(item, vars); err == nil {
+				items = []any{processed}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		result = append(result, items...)
+	}
+	return result, nil
+}
+
+// the items of the included lists and the included maps as items
+func flatten(values []any) []any {
+	var result []any
+	for _, v := range values {
+		if list, ok := v.([]any); ok {
+			result = append(result, list...)
+		} else {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// expand a loop directive, the template is processed for every loop value with the loop variable set to that value
+func (l *loader) loop(m map[string]any, vars map[string]string) ([]any, error) {
+	name, ok := m[forKey].(string)
+	if ref := "${" + name + "}"; !ok || strings.Contains(name, ":") || varRegexp.FindString(ref) != ref {
+		return nil, fmt.Errorf("%s: %v is not a valid variable name", forKey, m[forKey])
+	}
+	template, ok := m[templateKey]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: %s missing", forKey, name, templateKey)
+	}
+
+	var values []string
+	switch {
+	case m[inKey] != nil:
+		in, ok := m[inKey].([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s %s: %s must be a list of values", forKey, name, inKey)
+		}
+		for _, v := range in {
+			pv, err := l.process(v, vars)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, fmt.Sprint(pv))
+		}
+	case m[rangeKey] != nil:
+		r, ok := m[rangeKey].([]any)
+		if !ok || len(r) != 2 {
+			return nil, fmt.Errorf("%s %s: %s must be a list with the first and last value", forKey, name, rangeKey)
+		}
+		var bounds [2]int
+		for i, v := range r {
+			pv, err := l.process(v, vars)
+			if err != nil {
+				return nil, err
+			}
+			if bounds[i], err = strconv.Atoi(fmt.Sprint(pv)); err != nil {
+				return nil, fmt.Errorf("%s %s: %s value %v is not an integer", forKey, name, rangeKey, pv)
+			}
+		}
+		for i := bounds[0]; i <= bounds[1]; i++ {
+			values = append(values, strconv.Itoa(i))
+		}
+	default:
+		return nil, fmt.Errorf("%s %s: %s or %s missing", forKey, name, inKey, rangeKey)
+	}
+
+	var result []any
+	for _, value := range values {
+		loopVars := map[string]string{name: value}
+		for k, v := range vars {
+			if k != name {
+				loopVars[k] = v
+			}
+		}
+		item, err := l.process(template, loopVars)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s=%s: %w", forKey, name, value, err)
+		}
+		if list, ok := item.([]any); ok {
+			result = append(result, list...)
+		} else {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// merge the src map into the dst map, maps are merged recursively and other values are replaced
+func merge(dst map[string]any, src map[string]any) {
+	for key, value := range src {
+		dm, dok := dst[key].(map[string]any)
+		sm, sok := value.(map[string]any)
+		if dok && sok {
+			merge(dm, sm)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// substitute the variable references in the string with the loop variable or environment variable values. A string
+// that is only one typed variable reference is converted into a number or boolean, an unquoted (YAML) string that is
+// only one variable reference is converted into a number or boolean if the value is a number or boolean. Other strings
+// stay strings.
+func substitute(s string, vars map[string]string, unquoted bool) (any, error) {
+	loc := varRegexp.FindStringIndex(s)
+	whole := loc != nil && loc[0] == 0 && loc[1] == len(s)
+	var err error
+	var typ string
+	result := varRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		match := varRegexp.FindStringSubmatch(ref)
+		if match[2] != "" {
+			if !whole && err == nil {
+				err = fmt.Errorf("typed variable reference %s must be the whole value", ref)
+			}
+			typ = match[2]
+		}
+		if value, ok := vars[match[1]]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(match[1]); ok {
+			return value
+		}
+		if strings.Contains(ref, ":-") {
+			return match[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("variable %s is not set", match[1])
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !whole || s == "$${" || (typ == "" && !unquoted) {
+		return result, nil
+	}
+	if typ != "number" {
+		if b, err := strconv.ParseBool(result); err == nil && result == strconv.FormatBool(b) {
+			return b, nil
+		}
+	}
+	if typ != "bool" {
+		var n json.Number
+		if json.Unmarshal([]byte(result), &n) == nil {
+			return n, nil
+		}
+	}
+	if typ != "" {
+		return nil, fmt.Errorf("variable reference %s: value %q is not a %s", s, result, typ)
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package schema generates the JSON Schema of configuration structures from their Go types and JSON struct tags, so
+// editors can autocomplete and validate configuration files.
+package schema
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Draft is the JSON Schema version of the generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, only the keywords needed for the configuration structures are supported
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PropertyNames        *Schema            `json:"propertyNames,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false or *Schema
+	Required             []string           `json:"required,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Schemer can be implemented by configuration types with a custom JSON encoding to give their own schema. It is called
+// on a new zero value of the type.
+type Schemer interface {
+	JSONSchema() *Schema
+}
+
+var schemerType = reflect.TypeOf((*Schemer)(nil)).Elem()
+
+// Document returns the schema of the given configuration value as root schema of a configuration file with the given
+// title. A "$schema" key referring to the schema is allowed in the configuration file.
+func Document(v any, title string) *Schema {
+	s := Of(v)
+	s.Schema = Draft
+	s.Title = title
+	if s.Properties != nil {
+		s.Properties["$schema"] = &Schema{Type: "string", Description: "The JSON Schema of this file"}
+	}
+	return s
+}
+
+// Of returns the schema of the given configuration value, i.e. Of(&Config{})
+func Of(v any) *Schema {
+	return ofType(reflect.TypeOf(v))
+}
+
+func ofType(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Implements(schemerType) {
+		return reflect.New(t).Elem().Interface().(Schemer).JSONSchema()
+	}
+	if reflect.PointerTo(t).Implements(schemerType) {
+		return reflect.New(t).Interface().(Schemer).JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return ofType(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Minimum: new(int64)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes bytes as base64 string
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: ofType(t.Elem())}
+	case reflect.Map:
+		s := &Schema{Type: "object", AdditionalProperties: ofType(t.Elem())}
+		switch t.Key().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s.PropertyNames = &Schema{Pattern: "^-?[0-9]+$"}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			s.PropertyNames = &Schema{Pattern: "^[0-9]+$"}
+		}
+		return s
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		addFields(s, t)
+		return s
+	}
+
+	// interfaces and other kinds accept any value
+	return &Schema{}
+}
+
+// add the properties of the exported struct fields the way encoding/json decodes them
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// the fields of an untagged embedded struct are promoted, also when the struct type is unexported
+			addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := s.Properties[name]; !ok {
+			s.Properties[name] = ofType(f.Type)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mode string
+
+func (m mode) JSONSchema() *Schema {
+	return &Schema{Enum: []any{"fast", "slow"}}
+}
+
+type auto struct {
+	Value int
+}
+
+func (a *auto) JSONSchema() *Schema {
+	return &Schema{OneOf: []*Schema{{Type: "integer"}, {Enum: []any{"auto"}}}}
+}
+
+type base struct {
+	Name string `json:"name"`
+}
+
+type inner struct {
+	Size uint16 `json:"size"`
+}
+
+type testConfig struct {
+	*base
+	hidden   int
+	Skipped  string            `json:"-"`
+	Inner    *inner            `json:"inner,omitempty"`
+	Pages    map[int]uint64    `json:"pages"`
+	Users    map[string]string `json:"users"`
+	List     []float64         `json:"list"`
+	Data     []byte            `json:"data"`
+	Mode     mode              `json:"mode"`
+	Auto     auto              `json:"auto"`
+	Any      any               `json:"any"`
+	Untagged bool
+}
+
+func TestOf(t *testing.T) {
+	s := Of(&testConfig{})
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, false, s.AdditionalProperties)
+
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"name", "inner", "pages", "users", "list", "data", "mode", "auto", "any", "Untagged"},
+		names)
+
+	zero := int64(0)
+	assert.Equal(t, &Schema{Type: "string"}, s.Properties["name"])
+	assert.Equal(t, &Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{"size": {Type: "integer", Minimum: &zero}},
+		AdditionalProperties: false,
+	}, s.Properties["inner"])
+	assert.Equal(t, &Schema{
+		Type:                 "object",
+		PropertyNames:        &Schema{Pattern: "^-?[0-9]+$"},
+		AdditionalProperties: &Schema{Type: "integer", Minimum: &zero},
+	}, s.Properties["pages"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, s.Properties["users"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "number"}}, s.Properties["list"])
+	assert.Equal(t, &Schema{Type: "string"}, s.Properties["data"])
+	assert.Equal(t, &Schema{Enum: []any{"fast", "slow"}}, s.Properties["mode"])
+	assert.Equal(t, (&auto{}).JSONSchema(), s.Properties["auto"])
+	assert.Equal(t, &Schema{}, s.Properties["any"])
+	assert.Equal(t, &Schema{Type: "boolean"}, s.Properties["Untagged"])
+}
+
+func TestDocument(t *testing.T) {
+	s := Document(&inner{}, "Test configuration")
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "Test configuration", s.Title)
+	assert.Contains(t, s.Properties, "$schema")
+
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Test configuration",
+		"type": "object",
+		"properties": {
+			"$schema": {"type": "string", "description": "The JSON Schema of this file"},
+			"size": {"type": "integer", "minimum": 0}
+		},
+		"additionalProperties": false
+	}`, string(data))
+}
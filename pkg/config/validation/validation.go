@@ -0,0 +1,88 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package validation collects the errors found when validating a configuration, every error with the JSON path of the
+// faulty configuration value (i.e. chassis.pipelines[0].inputports[2].ifacename).
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a configuration error at a JSON path
+type Error struct {
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects configuration errors, the zero value is ready to use
+type Errors struct {
+	list []*Error
+}
+
+// Add adds the error found at the given JSON path
+func (e *Errors) Add(path string, err error) {
+	e.list = append(e.list, &Error{Path: path, Err: err})
+}
+
+// Addf adds an error formatted with fmt.Errorf found at the given JSON path
+func (e *Errors) Addf(path string, format string, args ...any) {
+	e.Add(path, fmt.Errorf(format, args...))
+}
+
+// List returns the collected errors in the order they were added
+func (e *Errors) List() []*Error {
+	return e.list
+}
+
+// Err returns nil if no errors are collected, otherwise the collected errors
+func (e *Errors) Err() error {
+	if len(e.list) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *Errors) Error() string {
+	lines := make([]string, 0, len(e.list)+1)
+	if len(e.list) == 1 {
+		lines = append(lines, "1 configuration error:")
+	} else {
+		lines = append(lines, fmt.Sprintf("%d configuration errors:", len(e.list)))
+	}
+	for _, err := range e.list {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Path returns the JSON path of an element below the given path. String elements are appended as object keys and
+// integer elements as array indexes, i.e. Path("chassis", "pipelines", 0, "spec") returns "chassis.pipelines[0].spec".
+func Path(path string, elems ...any) string {
+	var sb strings.Builder
+	sb.WriteString(path)
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case int:
+			fmt.Fprintf(&sb, "[%d]", e)
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			fmt.Fprint(&sb, e)
+		}
+	}
+	return sb.String()
+}
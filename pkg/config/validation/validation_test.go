@@ -0,0 +1,33 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "chassis.pipelines[0].spec", Path("chassis", "pipelines", 0, "spec"))
+	assert.Equal(t, "pktmbufs[1]", Path("", "pktmbufs", 1))
+	assert.Equal(t, "chassis", Path("chassis"))
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	assert.NoError(t, errs.Err())
+
+	notFound := errors.New("not found")
+	errs.Add("chassis.pktmbufs[0].name", errors.New("name missing"))
+	errs.Addf("chassis.interfaces[1].tap.rx.pktmbuf", "pktmbuf %s: %w", "MEMPOOL1", notFound)
+	err := errs.Err()
+	assert.Error(t, err)
+	assert.Len(t, errs.List(), 2)
+	assert.True(t, errors.Is(errs.List()[1], notFound))
+	assert.Equal(t, "2 configuration errors:\n"+
+		"  chassis.pktmbufs[0].name: name missing\n"+
+		"  chassis.interfaces[1].tap.rx.pktmbuf: pktmbuf MEMPOOL1: not found", err.Error())
+}
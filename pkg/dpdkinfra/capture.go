@@ -0,0 +1,488 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package dpdkinfra
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+	"github.com/stolsma/go-p4pack/pkg/pcap/filter"
+	"github.com/stolsma/go-p4pack/pkg/pcapng"
+	"golang.org/x/net/bpf"
+)
+
+const (
+	captureDefaultSnapLen = 2048
+	captureBufferSize     = 4096
+	capturePollInterval   = 10 * time.Millisecond
+	captureWriteBuffer    = 64 * 1024
+	captureMaxReads       = 64 // maximum number of buffer reads per poll interval
+)
+
+// CaptureParams defines what to capture and when to stop
+type CaptureParams struct {
+	Target   string        // interface name or pipeline port (<pipeline>:<port id>)
+	Rx       bool          // capture received packets (pipeline input ports), the default without direction
+	Tx       bool          // capture transmitted packets (pipeline output ports)
+	FileName string        // pcapng file to write to
+	Filter   string        // pcap filter expression, empty captures all packets
+	SnapLen  uint32        // maximum number of bytes captured per packet, 0 is default (2048)
+	Count    uint64        // stop after the given number of packets, 0 is no limit
+	Size     int64         // stop when the file reaches the given size in bytes, 0 is no limit
+	Duration time.Duration // stop after the given duration, 0 is no limit
+}
+
+// a captured pipeline port
+type capturePort struct {
+	pl      *pipeline.Pipeline
+	dir     int
+	portID  int
+	name    string
+	ifaceID uint32
+}
+
+func (cp *capturePort) String() string {
+	dir := "in"
+	if cp.dir == pipeline.PortOut {
+		dir = "out"
+	}
+	return fmt.Sprintf("%s:%s:%d", cp.pl.GetName(), dir, cp.portID)
+}
+
+// CaptureSession is a running or finished packet capture
+type CaptureSession struct {
+	id      uint32
+	params  CaptureParams
+	ports   []*capturePort
+	capture *pipeline.Capture
+	start   time.Time
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu      sync.Mutex
+	packets uint64
+	bytes   int64
+	dropped uint64
+	reason  string
+	err     error
+}
+
+type captures struct {
+	mu       sync.Mutex
+	nextID   uint32
+	sessions map[uint32]*CaptureSession
+}
+
+// ID returns the identifier of the capture session
+func (cs *CaptureSession) ID() uint32 {
+	return cs.id
+}
+
+// Params returns the parameters the capture session is started with
+func (cs *CaptureSession) Params() CaptureParams {
+	return cs.params
+}
+
+// Ports returns the captured pipeline ports (<pipeline>:<in|out>:<port id>)
+func (cs *CaptureSession) Ports() []string {
+	var ports []string
+	for _, cp := range cs.ports {
+		ports = append(ports, cp.String())
+	}
+	return ports
+}
+
+// Start returns the time the capture session started
+func (cs *CaptureSession) Start() time.Time {
+	return cs.start
+}
+
+// Running returns true if the capture session has not stopped yet
+func (cs *CaptureSession) Running() bool {
+	select {
+	case <-cs.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stats returns the number of written packets and bytes, and the number of packets dropped because the capture buffer
+// was full
+func (cs *CaptureSession) Stats() (packets uint64, bytes int64, dropped uint64) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	dropped = cs.dropped
+	if cs.capture != nil {
+		dropped = cs.capture.Dropped()
+	}
+	return cs.packets, cs.bytes, dropped
+}
+
+// Result returns the reason the capture stopped and the error if the capture stopped because of an error
+func (cs *CaptureSession) Result() (string, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.reason, cs.err
+}
+
+// Wait waits until the capture session is stopped
+func (cs *CaptureSession) Wait() {
+	<-cs.done
+}
+
+// resolve the capture target into the pipeline ports to capture
+func (di *DpdkInfra) capturePorts(params *CaptureParams) ([]*capturePort, error) {
+	var ports []*capturePort
+
+	// find the name of the port bound to the given pipeline port
+	portName := func(plName string, dir int, portID int) string {
+		name := ""
+		di.IteratePorts(func(key string, port portmngr.PortType) error {
+			find := func(index uint16, q device.Queue) error {
+				if q.Pipeline() == plName && q.PipelinePort() == portID {
+					name = port.Name()
+				}
+				return nil
+			}
+			if dir == pipeline.PortIn {
+				port.IterateRxQueues(find)
+			} else {
+				port.IterateTxQueues(find)
+			}
+			return nil
+		})
+		return name
+	}
+
+	add := func(plName string, dir int, portID int, name string) error {
+		pl := di.PipelineStore.Get(plName)
+		if pl == nil {
+			return fmt.Errorf("pipeline %s doesn't exist", plName)
+		}
+		ports = append(ports, &capturePort{pl: pl, dir: dir, portID: portID, name: name})
+		return nil
+	}
+
+	if port := di.GetPort(params.Target); port != nil {
+		// interface, capture all pipeline ports bound to its queues (a port without rx or tx queues returns an error)
+		var err error
+		bound := func(dir int) func(index uint16, q device.Queue) error {
+			return func(index uint16, q device.Queue) error {
+				if q.PipelinePort() == device.NotBound || err != nil {
+					return nil
+				}
+				err = add(q.Pipeline(), dir, q.PipelinePort(), port.Name())
+				return nil
+			}
+		}
+		if params.Rx {
+			port.IterateRxQueues(bound(pipeline.PortIn))
+		}
+		if params.Tx {
+			port.IterateTxQueues(bound(pipeline.PortOut))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(ports) == 0 {
+			return nil, fmt.Errorf("interface %s is not bound to a pipeline", params.Target)
+		}
+		return ports, nil
+	}
+
+	// pipeline port
+	i := strings.LastIndex(params.Target, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("%s is not an interface or pipeline port", params.Target)
+	}
+	plName := params.Target[:i]
+	portID, err := strconv.ParseUint(params.Target[i+1:], 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline port id (%s) is not a correct integer", params.Target[i+1:])
+	}
+	if params.Rx {
+		if err := add(plName, pipeline.PortIn, int(portID), portName(plName, pipeline.PortIn, int(portID))); err != nil {
+			return nil, err
+		}
+	}
+	if params.Tx {
+		if err := add(plName, pipeline.PortOut, int(portID), portName(plName, pipeline.PortOut, int(portID))); err != nil {
+			return nil, err
+		}
+	}
+	return ports, nil
+}
+
+// CaptureStart starts capturing the packets of an interface or pipeline port into a pcapng file. The capture runs
+// until it is stopped or one of the stop conditions (count, size or duration) is reached. When no direction is given
+// the received packets are captured.
+func (di *DpdkInfra) CaptureStart(params *CaptureParams) (*CaptureSession, error) {
+	if !params.Rx && !params.Tx {
+		params.Rx = true
+	}
+	if params.SnapLen == 0 {
+		params.SnapLen = captureDefaultSnapLen
+	}
+
+	ports, err := di.capturePorts(params)
+	if err != nil {
+		return nil, err
+	}
+
+	di.captures.mu.Lock()
+	defer di.captures.mu.Unlock()
+	id := di.captures.nextID
+
+	// create capture buffer and file
+	var program []bpf.RawInstruction
+	if params.Filter != "" {
+		if program, err = filter.Compile(params.Filter, params.SnapLen); err != nil {
+			return nil, err
+		}
+	}
+	capture, err := pipeline.NewCapture(fmt.Sprintf("CAP%d", id), captureBufferSize, params.SnapLen, program, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(params.FileName)
+	if err != nil {
+		capture.Free()
+		return nil, err
+	}
+	buf := bufio.NewWriterSize(file, captureWriteBuffer)
+
+	cs := &CaptureSession{
+		id:      id,
+		params:  *params,
+		capture: capture,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	// write the pcapng header with an interface description per captured pipeline port
+	cleanup := func(err error) (*CaptureSession, error) {
+		cs.detach()
+		capture.Free()
+		file.Close()
+		os.Remove(params.FileName)
+		return nil, err
+	}
+	comment := ""
+	if params.Filter != "" {
+		comment = "filter: " + params.Filter
+	}
+	pw, err := pcapng.NewWriter(buf, "go-p4pack", comment)
+	if err != nil {
+		return cleanup(err)
+	}
+	for _, cp := range ports {
+		name := cp.name
+		if name == "" {
+			name = cp.String()
+		}
+		cp.ifaceID, err = pw.AddInterface(pcapng.Interface{
+			Name:        name,
+			Description: cp.String(),
+			LinkType:    pcapng.LinkTypeEthernet,
+			SnapLen:     params.SnapLen,
+		})
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+
+	// and start capturing
+	for _, cp := range ports {
+		if err := cp.pl.CaptureAttach(cp.dir, cp.portID, capture, cp.ifaceID); err != nil {
+			return cleanup(fmt.Errorf("%s: %w", cp.String(), err))
+		}
+		cs.ports = append(cs.ports, cp)
+	}
+
+	cs.start = time.Now()
+	di.captures.nextID++
+	di.captures.sessions[id] = cs
+	go cs.run(pw, buf, file)
+
+	log.Infof("capture %d started on %s (%s) to %s", id, params.Target, strings.Join(cs.Ports(), " "), params.FileName)
+	return cs, nil
+}
+
+// detach the capture buffer from all attached pipeline ports
+func (cs *CaptureSession) detach() {
+	for _, cp := range cs.ports {
+		cp.pl.CaptureDetach(cp.dir, cp.portID)
+	}
+}
+
+// write the captured packets to the file until stopped
+func (cs *CaptureSession) run(pw *pcapng.Writer, buf *bufio.Writer, file *os.File) {
+	var err error
+	var timeout <-chan time.Time
+	var reason string // the reason the capture stopped
+	var limit string  // set when the count or size limit is reached
+
+	ifaceDir := make(map[uint32]pcapng.Direction)
+	for _, cp := range cs.ports {
+		ifaceDir[cp.ifaceID] = pcapng.DirectionInbound
+		if cp.dir == pipeline.PortOut {
+			ifaceDir[cp.ifaceID] = pcapng.DirectionOutbound
+		}
+	}
+
+	if cs.params.Duration > 0 {
+		timer := time.NewTimer(cs.params.Duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	ticker := time.NewTicker(capturePollInterval)
+	defer ticker.Stop()
+
+	write := func(ifIndex uint32, ts time.Time, origLen uint32, data []byte) {
+		if err != nil || limit != "" {
+			return
+		}
+		if err = pw.WritePacket(ifIndex, ts, origLen, data, ifaceDir[ifIndex]); err != nil {
+			return
+		}
+
+		cs.mu.Lock()
+		cs.packets++
+		cs.bytes = pw.Size()
+		cs.mu.Unlock()
+
+		if cs.params.Count > 0 && cs.packets >= cs.params.Count {
+			limit = "count reached"
+		} else if cs.params.Size > 0 && pw.Size() >= cs.params.Size {
+			limit = "size reached"
+		}
+	}
+	read := func() {
+		for i := 0; i < captureMaxReads && limit == "" && err == nil; i++ {
+			if cs.capture.Read(write) == 0 {
+				break
+			}
+		}
+	}
+
+	// read the capture buffer until stopped or a stop condition is reached
+	for reason == "" {
+		select {
+		case <-cs.stop:
+			reason = "stopped"
+		case <-timeout:
+			reason = "duration reached"
+		case <-ticker.C:
+			read()
+			if err == nil {
+				err = buf.Flush()
+			}
+			if limit != "" {
+				reason = limit
+			} else if err != nil {
+				reason = "error"
+			}
+		}
+	}
+
+	// stop capturing and write the packets still in the capture buffer
+	cs.detach()
+	read()
+	if flushErr := buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	cs.mu.Lock()
+	cs.reason = reason
+	cs.err = err
+	cs.bytes = pw.Size()
+	cs.dropped = cs.capture.Dropped()
+	cs.capture.Free()
+	cs.capture = nil
+	cs.mu.Unlock()
+
+	log.Infof("capture %d stopped (%s): %d packets written, %d dropped, err: %v", cs.id, reason, cs.packets, cs.dropped,
+		err)
+	close(cs.done)
+}
+
+// CaptureStop stops the capture session with the given id and waits until the capture file is closed
+func (di *DpdkInfra) CaptureStop(id uint32) (*CaptureSession, error) {
+	di.captures.mu.Lock()
+	cs, ok := di.captures.sessions[id]
+	if ok {
+		delete(di.captures.sessions, id)
+	}
+	di.captures.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("capture %d doesn't exist", id)
+	}
+
+	if cs.Running() {
+		close(cs.stop)
+	}
+	cs.Wait()
+	return cs, nil
+}
+
+// CaptureGet returns the capture session with the given id
+func (di *DpdkInfra) CaptureGet(id uint32) (*CaptureSession, error) {
+	di.captures.mu.Lock()
+	defer di.captures.mu.Unlock()
+
+	cs, ok := di.captures.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("capture %d doesn't exist", id)
+	}
+	return cs, nil
+}
+
+// CaptureList returns the running and finished (but not yet stopped) capture sessions sorted by id
+func (di *DpdkInfra) CaptureList() []*CaptureSession {
+	di.captures.mu.Lock()
+	defer di.captures.mu.Unlock()
+
+	var list []*CaptureSession
+	for _, cs := range di.captures.sessions {
+		list = append(list, cs)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].id < list[j].id
+	})
+	return list
+}
+
+// stop all capture sessions capturing ports of the given pipeline
+func (di *DpdkInfra) captureStopPipeline(pl *pipeline.Pipeline) {
+	for _, cs := range di.CaptureList() {
+		for _, cp := range cs.ports {
+			if cp.pl == pl {
+				di.CaptureStop(cs.id)
+				break
+			}
+		}
+	}
+}
+
+// stop all capture sessions
+func (di *DpdkInfra) captureCleanup() {
+	for _, cs := range di.CaptureList() {
+		di.CaptureStop(cs.id)
+	}
+}
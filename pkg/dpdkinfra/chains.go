@@ -0,0 +1,54 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package dpdkinfra
+
+import (
+	"sort"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+)
+
+// PipelineLink is an output port of a pipeline linked to an input port of a pipeline through a ring interface, i.e. a
+// ring created for a chain
+type PipelineLink struct {
+	From string // sending pipeline
+	Out  int    // output port of the sending pipeline
+	To   string // receiving pipeline
+	In   int    // input port of the receiving pipeline
+	Ring string // ring interface linking the ports
+}
+
+// PipelineLinks returns the links between the pipelines, i.e. the rings with the tx queue bound to a pipeline output
+// port and the rx queue bound to a pipeline input port, sorted by sending pipeline and port
+func (di *DpdkInfra) PipelineLinks() []PipelineLink {
+	var links []PipelineLink
+	di.IteratePorts(func(name string, port portmngr.PortType) error {
+		if di.GetPortType(name) != portmngr.PortTypeRing {
+			return nil
+		}
+
+		link := PipelineLink{Out: device.NotBound, In: device.NotBound, Ring: name}
+		port.IterateTxQueues(func(index uint16, q device.Queue) error {
+			link.From, link.Out = q.Pipeline(), q.PipelinePort()
+			return nil
+		})
+		port.IterateRxQueues(func(index uint16, q device.Queue) error {
+			link.To, link.In = q.Pipeline(), q.PipelinePort()
+			return nil
+		})
+		if link.Out != device.NotBound && link.In != device.NotBound {
+			links = append(links, link)
+		}
+		return nil
+	})
+
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].From != links[j].From {
+			return links[i].From < links[j].From
+		}
+		return links[i].Out < links[j].Out
+	})
+	return links
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+)
+
+func CaptureCmd(parents ...*cobra.Command) *cobra.Command {
+	captureCmd := &cobra.Command{
+		Use:     "capture",
+		Short:   "Base command for all packet capture actions",
+		Aliases: []string{"cap"},
+	}
+
+	CaptureStartCmd(captureCmd)
+	CaptureStopCmd(captureCmd)
+	CaptureListCmd(captureCmd)
+	return cli.AddCommand(parents, captureCmd)
+}
+
+func CaptureStartCmd(parents ...*cobra.Command) *cobra.Command {
+	var size int64
+	var duration time.Duration
+	var snaplen uint32
+	var wait bool
+
+	startCmd := &cobra.Command{
+		Use:   "start [interface|pipeline:port] [rx|tx|both] [file] [count] [filter...]",
+		Short: "Start capturing the packets of an interface or pipeline port into a pcapng file",
+		Long: `Start capturing the packets of an interface or pipeline port (<pipeline>:<port id>) into a pcapng file. When
+no direction is given the received packets (pipeline input ports) are captured. The capture stops after count packets
+(0/none is no limit), the given size or duration, or with the 'capture stop' command. The remaining arguments form a
+pcap filter expression (i.e. 'udp port 53').`,
+		Args: cobra.MinimumNArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCaptureTargetArg,
+			completeCaptureDirectionArg,
+			cli.AppendHelp("You must specify the pcapng file to write the captured packets to"),
+			cli.AppendHelp("You can specify the maximum number of packets to capture (0/none is no limit)"),
+			cli.AppendHelp("You can specify a pcap filter expression"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			params := dpdkinfra.CaptureParams{
+				Target:   args[0],
+				Size:     size,
+				Duration: duration,
+				SnapLen:  snaplen,
+			}
+			args = args[1:]
+
+			// get direction if available
+			switch args[0] {
+			case "rx":
+				params.Rx = true
+				args = args[1:]
+			case "tx":
+				params.Tx = true
+				args = args[1:]
+			case "both":
+				params.Rx, params.Tx = true, true
+				args = args[1:]
+			}
+
+			// get file name
+			if len(args) == 0 {
+				cmd.PrintErrf("No capture file given!\n")
+				return
+			}
+			params.FileName, args = args[0], args[1:]
+
+			// get count if available and the filter
+			if len(args) > 0 {
+				if count, err := strconv.ParseUint(args[0], 0, 64); err == nil {
+					params.Count, args = count, args[1:]
+				}
+			}
+			params.Filter = strings.Join(args, " ")
+
+			cs, err := dpdki.CaptureStart(&params)
+			if err != nil {
+				cmd.PrintErrf("Capture start err: %v\n", err)
+				return
+			}
+			cmd.Printf("Capture %d started on %s\n", cs.ID(), strings.Join(cs.Ports(), ", "))
+
+			if wait {
+				cs.Wait()
+				printCaptureResult(cmd, cs)
+			}
+		},
+	}
+
+	startCmd.Flags().Int64VarP(&size, "size", "s", 0, "Stop when the capture file reaches the given size in bytes.")
+	startCmd.Flags().DurationVarP(&duration, "duration", "d", 0, "Stop after the given duration (i.e. 30s or 5m).")
+	startCmd.Flags().Uint32Var(&snaplen, "snaplen", 0, "Maximum number of bytes captured per packet (default 2048).")
+	startCmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait until the capture is stopped.")
+	return cli.AddCommand(parents, startCmd)
+}
+
+func CaptureStopCmd(parents ...*cobra.Command) *cobra.Command {
+	stopCmd := &cobra.Command{
+		Use:   "stop [id]",
+		Short: "Stop a packet capture and remove it from the capture list",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCaptureIDArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			id, err := strconv.ParseUint(args[0], 0, 32)
+			if err != nil {
+				cmd.PrintErrf("Capture id (%s) is not a correct integer: %v\n", args[0], err)
+				return
+			}
+
+			cs, err := dpdki.CaptureStop(uint32(id))
+			if err != nil {
+				cmd.PrintErrf("Capture stop err: %v\n", err)
+				return
+			}
+			printCaptureResult(cmd, cs)
+		},
+	}
+
+	return cli.AddCommand(parents, stopCmd)
+}
+
+func CaptureListCmd(parents ...*cobra.Command) *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List all packet captures",
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			list := dpdki.CaptureList()
+			if len(list) == 0 {
+				cmd.Printf("No captures\n")
+				return
+			}
+
+			for _, cs := range list {
+				params := cs.Params()
+				packets, bytes, dropped := cs.Stats()
+				state := "running"
+				if !cs.Running() {
+					reason, err := cs.Result()
+					state = "stopped (" + reason + ")"
+					if err != nil {
+						state = fmt.Sprintf("stopped (%s: %v)", reason, err)
+					}
+				}
+				cmd.Printf("%d: %s -> %s\n", cs.ID(), params.Target, params.FileName)
+				cmd.Printf("  ports   : %s\n", strings.Join(cs.Ports(), ", "))
+				if params.Filter != "" {
+					cmd.Printf("  filter  : %s\n", params.Filter)
+				}
+				cmd.Printf("  state   : %s, started %s\n", state, cs.Start().Format(time.RFC3339))
+				cmd.Printf("  packets : %d, bytes: %d, dropped: %d\n", packets, bytes, dropped)
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, listCmd)
+}
+
+func printCaptureResult(cmd *cobra.Command, cs *dpdkinfra.CaptureSession) {
+	packets, bytes, dropped := cs.Stats()
+	reason, err := cs.Result()
+	if err != nil {
+		cmd.PrintErrf("Capture %d stopped (%s) with err: %v\n", cs.ID(), reason, err)
+	}
+	cmd.Printf("Capture %d stopped (%s): %d packets (%d bytes) written to %s, %d dropped\n",
+		cs.ID(), reason, packets, bytes, cs.Params().FileName, dropped)
+}
+
+// complete an interface or pipeline port argument
+func completeCaptureTargetArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	list := portList(BoundPorts)
+	list = append(list, pipelineList(AllPipelines)...)
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No interfaces or pipelines available for completion!")
+	return completions, directive
+}
+
+// complete the capture direction argument
+func completeCaptureDirectionArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	completions := cli.FilterCompletions([]string{"rx", "tx", "both"}, toComplete, &directive, "")
+	return completions, directive
+}
+
+// complete a capture id argument
+func completeCaptureIDArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+	list := []string{}
+
+	for _, cs := range dpdkinfra.Get().CaptureList() {
+		list = append(list, strconv.FormatUint(uint64(cs.ID()), 10))
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No captures available for completion!")
+	return completions, directive
+}
@@ -20,8 +20,18 @@ import (
 func completePktmbufArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	var directive = cobra.ShellCompDirectiveNoFileComp // | cobra.ShellCompDirectiveNoSpace
 
-	// get pktmbuf list
+	// get pktmbuf list, with the names of the automatically placed pktmbufs
 	listPktmbuf := pktmbufList()
+	autoNames := make(map[string]bool)
+	for _, name := range listPktmbuf {
+		if autoName, ok := dpdkinfra.Get().PktmbufAutoName(name); ok {
+			autoNames[autoName] = true
+		}
+	}
+	for name := range autoNames {
+		listPktmbuf = append(listPktmbuf, name)
+	}
+	sort.Strings(listPktmbuf)
 
 	// filter list with string to complete
 	completions := cli.FilterCompletions(listPktmbuf, toComplete, &directive, "No Pktmbufs available for completion!")
@@ -145,6 +155,19 @@ func completeUnusedEthdevPortList(cmd *cobra.Command, args []string, toComplete
 	return completions, directive
 }
 
+// handle completion of sorted list of created ethdev port names
+func completeCreatedEthdevPortList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp // | cobra.ShellCompDirectiveNoSpace
+
+	// get port list
+	portList := ethdevPortList(CreatedEthdevPorts)
+
+	// filter list with string to complete
+	completions := cli.FilterCompletions(portList, toComplete, &directive, "No Ports available for completion!")
+
+	return completions, directive
+}
+
 // get sorted list of ethdev port names filtered by given filteroption
 func ethdevPortList(filter EthdevPortFilter) []string {
 	var ports []*ethdev.Ethdev
@@ -21,6 +21,11 @@ func InterfaceCmd(parents ...*cobra.Command) *cobra.Command {
 	InterfaceShowCmd(interfaceCmd)
 	InterfaceStatsCmd(interfaceCmd)
 	InterfaceLinkUpDownCmd(interfaceCmd)
+	InterfaceConfigCmd(interfaceCmd)
+	InterfaceRssCmd(interfaceCmd)
+	InterfaceFlowCmd(interfaceCmd)
+	InterfaceEventsCmd(interfaceCmd)
+	InterfaceSinkCmd(interfaceCmd)
 	return cli.AddCommand(parents, interfaceCmd)
 }
 
@@ -0,0 +1,260 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+)
+
+func InterfaceConfigCmd(parents ...*cobra.Command) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Base command for all interface runtime configuration actions",
+		Aliases: []string{"cfg"},
+	}
+
+	InterfaceConfigMtuCmd(configCmd)
+	InterfaceConfigMacCmd(configCmd)
+	InterfaceConfigPromiscuousCmd(configCmd)
+	InterfaceConfigAllmulticastCmd(configCmd)
+	InterfaceConfigQueuesCmd(configCmd)
+	return cli.AddCommand(parents, configCmd)
+}
+
+// get the created ethdev port with the given name, prints an error and returns nil if it doesn't exist
+func getEthdev(cmd *cobra.Command, name string) *ethdev.Ethdev {
+	dpdki := dpdkinfra.Get()
+
+	port := dpdki.EthdevStore.Get(name)
+	if port == nil {
+		cmd.PrintErrf("Ethdev interface %s does not exist!\n", name)
+	}
+
+	return port
+}
+
+// parse on/off argument string
+func parseOnOff(cmd *cobra.Command, arg string) (bool, bool) {
+	switch strings.ToLower(arg) {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	default:
+		cmd.PrintErrf("Use on or off and not %v !\n", arg)
+		return false, false
+	}
+}
+
+func InterfaceConfigMtuCmd(parents ...*cobra.Command) *cobra.Command {
+	mtuCmd := &cobra.Command{
+		Use:   "mtu [name] [mtu]",
+		Short: "Change the MTU of an ethdev interface",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("You must specify the new MTU for the interface"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			mtu, err := strconv.ParseUint(args[1], 0, 16)
+			if err != nil {
+				cmd.PrintErrf("MTU (%s) is not a correct integer: %v\n", args[1], err)
+				return
+			}
+
+			if err := port.SetMTU(uint16(mtu)); err != nil {
+				cmd.PrintErrf("Interface %s set MTU err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s MTU changed to: %d\n", args[0], mtu)
+		},
+	}
+
+	return cli.AddCommand(parents, mtuCmd)
+}
+
+func InterfaceConfigMacCmd(parents ...*cobra.Command) *cobra.Command {
+	var add bool
+	macCmd := &cobra.Command{
+		Use:   "mac [name] [macaddr]",
+		Short: "Set the default MAC address of an ethdev interface or add a secondary MAC address",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("You must specify the MAC address (i.e. 00:11:22:33:44:55)"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			addr, err := net.ParseMAC(args[1])
+			if err != nil {
+				cmd.PrintErrf("MAC address (%s) is not correct: %v\n", args[1], err)
+				return
+			}
+
+			if add {
+				err = port.AddMACAddr(addr)
+			} else {
+				err = port.SetMACAddr(addr)
+			}
+			if err != nil {
+				cmd.PrintErrf("Interface %s set MAC address err: %v\n", args[0], err)
+				return
+			}
+
+			if add {
+				cmd.Printf("MAC address %s added to interface %s\n", addr, args[0])
+			} else {
+				cmd.Printf("Interface %s MAC address changed to: %s\n", args[0], addr)
+			}
+		},
+	}
+	macCmd.Flags().BoolVarP(&add, "add", "a", false, "Add the MAC address as secondary address.")
+
+	return cli.AddCommand(parents, macCmd)
+}
+
+func InterfaceConfigPromiscuousCmd(parents ...*cobra.Command) *cobra.Command {
+	promCmd := &cobra.Command{
+		Use:     "promiscuous [name] [on/off]",
+		Short:   "Set promiscuous mode of an ethdev interface on or off",
+		Aliases: []string{"prom"},
+		Args:    cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("Set promiscuous mode on or off (on/off)"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			on, ok := parseOnOff(cmd, args[1])
+			if !ok {
+				return
+			}
+
+			if err := port.SetPromiscuous(on); err != nil {
+				cmd.PrintErrf("Interface %s set promiscuous mode err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s promiscuous mode changed to: %s\n", args[0], args[1])
+		},
+	}
+
+	return cli.AddCommand(parents, promCmd)
+}
+
+func InterfaceConfigAllmulticastCmd(parents ...*cobra.Command) *cobra.Command {
+	allmultiCmd := &cobra.Command{
+		Use:     "allmulticast [name] [on/off]",
+		Short:   "Set allmulticast mode of an ethdev interface on or off",
+		Aliases: []string{"allmulti"},
+		Args:    cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("Set allmulticast mode on or off (on/off)"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			on, ok := parseOnOff(cmd, args[1])
+			if !ok {
+				return
+			}
+
+			if err := port.SetAllmulticast(on); err != nil {
+				cmd.PrintErrf("Interface %s set allmulticast mode err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s allmulticast mode changed to: %s\n", args[0], args[1])
+		},
+	}
+
+	return cli.AddCommand(parents, allmultiCmd)
+}
+
+func InterfaceConfigQueuesCmd(parents ...*cobra.Command) *cobra.Command {
+	queuesCmd := &cobra.Command{
+		Use:   "queues [name] [# rx queues] [rx queuesize] [# tx queues] [tx queuesize]",
+		Short: "Reconfigure the queues of an ethdev interface (the port is stopped and restarted!)",
+		Args:  cobra.ExactArgs(5),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("You must specify the number of receive queues"),
+			cli.AppendHelp("You must specify the receive queuesize"),
+			cli.AppendHelp("You must specify the number of transmit queues"),
+			cli.AppendHelp("You must specify the transmit queuesize"),
+			cli.AppendLastHelp(5, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			nrxq, err := strconv.ParseUint(args[1], 0, 16)
+			if err != nil {
+				cmd.PrintErrf("# RX Queues (%s) is not a correct integer: %v\n", args[1], err)
+				return
+			}
+
+			rxqsize, err := strconv.ParseUint(args[2], 0, 32)
+			if err != nil {
+				cmd.PrintErrf("RX Queuesize (%s) is not a correct integer: %v\n", args[2], err)
+				return
+			}
+
+			ntxq, err := strconv.ParseUint(args[3], 0, 16)
+			if err != nil {
+				cmd.PrintErrf("# TX Queues (%s) is not a correct integer: %v\n", args[3], err)
+				return
+			}
+
+			txqsize, err := strconv.ParseUint(args[4], 0, 32)
+			if err != nil {
+				cmd.PrintErrf("TX Queuesize (%s) is not a correct integer: %v\n", args[4], err)
+				return
+			}
+
+			err = dpdki.EthdevReconfigureQueues(args[0], uint16(nrxq), uint32(rxqsize), uint16(ntxq), uint32(txqsize))
+			if err != nil {
+				cmd.PrintErrf("Interface %s reconfigure queues err: %v\n", args[0], err)
+				return
+			}
+
+			// a failed reconfiguration is rolled back to the previous queues
+			params := dpdki.EthdevStore.Get(args[0]).Params()
+			if params.Rx.NQueues != uint16(nrxq) || params.Rx.QueueSize != uint32(rxqsize) ||
+				params.Tx.NQueues != uint16(ntxq) || params.Tx.QueueSize != uint32(txqsize) {
+				cmd.PrintErrf("Interface %s queues not reconfigured, previous queues restored (rx: %d/%d tx: %d/%d)\n",
+					args[0], params.Rx.NQueues, params.Rx.QueueSize, params.Tx.NQueues, params.Tx.QueueSize)
+				return
+			}
+			cmd.Printf("Interface %s queues reconfigured!\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, queuesCmd)
+}
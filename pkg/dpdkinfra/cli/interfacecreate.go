@@ -10,10 +10,33 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/stolsma/go-p4pack/pkg/cli"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/placement"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/tap"
 )
 
+// InterfaceCreateTypeCmd creates the `interface create <port type>` command of a port type, including the completion of
+// its arguments
+type InterfaceCreateTypeCmd func(parents ...*cobra.Command) *cobra.Command
+
+var interfaceCreateCmds = make(map[string]InterfaceCreateTypeCmd)
+
+// RegisterInterfaceCreateCmd registers the create command of a PortMngr port type. It is intended to be called from an
+// init function and panics if a create command for the port type is already registered.
+func RegisterInterfaceCreateCmd(portType string, createCmd InterfaceCreateTypeCmd) {
+	if _, ok := interfaceCreateCmds[portType]; ok {
+		panic("cli: RegisterInterfaceCreateCmd called twice for port type " + portType)
+	}
+	interfaceCreateCmds[portType] = createCmd
+}
+
+// The create commands of the built-in port types
+func init() {
+	RegisterInterfaceCreateCmd(portmngr.PortTypeTap, InterfaceCreateTapCmd)
+	RegisterInterfaceCreateCmd(portmngr.PortTypeEthdev, InterfaceCreateEthdevCmd)
+}
+
 func InterfaceCreateCmd(parents ...*cobra.Command) *cobra.Command {
 	createCmd := &cobra.Command{
 		Use:     "create",
@@ -21,8 +44,12 @@ func InterfaceCreateCmd(parents ...*cobra.Command) *cobra.Command {
 		Aliases: []string{"cr"},
 	}
 
-	InterfaceCreateTapCmd(createCmd)
-	InterfaceCreateEthdevCmd(createCmd)
+	// add the create commands of the registered port types
+	for _, portType := range portmngr.PortTypes() {
+		if createTypeCmd, ok := interfaceCreateCmds[portType]; ok {
+			createTypeCmd(createCmd)
+		}
+	}
 	return cli.AddCommand(parents, createCmd)
 }
 
@@ -41,11 +68,10 @@ func InterfaceCreateTapCmd(parents ...*cobra.Command) *cobra.Command {
 			dpdki := dpdkinfra.Get()
 			var params tap.Params
 
-			// get pktmbuf
-			arg1 := args[1]
-			params.Pktmbuf = dpdki.PktmbufStore.Get(arg1)
-			if params.Pktmbuf == nil {
-				cmd.PrintErrf("Pktmbuf %s not defined!\n", arg1)
+			// get pktmbuf, a tap interface isn't connected to a NUMA node
+			var err error
+			if params.Pktmbuf, err = dpdki.PktmbufForNuma(args[1], placement.NumaAny); err != nil {
+				cmd.PrintErrf("Pktmbuf %s err: %v\n", args[1], err)
 				return
 			}
 
@@ -95,10 +121,14 @@ func InterfaceCreateEthdevCmd(parents ...*cobra.Command) *cobra.Command {
 			// get device name
 			params.PortName = args[1]
 
-			// get pktmbuf
-			params.Rx.Mempool = dpdki.PktmbufStore.Get(args[2])
-			if params.Rx.Mempool == nil {
-				cmd.PrintErrf("Pktmbuf %s not defined!\n", args[2])
+			// get pktmbuf on the NUMA node of the device
+			numa, err := ethdev.PortNumaNode(params.PortName)
+			if err != nil {
+				cmd.PrintErrf("Device %s err: %v\n", params.PortName, err)
+				return
+			}
+			if params.Rx.Mempool, err = dpdki.PktmbufForNuma(args[2], numa); err != nil {
+				cmd.PrintErrf("Pktmbuf %s err: %v\n", args[2], err)
 				return
 			}
 
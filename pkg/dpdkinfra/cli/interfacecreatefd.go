@@ -0,0 +1,107 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/placement"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/fd"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	RegisterInterfaceCreateCmd(portmngr.PortTypeFd, InterfaceCreateFdCmd)
+}
+
+func InterfaceCreateFdCmd(parents ...*cobra.Command) *cobra.Command {
+	var netns string
+	var promiscuous bool
+
+	fdCmd := &cobra.Command{
+		Use:   "fd [name] [interface|fd] [pktmbuf] [mtu]",
+		Short: "Create a file descriptor interface on an existing Linux interface or an opened file descriptor",
+		Long: `Create a file descriptor interface. When an existing Linux interface (i.e. veth, bridge or macvlan) is given
+an AF_PACKET socket is bound to that interface, when a number is given that already opened file descriptor is used.
+If no MTU (or 0) is given the MTU of the Linux interface is used.`,
+		Args: cobra.RangeArgs(3, 4),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must choose a name for the fd interface you are adding"),
+			completeLinuxInterfaceList,
+			completePktmbufArg,
+			cli.AppendHelp("You can specify the MTU for the fd interface you are adding (0/none is MTU of the Linux interface)"),
+			cli.AppendLastHelp(4, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			var params fd.Params
+
+			// get Linux interface or file descriptor, stdin, stdout and stderr can't be used
+			if n, err := strconv.ParseUint(args[1], 0, 31); err == nil {
+				if n < 3 {
+					cmd.PrintErrf("File descriptor %d is stdin, stdout or stderr\n", n)
+					return
+				}
+				if _, err := unix.FcntlInt(uintptr(n), unix.F_GETFD, 0); err != nil {
+					cmd.PrintErrf("File descriptor %d is not valid: %v\n", n, err)
+					return
+				}
+				params.Fd = int(n)
+			} else {
+				params.Interface = args[1]
+			}
+			params.Netns = netns
+			params.Promiscuous = promiscuous
+
+			// get pktmbuf, a file descriptor isn't connected to a NUMA node
+			var err error
+			if params.Pktmbuf, err = dpdki.PktmbufForNuma(args[2], placement.NumaAny); err != nil {
+				cmd.PrintErrf("Pktmbuf %s err: %v\n", args[2], err)
+				return
+			}
+
+			// get MTU if available
+			if len(args) > 3 {
+				mtu, err := strconv.ParseInt(args[3], 0, 32)
+				if err != nil {
+					cmd.PrintErrf("Mtu (%s) is not a correct integer: %v\n", args[3], err)
+					return
+				}
+				params.Mtu = int(mtu)
+			}
+
+			// create
+			if _, err := dpdki.Create(portmngr.PortTypeFd, args[0], &params); err != nil {
+				cmd.PrintErrf("Fd %s create err: %v\n", args[0], err)
+				return
+			}
+
+			cmd.Printf("Fd %s created!\n", args[0])
+		},
+	}
+
+	fdCmd.Flags().StringVarP(&netns, "netns", "n", "", "Network namespace (name or path) of the Linux interface.")
+	fdCmd.Flags().BoolVarP(&promiscuous, "promiscuous", "p", false, "Receive all packets on the Linux interface.")
+	return cli.AddCommand(parents, fdCmd)
+}
+
+// complete the names of the Linux interfaces in the network namespace of the process
+func completeLinuxInterfaceList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+	var list []string
+
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			list = append(list, iface.Name)
+		}
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No Linux interfaces available for completion!")
+	return completions, directive
+}
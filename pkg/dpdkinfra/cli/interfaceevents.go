@@ -0,0 +1,70 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+)
+
+func InterfaceEventsCmd(parents ...*cobra.Command) *cobra.Command {
+	eventsCmd := &cobra.Command{
+		Use:     "events [name]",
+		Short:   "Show link state change and device events of all (or one given) interface(s), use CTRL-C to stop",
+		Aliases: []string{"ev"},
+		Args:    cobra.MaximumNArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completePortList,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+				if !dpdki.ContainsPort(name) {
+					cmd.PrintErrf("Interface %v doesn't exist\n", name)
+					return
+				}
+			}
+
+			events, unsubscribe := dpdki.Subscribe()
+			ctx, cancelFn := context.WithCancel(cmd.Context())
+
+			cmd.Printf("Press CTRL-C to quit!\n")
+			printEvents(ctx, cmd, events, name)
+
+			// wait for CTRL-C and then cancel output
+			cli.WaitForCtrlC(cmd.InOrStdin())
+			cancelFn()
+			unsubscribe()
+		},
+	}
+
+	return cli.AddCommand(parents, eventsCmd)
+}
+
+func printEvents(ctx context.Context, cmd *cobra.Command, events <-chan portmngr.Event, name string) {
+	go func() {
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				// device events are not related to a port so only show them when no specific port is requested
+				if name != "" && e.Port != name {
+					continue
+				}
+				cmd.Printf("%s\n", e.String())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,288 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/flow"
+)
+
+const flowRuleHelp = "[ingress|egress|transfer] [group <n>] [priority <n>] pattern <item> [<field> <value>]... [/ <item>...] actions <action> [<arg>]... [/ <action>...]"
+
+func InterfaceFlowCmd(parents ...*cobra.Command) *cobra.Command {
+	flowCmd := &cobra.Command{
+		Use:   "flow",
+		Short: "Base command for all interface rte_flow (hardware offload) rule actions",
+	}
+
+	InterfaceFlowValidateCmd(flowCmd)
+	InterfaceFlowCreateCmd(flowCmd)
+	InterfaceFlowListCmd(flowCmd)
+	InterfaceFlowQueryCmd(flowCmd)
+	InterfaceFlowDestroyCmd(flowCmd)
+	InterfaceFlowFlushCmd(flowCmd)
+	return cli.AddCommand(parents, flowCmd)
+}
+
+func InterfaceFlowValidateCmd(parents ...*cobra.Command) *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate [name] [rule]",
+		Short: "Check if a flow rule can be created on an ethdev interface",
+		Long:  "Check if a flow rule can be created on an ethdev interface. Flow rule syntax:\n  " + flowRuleHelp,
+		Args:  cobra.MinimumNArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			completeFlowRule,
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			rule, err := flow.ParseRule(args[1:])
+			if err != nil {
+				cmd.PrintErrf("Flow rule error: %v\n", err)
+				return
+			}
+
+			if err := port.FlowValidate(rule); err != nil {
+				cmd.PrintErrf("Interface %s flow rule not valid: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s flow rule is valid!\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, validateCmd)
+}
+
+func InterfaceFlowCreateCmd(parents ...*cobra.Command) *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create [name] [rule]",
+		Short: "Create a flow rule on an ethdev interface",
+		Long:  "Create a flow rule on an ethdev interface. Flow rule syntax:\n  " + flowRuleHelp,
+		Args:  cobra.MinimumNArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			completeFlowRule,
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			rule, err := flow.ParseRule(args[1:])
+			if err != nil {
+				cmd.PrintErrf("Flow rule error: %v\n", err)
+				return
+			}
+
+			f, err := port.FlowCreate(rule)
+			if err != nil {
+				cmd.PrintErrf("Interface %s flow rule create err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s flow rule %d created!\n", args[0], f.ID())
+		},
+	}
+
+	return cli.AddCommand(parents, createCmd)
+}
+
+func InterfaceFlowListCmd(parents ...*cobra.Command) *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:     "list [name]",
+		Short:   "List the flow rules created on an ethdev interface",
+		Aliases: []string{"ls"},
+		Args:    cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			flows := port.FlowList()
+			cmd.Printf("Interface %s flow rules (%d):\n", args[0], len(flows))
+			for _, f := range flows {
+				cmd.Printf("  %4d: %s\n", f.ID(), f.Rule().String())
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, listCmd)
+}
+
+func InterfaceFlowQueryCmd(parents ...*cobra.Command) *cobra.Command {
+	queryCmd := &cobra.Command{
+		Use:   "query [name] [id]",
+		Short: "Show the counters of a flow rule with a count action",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			completeFlowID,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port, id, ok := getEthdevFlowID(cmd, args)
+			if !ok {
+				return
+			}
+
+			count, err := port.FlowQuery(id)
+			if err != nil {
+				cmd.PrintErrf("Interface %s flow rule %d query err: %v\n", args[0], id, err)
+				return
+			}
+
+			cmd.Printf("Interface %s flow rule %d:\n", args[0], id)
+			if count.HitsSet {
+				cmd.Printf("  hits : %d\n", count.Hits)
+			}
+			if count.BytesSet {
+				cmd.Printf("  bytes: %d\n", count.Bytes)
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, queryCmd)
+}
+
+func InterfaceFlowDestroyCmd(parents ...*cobra.Command) *cobra.Command {
+	destroyCmd := &cobra.Command{
+		Use:   "destroy [name] [id]",
+		Short: "Destroy a flow rule on an ethdev interface",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			completeFlowID,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port, id, ok := getEthdevFlowID(cmd, args)
+			if !ok {
+				return
+			}
+
+			if err := port.FlowDestroy(id); err != nil {
+				cmd.PrintErrf("Interface %s flow rule %d destroy err: %v\n", args[0], id, err)
+				return
+			}
+			cmd.Printf("Interface %s flow rule %d destroyed!\n", args[0], id)
+		},
+	}
+
+	return cli.AddCommand(parents, destroyCmd)
+}
+
+func InterfaceFlowFlushCmd(parents ...*cobra.Command) *cobra.Command {
+	flushCmd := &cobra.Command{
+		Use:   "flush [name]",
+		Short: "Destroy all flow rules on an ethdev interface",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			if err := port.FlowFlush(); err != nil {
+				cmd.PrintErrf("Interface %s flow rules flush err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s flow rules flushed!\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, flushCmd)
+}
+
+// get the ethdev port and flow id given as first and second argument
+func getEthdevFlowID(cmd *cobra.Command, args []string) (*ethdev.Ethdev, uint32, bool) {
+	port := getEthdev(cmd, args[0])
+	if port == nil {
+		return nil, 0, false
+	}
+
+	id, err := strconv.ParseUint(args[1], 0, 32)
+	if err != nil {
+		cmd.PrintErrf("Flow id (%s) is not a correct integer: %v\n", args[1], err)
+		return nil, 0, false
+	}
+
+	return port, uint32(id), true
+}
+
+// complete the flow rule ids of the ethdev port given as first argument
+func completeFlowID(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+	var list []string
+
+	if port := dpdkinfra.Get().EthdevStore.Get(args[0]); port != nil {
+		for _, f := range port.FlowList() {
+			list = append(list, strconv.FormatUint(uint64(f.ID()), 10))
+		}
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No flow rules available for completion!")
+	return completions, directive
+}
+
+// complete the next word of a flow rule, depending on the part of the rule (attributes, pattern or actions)
+func completeFlowRule(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+	var list []string
+
+	// find the current rule section, the current pattern item and the number of item field words already given
+	section := ""
+	item := ""
+	words := 0
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "pattern" || arg == "actions":
+			section = arg
+		case arg == "/":
+			item = ""
+		case section == "pattern" && item == "":
+			item = arg
+			words = 0
+		default:
+			words++
+		}
+	}
+
+	switch section {
+	case "":
+		list = []string{"ingress", "egress", "transfer", "group", "priority", "pattern"}
+	case "pattern":
+		switch {
+		case item == "":
+			list = flow.ItemNames()
+		case words%2 == 1:
+			// field value expected
+			return cobra.AppendActiveHelp(nil, "You must specify the field value"), directive | cobra.ShellCompDirectiveNoSpace
+		default:
+			list = append(flow.ItemFieldNames(item), "/", "actions")
+		}
+	case "actions":
+		list = append(flow.ActionNames(), "/", "end")
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "")
+	return completions, directive
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+)
+
+func InterfaceRssCmd(parents ...*cobra.Command) *cobra.Command {
+	rssCmd := &cobra.Command{
+		Use:   "rss",
+		Short: "Base command for all interface RSS (receive side scaling) actions",
+	}
+
+	InterfaceRssShowCmd(rssCmd)
+	InterfaceRssHashCmd(rssCmd)
+	InterfaceRssRetaCmd(rssCmd)
+	return cli.AddCommand(parents, rssCmd)
+}
+
+func InterfaceRssShowCmd(parents ...*cobra.Command) *cobra.Command {
+	showCmd := &cobra.Command{
+		Use:   "show [name]",
+		Short: "Show the RSS hash configuration and redirection table of an ethdev interface",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			rss, err := port.RssGet()
+			if err != nil {
+				cmd.PrintErrf("Interface %s RSS err: %v\n", args[0], err)
+				return
+			}
+
+			reta, err := port.RetaGet()
+			if err != nil {
+				cmd.PrintErrf("Interface %s RSS redirection table err: %v\n", args[0], err)
+				return
+			}
+
+			cmd.Printf("Interface %s RSS configuration:\n", args[0])
+			cmd.Printf("  Hash functions : %s\n", ethdev.RssHfString(rss.Hf))
+			cmd.Printf("  Hash key       : %s\n", hex.EncodeToString(rss.Key))
+			cmd.Printf("  Symmetric      : %t\n", rss.Symmetric)
+			cmd.Printf("  Queues         : %v\n", rss.Queues)
+			cmd.Printf("  Redirection table (%d entries):\n", len(reta))
+			for i := 0; i < len(reta); i += 16 {
+				line := fmt.Sprintf("    %4d:", i)
+				for j := i; j < i+16 && j < len(reta); j++ {
+					line += fmt.Sprintf(" %3d", reta[j])
+				}
+				cmd.Printf("%s\n", line)
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, showCmd)
+}
+
+func InterfaceRssHashCmd(parents ...*cobra.Command) *cobra.Command {
+	var key string
+	var symmetric bool
+	hashCmd := &cobra.Command{
+		Use:   "hash [name] [hash functions]",
+		Short: "Change the RSS hash functions (comma separated list or default) and hash key of an ethdev interface",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			completeRssHfList,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			var hf uint64
+			var err error
+			if args[1] != "default" {
+				hf, err = ethdev.ParseRssHf(strings.Split(args[1], ","))
+				if err != nil {
+					cmd.PrintErrf("Hash functions (%s) are not correct: %v\n", args[1], err)
+					return
+				}
+			}
+
+			var k []byte
+			if key != "" {
+				k, err = hex.DecodeString(strings.ReplaceAll(key, ":", ""))
+				if err != nil {
+					cmd.PrintErrf("Hash key (%s) is not a correct hex string: %v\n", key, err)
+					return
+				}
+			}
+
+			if err := port.SetRssHash(k, hf, symmetric); err != nil {
+				cmd.PrintErrf("Interface %s set RSS hash err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s RSS hash configuration changed!\n", args[0])
+		},
+	}
+	hashCmd.Flags().StringVarP(&key, "key", "k", "", "Hash key as hex string (i.e. 6d5a6d5a...), size must be equal to the device hash key size.")
+	hashCmd.Flags().BoolVarP(&symmetric, "symmetric", "s", false, "Use a symmetric hash key so both directions of a flow use the same queue.")
+	hashCmd.MarkFlagsMutuallyExclusive("key", "symmetric")
+
+	return cli.AddCommand(parents, hashCmd)
+}
+
+func InterfaceRssRetaCmd(parents ...*cobra.Command) *cobra.Command {
+	retaCmd := &cobra.Command{
+		Use:   "reta [name] [queue] [queue...]",
+		Short: "Fill the RSS redirection table of an ethdev interface round robin with the given rx queues",
+		Args:  cobra.MinimumNArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeCreatedEthdevPortList,
+			cli.AppendHelp("You must specify one or more rx queues"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			port := getEthdev(cmd, args[0])
+			if port == nil {
+				return
+			}
+
+			var queues []uint16
+			for _, arg := range args[1:] {
+				q, err := strconv.ParseUint(arg, 0, 16)
+				if err != nil {
+					cmd.PrintErrf("Queue (%s) is not a correct integer: %v\n", arg, err)
+					return
+				}
+				queues = append(queues, uint16(q))
+			}
+
+			if err := port.SetRssQueues(queues); err != nil {
+				cmd.PrintErrf("Interface %s set RSS redirection table err: %v\n", args[0], err)
+				return
+			}
+			cmd.Printf("Interface %s RSS redirection table changed to queues: %v\n", args[0], queues)
+		},
+	}
+
+	return cli.AddCommand(parents, retaCmd)
+}
+
+// complete the last hash function name of a comma separated list of RSS hash function names
+func completeRssHfList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+
+	var list []string
+	if prefix == "" {
+		list = append(list, "default")
+	}
+	for _, name := range ethdev.RssHfNameList() {
+		list = append(list, prefix+name)
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No RSS hash functions available for completion!")
+	return completions, directive
+}
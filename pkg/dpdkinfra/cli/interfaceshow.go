@@ -56,6 +56,8 @@ func InterfaceShowCmd(parents ...*cobra.Command) *cobra.Command {
 						cmd.Printf("  Duplex           : %s\n", st["duplex"])
 						cmd.Printf("  Link speed       : %s\n", st["speed"])
 						cmd.Printf("  Promiscuous mode : %s\n", st["promiscuous"])
+						cmd.Printf("  Allmulticast mode: %s\n", st["allmulticast"])
+						cmd.Printf("  MTU              : %s\n", st["mtu"])
 						cmd.Printf("  MAC address      : %s\n", st["macaddr"])
 						cmd.Print("\n")
 						cmd.Print("  Port specific items:\n")
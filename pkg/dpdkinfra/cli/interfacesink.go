@@ -0,0 +1,116 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/sourcesink"
+)
+
+func InterfaceSinkCmd(parents ...*cobra.Command) *cobra.Command {
+	sinkCmd := &cobra.Command{
+		Use:   "sink",
+		Short: "Control the pcap file writing of sink interfaces",
+	}
+
+	InterfaceSinkActionCmd("pause", "Pause writing packets to the pcap file (packets are dropped)",
+		(*dpdkinfra.DpdkInfra).SinkPause, sinkCmd)
+	InterfaceSinkActionCmd("resume", "Resume writing packets to the pcap file",
+		(*dpdkinfra.DpdkInfra).SinkResume, sinkCmd)
+	InterfaceSinkActionCmd("reopen", "Close the current pcap file and open a new file",
+		(*dpdkinfra.DpdkInfra).SinkReopen, sinkCmd)
+	InterfaceSinkStatsCmd(sinkCmd)
+	return cli.AddCommand(parents, sinkCmd)
+}
+
+func InterfaceSinkActionCmd(
+	action string, short string, fn func(*dpdkinfra.DpdkInfra, string) error, parents ...*cobra.Command,
+) *cobra.Command {
+	actionCmd := &cobra.Command{
+		Use:   action + " [name]",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeSinkArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := fn(dpdkinfra.Get(), args[0]); err != nil {
+				cmd.PrintErrf("Sink %s %s err: %v\n", args[0], action, err)
+				return
+			}
+			cmd.Printf("Sink %s %s done!\n", args[0], action)
+		},
+	}
+
+	return cli.AddCommand(parents, actionCmd)
+}
+
+func InterfaceSinkStatsCmd(parents ...*cobra.Command) *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats [name]",
+		Short: "Show the pcap file writing statistics of one or all sinks",
+		Args:  cobra.MaximumNArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeSinkArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			names := sinkList()
+			if len(args) == 1 {
+				names = args
+			}
+
+			for _, name := range names {
+				stats, err := dpdki.SinkStats(name)
+				if err != nil {
+					cmd.PrintErrf("Sink stats err: %v\n", err)
+					return
+				}
+				printSinkStats(cmd, name, &stats)
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, statsCmd)
+}
+
+func printSinkStats(cmd *cobra.Command, name string, stats *sourcesink.SinkStats) {
+	cmd.Printf("%s:\n", name)
+	cmd.Printf("  file    : %s (%d files opened)\n", stats.FileName, stats.Files)
+	cmd.Printf("  paused  : %t\n", stats.Paused)
+	cmd.Printf("  packets : %d\n", stats.Packets)
+	cmd.Printf("  bytes   : %d\n", stats.Bytes)
+	cmd.Printf("  dropped : %d\n", stats.Dropped)
+	if stats.Err != nil {
+		cmd.Printf("  error   : %v\n", stats.Err)
+	}
+}
+
+// complete a sink argument
+func completeSinkArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	completions := cli.FilterCompletions(sinkList(), toComplete, &directive, "No sinks available for completion!")
+	return completions, directive
+}
+
+// get sorted list of sink names
+func sinkList() []string {
+	list := []string{}
+
+	dpdkinfra.Get().SinkStore.Iterate(func(key string, sink *sourcesink.Sink) error {
+		list = append(list, key)
+		return nil
+	})
+	sort.Strings(list)
+
+	return list
+}
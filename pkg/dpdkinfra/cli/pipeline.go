@@ -6,6 +6,7 @@ package cli
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,7 +24,16 @@ func PipelineCmd(parents ...*cobra.Command) *cobra.Command {
 		Aliases: []string{"pl"},
 	}
 
+	PipelineCreateCmd(pipelineCmd)
 	PipelineBindCmd(pipelineCmd)
+	PipelineBuildCmd(pipelineCmd)
+	PipelineEnableCmd(pipelineCmd)
+	PipelineDisableCmd(pipelineCmd)
+	PipelineCommitCmd(pipelineCmd)
+	PipelineAbortCmd(pipelineCmd)
+	PipelineDeleteCmd(pipelineCmd)
+	PipelineTableCmd(pipelineCmd)
+	PipelineChainsCmd(pipelineCmd)
 	PipelineInfoCmd(pipelineCmd)
 	PipelineStatsCmd(pipelineCmd)
 	return cli.AddCommand(parents, pipelineCmd)
@@ -31,33 +41,121 @@ func PipelineCmd(parents ...*cobra.Command) *cobra.Command {
 
 func PipelineBindCmd(parents ...*cobra.Command) *cobra.Command {
 	bindCmd := &cobra.Command{
-		Use:     "bind [interface:{rx|tx}:queue#] [pipeline] [pipeline port] [burstsize]",
-		Short:   "b",
+		Use:   "bind [interface:{rx|tx}:queue#] [pipeline] [pipeline port] [burstsize]",
+		Short: "Bind an interface queue to an input (rx) or output (tx) port of a pipeline that is not build yet",
+		Long: `Bind an interface queue to an input (rx queue) or output (tx queue) port of a pipeline that is not build
+yet. The pipeline ports are numbered from 0 without gaps, the number of input ports must be a power of 2 when the
+pipeline is build. The burstsize is 32 when not given.`,
 		Aliases: []string{"bin"},
-		Args:    cobra.RangeArgs(2, 4),
+		Args:    cobra.RangeArgs(3, 4),
 		ValidArgsFunction: cli.ValidateArguments(
 			completeUnboundQueueArg,
-			completeBuildNotEnabledPipelineArg,
+			completeNotBuildPipelineArg,
 			cli.AppendHelp("You must specify the pipeline port for the queue you are binding"),
 			cli.AppendHelp("You must specify the burstsize for the queue you are binding"),
 			cli.AppendLastHelp(4, "This command does not take any more arguments"),
 		),
 		Run: func(cmd *cobra.Command, args []string) {
-			/* TODO Handle bind command
 			dpdki := dpdkinfra.Get()
-			plName := ""
 
-			// check specific pipeline or all
-			if len(args) == 2 {
-				plName = args[1]
+			// interface queue
+			fields := strings.Split(args[0], ":")
+			if len(fields) != 3 || (fields[1] != "rx" && fields[1] != "tx") {
+				cmd.PrintErrf("Queue %s is not formatted as interface:{rx|tx}:queue#\n", args[0])
+				return
+			}
+			port := dpdki.GetPort(fields[0])
+			if port == nil {
+				cmd.PrintErrf("Interface %s doesn't exist\n", fields[0])
+				return
+			}
+			queue, err := strconv.ParseUint(fields[2], 10, 16)
+			if err != nil {
+				cmd.PrintErrf("Queue parse err: %v\n", err)
+				return
+			}
+
+			pl := dpdki.PipelineStore.Get(args[1])
+			if pl == nil {
+				cmd.PrintErrf("Pipeline %s doesn't exist\n", args[1])
+				return
+			}
+			if pl.IsBuild() {
+				cmd.PrintErrf("Pipeline %s is already build\n", args[1])
+				return
+			}
+
+			portID, err := strconv.ParseUint(args[2], 10, 31)
+			if err != nil {
+				cmd.PrintErrf("Pipeline port parse err: %v\n", err)
+				return
+			}
+			bsz := uint64(32)
+			if len(args) == 4 {
+				if bsz, err = strconv.ParseUint(args[3], 10, 32); err != nil {
+					cmd.PrintErrf("Burstsize parse err: %v\n", err)
+					return
+				}
+			}
+
+			if fields[1] == "rx" {
+				err = port.BindToPipelineInputPort(pl, int(portID), uint16(queue), uint(bsz))
+			} else {
+				err = port.BindToPipelineOutputPort(pl, int(portID), uint16(queue), uint(bsz))
+			}
+			if err != nil {
+				cmd.PrintErrf("Pipeline bind err: %v\n", err)
+				return
 			}
-			*/
+			cmd.Printf("%s bound to pipeline %s port %d\n", args[0], args[1], portID)
 		},
 	}
 
 	return cli.AddCommand(parents, bindCmd)
 }
 
+func PipelineChainsCmd(parents ...*cobra.Command) *cobra.Command {
+	chainsCmd := &cobra.Command{
+		Use:     "chains [pipeline]",
+		Short:   "Show the pipelines linked through rings, optionally only the links of the given pipeline",
+		Aliases: []string{"ch", "graph"},
+		Args:    cobra.MaximumNArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completePipelineArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			// group the links per sending pipeline
+			var from []string
+			links := make(map[string][]dpdkinfra.PipelineLink)
+			for _, link := range dpdki.PipelineLinks() {
+				if len(args) == 1 && link.From != args[0] && link.To != args[0] {
+					continue
+				}
+				if links[link.From] == nil {
+					from = append(from, link.From)
+				}
+				links[link.From] = append(links[link.From], link)
+			}
+
+			if len(from) == 0 {
+				cmd.Printf("No pipelines linked\n")
+				return
+			}
+			for _, plName := range from {
+				cmd.Printf("%s\n", plName)
+				for _, link := range links[plName] {
+					cmd.Printf("  out %d -> %s in %d (ring %s)\n", link.Out, link.To, link.In, link.Ring)
+				}
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, chainsCmd)
+}
+
 func PipelineInfoCmd(parents ...*cobra.Command) *cobra.Command {
 	infoCmd := &cobra.Command{
 		Use:     "info [pipeline]",
@@ -203,6 +301,45 @@ func completeBuildNotEnabledPipelineArg(cmd *cobra.Command, args []string, toCom
 	return completions, directive
 }
 
+// complete a not build pipeline argument
+func completeNotBuildPipelineArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	// get NotBuildPipelines list
+	listPl := pipelineList(NotBuildPipelines)
+
+	// filter list with string to complete
+	completions := cli.FilterCompletions(listPl, toComplete, &directive, "No Pipelines available for completion!")
+
+	return completions, directive
+}
+
+// complete a build pipeline argument
+func completeBuildPipelineArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	// get BuildPipelines list
+	listPl := pipelineList(BuildPipelines)
+
+	// filter list with string to complete
+	completions := cli.FilterCompletions(listPl, toComplete, &directive, "No Pipelines available for completion!")
+
+	return completions, directive
+}
+
+// complete an enabled pipeline argument
+func completeEnabledPipelineArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	// get EnabledPipelines list
+	listPl := pipelineList(EnabledPipelines)
+
+	// filter list with string to complete
+	completions := cli.FilterCompletions(listPl, toComplete, &directive, "No Pipelines available for completion!")
+
+	return completions, directive
+}
+
 // complete a queue argument
 func completeUnboundQueueArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	var directive = cobra.ShellCompDirectiveNoFileComp
@@ -220,6 +357,7 @@ type PipelineFilter uint
 
 const (
 	AllPipelines PipelineFilter = iota + 1
+	NotBuildPipelines
 	BuildPipelines
 	BuildNotEnabledPipelines
 	EnabledPipelines
@@ -232,6 +370,10 @@ func pipelineList(filter PipelineFilter) []string {
 	list := []string{}
 	dpdki.PipelineStore.Iterate(func(key string, pl *pipeline.Pipeline) error {
 		switch filter {
+		case NotBuildPipelines:
+			if pl.IsBuild() {
+				return nil
+			}
 		case BuildPipelines:
 			if !pl.IsBuild() {
 				return nil
@@ -0,0 +1,260 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+)
+
+func PipelineCreateCmd(parents ...*cobra.Command) *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create [name] [numanode]",
+		Short: "Create an empty pipeline on the given NUMA node, ready to bind ports to and build",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must choose a name for the pipeline you are creating"),
+			completeNumaNodeArg,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if dpdki.PipelineStore.Contains(args[0]) {
+				cmd.PrintErrf("Pipeline %s already exists\n", args[0])
+				return
+			}
+
+			numaNode, err := strconv.ParseInt(args[1], 10, 32)
+			if err != nil {
+				cmd.PrintErrf("NUMA node parse err: %v\n", err)
+				return
+			}
+			if !validNumaNode(int(numaNode)) {
+				cmd.PrintErrf("NUMA node %d doesn't exist, available NUMA nodes: %v\n", numaNode, eal.NumaNodes())
+				return
+			}
+
+			if _, err = dpdki.PipelineCreate(args[0], int(numaNode)); err != nil {
+				cmd.PrintErrf("Pipeline create err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s created on NUMA node %d\n", args[0], numaNode)
+		},
+	}
+
+	return cli.AddCommand(parents, createCmd)
+}
+
+func PipelineBuildCmd(parents ...*cobra.Command) *cobra.Command {
+	buildCmd := &cobra.Command{
+		Use:   "build [pipeline] [specfile]",
+		Short: "Build a pipeline with bound ports from a spec file and commit the build",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeNotBuildPipelineArg,
+			cli.AppendHelp("You must specify the spec file to build the pipeline with"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if err := dpdki.PipelineBuild(args[0], args[1]); err != nil {
+				cmd.PrintErrf("Pipeline build err: %v\n", err)
+				return
+			}
+			if err := dpdki.PipelineCommit(args[0]); err != nil {
+				cmd.PrintErrf("Pipeline commit err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s build with spec file %s\n", args[0], args[1])
+		},
+	}
+
+	return cli.AddCommand(parents, buildCmd)
+}
+
+func PipelineEnableCmd(parents ...*cobra.Command) *cobra.Command {
+	enableCmd := &cobra.Command{
+		Use:   "enable [pipeline] [thread]",
+		Short: "Run a build pipeline on the given worker thread, or on the least loaded thread of its NUMA node",
+		Args:  cobra.RangeArgs(1, 2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeBuildNotEnabledPipelineArg,
+			completeThreadArg,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			plName := args[0]
+
+			pl := dpdki.PipelineStore.Get(plName)
+			if pl == nil {
+				cmd.PrintErrf("Pipeline %s doesn't exist\n", plName)
+				return
+			}
+			numaNode, err := pl.NumaNodeGet()
+			if err != nil {
+				cmd.PrintErrf("Pipeline NUMA node err: %v\n", err)
+				return
+			}
+
+			var threadID uint
+			if len(args) == 2 {
+				id, err := strconv.ParseUint(args[1], 10, 32)
+				if err != nil {
+					cmd.PrintErrf("Thread parse err: %v\n", err)
+					return
+				}
+				threadID = uint(id)
+			} else if threadID, err = dpdki.PipelineThread(numaNode); err != nil {
+				cmd.PrintErrf("Pipeline thread err: %v\n", err)
+				return
+			}
+
+			dpdki.CheckPipelinePlacement(plName, numaNode, threadID, nil)
+			if err := dpdki.PipelineEnable(plName, threadID); err != nil {
+				cmd.PrintErrf("Pipeline enable err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s enabled on thread %d\n", plName, threadID)
+		},
+	}
+
+	return cli.AddCommand(parents, enableCmd)
+}
+
+func PipelineDisableCmd(parents ...*cobra.Command) *cobra.Command {
+	disableCmd := &cobra.Command{
+		Use:   "disable [pipeline]",
+		Short: "Stop running a pipeline on its worker thread",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeEnabledPipelineArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if err := dpdki.PipelineDisable(args[0]); err != nil {
+				cmd.PrintErrf("Pipeline disable err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s disabled\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, disableCmd)
+}
+
+func PipelineCommitCmd(parents ...*cobra.Command) *cobra.Command {
+	commitCmd := &cobra.Command{
+		Use:   "commit [pipeline]",
+		Short: "Commit the table changes scheduled for a pipeline",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeBuildPipelineArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if err := dpdki.PipelineCommit(args[0]); err != nil {
+				cmd.PrintErrf("Pipeline commit err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s changes committed\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, commitCmd)
+}
+
+func PipelineAbortCmd(parents ...*cobra.Command) *cobra.Command {
+	abortCmd := &cobra.Command{
+		Use:   "abort [pipeline]",
+		Short: "Discard the table changes scheduled for a pipeline",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeBuildPipelineArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if err := dpdki.PipelineAbort(args[0]); err != nil {
+				cmd.PrintErrf("Pipeline abort err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s changes discarded\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, abortCmd)
+}
+
+func PipelineDeleteCmd(parents ...*cobra.Command) *cobra.Command {
+	deleteCmd := &cobra.Command{
+		Use:   "delete [pipeline]",
+		Short: "Delete a pipeline and release the interface queues bound to it",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completePipelineArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			if err := dpdki.PipelineDelete(args[0]); err != nil {
+				cmd.PrintErrf("Pipeline delete err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pipeline %s deleted\n", args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, deleteCmd)
+}
+
+// check if the given NUMA node is one of the NUMA nodes of the system
+func validNumaNode(numaNode int) bool {
+	for _, numa := range eal.NumaNodes() {
+		if numa == numaNode {
+			return true
+		}
+	}
+	return false
+}
+
+// complete a NUMA node argument
+func completeNumaNodeArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	list := []string{}
+	for _, numa := range eal.NumaNodes() {
+		list = append(list, strconv.Itoa(numa))
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No NUMA nodes available for completion!")
+
+	return completions, directive
+}
+
+// complete a worker thread (lcore) argument
+func completeThreadArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	list := []string{}
+	for _, lcore := range eal.GetLcoresWorkers() {
+		list = append(list, strconv.FormatUint(uint64(lcore), 10))
+	}
+
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No worker threads available for completion!")
+
+	return completions, directive
+}
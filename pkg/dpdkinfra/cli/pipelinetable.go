@@ -0,0 +1,305 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+)
+
+var tableOps = []string{"add", "delete", "default", "list", "load"}
+
+func PipelineTableCmd(parents ...*cobra.Command) *cobra.Command {
+	var commit bool
+
+	tableCmd := &cobra.Command{
+		Use:   "table [pipeline] [table] [add|delete|default|list|load] [args...]",
+		Short: "Add, delete and list the entries of a pipeline table or set its default entry",
+		Long: `Add, delete and list the entries of a pipeline table or set its default entry:
+
+  table [pipeline] [table] add match [field...] [priority [value]] action [action] [arg value...]
+  table [pipeline] [table] delete match [field...]
+  table [pipeline] [table] default action [action] [arg value...]
+  table [pipeline] [table] list
+  table [pipeline] [table] load [file]
+
+The match fields are given in the order of the table key, wildcard and lpm fields as value or value/mask. The load
+file has one entry per line in the add format, empty lines and lines starting with # or ; are skipped. The changes
+are scheduled and applied with pipeline commit (or --commit), pipeline abort discards them. The list shows the
+committed entries added with this command, the configuration or a script.`,
+		Aliases:           []string{"tab"},
+		Args:              cobra.MinimumNArgs(3),
+		ValidArgsFunction: completeTableCmdArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			plName, tableName, op := args[0], args[1], args[2]
+
+			pl := dpdki.PipelineStore.Get(plName)
+			if pl == nil {
+				cmd.PrintErrf("Pipeline %s doesn't exist\n", plName)
+				return
+			}
+			if pl.GetTable(tableName) == nil {
+				cmd.PrintErrf("Pipeline %s has no table %s\n", plName, tableName)
+				return
+			}
+
+			line := strings.Join(args[3:], " ")
+			switch op {
+			case "add":
+				if err := dpdki.TableEntryAdd(plName, tableName, line); err != nil {
+					cmd.PrintErrf("Table entry add err: %v\n", err)
+					return
+				}
+			case "delete":
+				if err := dpdki.TableEntryDelete(plName, tableName, line); err != nil {
+					cmd.PrintErrf("Table entry delete err: %v\n", err)
+					return
+				}
+			case "default":
+				if err := dpdki.TableDefaultEntryAdd(plName, tableName, line); err != nil {
+					cmd.PrintErrf("Table default entry err: %v\n", err)
+					return
+				}
+			case "list":
+				tableList(cmd, dpdki, plName, tableName)
+				return
+			case "load":
+				if len(args) != 4 {
+					cmd.PrintErrf("Table load needs one file argument\n")
+					return
+				}
+				lines, err := dpdki.TableEntriesLoad(plName, tableName, args[3])
+				if err != nil {
+					cmd.PrintErrf("Table load err: %v\n", err)
+					if len(lines) > 0 {
+						cmd.PrintErrf("The %d entries before are scheduled, use pipeline abort to discard them\n",
+							len(lines))
+					}
+					return
+				}
+				cmd.Printf("%d entries loaded\n", len(lines))
+			default:
+				cmd.PrintErrf("Unknown table operation %s, use one of %s\n", op, strings.Join(tableOps, ", "))
+				return
+			}
+
+			if !commit {
+				cmd.Printf("Table %s change scheduled, use pipeline commit to apply it\n", tableName)
+				return
+			}
+			if err := dpdki.PipelineCommit(plName); err != nil {
+				cmd.PrintErrf("Pipeline commit err: %v\n", err)
+				return
+			}
+			cmd.Printf("Table %s change committed\n", tableName)
+		},
+	}
+	tableCmd.Flags().BoolVarP(&commit, "commit", "c", false, "commit the pipeline changes directly")
+
+	return cli.AddCommand(parents, tableCmd)
+}
+
+// print the committed entries and the default entry of the table
+func tableList(cmd *cobra.Command, dpdki *dpdkinfra.DpdkInfra, plName string, tableName string) {
+	for _, te := range dpdki.PipelineTableEntries(plName) {
+		if te.Table != tableName {
+			continue
+		}
+		if te.Default != "" {
+			cmd.Printf("default: %s\n", te.Default)
+		}
+		for _, entry := range te.Entries {
+			cmd.Println(entry)
+		}
+		cmd.Printf("%d entries\n", len(te.Entries))
+		return
+	}
+	cmd.Println("0 entries")
+}
+
+// complete the table command arguments: pipeline, table, operation and then the entry tokens from the table model
+func completeTableCmdArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	switch len(args) {
+	case 0:
+		return completeBuildPipelineArg(cmd, args, toComplete)
+	case 1:
+		completions := cli.FilterCompletions(tableNameList(args[0]), toComplete, &directive,
+			"No tables available for completion!")
+		return completions, directive
+	case 2:
+		return cli.FilterCompletions(tableOps, toComplete, &directive, ""), directive
+	}
+
+	var list []string
+	var help string
+	table := getTable(args[0], args[1])
+	switch {
+	case table == nil:
+		help = fmt.Sprintf("Pipeline %s has no table %s", args[0], args[1])
+	case args[2] == "add" || args[2] == "delete":
+		list, help = entryCompletions(table, args[3:], args[2] == "add")
+	case args[2] == "default":
+		list, help = defaultEntryCompletions(table, args[3:])
+	case args[2] == "load" && len(args) == 3:
+		help = "You must specify the table entries file to load"
+	default:
+		help = "This command does not take any more arguments"
+	}
+
+	if len(list) > 0 {
+		return cli.FilterCompletions(list, toComplete, &directive, help), directive
+	}
+	if toComplete == "" {
+		directive |= cobra.ShellCompDirectiveNoSpace
+		return cobra.AppendActiveHelp(nil, help), directive
+	}
+	return nil, directive
+}
+
+// returns the table with the given name of a build pipeline or nil
+func getTable(plName string, tableName string) *pipeline.Table {
+	pl := dpdkinfra.Get().PipelineStore.Get(plName)
+	if pl == nil {
+		return nil
+	}
+	return pl.GetTable(tableName)
+}
+
+func tableNameList(plName string) []string {
+	list := []string{}
+	pl := dpdkinfra.Get().PipelineStore.Get(plName)
+	if pl == nil {
+		return list
+	}
+	pl.GetTables().ForEach(func(key string, table *pipeline.Table) error {
+		list = append(list, key)
+		return nil
+	})
+	sort.Strings(list)
+	return list
+}
+
+// the completion candidates or help for the next token of a table entry following the given tokens. The entry format
+// is "match <field>... [priority <value>] action <action> [<arg> <value>]...", a delete entry only needs the match.
+func entryCompletions(table *pipeline.Table, tokens []string, withAction bool) ([]string, string) {
+	if len(tokens) == 0 {
+		return []string{"match"}, ""
+	}
+	if tokens[0] != "match" {
+		return nil, "A table entry starts with match"
+	}
+
+	// match fields in key order
+	nFields := len(table.GetMatchFields())
+	wildcard := false
+	for i := 0; i < nFields; i++ {
+		field := table.GetMatchFields().FindIndex(uint(i))
+		if field.GetMatchType() != pipeline.MatchExact {
+			wildcard = true
+		}
+		if len(tokens) == i+1 {
+			return nil, matchFieldHelp(field)
+		}
+	}
+
+	tokens = tokens[nFields+1:]
+	if !withAction {
+		return nil, "This command does not take any more arguments"
+	}
+	if wildcard && len(tokens) > 0 && tokens[0] == "priority" {
+		if len(tokens) == 1 {
+			return nil, "Entry priority, 0 is the highest priority"
+		}
+		tokens = tokens[2:]
+	}
+	if len(tokens) == 0 {
+		if wildcard {
+			return []string{"action", "priority"}, ""
+		}
+		return []string{"action"}, ""
+	}
+
+	return actionCompletions(table, tokens, func(ta *pipeline.TableAction) bool {
+		return ta.GetActionIsForTableEntries()
+	})
+}
+
+// the completion candidates or help for the next token of a table default entry: "action <action> [<arg> <value>]..."
+func defaultEntryCompletions(table *pipeline.Table, tokens []string) ([]string, string) {
+	if table.GetDefaultActionIsConst() {
+		return nil, fmt.Sprintf("The default action of table %s can't be changed", table.GetName())
+	}
+	if len(tokens) == 0 {
+		return []string{"action"}, ""
+	}
+
+	return actionCompletions(table, tokens, func(ta *pipeline.TableAction) bool {
+		return ta.GetActionIsForDefaultEntry()
+	})
+}
+
+// complete "action <action> [<arg> <value>]..." with the table actions accepted by the filter
+func actionCompletions(table *pipeline.Table, tokens []string, filter func(ta *pipeline.TableAction) bool,
+) ([]string, string) {
+	if tokens[0] != "action" {
+		return nil, "Expected action"
+	}
+
+	if len(tokens) == 1 {
+		list := []string{}
+		table.GetActions().ForEach(func(key string, ta *pipeline.TableAction) error {
+			if filter(ta) {
+				list = append(list, key)
+			}
+			return nil
+		})
+		sort.Strings(list)
+		return list, "No actions available for completion!"
+	}
+
+	ta := table.GetActions().FindName(tokens[1])
+	if ta == nil || !filter(ta) {
+		return nil, fmt.Sprintf("Action %s can't be used here", tokens[1])
+	}
+
+	// the action arguments as name value pairs in argument order
+	actionArgs := ta.GetActionArgs()
+	n := len(tokens) - 2
+	switch {
+	case n >= 2*len(actionArgs):
+		return nil, "This command does not take any more arguments"
+	case n%2 == 0:
+		return []string{actionArgs[n/2].GetName()}, ""
+	default:
+		arg := actionArgs[n/2]
+		return nil, fmt.Sprintf("%d bit value for argument %s", arg.GetNBits(), arg.GetName())
+	}
+}
+
+func matchFieldHelp(field *pipeline.TableMatchField) string {
+	kind := "meta-data"
+	if field.GetIsHeader() {
+		kind = "header"
+	}
+
+	switch field.GetMatchType() {
+	case pipeline.MatchExact:
+		return fmt.Sprintf("Match field %d: %d bit %s value, exact match", field.GetIndex(), field.GetNBits(), kind)
+	case pipeline.MatchLpm:
+		return fmt.Sprintf("Match field %d: %d bit %s value/mask, longest prefix match", field.GetIndex(),
+			field.GetNBits(), kind)
+	default:
+		return fmt.Sprintf("Match field %d: %d bit %s value[/mask], wildcard match", field.GetIndex(), field.GetNBits(),
+			kind)
+	}
+}
@@ -4,12 +4,12 @@
 package cli
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/spf13/cobra"
 	"github.com/stolsma/go-p4pack/pkg/cli"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
 )
 
 func PktmbufCmd(parents ...*cobra.Command) *cobra.Command {
@@ -20,6 +20,9 @@ func PktmbufCmd(parents ...*cobra.Command) *cobra.Command {
 
 	PktmbufCreateCmd(pktmbufCmd)
 	PktmbufListCmd(pktmbufCmd)
+	PktmbufStatsCmd(pktmbufCmd)
+	PktmbufAlarmCmd(pktmbufCmd)
+	PktmbufEventsCmd(pktmbufCmd)
 	return cli.AddCommand(parents, pktmbufCmd)
 }
 
@@ -85,16 +88,145 @@ func PktmbufListCmd(parents ...*cobra.Command) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			dpdki := dpdkinfra.Get()
 
-			err := dpdki.PktmbufStore.Iterate(func(key string, value *pktmbuf.Pktmbuf) error {
-				cmd.Println(key)
-				return nil
-			})
+			for _, name := range pktmbufList() {
+				pm := dpdki.PktmbufStore.Get(name)
+				if pm == nil {
+					continue
+				}
+				stats := pm.Stats()
+				cmd.Printf("%s: buffer size %d, pool size %d, in use %d (%.1f%%)\n", name, pm.BufferSize(), stats.Size,
+					stats.InUse, stats.Usage())
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, listCmd)
+}
+
+func PktmbufStatsCmd(parents ...*cobra.Command) *cobra.Command {
+	var reset bool
+	statsCmd := &cobra.Command{
+		Use:     "stats [name]",
+		Short:   "Show the mempool utilisation of all (or one given) pktmbuf(s)",
+		Aliases: []string{"st"},
+		Args:    cobra.MaximumNArgs(1),
+		ValidArgsFunction: cli.ValidateArguments(
+			completePktmbufArg,
+			cli.AppendLastHelp(1, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
 
+			stats, err := dpdki.PktmbufStats(name)
 			if err != nil {
-				cmd.PrintErrf("List err: %v\n", err)
+				cmd.PrintErrf("Pktmbuf stats err: %v\n", err)
+				return
+			}
+
+			for _, name := range pktmbufList() {
+				s, ok := stats[name]
+				if !ok {
+					continue
+				}
+				cmd.Printf("%s:\n", name)
+				cmd.Printf("  size       : %d\n", s.Size)
+				cmd.Printf("  in use     : %d (%.1f%%)\n", s.InUse, s.Usage())
+				cmd.Printf("  available  : %d\n", s.Avail)
+				cmd.Printf("  high water : %d\n", s.HighWater)
+				if threshold, raised := dpdki.PktmbufAlarm(name); threshold > 0 {
+					state := "ok"
+					if raised {
+						state = "RAISED"
+					}
+					cmd.Printf("  alarm      : %d%% (%s)\n", threshold, state)
+				}
+				if s.CacheSize > 0 {
+					cmd.Printf("  cache size : %d\n", s.CacheSize)
+					for _, c := range s.Caches {
+						cmd.Printf("    lcore %-3d: %d\n", c.Lcore, c.Count)
+					}
+				}
+			}
+
+			if reset {
+				if err := dpdki.PktmbufResetHighWater(name); err != nil {
+					cmd.PrintErrf("Pktmbuf high water reset err: %v\n", err)
+				}
 			}
 		},
 	}
 
-	return cli.AddCommand(parents, listCmd)
+	statsCmd.Flags().BoolVarP(&reset, "reset", "z", false, "Reset the high water mark after showing the statistics.")
+	return cli.AddCommand(parents, statsCmd)
+}
+
+func PktmbufAlarmCmd(parents ...*cobra.Command) *cobra.Command {
+	alarmCmd := &cobra.Command{
+		Use:   "alarm [name] [threshold]",
+		Short: "Set the usage alarm threshold (in percent of the pool size, 0 disables) of a pktmbuf",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: cli.ValidateArguments(
+			completePktmbufArg,
+			cli.AppendHelp("You must specify the alarm threshold in percent (0 disables the alarm)"),
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			threshold, err := strconv.ParseUint(args[1], 10, 8)
+			if err != nil {
+				cmd.PrintErrf("Threshold parse err: %v\n", err)
+				return
+			}
+
+			if err := dpdki.PktmbufSetAlarm(args[0], uint(threshold)); err != nil {
+				cmd.PrintErrf("Pktmbuf alarm err: %v\n", err)
+				return
+			}
+			cmd.Printf("Pktmbuf %s alarm threshold set to %d%%!\n", args[0], threshold)
+		},
+	}
+
+	return cli.AddCommand(parents, alarmCmd)
+}
+
+func PktmbufEventsCmd(parents ...*cobra.Command) *cobra.Command {
+	eventsCmd := &cobra.Command{
+		Use:     "events",
+		Short:   "Show the usage alarm events of all pktmbufs, use CTRL-C to stop",
+		Aliases: []string{"ev"},
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dpdki := dpdkinfra.Get()
+
+			events, unsubscribe := dpdki.PktmbufSubscribe()
+			ctx, cancelFn := context.WithCancel(cmd.Context())
+
+			cmd.Printf("Press CTRL-C to quit!\n")
+			go func() {
+				for {
+					select {
+					case e, ok := <-events:
+						if !ok {
+							return
+						}
+						cmd.Printf("%s\n", e.String())
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			// wait for CTRL-C and then cancel output
+			cli.WaitForCtrlC(cmd.InOrStdin())
+			cancelFn()
+			unsubscribe()
+		},
+	}
+
+	return cli.AddCommand(parents, eventsCmd)
 }
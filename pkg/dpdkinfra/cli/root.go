@@ -25,6 +25,7 @@ func GetCommand(parent *cobra.Command) *cobra.Command {
 	PktmbufCmd(parent)
 	InterfaceCmd(parent)
 	PipelineCmd(parent)
+	CaptureCmd(parent)
 
 	return parent
 }
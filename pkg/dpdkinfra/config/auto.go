@@ -0,0 +1,49 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+)
+
+const autoValue = "auto"
+
+// AutoInt is a config integer value that can also be given as "auto", i.e. a NUMA node or thread id that is selected
+// automatically
+type AutoInt struct {
+	Auto  bool
+	Value int
+}
+
+func (a *AutoInt) UnmarshalJSON(data []byte) error {
+	var s string
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s != autoValue {
+			return fmt.Errorf("%q is not an integer or %q", s, autoValue)
+		}
+		*a = AutoInt{Auto: true}
+		return nil
+	}
+
+	*a = AutoInt{}
+	return json.Unmarshal(data, &a.Value)
+}
+
+func (a AutoInt) MarshalJSON() ([]byte, error) {
+	if a.Auto {
+		return json.Marshal(autoValue)
+	}
+	return json.Marshal(a.Value)
+}
+
+func (a *AutoInt) JSONSchema() *schema.Schema {
+	return &schema.Schema{OneOf: []*schema.Schema{{Type: "integer"}, {Enum: []any{autoValue}}}}
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+)
+
+const (
+	chainRingSize  = 1024 // default size of a chain ring
+	chainBsz       = 32   // default burst size of the chain ring pipeline ports
+	maxRingNameLen = 28   // RTE_RING_NAMESIZE minus the ring memzone prefix and the terminating zero
+)
+
+// chain string format, i.e. "PIPE_A out 3 -> PIPE_B in 1"
+var chainRegexp = regexp.MustCompile(`^\s*(\S+)\s+out\s+([0-9]+)\s*->\s*(\S+)\s+in\s+([0-9]+)\s*$`)
+
+type ChainsConfig []*ChainConfig
+
+// ChainConfig links an output port of a pipeline to an input port of another pipeline through a ring interface that
+// is created for the chain. It can also be given as string, i.e. "PIPE_A out 3 -> PIPE_B in 1".
+type ChainConfig struct {
+	From     string   `json:"from"`               // sending pipeline
+	Out      uint     `json:"out"`                // output port of the sending pipeline
+	To       string   `json:"to"`                 // receiving pipeline
+	In       uint     `json:"in"`                 // input port of the receiving pipeline
+	Name     string   `json:"name,omitempty"`     // ring interface name, "<from>_<out>_<to>_<in>" when not set
+	Size     uint     `json:"size,omitempty"`     // ring size, 1024 when not set
+	Bsz      uint     `json:"bsz,omitempty"`      // burst size of both pipeline ports, 32 when not set
+	NumaNode *AutoInt `json:"numanode,omitempty"` // NUMA node of the ring, "auto" when not set
+}
+
+type chainConfig ChainConfig
+
+func (c *ChainConfig) UnmarshalJSON(data []byte) error {
+	// chain string only
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return c.parse(s)
+	}
+
+	return config.Unmarshal(data, (*chainConfig)(c))
+}
+
+func (c *ChainConfig) JSONSchema() *schema.Schema {
+	return &schema.Schema{OneOf: []*schema.Schema{
+		{Type: "string", Pattern: chainRegexp.String()},
+		schema.Of(&chainConfig{}),
+	}}
+}
+
+func (c *ChainConfig) parse(s string) error {
+	m := chainRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return fmt.Errorf("chain %q is not formatted as \"<pipeline> out <port> -> <pipeline> in <port>\"", s)
+	}
+
+	out, err := strconv.ParseUint(m[2], 10, 32)
+	if err != nil {
+		return fmt.Errorf("chain %q output port: %w", s, err)
+	}
+	in, err := strconv.ParseUint(m[4], 10, 32)
+	if err != nil {
+		return fmt.Errorf("chain %q input port: %w", s, err)
+	}
+
+	*c = ChainConfig{From: m[1], Out: uint(out), To: m[3], In: uint(in)}
+	return nil
+}
+
+func (c *ChainConfig) String() string {
+	return fmt.Sprintf("%s out %d -> %s in %d", c.From, c.Out, c.To, c.In)
+}
+
+func (c *ChainConfig) GetName() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s_%d_%s_%d", c.From, c.Out, c.To, c.In)
+	}
+	return c.Name
+}
+
+func (c *ChainConfig) GetSize() uint {
+	if c.Size == 0 {
+		return chainRingSize
+	}
+	return c.Size
+}
+
+func (c *ChainConfig) GetBsz() uint {
+	if c.Bsz == 0 {
+		return chainBsz
+	}
+	return c.Bsz
+}
+
+// the NUMA node of the ring. With "auto" the NUMA node of the receiving pipeline is used, or else of the sending
+// pipeline. When both pipelines are placed automatically the ring is placed with the sending pipeline, its NUMA node
+// is only known when the ring is created and ok is false.
+func (c *ChainConfig) getNumaNode(pipelines PipelinesConfig) (numaNode int, ok bool) {
+	if c.NumaNode != nil && !c.NumaNode.Auto {
+		return c.NumaNode.Value, true
+	}
+	for _, name := range []string{c.To, c.From} {
+		if pc, ok := find(pipelines, name); ok && !pc.NumaNode.Auto {
+			return pc.GetNumaNode(), true
+		}
+	}
+	return 0, false
+}
+
+// pipeline port of a chain end
+type chainPort struct {
+	pipeline string
+	port     uint
+	in       bool
+}
+
+// Validate checks the chains and adds the errors found to errs. The ring names must not be used by the interfaces and
+// the chain ports must fit between the configured ports of the pipelines, see Config.chained.
+func (c ChainsConfig) Validate(path string, pipelines PipelinesConfig, interfaces InterfacesConfig,
+	errs *validation.Errors,
+) {
+	names := make(map[string]bool)
+	ports := make(map[chainPort]bool)
+	nPorts := make(map[chainPort]uint) // number of chain ports per pipeline and direction, port is not used
+
+	for _, chain := range c {
+		if _, ok := find(pipelines, chain.From); ok {
+			nPorts[chainPort{pipeline: chain.From}]++
+		}
+		if _, ok := find(pipelines, chain.To); ok {
+			nPorts[chainPort{pipeline: chain.To, in: true}]++
+		}
+	}
+
+	for i, chain := range c {
+		cpath := validation.Path(path, i)
+
+		name := chain.GetName()
+		switch {
+		case len(name) > maxRingNameLen:
+			errs.Addf(validation.Path(cpath, "name"), "ring name %s is longer than %d characters", name, maxRingNameLen)
+		case names[name] || interfaces.get(name) != nil:
+			errs.Addf(validation.Path(cpath, "name"), "ring name %s already used", name)
+		}
+		names[name] = true
+
+		if size := chain.GetSize(); size&(size-1) != 0 {
+			errs.Addf(validation.Path(cpath, "size"), "size %d is not a power of 2", size)
+		}
+
+		// check a chain end, the port must be free and fit in the port list of the pipeline
+		checkPort := func(key string, portKey string, cp chainPort) {
+			pc, ok := find(pipelines, cp.pipeline)
+			switch {
+			case cp.pipeline == "":
+				errs.Addf(validation.Path(cpath, key), "pipeline missing")
+				return
+			case !ok:
+				errs.Addf(validation.Path(cpath, key), "unknown pipeline %s", cp.pipeline)
+				return
+			case ports[cp]:
+				errs.Addf(validation.Path(cpath, portKey), "port %d of pipeline %s already chained", cp.port,
+					cp.pipeline)
+				return
+			}
+			ports[cp] = true
+
+			n := uint(len(pc.OutputPorts))
+			if cp.in {
+				n = uint(len(pc.InputPorts))
+			}
+			n += nPorts[chainPort{pipeline: cp.pipeline, in: cp.in}]
+			if cp.port >= n {
+				errs.Addf(validation.Path(cpath, portKey), "port %d of pipeline %s out of range, the pipeline has %d ports",
+					cp.port, cp.pipeline, n)
+			}
+		}
+		checkPort("from", "out", chainPort{pipeline: chain.From, port: chain.Out})
+		checkPort("to", "in", chainPort{pipeline: chain.To, port: chain.In, in: true})
+	}
+}
+
+// chained returns a copy of the config with the ring interfaces and the pipeline port bindings of the chains added,
+// the config itself when it has no chains. The chain ports get their given port IDs and the configured ports of a
+// pipeline fill the other port IDs in order.
+func (c *Config) chained() *Config {
+	if len(c.Chains) == 0 {
+		return c
+	}
+
+	cc := c.copy()
+	ins := make(map[string]map[uint]*InPortConfig)
+	outs := make(map[string]map[uint]*OutPortConfig)
+	autoRings := make(map[*RingParams]string) // rings placed with the automatically placed sending pipeline
+	for _, chain := range c.Chains {
+		_, fromOk := find(c.Pipelines, chain.From)
+		_, toOk := find(c.Pipelines, chain.To)
+		if !fromOk || !toOk {
+			continue
+		}
+
+		name := chain.GetName()
+		numaNode, ok := chain.getNumaNode(c.Pipelines)
+		params := &RingParams{Size: chain.GetSize(), NumaNode: uint32(numaNode)}
+		if !ok {
+			autoRings[params] = chain.From
+		}
+		cc.Interfaces = append(cc.Interfaces, &InterfaceConfig{Name: name, Type: portmngr.PortTypeRing, Params: params})
+
+		if outs[chain.From] == nil {
+			outs[chain.From] = make(map[uint]*OutPortConfig)
+		}
+		outs[chain.From][chain.Out] = &OutPortConfig{IfaceName: name, Bsz: chain.GetBsz()}
+		if ins[chain.To] == nil {
+			ins[chain.To] = make(map[uint]*InPortConfig)
+		}
+		ins[chain.To][chain.In] = &InPortConfig{IfaceName: name, Bsz: chain.GetBsz()}
+	}
+
+	for i, pc := range cc.Pipelines {
+		if ins[pc.GetName()] == nil && outs[pc.GetName()] == nil {
+			continue
+		}
+		npc := *pc
+		npc.InputPorts = mergePorts(pc.InputPorts, ins[pc.GetName()])
+		npc.OutputPorts = mergePorts(pc.OutputPorts, outs[pc.GetName()])
+		cc.Pipelines[i] = &npc
+	}
+
+	for params, from := range autoRings {
+		params.numaPipeline, _ = find(cc.Pipelines, from)
+	}
+
+	return cc
+}
+
+// merge the chain ports at their port IDs into the ports, the ports fill the other port IDs in order. Port IDs out of
+// range (reported by the validation) are skipped.
+func mergePorts[T any](ports []T, chainPorts map[uint]T) []T {
+	n := len(ports) + len(chainPorts)
+	result := make([]T, 0, n)
+	next := 0
+	for id := uint(0); id < uint(n); id++ {
+		if p, ok := chainPorts[id]; ok {
+			result = append(result, p)
+		} else if next < len(ports) {
+			result = append(result, ports[next])
+			next++
+		}
+	}
+	return append(result, ports[next:]...)
+}
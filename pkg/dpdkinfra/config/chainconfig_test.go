@@ -0,0 +1,181 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainConfigUnmarshal(t *testing.T) {
+	tests := []struct {
+		data  string
+		chain ChainConfig
+		err   string
+	}{
+		{`"PIPE_A out 3 -> PIPE_B in 1"`, ChainConfig{From: "PIPE_A", Out: 3, To: "PIPE_B", In: 1}, ""},
+		{`"  A out 0->B in 2 "`, ChainConfig{From: "A", Out: 0, To: "B", In: 2}, ""},
+		{`{"from": "A", "out": 1, "to": "B", "in": 0, "size": 512}`,
+			ChainConfig{From: "A", Out: 1, To: "B", In: 0, Size: 512}, ""},
+		{`"A out x -> B in 1"`, ChainConfig{},
+			`chain "A out x -> B in 1" is not formatted as "<pipeline> out <port> -> <pipeline> in <port>"`},
+		{`"A in 1 -> B out 1"`, ChainConfig{},
+			`chain "A in 1 -> B out 1" is not formatted as "<pipeline> out <port> -> <pipeline> in <port>"`},
+		{`"A out 4294967296 -> B in 1"`, ChainConfig{}, `chain "A out 4294967296 -> B in 1" output port: ` +
+			`strconv.ParseUint: parsing "4294967296": value out of range`},
+	}
+
+	for _, test := range tests {
+		var chain ChainConfig
+		err := json.Unmarshal([]byte(test.data), &chain)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.data)
+			continue
+		}
+		if assert.NoError(t, err, test.data) {
+			assert.Equal(t, test.chain, chain, test.data)
+		}
+	}
+
+	chain := ChainConfig{From: "A", Out: 1, To: "B", In: 2}
+	assert.Equal(t, "A out 1 -> B in 2", chain.String())
+	assert.Equal(t, "A_1_B_2", chain.GetName())
+	assert.Equal(t, uint(chainRingSize), chain.GetSize())
+	assert.Equal(t, uint(chainBsz), chain.GetBsz())
+}
+
+func TestChainConfigGetNumaNode(t *testing.T) {
+	pipelines := PipelinesConfig{
+		{Name: "auto0", NumaNode: AutoInt{Auto: true}},
+		{Name: "auto1", NumaNode: AutoInt{Auto: true}},
+		{Name: "numa1", NumaNode: AutoInt{Value: 1}},
+		{Name: "numa2", NumaNode: AutoInt{Value: 2}},
+	}
+
+	tests := []struct {
+		chain    ChainConfig
+		numaNode int
+		ok       bool
+	}{
+		{ChainConfig{From: "numa1", To: "numa2", NumaNode: &AutoInt{Value: 3}}, 3, true},
+		{ChainConfig{From: "numa1", To: "numa2", NumaNode: &AutoInt{Auto: true}}, 2, true},
+		{ChainConfig{From: "numa1", To: "numa2"}, 2, true},
+		{ChainConfig{From: "numa1", To: "auto0"}, 1, true},
+		{ChainConfig{From: "auto0", To: "numa2"}, 2, true},
+		{ChainConfig{From: "auto0", To: "auto1"}, 0, false},
+	}
+
+	for _, test := range tests {
+		numaNode, ok := test.chain.getNumaNode(pipelines)
+		assert.Equal(t, test.numaNode, numaNode, test.chain.String())
+		assert.Equal(t, test.ok, ok, test.chain.String())
+	}
+}
+
+func TestChainsConfigValidate(t *testing.T) {
+	pipelines := PipelinesConfig{
+		{Name: "A", OutputPorts: []*OutPortConfig{{IfaceName: "out0"}}},
+		{Name: "B", InputPorts: []*InPortConfig{{IfaceName: "in0"}}},
+	}
+	interfaces := InterfacesConfig{{Name: "RING0", Type: portmngr.PortTypeRing}}
+
+	tests := []struct {
+		name   string
+		chains ChainsConfig
+		errs   []string
+	}{
+		{"valid", ChainsConfig{{From: "A", Out: 1, To: "B", In: 0}, {From: "A", Out: 0, To: "B", In: 2}}, nil},
+		{"size", ChainsConfig{{From: "A", Out: 1, To: "B", In: 1, Size: 1000}},
+			[]string{"chains[0].size: size 1000 is not a power of 2"}},
+		{"duplicate port", ChainsConfig{{From: "A", Out: 1, To: "B", In: 1}, {From: "A", Out: 1, To: "B", In: 2}},
+			[]string{"chains[1].out: port 1 of pipeline A already chained"}},
+		{"pipeline missing", ChainsConfig{{Out: 1, To: "B", In: 1}}, []string{"chains[0].from: pipeline missing"}},
+		{"unknown pipeline", ChainsConfig{{From: "A", Out: 1, To: "C", In: 1}},
+			[]string{"chains[0].to: unknown pipeline C"}},
+		{"out of range", ChainsConfig{{From: "A", Out: 2, To: "B", In: 3}}, []string{
+			"chains[0].out: port 2 of pipeline A out of range, the pipeline has 2 ports",
+			"chains[0].in: port 3 of pipeline B out of range, the pipeline has 2 ports",
+		}},
+		{"name too long", ChainsConfig{{From: "A", Out: 1, To: "B", In: 1, Name: "RING_WITH_A_VERY_LONG_NAME_012"}},
+			[]string{"chains[0].name: ring name RING_WITH_A_VERY_LONG_NAME_012 is longer than 28 characters"}},
+		{"name used by interface", ChainsConfig{{From: "A", Out: 1, To: "B", In: 1, Name: "RING0"}},
+			[]string{"chains[0].name: ring name RING0 already used"}},
+		{"name used by chain", ChainsConfig{
+			{From: "A", Out: 1, To: "B", In: 1, Name: "RING1"},
+			{From: "A", Out: 0, To: "B", In: 0, Name: "RING1"},
+		}, []string{"chains[1].name: ring name RING1 already used"}},
+	}
+
+	for _, test := range tests {
+		var errs validation.Errors
+		test.chains.Validate("chains", pipelines, interfaces, &errs)
+		var msgs []string
+		for _, err := range errs.List() {
+			msgs = append(msgs, err.Error())
+		}
+		assert.Equal(t, test.errs, msgs, test.name)
+	}
+}
+
+func TestMergePorts(t *testing.T) {
+	tests := []struct {
+		ports  []string
+		chains map[uint]string
+		result []string
+	}{
+		{[]string{"a", "b"}, nil, []string{"a", "b"}},
+		{nil, map[uint]string{0: "x", 1: "y"}, []string{"x", "y"}},
+		{[]string{"a", "b"}, map[uint]string{0: "x"}, []string{"x", "a", "b"}},
+		{[]string{"a", "b"}, map[uint]string{1: "x", 3: "y"}, []string{"a", "x", "b", "y"}},
+		{[]string{"a", "b"}, map[uint]string{2: "x"}, []string{"a", "b", "x"}},
+		{[]string{"a"}, map[uint]string{5: "x"}, []string{"a"}}, // out of range chain port is skipped
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.result, mergePorts(test.ports, test.chains), test.chains)
+	}
+}
+
+func TestConfigChained(t *testing.T) {
+	c := &Config{
+		Interfaces: InterfacesConfig{{Name: "LINK0", Type: portmngr.PortTypeEthdev}},
+		Pipelines: PipelinesConfig{
+			{Name: "A", NumaNode: AutoInt{Auto: true}, InputPorts: []*InPortConfig{{IfaceName: "LINK0", Bsz: 8}}},
+			{Name: "B", NumaNode: AutoInt{Auto: true}, OutputPorts: []*OutPortConfig{{IfaceName: "LINK0", Bsz: 8}}},
+			{Name: "C", NumaNode: AutoInt{Value: 1}},
+		},
+		Chains: ChainsConfig{
+			{From: "A", Out: 0, To: "B", In: 0, Bsz: 16},
+			{From: "B", Out: 0, To: "C", In: 0, Size: 256},
+			{From: "A", Out: 1, To: "D", In: 0}, // unknown pipeline, skipped
+		},
+	}
+
+	cc := c.chained()
+	assert.Len(t, c.Interfaces, 1)
+	assert.Len(t, c.Pipelines[0].OutputPorts, 0)
+	if !assert.Len(t, cc.Interfaces, 3) {
+		return
+	}
+
+	// both pipelines are placed automatically, the ring is created on the NUMA node of the sending pipeline
+	assert.Equal(t, "A_0_B_0", cc.Interfaces[1].Name)
+	assert.Equal(t, portmngr.PortTypeRing, cc.Interfaces[1].Type)
+	assert.Equal(t, &RingParams{Size: chainRingSize, numaPipeline: cc.Pipelines[0]}, cc.Interfaces[1].Params)
+	assert.Equal(t, &RingParams{Size: 256, NumaNode: 1}, cc.Interfaces[2].Params)
+
+	assert.Equal(t, []*OutPortConfig{{IfaceName: "A_0_B_0", Bsz: 16}}, cc.Pipelines[0].OutputPorts)
+	assert.Equal(t, []*InPortConfig{{IfaceName: "A_0_B_0", Bsz: 16}}, cc.Pipelines[1].InputPorts)
+	assert.Equal(t, []*OutPortConfig{{IfaceName: "B_0_C_0", Bsz: chainBsz}, {IfaceName: "LINK0", Bsz: 8}},
+		cc.Pipelines[1].OutputPorts)
+	assert.Equal(t, []*InPortConfig{{IfaceName: "B_0_C_0", Bsz: chainBsz}}, cc.Pipelines[2].InputPorts)
+
+	// without chains the config itself is used
+	nc := &Config{Pipelines: PipelinesConfig{{Name: "A"}}}
+	assert.Same(t, nc, nc.chained())
+}
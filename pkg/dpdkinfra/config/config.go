@@ -5,6 +5,7 @@ package config
 
 import (
 	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/logging"
 )
 
@@ -19,14 +20,24 @@ func init() {
 
 type Config struct {
 	*config.Base
+	Eal        *EalConfig       `json:"eal"` // used to initialize DPDK, not processed by Apply
 	Pktmbufs   PktmbufsConfig   `json:"pktmbufs"`
 	Devices    DevicesConfig    `json:"devices"`
 	Interfaces InterfacesConfig `json:"interfaces"`
 	Pipelines  PipelinesConfig  `json:"pipelines"`
+	Chains     ChainsConfig     `json:"chains,omitempty"` // pipeline links, expanded into rings and port bindings
 }
 
 // Process everything in this config structure
 func (c *Config) Apply() error {
+	running.Lock()
+	running.conf.SetBasePath(c.GetBasePath())
+	running.conf.Eal = c.Eal
+	running.Unlock()
+
+	// the chains are created as ring interfaces bound to the pipelines
+	c = c.chained()
+
 	// Order of processing is important because Pipeline needs Interface and Interface needs Pktmbuf!!
 	if err := c.Pktmbufs.Apply(); err != nil {
 		return err
@@ -47,6 +58,19 @@ func (c *Config) Apply() error {
 	return nil
 }
 
+// Validate checks everything in this config structure without applying it and adds the errors found to errs. The EAL
+// settings are not checked because they can be overruled by the command line, use EalConfig.Validate for those.
+func (c *Config) Validate(path string, errs *validation.Errors) {
+	c.Pktmbufs.Validate(validation.Path(path, "pktmbufs"), errs)
+	c.Devices.Validate(validation.Path(path, "devices"), errs)
+	c.Chains.Validate(validation.Path(path, "chains"), c.Pipelines, c.Interfaces, errs)
+
+	// the interfaces and pipelines including the rings and port bindings of the chains
+	cc := c.chained()
+	cc.Interfaces.Validate(validation.Path(path, "interfaces"), c.Pktmbufs.names(), errs)
+	cc.Pipelines.Validate(validation.Path(path, "pipelines"), c.GetBasePath(), cc.Interfaces, errs)
+}
+
 func Create() *Config {
 	return &Config{Base: &config.Base{}}
 }
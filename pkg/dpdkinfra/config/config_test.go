@@ -0,0 +1,61 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"interface", `{"interfaces": [{"name": "RING0", "ring": {"size": 1024}, "comment": "x"}]}`,
+			`interface RING0: unknown field "comment"`},
+		{"interface type", `{"interfaces": [{"name": "RING0", "ring": {"size": 1024, "numa": 0}}]}`,
+			`interface RING0 ring config: json: unknown field "numa"`},
+		{"rss", `{"interfaces": [{"name": "LINK0", "ethdev": {"portname": "net_tap0", ` +
+			`"rx": {"nqueues": 2, "rss": {"queues": [0, 1], "hash": "ip"}}}}]}`, `json: unknown field "hash"`},
+		{"chain", `{"chains": [{"from": "A", "out": 0, "to": "B", "in": 0, "ring": "R"}]}`,
+			`json: unknown field "ring"`},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		filename := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(filename, []byte(test.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// the unknown keys are ignored by the lenient loader
+		c := &Config{Base: &config.Base{}}
+		assert.NoError(t, config.LoadConfig(filename, c), test.name)
+
+		c = &Config{Base: &config.Base{}}
+		assert.ErrorContains(t, config.LoadConfigStrict(filename, c), test.err, test.name)
+	}
+
+	// the known fields are decoded by the lenient loader
+	filename := filepath.Join(dir, "config.json")
+	data := `{"interfaces": [{"name": "LINK0", "comment": "x", "ethdev": {"portname": "net_tap0", "x": 1, ` +
+		`"rx": {"nqueues": 2, "rss": {"queues": [1], "x": 1}}}}], "chains": ["A out 0 -> B in 1"]}`
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{Base: &config.Base{}}
+	if !assert.NoError(t, config.LoadConfig(filename, c)) || !assert.Len(t, c.Interfaces, 1) {
+		return
+	}
+	assert.Equal(t, &PMDParams{PortName: "net_tap0", Rx: &PMDRxParams{NQueues: 2, Rss: &RssParams{Queues: []uint16{1}}}},
+		c.Interfaces[0].Params)
+	assert.Equal(t, ChainsConfig{{From: "A", Out: 0, To: "B", In: 1}}, c.Chains)
+	assert.False(t, config.Strict())
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
 )
 
@@ -19,15 +20,42 @@ func (c DevicesConfig) Apply() error {
 		return errors.New("dpdkinfra module is not initialized")
 	}
 
+	running.Lock()
+	defer running.Unlock()
+
 	// hotplug devices
 	for _, devArgString := range c {
-		devArgs, err := dpdki.AttachDevice(devArgString)
-		if err != nil {
-			log.Infof("Hotplug (devargs: %s) error: %v", devArgString, err)
-			return fmt.Errorf("error creating hotplug device: %v", err)
+		if err := attachDevice(dpdki, devArgString); err != nil {
+			return err
 		}
-		log.Infof("Device (%s) created via hotplug on bus %s!", devArgs.Name(), devArgs.Bus())
 	}
 
 	return nil
 }
+
+// attach the device and add it to the running configuration
+func attachDevice(dpdki *dpdkinfra.DpdkInfra, devArgString string) error {
+	devArgs, err := dpdki.AttachDevice(devArgString)
+	if err != nil {
+		log.Infof("Hotplug (devargs: %s) error: %v", devArgString, err)
+		return fmt.Errorf("error creating hotplug device: %v", err)
+	}
+	running.conf.Devices = replaceDevice(running.conf.Devices, devArgString)
+	log.Infof("Device (%s) created via hotplug on bus %s!", devArgs.Name(), devArgs.Bus())
+	return nil
+}
+
+// Validate checks the devices configuration without creating the devices and adds the errors found to errs
+func (c DevicesConfig) Validate(path string, errs *validation.Errors) {
+	names := make(map[string]bool)
+	for i, devArgString := range c {
+		name := deviceName(devArgString)
+		switch {
+		case name == "":
+			errs.Addf(validation.Path(path, i), "device name missing")
+		case names[name]:
+			errs.Addf(validation.Path(path, i), "duplicate device %s", name)
+		}
+		names[name] = true
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EalConfig are the DPDK EAL (Environment Abstraction Layer) settings. The settings are rendered into EAL arguments
+// with Args and used to initialize DPDK before the rest of the chassis configuration is applied.
+type EalConfig struct {
+	Lcores         string   `json:"lcores,omitempty"`         // lcore list or mapping (--lcores), i.e. "0-3" or "1@(0,2)"
+	CoreMask       string   `json:"coremask,omitempty"`       // hexadecimal core mask (-c), i.e. "0x3"
+	MainLcore      *uint    `json:"mainlcore,omitempty"`      // main lcore id (--main-lcore)
+	MemoryChannels uint     `json:"memorychannels,omitempty"` // number of memory channels (-n)
+	Memory         uint     `json:"memory,omitempty"`         // memory to preallocate in MB (-m)
+	SocketMem      []uint   `json:"socketmem,omitempty"`      // memory to preallocate per NUMA node in MB (--socket-mem)
+	HugeDir        string   `json:"hugedir,omitempty"`        // hugetlbfs directory to use (--huge-dir)
+	FilePrefix     string   `json:"fileprefix,omitempty"`     // prefix of the hugepage and runtime files (--file-prefix)
+	NoHuge         bool     `json:"nohuge,omitempty"`         // use anonymous memory instead of hugepages (--no-huge)
+	InMemory       bool     `json:"inmemory,omitempty"`       // don't create any shared runtime files (--in-memory)
+	Allow          []string `json:"allow,omitempty"`          // PCI devices to use exclusively (-a)
+	Block          []string `json:"block,omitempty"`          // PCI devices not to use (-b)
+	Vdevs          []string `json:"vdevs,omitempty"`          // virtual devices to create (--vdev)
+	LogLevels      []string `json:"loglevels,omitempty"`      // log levels as <level> or <logtype pattern>:<level>
+	IovaMode       string   `json:"iovamode,omitempty"`       // IO virtual address mode "pa" or "va" (--iova-mode)
+	ProcType       string   `json:"proctype,omitempty"`       // "primary", "secondary" or "auto" (--proc-type)
+	ExtraArgs      []string `json:"extraargs,omitempty"`      // additional EAL arguments added as given
+}
+
+// the DPDK log level names, the level number is the index + 1
+var ealLogLevels = []string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// DefaultEalConfig returns the EAL settings used when no EAL configuration is given
+func DefaultEalConfig() *EalConfig {
+	return &EalConfig{
+		CoreMask:       "0x3",
+		MemoryChannels: 4,
+	}
+}
+
+// Validate checks the EAL settings for invalid values and conflicting options
+func (ec *EalConfig) Validate() error {
+	if ec.Lcores != "" && ec.CoreMask != "" {
+		return errors.New("eal: lcores and coremask can't both be given")
+	}
+	if ec.Lcores != "" {
+		if strings.Trim(ec.Lcores, "0123456789,-@()") != "" {
+			return fmt.Errorf("eal: invalid lcores %q", ec.Lcores)
+		}
+	}
+
+	var mask uint64
+	if ec.CoreMask != "" {
+		m, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(ec.CoreMask), "0x"), 16, 64)
+		if err != nil || m == 0 {
+			return fmt.Errorf("eal: invalid coremask %q", ec.CoreMask)
+		}
+		mask = m
+	}
+	if ec.MainLcore != nil && mask != 0 && (*ec.MainLcore >= 64 || mask&(1<<*ec.MainLcore) == 0) {
+		return fmt.Errorf("eal: main lcore %d is not in coremask %s", *ec.MainLcore, ec.CoreMask)
+	}
+
+	if ec.Memory != 0 && len(ec.SocketMem) > 0 {
+		return errors.New("eal: memory and socketmem can't both be given")
+	}
+	if ec.NoHuge && len(ec.SocketMem) > 0 {
+		return errors.New("eal: socketmem can't be used with nohuge")
+	}
+	if ec.NoHuge && ec.HugeDir != "" {
+		return errors.New("eal: hugedir can't be used with nohuge")
+	}
+	if strings.ContainsAny(ec.FilePrefix, "/ ") {
+		return fmt.Errorf("eal: invalid fileprefix %q", ec.FilePrefix)
+	}
+
+	if len(ec.Allow) > 0 && len(ec.Block) > 0 {
+		return errors.New("eal: allow and block lists can't both be given")
+	}
+	for _, dev := range append(append([]string{}, ec.Allow...), ec.Block...) {
+		if dev == "" {
+			return errors.New("eal: empty device in allow or block list")
+		}
+	}
+	for _, vdev := range ec.Vdevs {
+		if vdev == "" {
+			return errors.New("eal: empty vdev")
+		}
+	}
+
+	for _, ll := range ec.LogLevels {
+		if err := validateLogLevel(ll); err != nil {
+			return err
+		}
+	}
+
+	switch ec.IovaMode {
+	case "", "pa", "va":
+	default:
+		return fmt.Errorf("eal: invalid iovamode %q, should be pa or va", ec.IovaMode)
+	}
+
+	switch ec.ProcType {
+	case "", "primary", "secondary", "auto":
+	default:
+		return fmt.Errorf("eal: invalid proctype %q, should be primary, secondary or auto", ec.ProcType)
+	}
+	if ec.ProcType == "secondary" && ec.InMemory {
+		return errors.New("eal: a secondary process can't run inmemory")
+	}
+
+	return nil
+}
+
+// check a log level given as <level> or <logtype pattern>:<level>
+func validateLogLevel(ll string) error {
+	level := ll
+	if i := strings.LastIndexAny(ll, ":,"); i >= 0 {
+		if i == 0 {
+			return fmt.Errorf("eal: no logtype pattern in loglevel %q", ll)
+		}
+		level = ll[i+1:]
+	}
+
+	if n, err := strconv.Atoi(level); err == nil {
+		if n < 1 || n > len(ealLogLevels) {
+			return fmt.Errorf("eal: loglevel %q out of range 1-%d", ll, len(ealLogLevels))
+		}
+		return nil
+	}
+	for _, name := range ealLogLevels {
+		if level == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("eal: invalid level in loglevel %q", ll)
+}
+
+// Args validates the EAL settings and renders them into EAL arguments, the first argument is the given program name
+func (ec *EalConfig) Args(program string) ([]string, error) {
+	if err := ec.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := []string{program}
+	add := func(arg ...string) {
+		args = append(args, arg...)
+	}
+
+	if ec.Lcores != "" {
+		add("--lcores", ec.Lcores)
+	}
+	if ec.CoreMask != "" {
+		add("-c", ec.CoreMask)
+	}
+	if ec.MainLcore != nil {
+		add("--main-lcore", strconv.FormatUint(uint64(*ec.MainLcore), 10))
+	}
+	if ec.MemoryChannels != 0 {
+		add("-n", strconv.FormatUint(uint64(ec.MemoryChannels), 10))
+	}
+	if ec.Memory != 0 {
+		add("-m", strconv.FormatUint(uint64(ec.Memory), 10))
+	}
+	if len(ec.SocketMem) > 0 {
+		mem := make([]string, len(ec.SocketMem))
+		for i, m := range ec.SocketMem {
+			mem[i] = strconv.FormatUint(uint64(m), 10)
+		}
+		add("--socket-mem", strings.Join(mem, ","))
+	}
+	if ec.HugeDir != "" {
+		add("--huge-dir", ec.HugeDir)
+	}
+	if ec.FilePrefix != "" {
+		add("--file-prefix", ec.FilePrefix)
+	}
+	if ec.NoHuge {
+		add("--no-huge")
+	}
+	if ec.InMemory {
+		add("--in-memory")
+	}
+	for _, dev := range ec.Allow {
+		add("-a", dev)
+	}
+	for _, dev := range ec.Block {
+		add("-b", dev)
+	}
+	for _, vdev := range ec.Vdevs {
+		add("--vdev", vdev)
+	}
+	for _, ll := range ec.LogLevels {
+		add("--log-level", ll)
+	}
+	if ec.IovaMode != "" {
+		add("--iova-mode", ec.IovaMode)
+	}
+	if ec.ProcType != "" {
+		add("--proc-type", ec.ProcType)
+	}
+	add(ec.ExtraArgs...)
+
+	return args, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEalConfigValidate(t *testing.T) {
+	lcore := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name string
+		ec   EalConfig
+		err  string
+	}{
+		{"empty", EalConfig{}, ""},
+		{"default", *DefaultEalConfig(), ""},
+		{"lcores", EalConfig{Lcores: "1@(0,2),3-4"}, ""},
+		{"lcores and coremask", EalConfig{Lcores: "0-3", CoreMask: "0x3"}, "eal: lcores and coremask can't both be given"},
+		{"lcores invalid", EalConfig{Lcores: "0-3;5"}, `eal: invalid lcores "0-3;5"`},
+		{"coremask uppercase", EalConfig{CoreMask: "0XF"}, ""},
+		{"coremask invalid", EalConfig{CoreMask: "0xg"}, `eal: invalid coremask "0xg"`},
+		{"coremask zero", EalConfig{CoreMask: "0x0"}, `eal: invalid coremask "0x0"`},
+		{"main lcore", EalConfig{CoreMask: "0x6", MainLcore: lcore(2)}, ""},
+		{"main lcore without coremask", EalConfig{MainLcore: lcore(100)}, ""},
+		{"main lcore not in coremask", EalConfig{CoreMask: "0x6", MainLcore: lcore(0)},
+			"eal: main lcore 0 is not in coremask 0x6"},
+		{"main lcore out of coremask range", EalConfig{CoreMask: "0x6", MainLcore: lcore(64)},
+			"eal: main lcore 64 is not in coremask 0x6"},
+		{"memory and socketmem", EalConfig{Memory: 1024, SocketMem: []uint{512}},
+			"eal: memory and socketmem can't both be given"},
+		{"nohuge and socketmem", EalConfig{NoHuge: true, SocketMem: []uint{512}},
+			"eal: socketmem can't be used with nohuge"},
+		{"nohuge and hugedir", EalConfig{NoHuge: true, HugeDir: "/dev/hugepages"}, "eal: hugedir can't be used with nohuge"},
+		{"fileprefix", EalConfig{FilePrefix: "a b"}, `eal: invalid fileprefix "a b"`},
+		{"allow and block", EalConfig{Allow: []string{"0000:00:04.0"}, Block: []string{"0000:00:05.0"}},
+			"eal: allow and block lists can't both be given"},
+		{"empty allow", EalConfig{Allow: []string{""}}, "eal: empty device in allow or block list"},
+		{"empty vdev", EalConfig{Vdevs: []string{"net_tap0", ""}}, "eal: empty vdev"},
+		{"loglevels", EalConfig{LogLevels: []string{"debug", "8", "lib.eal:info", "pmd.*,3"}}, ""},
+		{"loglevel range", EalConfig{LogLevels: []string{"9"}}, `eal: loglevel "9" out of range 1-8`},
+		{"loglevel name", EalConfig{LogLevels: []string{"lib.eal:verbose"}},
+			`eal: invalid level in loglevel "lib.eal:verbose"`},
+		{"loglevel pattern", EalConfig{LogLevels: []string{":debug"}}, `eal: no logtype pattern in loglevel ":debug"`},
+		{"iovamode", EalConfig{IovaMode: "io"}, `eal: invalid iovamode "io", should be pa or va`},
+		{"proctype", EalConfig{ProcType: "main"}, `eal: invalid proctype "main", should be primary, secondary or auto`},
+		{"secondary inmemory", EalConfig{ProcType: "secondary", InMemory: true},
+			"eal: a secondary process can't run inmemory"},
+	}
+
+	for _, test := range tests {
+		err := test.ec.Validate()
+		if test.err == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.err, test.name)
+		}
+	}
+}
+
+func TestEalConfigArgs(t *testing.T) {
+	mainLcore := uint(1)
+
+	tests := []struct {
+		name string
+		ec   EalConfig
+		args []string
+	}{
+		{"empty", EalConfig{}, []string{"p4pack"}},
+		{"default", *DefaultEalConfig(), []string{"p4pack", "-c", "0x3", "-n", "4"}},
+		{"all", EalConfig{
+			Lcores:         "0-3",
+			MainLcore:      &mainLcore,
+			MemoryChannels: 2,
+			SocketMem:      []uint{1024, 512},
+			HugeDir:        "/dev/hugepages",
+			FilePrefix:     "p4",
+			InMemory:       true,
+			Allow:          []string{"0000:00:04.0", "0000:00:05.0"},
+			Vdevs:          []string{"net_tap0,iface=tap0"},
+			LogLevels:      []string{"lib.eal:debug", "notice"},
+			IovaMode:       "va",
+			ProcType:       "primary",
+			ExtraArgs:      []string{"--no-telemetry"},
+		}, []string{
+			"p4pack", "--lcores", "0-3", "--main-lcore", "1", "-n", "2", "--socket-mem", "1024,512",
+			"--huge-dir", "/dev/hugepages", "--file-prefix", "p4", "--in-memory", "-a", "0000:00:04.0",
+			"-a", "0000:00:05.0", "--vdev", "net_tap0,iface=tap0", "--log-level", "lib.eal:debug",
+			"--log-level", "notice", "--iova-mode", "va", "--proc-type", "primary", "--no-telemetry",
+		}},
+		{"memory", EalConfig{CoreMask: "0x1", Memory: 512, NoHuge: true, Block: []string{"0000:00:04.0"}},
+			[]string{"p4pack", "-c", "0x1", "-m", "512", "--no-huge", "-b", "0000:00:04.0"}},
+	}
+
+	for _, test := range tests {
+		args, err := test.ec.Args("p4pack")
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.args, args, test.name)
+		}
+	}
+
+	// invalid settings give no arguments
+	args, err := (&EalConfig{IovaMode: "io"}).Args("p4pack")
+	assert.EqualError(t, err, `eal: invalid iovamode "io", should be pa or va`)
+	assert.Nil(t, args)
+}
@@ -0,0 +1,253 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+)
+
+// Export returns the configuration of all the resources currently created in the dpdkinfra module, also the resources
+// created through the CLI. The resources of the running configuration are given in the same order, the other
+// resources are sorted by name and added after them. Applying the returned configuration to a fresh dpdkinfra module
+// creates an equivalent system.
+func Export() (*Config, error) {
+	dpdki := dpdkinfra.Get()
+	if dpdki == nil {
+		return nil, errors.New("dpdkinfra module is not initialized")
+	}
+
+	running.Lock()
+	defer running.Unlock()
+
+	conf := Create()
+	conf.SetBasePath(running.conf.GetBasePath())
+	conf.Eal = running.conf.Eal
+	conf.Pktmbufs = exportPktmbufs(dpdki)
+	conf.Devices = dpdki.AttachedDevices()
+
+	var err error
+	if conf.Interfaces, err = exportInterfaces(dpdki); err != nil {
+		return nil, err
+	}
+	if conf.Pipelines, err = exportPipelines(dpdki); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// the given names in order of the names of the running configuration, the other names sorted after them
+func orderNames[T named](names []string, list []T) []string {
+	index := make(map[string]int)
+	for i, item := range list {
+		index[item.GetName()] = i
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ii, iok := index[names[i]]
+		ij, jok := index[names[j]]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return ii < ij
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// the configured name of a created pktmbuf, the pktmbufs of an automatically placed pktmbuf have the same name
+func exportPktmbufName(dpdki *dpdkinfra.DpdkInfra, pm *pktmbuf.Pktmbuf) string {
+	if pm == nil {
+		return ""
+	}
+	if name, ok := dpdki.PktmbufAutoName(pm.Name()); ok {
+		return name
+	}
+	return pm.Name()
+}
+
+func exportPktmbufs(dpdki *dpdkinfra.DpdkInfra) PktmbufsConfig {
+	pms := make(map[string]*PktmbufConfig)
+	dpdki.PktmbufStore.Iterate(func(name string, pm *pktmbuf.Pktmbuf) error {
+		mpc := &PktmbufConfig{Name: name, BufferSize: pm.BufferSize(), CPUID: AutoInt{Value: pm.NumaNode()}}
+		if autoName, ok := dpdki.PktmbufAutoName(name); ok {
+			if _, ok := pms[autoName]; ok {
+				return nil
+			}
+			mpc.Name, mpc.CPUID = autoName, AutoInt{Auto: true}
+		}
+		stats := pm.Stats()
+		mpc.PoolSize, mpc.CacheSize = stats.Size, stats.CacheSize
+		mpc.Alarm, _ = dpdki.PktmbufAlarm(name)
+		pms[mpc.Name] = mpc
+		return nil
+	})
+
+	var names []string
+	for name := range pms {
+		names = append(names, name)
+	}
+	var result PktmbufsConfig
+	for _, name := range orderNames(names, running.conf.Pktmbufs) {
+		result = append(result, pms[name])
+	}
+	return result
+}
+
+func exportInterfaces(dpdki *dpdkinfra.DpdkInfra) (InterfacesConfig, error) {
+	var names []string
+	dpdki.IteratePorts(func(name string, port portmngr.PortType) error {
+		names = append(names, name)
+		return nil
+	})
+
+	var result InterfacesConfig
+	for _, name := range orderNames(names, running.conf.Interfaces) {
+		port := dpdki.GetPort(name)
+		if port == nil {
+			continue
+		}
+		portType := dpdki.GetPortType(name)
+		newParams, ok := interfaceTypes[portType]
+		if !ok {
+			return nil, fmt.Errorf("interface %s: port type %s has no config schema", name, portType)
+		}
+
+		params := newParams()
+		exp, ok := params.(InterfaceExport)
+		if !ok {
+			// use the config the interface is created with, if available
+			i, ok := find(running.conf.Interfaces, name)
+			if !ok {
+				return nil, fmt.Errorf("interface %s: port type %s can't be exported", name, portType)
+			}
+			result = append(result, i)
+			continue
+		}
+		err := exp.Export(port, dpdki.GetPortParams(name), func(pm *pktmbuf.Pktmbuf) string {
+			return exportPktmbufName(dpdki, pm)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		result = append(result, &InterfaceConfig{Name: name, Type: portType, Params: params})
+	}
+	return result, nil
+}
+
+func exportPipelines(dpdki *dpdkinfra.DpdkInfra) (PipelinesConfig, error) {
+	var names []string
+	dpdki.PipelineStore.Iterate(func(name string, pl *pipeline.Pipeline) error {
+		names = append(names, name)
+		return nil
+	})
+
+	var result PipelinesConfig
+	for _, name := range orderNames(names, running.conf.Pipelines) {
+		pl := dpdki.PipelineStore.Get(name)
+		if pl == nil {
+			continue
+		}
+		// a pipeline that isn't build yet can't be recreated from a configuration
+		if dpdki.PipelineSpec(name) == "" {
+			log.Warnf("pipeline %s is not build and not exported", name)
+			continue
+		}
+		pc, err := exportPipeline(dpdki, pl)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", name, err)
+		}
+		result = append(result, pc)
+	}
+	return result, nil
+}
+
+func exportPipeline(dpdki *dpdkinfra.DpdkInfra, pl *pipeline.Pipeline) (*PipelineConfig, error) {
+	name := pl.GetName()
+	pc := &PipelineConfig{Name: name, ThreadID: AutoInt{Value: int(pl.GetThreadID())}, Disabled: !pl.IsEnabled()}
+
+	numaNode, err := pl.NumaNodeGet()
+	if err != nil {
+		return nil, err
+	}
+	pc.NumaNode = AutoInt{Value: numaNode}
+
+	// the spec file with an absolute base path, so it is found independent of the location of the exported config
+	spec := dpdki.PipelineSpec(name)
+	if spec == "" {
+		return nil, errors.New("pipeline is not build")
+	}
+	if spec, err = filepath.Abs(spec); err != nil {
+		return nil, err
+	}
+	pc.BasePath, pc.Spec = filepath.Split(spec)
+
+	// the port bindings from the pipeline bindings of the interface queues
+	inPorts := make(map[int]*InPortConfig)
+	outPorts := make(map[int]*OutPortConfig)
+	dpdki.IteratePorts(func(ifaceName string, port portmngr.PortType) error {
+		port.IterateRxQueues(func(index uint16, q device.Queue) error {
+			if q.Pipeline() == name && q.PipelinePort() != device.NotBound {
+				inPorts[q.PipelinePort()] = &InPortConfig{IfaceName: ifaceName, RxQueue: index, Bsz: q.Bsz()}
+			}
+			return nil
+		})
+		port.IterateTxQueues(func(index uint16, q device.Queue) error {
+			if q.Pipeline() == name && q.PipelinePort() != device.NotBound {
+				outPorts[q.PipelinePort()] = &OutPortConfig{IfaceName: ifaceName, TxQueue: index, Bsz: q.Bsz()}
+			}
+			return nil
+		})
+		return nil
+	})
+	for i := 0; i < len(inPorts); i++ {
+		p, ok := inPorts[i]
+		if !ok {
+			return nil, fmt.Errorf("input port %d not bound", i)
+		}
+		pc.InputPorts = append(pc.InputPorts, p)
+	}
+	for i := 0; i < len(outPorts); i++ {
+		p, ok := outPorts[i]
+		if !ok {
+			return nil, fmt.Errorf("output port %d not bound", i)
+		}
+		pc.OutputPorts = append(pc.OutputPorts, p)
+	}
+
+	// the committed table entries and default entries, the other start settings can't be read back and are taken from
+	// the running configuration
+	if rpc, ok := find(running.conf.Pipelines, name); ok {
+		pc.Start = rpc.Start.withoutEntries()
+	}
+	for _, te := range dpdki.PipelineTableEntries(name) {
+		if pc.Start == nil {
+			pc.Start = &StartConfig{}
+		}
+		found := false
+		for i := range pc.Start.Tables {
+			if pc.Start.Tables[i].Name == te.Table {
+				pc.Start.Tables[i].Data = te.Entries
+				if te.Default != "" {
+					pc.Start.Tables[i].Default = te.Default
+				}
+				found = true
+			}
+		}
+		if !found {
+			pc.Start.Tables = append(pc.Start.Tables, TableConfig{Name: te.Table, Default: te.Default, Data: te.Entries})
+		}
+	}
+
+	return pc, nil
+}
@@ -4,74 +4,153 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
+	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/netlink"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ring"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/sourcesink"
-	"github.com/stolsma/go-p4pack/pkg/dpdkswx/tap"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
 )
 
+// InterfaceParams is the config schema of a port type in the interfaces config section
+type InterfaceParams interface {
+	// Params converts the config into the port type specific parameters given to the PortMngr port factory
+	Params(name string) (any, error)
+}
+
+// InterfaceSetup can be implemented by InterfaceParams types that need to setup the interface after it is created
+type InterfaceSetup interface {
+	Setup(name string)
+}
+
+// InterfaceValidate can be implemented by InterfaceParams types to check their config without creating the interface.
+// Pktmbufs contains the names of the configured pktmbufs.
+type InterfaceValidate interface {
+	Validate(path string, pktmbufs map[string]bool, errs *validation.Errors)
+}
+
+// InterfaceQueues can be implemented by InterfaceParams types to give the number of rx and tx queues of the interface.
+// It is used to validate the pipeline port bindings of the interface.
+type InterfaceQueues interface {
+	Queues() (rx uint16, tx uint16)
+}
+
+// InterfaceDependencies can be implemented by InterfaceParams types to give the names of the pktmbufs and devices used
+// by the interface. Reconcile recreates the interface when one of them is recreated.
+type InterfaceDependencies interface {
+	Dependencies() (pktmbufs []string, devices []string)
+}
+
+// InterfaceExport can be implemented by InterfaceParams types to fill the config from a created port and the parameters
+// it is created with. PktmbufName returns the configured name of a created pktmbuf. It is used to export the running
+// state as configuration.
+type InterfaceExport interface {
+	Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error
+}
+
+var interfaceTypes = make(map[string]func() InterfaceParams)
+
+// RegisterInterfaceType registers the config schema of a PortMngr port type. The port type name is used as JSON key
+// of the port type config in an interface config, newParams must return a new empty config structure to decode into.
+// It is intended to be called from an init function and panics if the port type is already registered.
+func RegisterInterfaceType(portType string, newParams func() InterfaceParams) {
+	if _, ok := interfaceTypes[portType]; ok {
+		panic("config: RegisterInterfaceType called twice for port type " + portType)
+	}
+	interfaceTypes[portType] = newParams
+}
+
 type InterfacesConfig []*InterfaceConfig
 
+// InterfaceConfig is the config of one interface, i.e. its name and the config of exactly one registered port type:
+//
+//	{ "name": "<name>", "<port type>": { <port type config> } }
 type InterfaceConfig struct {
-	Name   string        `json:"name"`
-	Tap    *TapParams    `json:"tap"`
-	EthDev *PMDParams    `json:"ethdev"`
-	Ring   *RingParams   `json:"ring"`
-	Source *SourceParams `json:"source"`
-	Sink   *SinkParams   `json:"sink"`
+	Name   string
+	Type   string
+	Params InterfaceParams
 }
 
 func (i *InterfaceConfig) GetName() string {
 	return i.Name
 }
 
-// TapConfig represents Tap config parameters
-type TapParams struct {
-	Rx *struct {
-		Mtu     int    `json:"mtu"`
-		PktMbuf string `json:"pktmbuf"`
+func (i *InterfaceConfig) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
 	}
-}
 
-type RingParams struct {
-	Size     uint   `json:"size"`
-	NumaNode uint32 `json:"numanode"`
-}
+	if name, ok := fields["name"]; ok {
+		if err := json.Unmarshal(name, &i.Name); err != nil {
+			return fmt.Errorf("interface name: %w", err)
+		}
+	}
 
-type SourceParams struct {
-	Rx *struct {
-		FileName string `json:"filename"`
-		NLoops   uint64 `json:"n_loops"`
-		NPktsMax uint32 `json:"n_pkts_max"`
-		PktMbuf  string `json:"pktmbuf"`
+	// find the port type config, sorted for a predictable error when more port types are given
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "name" {
+			continue
+		}
+		newParams, ok := interfaceTypes[key]
+		switch {
+		case !ok && config.Strict():
+			return fmt.Errorf("interface %s: unknown field %q", i.Name, key)
+		case !ok:
+			continue
+		}
+		if i.Params != nil {
+			return fmt.Errorf("interface %s has more than one interface type (%s and %s)", i.Name, i.Type, key)
+		}
+
+		params := newParams()
+		if err := config.Unmarshal(fields[key], params); err != nil {
+			return fmt.Errorf("interface %s %s config: %w", i.Name, key, err)
+		}
+		i.Type = key
+		i.Params = params
 	}
-}
 
-type SinkParams struct {
-	Tx *struct {
-		FileName string `json:"filename"`
+	if i.Params == nil {
+		return fmt.Errorf("interface %s has no known interface type", i.Name)
 	}
+	return nil
+}
+
+func (i *InterfaceConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"name": i.Name, i.Type: i.Params})
 }
 
-type PMDParams struct {
-	PortName string `json:"portname"`
-	Rx       *struct {
-		Mtu         uint16           `json:"mtu"`
-		NQueues     uint16           `json:"nqueues"`
-		QueueSize   uint32           `json:"queuesize"`
-		PktMbuf     string           `json:"pktmbuf"`
-		Rss         ethdev.ParamsRss `json:"rss"`
-		Promiscuous bool             `json:"promiscuous"`
+// JSONSchema returns the schema of an interface config with the config schemas of all registered port types
+func (i *InterfaceConfig) JSONSchema() *schema.Schema {
+	s := &schema.Schema{
+		Type:                 "object",
+		Properties:           map[string]*schema.Schema{"name": {Type: "string"}},
+		AdditionalProperties: false,
+		Required:             []string{"name"},
 	}
-	Tx *struct {
-		NQueues   uint16 `json:"nqueues"`
-		QueueSize uint32 `json:"queuesize"`
+
+	var types []string
+	for portType := range interfaceTypes {
+		types = append(types, portType)
+	}
+	sort.Strings(types)
+	for _, portType := range types {
+		s.Properties[portType] = schema.Of(interfaceTypes[portType]())
+		s.OneOf = append(s.OneOf, &schema.Schema{Required: []string{portType}})
 	}
+	return s
 }
 
 // Create interfaces with a given interface configuration list
@@ -81,131 +160,86 @@ func (c InterfacesConfig) Apply() error {
 		return errors.New("dpdkinfra module is not initialized")
 	}
 
-	// create/bind & configure TAP interface devices
-	for _, ifConfig := range c {
-		if ifConfig.Tap != nil {
-			var tp tap.Params
-			name := ifConfig.GetName()
-
-			// get Packet buffer memory pool & MTU
-			mpName := ifConfig.Tap.Rx.PktMbuf
-			tp.Pktmbuf = dpdki.PktmbufStore.Get(mpName)
-			if tp.Pktmbuf == nil {
-				return fmt.Errorf("vhost %s mempool %s not found", name, mpName)
-			}
-			tp.Mtu = ifConfig.Tap.Rx.Mtu
-
-			// create tap
-			_, err := dpdki.TapCreate(name, &tp)
-			if err != nil {
-				return fmt.Errorf("tap %s create err: %d", name, err)
-			}
+	running.Lock()
+	defer running.Unlock()
 
-			// TODO Temporaraly set interface up here but refactor interfaces into seperate dpdki module!
-			netlink.InterfaceUp(name)
-			netlink.RemoveAllAddr(name)
-			log.Infof("tap %s created!", name)
-			continue
+	for _, ifConfig := range c {
+		if err := ifConfig.create(dpdki); err != nil {
+			return err
 		}
+	}
 
-		if ifConfig.Ring != nil {
-			var r ring.Params
-			name := ifConfig.GetName()
+	return nil
+}
 
-			r.Size = ifConfig.Ring.Size
-			r.NumaNode = ifConfig.Ring.NumaNode
+// create the interface and add it to the running configuration
+func (i *InterfaceConfig) create(dpdki *dpdkinfra.DpdkInfra) error {
+	name := i.GetName()
+	if i.Params == nil {
+		log.Errorf("Unknown interface type or wrong configuration for interface %s", name)
+		return errors.New("error in interface configuration")
+	}
 
-			// create ring
-			_, err := dpdki.RingCreate(name, &r)
-			if err != nil {
-				return fmt.Errorf("ring %s create err: %d", name, err)
-			}
+	params, err := i.Params.Params(name)
+	if err != nil {
+		return fmt.Errorf("%s %s %w", i.Type, name, err)
+	}
 
-			log.Infof("ring %s created!", name)
-			continue
-		}
+	if _, err = dpdki.Create(i.Type, name, params); err != nil {
+		return fmt.Errorf("%s %s create err: %w", i.Type, name, err)
+	}
+	running.conf.Interfaces = replace(running.conf.Interfaces, i)
 
-		if ifConfig.Source != nil {
-			var s sourcesink.SourceParams
-			name := ifConfig.GetName()
+	if setup, ok := i.Params.(InterfaceSetup); ok {
+		setup.Setup(name)
+	}
 
-			// get Packet buffer memory pool & all other parameters
-			mpName := ifConfig.Source.Rx.PktMbuf
-			s.Pktmbuf = dpdki.PktmbufStore.Get(mpName)
-			if s.Pktmbuf == nil {
-				return fmt.Errorf("source %s mempool %s not found", name, mpName)
-			}
-			s.FileName = ifConfig.Source.Rx.FileName
-			s.NLoops = ifConfig.Source.Rx.NLoops
-			s.NPktsMax = ifConfig.Source.Rx.NPktsMax
-
-			// create ring
-			_, err := dpdki.SourceCreate(name, &s)
-			if err != nil {
-				return fmt.Errorf("ring %s create err: %d", name, err)
-			}
+	log.Infof("%s %s created!", i.Type, name)
+	return nil
+}
 
-			log.Infof("Ring %s created!", name)
-			continue
+// Validate checks the interfaces configuration without creating the interfaces and adds the errors found to errs.
+// Pktmbufs contains the names of the configured pktmbufs.
+func (c InterfacesConfig) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	names := make(map[string]bool)
+	portNames := make(map[string]string)
+	for i, ifConfig := range c {
+		ipath := validation.Path(path, i)
+		name := ifConfig.GetName()
+		switch {
+		case name == "":
+			errs.Addf(validation.Path(ipath, "name"), "interface name missing")
+		case names[name]:
+			errs.Addf(validation.Path(ipath, "name"), "duplicate interface %s", name)
 		}
+		names[name] = true
 
-		if ifConfig.Sink != nil {
-			var s sourcesink.SinkParams
-			name := ifConfig.GetName()
-
-			// get parameters
-			s.FileName = ifConfig.Sink.Tx.FileName
-
-			// create sink
-			_, err := dpdki.SinkCreate(name, &s)
-			if err != nil {
-				return fmt.Errorf("sink %s create err: %d", name, err)
-			}
-
-			log.Infof("sink %s created!", name)
+		if ifConfig.Params == nil {
+			errs.Addf(ipath, "no known interface type given")
 			continue
 		}
+		ppath := validation.Path(ipath, ifConfig.Type)
+		if v, ok := ifConfig.Params.(InterfaceValidate); ok {
+			v.Validate(ppath, pktmbufs, errs)
+		}
 
-		// create and/or bind & configure PMD devices to this environment
-		if ifConfig.EthDev != nil {
-			var vh = ifConfig.EthDev
-			var p ethdev.Params
-			name := ifConfig.GetName()
-
-			// get Packet buffer memory pool
-			mp := dpdki.PktmbufStore.Get(vh.Rx.PktMbuf)
-			if mp == nil {
-				return fmt.Errorf("vhost %s mempool %s not found", name, vh.Rx.PktMbuf)
-			}
-
-			// copy parameters
-			p.PortName = vh.PortName
-			p.Rx.Mtu = vh.Rx.Mtu
-			p.Rx.NQueues = vh.Rx.NQueues
-			p.Rx.QueueSize = vh.Rx.QueueSize
-			p.Rx.Mempool = mp
-			p.Rx.Rss = vh.Rx.Rss
-			p.Tx.NQueues = vh.Tx.NQueues
-			p.Tx.QueueSize = vh.Tx.QueueSize
-			p.Promiscuous = vh.Rx.Promiscuous
-
-			// create and configure the PMD interface
-			_, err := dpdki.EthdevCreate(name, &p)
-			if err != nil {
-				return fmt.Errorf("vdev/ethdev %s create err: %d", name, err)
+		// an ethdev port can only be used by one interface
+		if pmd, ok := ifConfig.Params.(*PMDParams); ok && pmd.PortName != "" {
+			if other, ok := portNames[pmd.PortName]; ok {
+				errs.Addf(validation.Path(ppath, "portname"), "port %s already used by interface %s", pmd.PortName, other)
+			} else {
+				portNames[pmd.PortName] = name
 			}
-
-			// TODO Temporaraly set interface up here but refactor interfaces into seperate dpdki module!
-			netlink.InterfaceUp(name)
-			netlink.RemoveAllAddr(name)
-
-			log.Infof("Ethdev %s (device port name: %s) created!", name, vh.PortName)
-			continue
 		}
-
-		log.Errorf("Unknown interface type or wrong configuration for interface %s", ifConfig.GetName())
-		return errors.New("error in interface configuration")
 	}
+}
 
+// get the interface config with the given name
+func (c InterfacesConfig) get(name string) *InterfaceConfig {
+	for _, ifConfig := range c {
+		if ifConfig.GetName() == name {
+			return ifConfig
+		}
+	}
 	return nil
 }
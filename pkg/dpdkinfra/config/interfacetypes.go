@@ -0,0 +1,586 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/placement"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/fd"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/netlink"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ring"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/sourcesink"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/tap"
+	"github.com/stolsma/go-p4pack/pkg/flowtest"
+)
+
+// The config schemas of the built-in port types
+func init() {
+	RegisterInterfaceType(portmngr.PortTypeTap, func() InterfaceParams { return &TapParams{} })
+	RegisterInterfaceType(portmngr.PortTypeEthdev, func() InterfaceParams { return &PMDParams{} })
+	RegisterInterfaceType(portmngr.PortTypeRing, func() InterfaceParams { return &RingParams{} })
+	RegisterInterfaceType(portmngr.PortTypeSource, func() InterfaceParams { return &SourceParams{} })
+	RegisterInterfaceType(portmngr.PortTypeSink, func() InterfaceParams { return &SinkParams{} })
+	RegisterInterfaceType(portmngr.PortTypeFd, func() InterfaceParams { return &FdParams{} })
+}
+
+// get the packet buffer memory pool with the given name
+func getPktmbuf(name string) (*pktmbuf.Pktmbuf, error) {
+	return dpdkinfra.Get().PktmbufForNuma(name, placement.NumaAny)
+}
+
+// check if the pktmbuf with the given name is configured
+func validatePktmbuf(path string, name string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if name == "" {
+		errs.Addf(path, "pktmbuf missing")
+	} else if !pktmbufs[name] {
+		errs.Addf(path, "unknown pktmbuf %s", name)
+	}
+}
+
+func exportErr(params any) error {
+	return fmt.Errorf("can't export parameter type %T", params)
+}
+
+// TapConfig represents Tap config parameters
+type TapParams struct {
+	Rx *TapRxParams `json:"rx"`
+}
+
+type TapRxParams struct {
+	Mtu     int    `json:"mtu"`
+	PktMbuf string `json:"pktmbuf"`
+}
+
+func (t *TapParams) Params(name string) (any, error) {
+	var err error
+	var tp tap.Params
+
+	if t.Rx == nil {
+		return nil, errors.New("rx config missing")
+	}
+
+	// get Packet buffer memory pool & MTU
+	if tp.Pktmbuf, err = getPktmbuf(t.Rx.PktMbuf); err != nil {
+		return nil, err
+	}
+	tp.Mtu = t.Rx.Mtu
+
+	return &tp, nil
+}
+
+func (t *TapParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if t.Rx == nil {
+		errs.Addf(validation.Path(path, "rx"), "rx config missing")
+		return
+	}
+	validatePktmbuf(validation.Path(path, "rx", "pktmbuf"), t.Rx.PktMbuf, pktmbufs, errs)
+}
+
+func (t *TapParams) Queues() (rx uint16, tx uint16) {
+	return 1, 1
+}
+
+func (t *TapParams) Dependencies() (pktmbufs []string, devices []string) {
+	if t.Rx != nil {
+		pktmbufs = append(pktmbufs, t.Rx.PktMbuf)
+	}
+	return pktmbufs, nil
+}
+
+func (t *TapParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	p, ok := params.(*tap.Params)
+	if !ok {
+		return exportErr(params)
+	}
+	t.Rx = &TapRxParams{Mtu: p.Mtu, PktMbuf: pktmbufName(p.Pktmbuf)}
+	return nil
+}
+
+func (t *TapParams) Setup(name string) {
+	// TODO Temporaraly set interface up here but refactor interfaces into seperate dpdki module!
+	netlink.InterfaceUp(name)
+	netlink.RemoveAllAddr(name)
+}
+
+type RingParams struct {
+	Size     uint   `json:"size"`
+	NumaNode uint32 `json:"numanode"`
+
+	numaPipeline *PipelineConfig // automatically placed pipeline the ring is created on the NUMA node of, see chained
+}
+
+func (r *RingParams) Params(name string) (any, error) {
+	numaNode := r.NumaNode
+	if r.numaPipeline != nil {
+		if dpdki := dpdkinfra.Get(); dpdki != nil {
+			pipeName := r.numaPipeline.GetName()
+			numaNode = uint32(dpdki.PipelineNumaNode(pipeName, r.numaPipeline.GetPortNames()))
+		}
+	}
+	return &ring.Params{Size: r.Size, NumaNode: numaNode}, nil
+}
+
+// a ring doesn't use pktmbufs or devices
+func (r *RingParams) Dependencies() (pktmbufs []string, devices []string) {
+	return nil, nil
+}
+
+func (r *RingParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if r.Size == 0 || r.Size&(r.Size-1) != 0 {
+		errs.Addf(validation.Path(path, "size"), "size %d is not a power of 2", r.Size)
+	}
+}
+
+func (r *RingParams) Queues() (rx uint16, tx uint16) {
+	return 1, 1
+}
+
+func (r *RingParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	p, ok := params.(*ring.Params)
+	if !ok {
+		return exportErr(params)
+	}
+	r.Size, r.NumaNode = p.Size, p.NumaNode
+	return nil
+}
+
+type SourceParams struct {
+	Rx *SourceRxParams `json:"rx"`
+}
+
+type SourceRxParams struct {
+	FileName string                    `json:"filename"`
+	Packets  []flowtest.PacketTemplate `json:"packets"` // used when no filename is given
+	NLoops   uint64                    `json:"n_loops"`
+	NPktsMax uint32                    `json:"n_pkts_max"`
+	PktMbuf  string                    `json:"pktmbuf"`
+}
+
+func (s *SourceParams) Params(name string) (any, error) {
+	var err error
+	var sp sourcesink.SourceParams
+
+	if s.Rx == nil {
+		return nil, errors.New("rx config missing")
+	}
+
+	// get Packet buffer memory pool & all other parameters
+	if sp.Pktmbuf, err = getPktmbuf(s.Rx.PktMbuf); err != nil {
+		return nil, err
+	}
+	sp.FileName = s.Rx.FileName
+	if sp.FileName == "" && len(s.Rx.Packets) == 0 {
+		return nil, errors.New("filename or packets must be given")
+	}
+	for i, pt := range s.Rx.Packets {
+		packets, err := pt.ToByteArrays(nil)
+		if err != nil {
+			return nil, fmt.Errorf("packets %d: %w", i, err)
+		}
+		sp.Packets = append(sp.Packets, packets...)
+	}
+	sp.NLoops = s.Rx.NLoops
+	sp.NPktsMax = s.Rx.NPktsMax
+
+	return &sp, nil
+}
+
+func (s *SourceParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if s.Rx == nil {
+		errs.Addf(validation.Path(path, "rx"), "rx config missing")
+		return
+	}
+	validatePktmbuf(validation.Path(path, "rx", "pktmbuf"), s.Rx.PktMbuf, pktmbufs, errs)
+
+	if s.Rx.FileName == "" && len(s.Rx.Packets) == 0 {
+		errs.Addf(validation.Path(path, "rx"), "filename or packets must be given")
+	}
+	if s.Rx.FileName != "" {
+		if _, err := os.Stat(s.Rx.FileName); err != nil {
+			errs.Add(validation.Path(path, "rx", "filename"), err)
+		}
+	}
+	for i, pt := range s.Rx.Packets {
+		if _, err := pt.ToByteArrays(nil); err != nil {
+			errs.Add(validation.Path(path, "rx", "packets", i), err)
+		}
+	}
+}
+
+func (s *SourceParams) Dependencies() (pktmbufs []string, devices []string) {
+	if s.Rx != nil {
+		pktmbufs = append(pktmbufs, s.Rx.PktMbuf)
+	}
+	return pktmbufs, nil
+}
+
+func (s *SourceParams) Queues() (rx uint16, tx uint16) {
+	return 1, 0
+}
+
+func (s *SourceParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	p, ok := params.(*sourcesink.SourceParams)
+	if !ok {
+		return exportErr(params)
+	}
+	s.Rx = &SourceRxParams{
+		FileName: p.FileName,
+		NLoops:   p.NLoops,
+		NPktsMax: p.NPktsMax,
+		PktMbuf:  pktmbufName(p.Pktmbuf),
+	}
+
+	// the created packets as raw packet templates
+	for _, packet := range p.Packets {
+		s.Rx.Packets = append(s.Rx.Packets, flowtest.PacketTemplate{Packet: flowtest.Packet{
+			Layout: []string{"data"},
+			Fields: map[string]flowtest.HexArray{"data": packet},
+		}})
+	}
+	return nil
+}
+
+type SinkParams struct {
+	Tx *SinkTxParams `json:"tx"`
+}
+
+type SinkTxParams struct {
+	FileName string `json:"filename"` // file name template, i.e. "sink-%Y%m%d-%H%M%S.pcap"
+	MaxSize  int64  `json:"maxsize"`  // rotate when the file would exceed this size in bytes
+	Interval string `json:"interval"` // rotate after this duration, i.e. "1h"
+	MaxFiles int    `json:"maxfiles"` // maximum number of files kept
+	SnapLen  uint32 `json:"snaplen"`
+}
+
+func (s *SinkParams) Params(name string) (any, error) {
+	if s.Tx == nil {
+		return nil, errors.New("tx config missing")
+	}
+
+	sp := sourcesink.SinkParams{
+		FileName: s.Tx.FileName,
+		MaxSize:  s.Tx.MaxSize,
+		MaxFiles: s.Tx.MaxFiles,
+		SnapLen:  s.Tx.SnapLen,
+	}
+	if s.Tx.Interval != "" {
+		interval, err := time.ParseDuration(s.Tx.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("interval: %w", err)
+		}
+		sp.Interval = interval
+	}
+
+	return &sp, nil
+}
+
+func (s *SinkParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if s.Tx == nil {
+		errs.Addf(validation.Path(path, "tx"), "tx config missing")
+		return
+	}
+	if s.Tx.Interval != "" {
+		if _, err := time.ParseDuration(s.Tx.Interval); err != nil {
+			errs.Add(validation.Path(path, "tx", "interval"), err)
+		}
+	}
+	if s.Tx.MaxSize < 0 {
+		errs.Addf(validation.Path(path, "tx", "maxsize"), "maxsize can't be negative")
+	}
+	if s.Tx.MaxFiles < 0 {
+		errs.Addf(validation.Path(path, "tx", "maxfiles"), "maxfiles can't be negative")
+	}
+}
+
+func (s *SinkParams) Queues() (rx uint16, tx uint16) {
+	return 0, 1
+}
+
+func (s *SinkParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	p, ok := params.(*sourcesink.SinkParams)
+	if !ok {
+		return exportErr(params)
+	}
+	s.Tx = &SinkTxParams{FileName: p.FileName, MaxSize: p.MaxSize, MaxFiles: p.MaxFiles, SnapLen: p.SnapLen}
+	if p.Interval > 0 {
+		s.Tx.Interval = p.Interval.String()
+	}
+	return nil
+}
+
+// FdParams represents the config parameters of a file descriptor interface, i.e. an AF_PACKET socket on an existing
+// Linux interface (optionally in another network namespace) or an already opened file descriptor.
+type FdParams struct {
+	Interface   string      `json:"interface"`
+	Netns       string      `json:"netns"`
+	Promiscuous bool        `json:"promiscuous"`
+	Fd          *int        `json:"fd"`
+	Rx          *FdRxParams `json:"rx"`
+}
+
+type FdRxParams struct {
+	Mtu     int    `json:"mtu"`
+	PktMbuf string `json:"pktmbuf"`
+}
+
+func (f *FdParams) Params(name string) (any, error) {
+	var err error
+	var fp fd.Params
+
+	if f.Rx == nil {
+		return nil, errors.New("rx config missing")
+	}
+	if (f.Interface == "") == (f.Fd == nil) {
+		return nil, errors.New("either interface or fd must be given")
+	}
+
+	// get Packet buffer memory pool & MTU
+	if fp.Pktmbuf, err = getPktmbuf(f.Rx.PktMbuf); err != nil {
+		return nil, err
+	}
+	fp.Mtu = f.Rx.Mtu
+
+	fp.Interface = f.Interface
+	fp.Netns = f.Netns
+	fp.Promiscuous = f.Promiscuous
+	if f.Fd != nil {
+		fp.Fd = *f.Fd
+	}
+
+	return &fp, nil
+}
+
+func (f *FdParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if (f.Interface == "") == (f.Fd == nil) {
+		errs.Addf(path, "either interface or fd must be given")
+	}
+	if f.Rx == nil {
+		errs.Addf(validation.Path(path, "rx"), "rx config missing")
+		return
+	}
+	validatePktmbuf(validation.Path(path, "rx", "pktmbuf"), f.Rx.PktMbuf, pktmbufs, errs)
+}
+
+func (f *FdParams) Dependencies() (pktmbufs []string, devices []string) {
+	if f.Rx != nil {
+		pktmbufs = append(pktmbufs, f.Rx.PktMbuf)
+	}
+	return pktmbufs, nil
+}
+
+func (f *FdParams) Queues() (rx uint16, tx uint16) {
+	return 1, 1
+}
+
+func (f *FdParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	p, ok := params.(*fd.Params)
+	if !ok {
+		return exportErr(params)
+	}
+	f.Interface, f.Netns, f.Promiscuous = p.Interface, p.Netns, p.Promiscuous
+	if p.Interface == "" {
+		fdNum := p.Fd
+		f.Fd = &fdNum
+	}
+	f.Rx = &FdRxParams{Mtu: p.Mtu, PktMbuf: pktmbufName(p.Pktmbuf)}
+	return nil
+}
+
+type PMDParams struct {
+	PortName string       `json:"portname"`
+	Rx       *PMDRxParams `json:"rx"`
+	Tx       *PMDTxParams `json:"tx"`
+}
+
+type PMDRxParams struct {
+	Mtu          uint16     `json:"mtu"`
+	NQueues      uint16     `json:"nqueues"`
+	QueueSize    uint32     `json:"queuesize"`
+	PktMbuf      string     `json:"pktmbuf"`
+	Rss          *RssParams `json:"rss"`
+	Promiscuous  bool       `json:"promiscuous"`
+	Allmulticast bool       `json:"allmulticast"`
+}
+
+type PMDTxParams struct {
+	NQueues   uint16 `json:"nqueues"`
+	QueueSize uint32 `json:"queuesize"`
+}
+
+func (vh *PMDParams) Params(name string) (any, error) {
+	var err error
+	var p ethdev.Params
+
+	if vh.Rx == nil || vh.Tx == nil {
+		return nil, errors.New("rx or tx config missing")
+	}
+
+	// get Packet buffer memory pool on the NUMA node of the device
+	numa, err := ethdev.PortNumaNode(vh.PortName)
+	if err != nil {
+		return nil, err
+	}
+	if p.Rx.Mempool, err = dpdkinfra.Get().PktmbufForNuma(vh.Rx.PktMbuf, numa); err != nil {
+		return nil, err
+	}
+
+	// copy parameters
+	p.PortName = vh.PortName
+	p.Rx.Mtu = vh.Rx.Mtu
+	p.Rx.NQueues = vh.Rx.NQueues
+	p.Rx.QueueSize = vh.Rx.QueueSize
+	if vh.Rx.Rss != nil {
+		if p.Rx.Rss, err = vh.Rx.Rss.toParams(); err != nil {
+			return nil, err
+		}
+	}
+	p.Tx.NQueues = vh.Tx.NQueues
+	p.Tx.QueueSize = vh.Tx.QueueSize
+	p.Promiscuous = vh.Rx.Promiscuous
+	p.Allmulticast = vh.Rx.Allmulticast
+
+	return &p, nil
+}
+
+func (vh *PMDParams) Validate(path string, pktmbufs map[string]bool, errs *validation.Errors) {
+	if vh.PortName == "" {
+		errs.Addf(validation.Path(path, "portname"), "portname missing")
+	}
+	if vh.Tx == nil {
+		errs.Addf(validation.Path(path, "tx"), "tx config missing")
+	} else if vh.Tx.NQueues == 0 {
+		errs.Addf(validation.Path(path, "tx", "nqueues"), "at least 1 tx queue needed")
+	}
+	if vh.Rx == nil {
+		errs.Addf(validation.Path(path, "rx"), "rx config missing")
+		return
+	}
+	validatePktmbuf(validation.Path(path, "rx", "pktmbuf"), vh.Rx.PktMbuf, pktmbufs, errs)
+	if vh.Rx.NQueues == 0 {
+		errs.Addf(validation.Path(path, "rx", "nqueues"), "at least 1 rx queue needed")
+	}
+
+	if vh.Rx.Rss != nil {
+		rpath := validation.Path(path, "rx", "rss")
+		for i, q := range vh.Rx.Rss.Queues {
+			if q >= vh.Rx.NQueues {
+				errs.Addf(validation.Path(rpath, "queues", i), "rx queue %d not available (nqueues %d)", q, vh.Rx.NQueues)
+			}
+		}
+		if _, err := vh.Rx.Rss.toParams(); err != nil {
+			errs.Add(rpath, err)
+		}
+	}
+}
+
+func (vh *PMDParams) Queues() (rx uint16, tx uint16) {
+	if vh.Rx != nil {
+		rx = vh.Rx.NQueues
+	}
+	if vh.Tx != nil {
+		tx = vh.Tx.NQueues
+	}
+	return rx, tx
+}
+
+func (vh *PMDParams) Dependencies() (pktmbufs []string, devices []string) {
+	if vh.Rx != nil {
+		pktmbufs = append(pktmbufs, vh.Rx.PktMbuf)
+	}
+	return pktmbufs, []string{vh.PortName}
+}
+
+// Export uses the current configuration of the ethdev port, i.e. including MTU, queue and RSS changes
+func (vh *PMDParams) Export(port portmngr.PortType, params any, pktmbufName func(*pktmbuf.Pktmbuf) string) error {
+	e, ok := port.(*ethdev.Ethdev)
+	if !ok {
+		return exportErr(port)
+	}
+	p := e.Params()
+
+	vh.PortName = p.PortName
+	vh.Rx = &PMDRxParams{
+		Mtu:          p.Rx.Mtu,
+		NQueues:      p.Rx.NQueues,
+		QueueSize:    p.Rx.QueueSize,
+		PktMbuf:      pktmbufName(p.Rx.Mempool),
+		Promiscuous:  p.Promiscuous,
+		Allmulticast: p.Allmulticast,
+	}
+	if rss := p.Rx.Rss; rss != nil {
+		vh.Rx.Rss = &RssParams{Queues: rss.Queues, Key: hex.EncodeToString(rss.Key), Symmetric: rss.Symmetric}
+		if rss.Hf != 0 {
+			vh.Rx.Rss.Hf = strings.Fields(ethdev.RssHfString(rss.Hf))
+		}
+	}
+	vh.Tx = &PMDTxParams{NQueues: p.Tx.NQueues, QueueSize: p.Tx.QueueSize}
+	return nil
+}
+
+func (vh *PMDParams) Setup(name string) {
+	// TODO Temporaraly set interface up here but refactor interfaces into seperate dpdki module!
+	netlink.InterfaceUp(name)
+	netlink.RemoveAllAddr(name)
+}
+
+// RssParams represents the RSS (receive side scaling) config parameters of a PMD interface. For backwards compatibility
+// the RSS config can also be given as only a list of queues.
+type RssParams struct {
+	Queues    []uint16 `json:"queues"`
+	Key       string   `json:"key"`
+	Hf        []string `json:"hf"`
+	Symmetric bool     `json:"symmetric"`
+}
+
+// the RssParams fields without the custom JSON encoding
+type rssParams RssParams
+
+func (r *RssParams) UnmarshalJSON(data []byte) error {
+	// RSS queue list only
+	var queues []uint16
+	if err := json.Unmarshal(data, &queues); err == nil {
+		*r = RssParams{Queues: queues}
+		return nil
+	}
+
+	return config.Unmarshal(data, (*rssParams)(r))
+}
+
+func (r *RssParams) JSONSchema() *schema.Schema {
+	return &schema.Schema{OneOf: []*schema.Schema{schema.Of([]uint16{}), schema.Of(&rssParams{})}}
+}
+
+// convert to the ethdev RSS parameters, the key is a hex string with optional ':' separators
+func (r *RssParams) toParams() (*ethdev.ParamsRss, error) {
+	var err error
+	rss := &ethdev.ParamsRss{Queues: r.Queues, Symmetric: r.Symmetric}
+
+	if r.Key != "" {
+		rss.Key, err = hex.DecodeString(strings.ReplaceAll(r.Key, ":", ""))
+		if err != nil {
+			return nil, fmt.Errorf("rss key error: %w", err)
+		}
+	}
+
+	rss.Hf, err = ethdev.ParseRssHf(r.Hf)
+	if err != nil {
+		return nil, err
+	}
+
+	return rss, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRssParamsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		rss  RssParams
+		err  bool
+	}{
+		{"queue list", `[0, 2]`, RssParams{Queues: []uint16{0, 2}}, false},
+		{"empty queue list", `[]`, RssParams{Queues: []uint16{}}, false},
+		{"object", `{"queues": [1], "key": "6d:5a", "hf": ["ip", "tcp"], "symmetric": true}`,
+			RssParams{Queues: []uint16{1}, Key: "6d:5a", Hf: []string{"ip", "tcp"}, Symmetric: true}, false},
+		{"object without queues", `{"hf": ["udp"]}`, RssParams{Hf: []string{"udp"}}, false},
+		{"invalid queue", `[65536]`, RssParams{}, true},
+		{"invalid", `"all"`, RssParams{}, true},
+	}
+
+	for _, test := range tests {
+		var rss RssParams
+		err := json.Unmarshal([]byte(test.data), &rss)
+		if test.err {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.rss, rss, test.name)
+	}
+}
+
+func TestRssParamsToParams(t *testing.T) {
+	// no queues means all rx queues
+	rss, err := (&RssParams{Queues: []uint16{}}).toParams()
+	if assert.NoError(t, err) {
+		assert.Empty(t, rss.Queues)
+		assert.Nil(t, rss.Key)
+	}
+
+	rss, err = (&RssParams{Queues: []uint16{1, 0}, Key: "6d:5A:6d5a", Symmetric: true}).toParams()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []uint16{1, 0}, rss.Queues)
+		assert.Equal(t, []byte{0x6d, 0x5a, 0x6d, 0x5a}, rss.Key)
+		assert.True(t, rss.Symmetric)
+	}
+
+	_, err = (&RssParams{Key: "6g"}).toParams()
+	assert.ErrorContains(t, err, "rss key error")
+}
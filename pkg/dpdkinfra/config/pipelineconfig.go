@@ -6,19 +6,24 @@ package config
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 )
 
 type PipelinesConfig []*PipelineConfig
 
 type PipelineConfig struct {
 	Name        string           `json:"name"`
-	NumaNode    int              `json:"numanode"`
+	NumaNode    AutoInt          `json:"numanode"` // "auto" selects the NUMA node of the ports
 	BasePath    string           `json:"basepath"`
 	Spec        string           `json:"spec"`
-	ThreadID    uint             `json:"threadid"`
+	ThreadID    AutoInt          `json:"threadid"` // "auto" selects the least loaded thread on the NUMA node
+	Disabled    bool             `json:"disabled"` // build and commit the pipeline but don't enable it on a thread
 	OutputPorts []*OutPortConfig `json:"outputports"`
 	InputPorts  []*InPortConfig  `json:"inputports"`
 	Start       *StartConfig     `json:"start"`
@@ -29,7 +34,7 @@ func (pc *PipelineConfig) GetName() string {
 }
 
 func (pc *PipelineConfig) GetNumaNode() int {
-	return pc.NumaNode
+	return pc.NumaNode.Value
 }
 
 func (pc *PipelineConfig) SetBasePath(basePath string) bool {
@@ -49,7 +54,19 @@ func (pc *PipelineConfig) GetSpec() string {
 }
 
 func (pc *PipelineConfig) GetThreadID() uint {
-	return pc.ThreadID
+	return uint(pc.ThreadID.Value)
+}
+
+// GetPortNames returns the names of the interfaces bound to the pipeline input and output ports
+func (pc *PipelineConfig) GetPortNames() []string {
+	var names []string
+	for _, p := range pc.InputPorts {
+		names = append(names, p.GetIfaceName())
+	}
+	for _, p := range pc.OutputPorts {
+		names = append(names, p.GetIfaceName())
+	}
+	return names
 }
 
 type InPortConfig struct {
@@ -88,13 +105,94 @@ func (pc *OutPortConfig) GetBsz() uint {
 	return pc.Bsz
 }
 
-type StartConfig struct {
-	Tables []TableConfig `json:"tables"`
+// pipeline port binding of an interface queue
+type queueBinding struct {
+	iface string
+	queue uint16
+	rx    bool
 }
 
-type TableConfig struct {
-	Name string   `json:"name"`
-	Data []string `json:"data"`
+// Validate checks the pipelines configuration without creating the pipelines and adds the errors found to errs. The
+// spec files are searched relative to the given base path, as in Apply.
+func (c PipelinesConfig) Validate(path string, basePath string, interfaces InterfacesConfig, errs *validation.Errors) {
+	names := make(map[string]bool)
+	bound := make(map[queueBinding]string)
+
+	// check the interface and queue of a pipeline port
+	validatePort := func(ppath string, pipeName string, ifaceName string, queue uint16, rx bool) {
+		qname, dir := "txqueue", "tx"
+		if rx {
+			qname, dir = "rxqueue", "rx"
+		}
+
+		ifConfig := interfaces.get(ifaceName)
+		switch {
+		case ifaceName == "":
+			errs.Addf(validation.Path(ppath, "ifacename"), "ifacename missing")
+			return
+		case ifConfig == nil:
+			errs.Addf(validation.Path(ppath, "ifacename"), "unknown interface %s", ifaceName)
+			return
+		}
+
+		if q, ok := ifConfig.Params.(InterfaceQueues); ok {
+			nRx, nTx := q.Queues()
+			n := nTx
+			if rx {
+				n = nRx
+			}
+			if queue >= n {
+				errs.Addf(validation.Path(ppath, qname), "%s queue %d not available, interface %s (%s) has %d %s queues",
+					dir, queue, ifaceName, ifConfig.Type, n, dir)
+				return
+			}
+		}
+
+		binding := queueBinding{iface: ifaceName, queue: queue, rx: rx}
+		if other, ok := bound[binding]; ok {
+			errs.Addf(validation.Path(ppath, qname), "%s queue %d of interface %s already bound to pipeline %s",
+				dir, queue, ifaceName, other)
+			return
+		}
+		bound[binding] = pipeName
+	}
+
+	for i, pConfig := range c {
+		ppath := validation.Path(path, i)
+		pipeName := pConfig.GetName()
+		switch {
+		case pipeName == "":
+			errs.Addf(validation.Path(ppath, "name"), "pipeline name missing")
+		case names[pipeName]:
+			errs.Addf(validation.Path(ppath, "name"), "duplicate pipeline %s", pipeName)
+		}
+		names[pipeName] = true
+
+		bp := pConfig.GetBasePath()
+		if bp == "" {
+			bp = basePath
+		}
+		if pConfig.Spec == "" {
+			errs.Addf(validation.Path(ppath, "spec"), "spec file missing")
+		} else if _, err := os.Stat(filepath.Join(bp, pConfig.Spec)); err != nil {
+			errs.Add(validation.Path(ppath, "spec"), err)
+		}
+
+		// the SWX pipeline needs a power of 2 number of input ports
+		if n := len(pConfig.InputPorts); n == 0 || n&(n-1) != 0 {
+			errs.Addf(validation.Path(ppath, "inputports"), "number of input ports (%d) is not a power of 2", n)
+		}
+		for j, p := range pConfig.InputPorts {
+			validatePort(validation.Path(ppath, "inputports", j), pipeName, p.GetIfaceName(), p.GetRxQueue(), true)
+		}
+		for j, p := range pConfig.OutputPorts {
+			validatePort(validation.Path(ppath, "outputports", j), pipeName, p.GetIfaceName(), p.GetTxQueue(), false)
+		}
+
+		if pConfig.Start != nil {
+			pConfig.Start.validate(validation.Path(ppath, "start"), bp, errs)
+		}
+	}
 }
 
 // Create pipelines with a given pipeline configuration list
@@ -104,88 +202,128 @@ func (c PipelinesConfig) Apply(basePath string) error {
 		return errors.New("dpdkinfra module is not initialized")
 	}
 
+	running.Lock()
+	defer running.Unlock()
+
 	// Create pipeline
 	for _, pConfig := range c {
 		pConfig.SetBasePath(basePath)
-		pipeName := pConfig.GetName()
-		pl, err := dpdki.PipelineCreate(pipeName, pConfig.GetNumaNode())
-		if err != nil {
-			return fmt.Errorf("%s create err: %v", pipeName, err)
+		if err := pConfig.create(dpdki); err != nil {
+			return err
 		}
-		log.Infof("%s created!", pipeName)
+	}
 
-		// Add input ports to pipeline
-		for i, t := range pConfig.InputPorts {
-			pName := t.GetIfaceName()
-			port := dpdki.GetPort(pName)
-			if port == nil {
-				return fmt.Errorf("pipeconfig %s input device %s does not exist", pipeName, pName)
-			}
+	return nil
+}
 
-			err = port.BindToPipelineInputPort(pl, i, t.GetRxQueue(), t.GetBsz())
-			if err != nil {
-				return fmt.Errorf("AddInPort %s:%s err: %v", pipeName, pName, err)
-			}
+// create, build and enable the pipeline, apply its start config and add it to the running configuration. A
+// partially created pipeline is deleted when something goes wrong.
+func (pc *PipelineConfig) create(dpdki *dpdkinfra.DpdkInfra) error {
+	pipeName := pc.GetName()
+	numaNode := pc.GetNumaNode()
+	if pc.NumaNode.Auto {
+		numaNode = dpdki.PipelineNumaNode(pipeName, pc.GetPortNames())
+	}
+	pl, err := dpdki.PipelineCreate(pipeName, numaNode)
+	if err != nil {
+		return fmt.Errorf("%s create err: %v", pipeName, err)
+	}
+	log.Infof("%s created on NUMA node %d!", pipeName, numaNode)
 
-			log.Infof("AddInPort %s:%s ready!", pipeName, pName)
+	if err = pc.setup(dpdki, pl, numaNode); err != nil {
+		if derr := dpdki.PipelineDelete(pipeName); derr != nil {
+			log.Errorf("deleting partially created pipeline %s err: %v", pipeName, derr)
 		}
+		return err
+	}
 
-		// Add output ports to pipeline
-		for i, t := range pConfig.OutputPorts {
-			pName := t.GetIfaceName()
-			port := dpdki.GetPort(pName)
-			if port == nil {
-				return fmt.Errorf("pipeconfig %s input device %s does not exist", pipeName, pName)
-			}
+	running.conf.Pipelines = replace(running.conf.Pipelines, pc)
+	return nil
+}
 
-			err = port.BindToPipelineOutputPort(pl, i, t.GetTxQueue(), t.GetBsz())
-			if err != nil {
-				return fmt.Errorf("AddOutPort %s:%s err: %v", pipeName, pName, err)
-			}
+// enable the pipeline on the configured thread or the least loaded thread of the NUMA node
+func (pc *PipelineConfig) enable(dpdki *dpdkinfra.DpdkInfra, numaNode int) error {
+	var err error
+	pipeName := pc.GetName()
+	threadID := pc.GetThreadID()
+	if pc.ThreadID.Auto {
+		if threadID, err = dpdki.PipelineThread(numaNode); err != nil {
+			return fmt.Errorf("pipeline %s thread err: %v", pipeName, err)
+		}
+	}
+	dpdki.CheckPipelinePlacement(pipeName, numaNode, threadID, pc.GetPortNames())
+	err = dpdki.PipelineEnable(pipeName, threadID)
+	if err != nil {
+		return fmt.Errorf("pipelineEnable %s err: %v", pipeName, err)
+	}
+	log.Infof("Pipeline %s enabled on thread %d!", pipeName, threadID)
+	return nil
+}
 
-			log.Infof("AddOutPort %s:%s ready!", pipeName, pName)
+// bind the ports, build, commit and enable the created pipeline and apply the start config
+func (pc *PipelineConfig) setup(dpdki *dpdkinfra.DpdkInfra, pl *pipeline.Pipeline, numaNode int) error {
+	var err error
+	pipeName := pc.GetName()
+
+	// Add input ports to pipeline
+	for i, t := range pc.InputPorts {
+		pName := t.GetIfaceName()
+		port := dpdki.GetPort(pName)
+		if port == nil {
+			return fmt.Errorf("pipeconfig %s input device %s does not exist", pipeName, pName)
 		}
 
-		// Build the pipeline program
-		err = dpdki.PipelineBuild(pipeName, pConfig.GetSpec())
+		err = port.BindToPipelineInputPort(pl, i, t.GetRxQueue(), t.GetBsz())
 		if err != nil {
-			return fmt.Errorf("pipelinebuild %s specfile: %s err: %v", pipeName, pConfig.GetSpec(), err)
+			return fmt.Errorf("AddInPort %s:%s err: %v", pipeName, pName, err)
 		}
-		log.Infof("Pipeline %s build with specfile: %s ", pipeName, pConfig.GetSpec())
 
-		// Commit program to pipeline
-		err = dpdki.PipelineCommit(pipeName)
-		if err != nil {
-			return fmt.Errorf("pipeline %s commit err: %v", pipeName, err)
+		log.Infof("AddInPort %s:%s ready!", pipeName, pName)
+	}
+
+	// Add output ports to pipeline
+	for i, t := range pc.OutputPorts {
+		pName := t.GetIfaceName()
+		port := dpdki.GetPort(pName)
+		if port == nil {
+			return fmt.Errorf("pipeconfig %s input device %s does not exist", pipeName, pName)
 		}
-		log.Infof("Pipeline %s commited!", pipeName)
 
-		// And run pipeline
-		err = dpdki.PipelineEnable(pipeName, pConfig.GetThreadID())
+		err = port.BindToPipelineOutputPort(pl, i, t.GetTxQueue(), t.GetBsz())
 		if err != nil {
-			return fmt.Errorf("pipelineEnable %s err: %v", pipeName, err)
-		}
-		log.Infof("Pipeline %s enabled!", pipeName)
-
-		// Add Table startconfig if available
-		if pConfig.Start != nil && pConfig.Start.Tables != nil {
-			for _, table := range pConfig.Start.Tables {
-				for _, line := range table.Data {
-					err := dpdki.TableEntryAdd(pipeName, table.Name, line)
-					if err != nil {
-						return fmt.Errorf("table entry add went wrong (Pipeline: %s, Table: %s, Line: %s). err: %v",
-							pipeName, table.Name, line, err)
-					}
-				}
-			}
+			return fmt.Errorf("AddOutPort %s:%s err: %v", pipeName, pName, err)
+		}
 
-			// Commit Table changes to pipeline
-			err = dpdki.PipelineCommit(pipeName)
-			if err != nil {
-				return fmt.Errorf("pipeline %s commit err: %v", pipeName, err)
-			}
-			log.Infof("Table config on pipeline %s commited!", pipeName)
+		log.Infof("AddOutPort %s:%s ready!", pipeName, pName)
+	}
+
+	// Build the pipeline program
+	err = dpdki.PipelineBuild(pipeName, pc.GetSpec())
+	if err != nil {
+		return fmt.Errorf("pipelinebuild %s specfile: %s err: %v", pipeName, pc.GetSpec(), err)
+	}
+	log.Infof("Pipeline %s build with specfile: %s ", pipeName, pc.GetSpec())
+
+	// Commit program to pipeline
+	err = dpdki.PipelineCommit(pipeName)
+	if err != nil {
+		return fmt.Errorf("pipeline %s commit err: %v", pipeName, err)
+	}
+	log.Infof("Pipeline %s commited!", pipeName)
+
+	// And run pipeline, a disabled pipeline can be enabled later on with the CLI
+	if pc.Disabled {
+		log.Infof("Pipeline %s not enabled!", pipeName)
+	} else if err = pc.enable(dpdki, numaNode); err != nil {
+		return err
+	}
+
+	// Set the initial state of the pipeline objects if available
+	if pc.Start != nil {
+		if err = pc.Start.apply(dpdki, pipeName, pc.GetBasePath()); err != nil {
+			return fmt.Errorf("pipeline %s start config: %v", pipeName, err)
 		}
+		log.Infof("Start config on pipeline %s commited!", pipeName)
 	}
 
 	return nil
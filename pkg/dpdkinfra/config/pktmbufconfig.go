@@ -7,18 +7,21 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"github.com/stolsma/go-p4pack/pkg/hugepages"
 )
 
 type PktmbufsConfig []*PktmbufConfig
 
 type PktmbufConfig struct {
-	Name       string `json:"name"`
-	BufferSize uint   `json:"buffersize"`
-	PoolSize   uint32 `json:"poolsize"`
-	CacheSize  uint32 `json:"cachesize"`
-	CPUID      int    `json:"cpuid"`
+	Name       string  `json:"name"`
+	BufferSize uint    `json:"buffersize"`
+	PoolSize   uint32  `json:"poolsize"`
+	CacheSize  uint32  `json:"cachesize"`
+	CPUID      AutoInt `json:"cpuid"` // NUMA node, "auto" creates a pktmbuf per NUMA node
+	Alarm      uint    `json:"alarm"` // usage alarm threshold in percent of the pool size, 0 is no alarm
 }
 
 func (mpc *PktmbufConfig) GetName() string {
@@ -41,7 +44,7 @@ func (mpc *PktmbufConfig) GetCacheSize() uint32 {
 }
 
 func (mpc *PktmbufConfig) GetCPUID() int {
-	return mpc.CPUID
+	return mpc.CPUID.Value
 }
 
 // Create pktmbufs through the DpdkInfra API
@@ -51,15 +54,100 @@ func (c PktmbufsConfig) Apply() error {
 		return errors.New("dpdkinfra module is not initialized")
 	}
 
+	running.Lock()
+	defer running.Unlock()
+
 	// Create PktMbuf memory pool
 	for _, m := range c {
-		name := m.GetName()
-		_, err := dpdki.PktmbufCreate(name, m.GetBufferSize(), m.GetPoolSize(), m.GetCacheSize(), m.GetCPUID())
+		if err := m.create(dpdki); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create the pktmbuf and add it to the running configuration
+func (mpc *PktmbufConfig) create(dpdki *dpdkinfra.DpdkInfra) error {
+	name := mpc.GetName()
+	var pms []*pktmbuf.Pktmbuf
+	if mpc.CPUID.Auto {
+		created, err := dpdki.PktmbufCreateAuto(name, mpc.GetBufferSize(), mpc.GetPoolSize(), mpc.GetCacheSize())
 		if err != nil {
-			return fmt.Errorf("pktmbuf %s create err: %d", name, err)
+			return fmt.Errorf("pktmbuf %s create err: %v", name, err)
 		}
-		log.Infof("Pktmbuf Mempool %s ready!", name)
+		pms = created
+	} else {
+		pm, err := dpdki.PktmbufCreate(name, mpc.GetBufferSize(), mpc.GetPoolSize(), mpc.GetCacheSize(), mpc.GetCPUID())
+		if err != nil {
+			return fmt.Errorf("pktmbuf %s create err: %v", name, err)
+		}
+		pms = append(pms, pm)
 	}
+	running.conf.Pktmbufs = replace(running.conf.Pktmbufs, mpc)
 
+	if mpc.Alarm > 0 {
+		for _, pm := range pms {
+			if err := dpdki.PktmbufSetAlarm(pm.Name(), mpc.Alarm); err != nil {
+				return fmt.Errorf("pktmbuf %s alarm err: %w", pm.Name(), err)
+			}
+		}
+	}
+	log.Infof("Pktmbuf Mempool %s ready!", name)
 	return nil
 }
+
+// Validate checks the pktmbufs configuration without creating the pktmbufs and adds the errors found to errs
+func (c PktmbufsConfig) Validate(path string, errs *validation.Errors) {
+	names := make(map[string]bool)
+	for i, m := range c {
+		mpath := validation.Path(path, i)
+		switch {
+		case m.Name == "":
+			errs.Addf(validation.Path(mpath, "name"), "pktmbuf name missing")
+		case names[m.Name]:
+			errs.Addf(validation.Path(mpath, "name"), "duplicate pktmbuf %s", m.Name)
+		}
+		names[m.Name] = true
+
+		if m.PoolSize == 0 {
+			errs.Addf(validation.Path(mpath, "poolsize"), "pool size missing")
+		}
+		if m.CacheSize > m.PoolSize {
+			errs.Addf(validation.Path(mpath, "cachesize"), "cache size %d larger than pool size %d", m.CacheSize, m.PoolSize)
+		}
+		if m.Alarm > 100 {
+			errs.Addf(validation.Path(mpath, "alarm"), "alarm threshold %d%% above 100%%", m.Alarm)
+		}
+	}
+}
+
+// names returns the names of the configured pktmbufs
+func (c PktmbufsConfig) names() map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range c {
+		names[m.Name] = true
+	}
+	return names
+}
+
+// HugepageDemand returns an estimate of the hugepage memory in bytes needed by the pktmbufs per NUMA node. Pktmbufs
+// created on every NUMA node ("auto") are counted for every one of the given NUMA nodes, or as not bound to a NUMA
+// node if no NUMA nodes are given.
+func (c PktmbufsConfig) HugepageDemand(nodes []int) map[int]uint64 {
+	demand := make(map[int]uint64)
+	for _, m := range c {
+		size := hugepages.MempoolMemory(m.GetBufferSize(), m.GetPoolSize(), m.GetCacheSize())
+		switch {
+		case m.CPUID.Auto && len(nodes) > 0:
+			for _, node := range nodes {
+				demand[node] += size
+			}
+		case m.CPUID.Auto:
+			demand[hugepages.NodeAny] += size
+		default:
+			demand[m.GetCPUID()] += size
+		}
+	}
+	return demand
+}
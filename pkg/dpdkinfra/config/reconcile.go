@@ -0,0 +1,394 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/pipemngr"
+)
+
+// Plan is the ordered list of steps changing the running configuration into a new configuration. Resources are deleted
+// before they are (re)created and dependencies are created before the resources depending on them.
+type Plan struct {
+	Steps []*Step
+}
+
+// Step is one change of a Plan
+type Step struct {
+	Action string // create, delete, attach, detach, add, remove or commit
+	Kind   string // pktmbuf, device, interface, pipeline or table entry
+	Name   string
+	Reason string
+	do     func(dpdki *dpdkinfra.DpdkInfra) error
+}
+
+func (s *Step) String() string {
+	str := fmt.Sprintf("%s %s %s", s.Action, s.Kind, s.Name)
+	if s.Reason != "" {
+		str += " (" + s.Reason + ")"
+	}
+	return str
+}
+
+// Empty returns true if the running configuration is equal to the new configuration
+func (p *Plan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no changes"
+	}
+
+	lines := make([]string, 0, len(p.Steps))
+	for i, step := range p.Steps {
+		lines = append(lines, fmt.Sprintf("%3d. %s", i+1, step))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *Plan) add(action string, kind string, name string, reason string, do func(*dpdkinfra.DpdkInfra) error) {
+	p.Steps = append(p.Steps, &Step{Action: action, Kind: kind, Name: name, Reason: reason, do: do})
+}
+
+// Diff returns the plan changing the running configuration into the given configuration without applying it. The
+// given configuration should be validated first.
+func Diff(newConf *Config) *Plan {
+	running.Lock()
+	defer running.Unlock()
+	return diff(running.conf, newConf.chained())
+}
+
+// Reconcile changes the running configuration into the given configuration and returns the executed plan. The given
+// configuration should be validated first. The steps are executed in order and the first failing step stops the
+// execution, the running configuration then reflects the resources changed by the executed steps and a next Reconcile
+// continues from there.
+func Reconcile(newConf *Config) (*Plan, error) {
+	dpdki := dpdkinfra.Get()
+	if dpdki == nil {
+		return nil, errors.New("dpdkinfra module is not initialized")
+	}
+
+	running.Lock()
+	defer running.Unlock()
+
+	plan := diff(running.conf, newConf.chained())
+	for i, step := range plan.Steps {
+		log.Infof("Reconcile step %d/%d: %s", i+1, len(plan.Steps), step)
+		if err := step.do(dpdki); err != nil {
+			return plan, fmt.Errorf("step %d (%s) failed: %w", i+1, step, err)
+		}
+	}
+	running.conf.SetBasePath(newConf.GetBasePath())
+
+	return plan, nil
+}
+
+// compare the JSON representation of two config values
+func jsonEqual(a any, b any) bool {
+	ja, erra := json.Marshal(a)
+	jb, errb := json.Marshal(b)
+	return erra == nil && errb == nil && string(ja) == string(jb)
+}
+
+func (mpc *PktmbufConfig) equal(o *PktmbufConfig) bool {
+	// set the default buffer size on copies of both
+	a, b := *mpc, *o
+	a.GetBufferSize()
+	b.GetBufferSize()
+	return jsonEqual(&a, &b)
+}
+
+// compare the pipeline configs without the start table entries, returns the reason if not equal
+func (pc *PipelineConfig) compare(o *PipelineConfig) string {
+	a, b := *pc, *o
+	a.Start, b.Start = pc.Start.withoutEntries(), o.Start.withoutEntries()
+	if !jsonEqual(a.InputPorts, b.InputPorts) || !jsonEqual(a.OutputPorts, b.OutputPorts) {
+		return "port bindings changed"
+	}
+	if !jsonEqual(a, b) {
+		return "changed"
+	}
+	return ""
+}
+
+// the normalized table entry lines of the start config per table, comments and empty lines are skipped. The table
+// files are relative to the given base path, a file that can't be read has no entries.
+func (sc *StartConfig) entries(basePath string) map[string][]string {
+	entries := make(map[string][]string)
+	if sc == nil {
+		return entries
+	}
+	for i := range sc.Tables {
+		table := &sc.Tables[i]
+		lines, err := table.fileEntries(basePath)
+		if err != nil {
+			log.Warnf("table %s entries not available: %v", table.Name, err)
+		}
+		for _, line := range lines {
+			entries[table.Name] = append(entries[table.Name], line.Text)
+		}
+		for _, line := range table.Data {
+			if text := pipemngr.TableEntryNormalize(line); text != "" {
+				entries[table.Name] = append(entries[table.Name], text)
+			}
+		}
+	}
+	return entries
+}
+
+// compute the plan changing the old (running) configuration into the new configuration. The new configuration isn't
+// changed, the plan steps use a copy of it.
+func diff(old *Config, new *Config) *Plan {
+	plan := &Plan{}
+
+	// the pipeline specs are relative to the base path of the new config
+	new = new.copy()
+	for i, pc := range new.Pipelines {
+		npc := *pc
+		npc.SetBasePath(new.GetBasePath())
+		new.Pipelines[i] = &npc
+	}
+
+	// find the removed and changed resources in dependency order, a resource is also recreated when a resource it
+	// depends on is recreated or removed. The reason is given per resource name.
+	gonePktmbufs := make(map[string]string)
+	for _, m := range old.Pktmbufs {
+		if nm, ok := find(new.Pktmbufs, m.GetName()); !ok {
+			gonePktmbufs[m.GetName()] = "removed"
+		} else if !m.equal(nm) {
+			gonePktmbufs[m.GetName()] = "changed"
+		}
+	}
+
+	goneDevices := make(map[string]string)
+	for _, devArgs := range old.Devices {
+		name := deviceName(devArgs)
+		if nd, ok := findDevice(new.Devices, name); !ok {
+			goneDevices[name] = "removed"
+		} else if nd != devArgs {
+			goneDevices[name] = "changed"
+		}
+	}
+
+	goneInterfaces := make(map[string]string)
+	for _, i := range old.Interfaces {
+		ni, ok := find(new.Interfaces, i.GetName())
+		switch {
+		case !ok:
+			goneInterfaces[i.GetName()] = "removed"
+		case !jsonEqual(i, ni):
+			goneInterfaces[i.GetName()] = "changed"
+		default:
+			if deps, ok := i.Params.(InterfaceDependencies); ok {
+				pktmbufs, devices := deps.Dependencies()
+				for _, name := range pktmbufs {
+					if _, ok := gonePktmbufs[name]; ok {
+						goneInterfaces[i.GetName()] = "pktmbuf " + name + " recreated"
+					}
+				}
+				for _, name := range devices {
+					if _, ok := goneDevices[name]; ok {
+						goneInterfaces[i.GetName()] = "device " + name + " recreated"
+					}
+				}
+			}
+		}
+	}
+
+	gonePipelines := make(map[string]string)
+	for _, pc := range old.Pipelines {
+		npc, ok := find(new.Pipelines, pc.GetName())
+		if !ok {
+			gonePipelines[pc.GetName()] = "removed"
+			continue
+		}
+		if reason := pc.compare(npc); reason != "" {
+			gonePipelines[pc.GetName()] = reason
+			continue
+		}
+		for _, name := range pc.GetPortNames() {
+			if _, ok := goneInterfaces[name]; ok {
+				gonePipelines[pc.GetName()] = "interface " + name + " recreated"
+				break
+			}
+		}
+	}
+
+	// delete in reverse dependency order
+	for _, pc := range old.Pipelines {
+		name := pc.GetName()
+		if reason, ok := gonePipelines[name]; ok {
+			plan.add("delete", "pipeline", name, reason, func(dpdki *dpdkinfra.DpdkInfra) error {
+				if err := dpdki.PipelineDelete(name); err != nil {
+					return err
+				}
+				running.conf.Pipelines = remove(running.conf.Pipelines, name)
+				return nil
+			})
+		}
+	}
+	for _, i := range old.Interfaces {
+		name := i.GetName()
+		if reason, ok := goneInterfaces[name]; ok {
+			plan.add("delete", "interface", name, reason, func(dpdki *dpdkinfra.DpdkInfra) error {
+				if err := dpdki.PortDelete(name); err != nil {
+					return err
+				}
+				running.conf.Interfaces = remove(running.conf.Interfaces, name)
+				return nil
+			})
+		}
+	}
+	for _, devArgs := range old.Devices {
+		name, devArgs := deviceName(devArgs), devArgs
+		if reason, ok := goneDevices[name]; ok {
+			plan.add("detach", "device", name, reason, func(dpdki *dpdkinfra.DpdkInfra) error {
+				if _, err := dpdki.DetachDevice(devArgs); err != nil {
+					return err
+				}
+				running.conf.Devices = removeDevice(running.conf.Devices, name)
+				return nil
+			})
+		}
+	}
+	for _, m := range old.Pktmbufs {
+		name := m.GetName()
+		if reason, ok := gonePktmbufs[name]; ok {
+			plan.add("delete", "pktmbuf", name, reason, func(dpdki *dpdkinfra.DpdkInfra) error {
+				if err := dpdki.PktmbufDelete(name); err != nil {
+					return err
+				}
+				running.conf.Pktmbufs = remove(running.conf.Pktmbufs, name)
+				return nil
+			})
+		}
+	}
+
+	// the reason to create a resource of the new config, empty if the resource is kept
+	createReason := func(exists bool, reason string, gone bool) string {
+		switch {
+		case !exists:
+			return "added"
+		case gone:
+			return reason
+		}
+		return ""
+	}
+
+	// (re)create in dependency order
+	for _, m := range new.Pktmbufs {
+		m := m
+		_, exists := find(old.Pktmbufs, m.GetName())
+		reason, gone := gonePktmbufs[m.GetName()]
+		if reason = createReason(exists, reason, gone); reason != "" {
+			plan.add("create", "pktmbuf", m.GetName(), reason, m.create)
+		}
+	}
+	for _, devArgs := range new.Devices {
+		name, devArgs := deviceName(devArgs), devArgs
+		_, exists := findDevice(old.Devices, name)
+		reason, gone := goneDevices[name]
+		if reason = createReason(exists, reason, gone); reason != "" {
+			plan.add("attach", "device", name, reason, func(dpdki *dpdkinfra.DpdkInfra) error {
+				return attachDevice(dpdki, devArgs)
+			})
+		}
+	}
+	for _, i := range new.Interfaces {
+		i := i
+		_, exists := find(old.Interfaces, i.GetName())
+		reason, gone := goneInterfaces[i.GetName()]
+		if reason = createReason(exists, reason, gone); reason != "" {
+			plan.add("create", "interface", i.GetName(), reason, i.create)
+		}
+	}
+	for _, pc := range new.Pipelines {
+		pc := pc
+		_, exists := find(old.Pipelines, pc.GetName())
+		reason, gone := gonePipelines[pc.GetName()]
+		if reason = createReason(exists, reason, gone); reason != "" {
+			plan.add("create", "pipeline", pc.GetName(), reason, pc.create)
+		}
+	}
+
+	// change the table entries of the kept pipelines
+	for _, pc := range new.Pipelines {
+		opc, exists := find(old.Pipelines, pc.GetName())
+		if _, gone := gonePipelines[pc.GetName()]; !exists || gone {
+			continue
+		}
+		diffTableEntries(plan, opc, pc)
+	}
+
+	return plan
+}
+
+// add the steps removing and adding the changed start table entries of a kept pipeline
+func diffTableEntries(plan *Plan, old *PipelineConfig, new *PipelineConfig) {
+	oldEntries, newEntries := old.Start.entries(old.GetBasePath()), new.Start.entries(new.GetBasePath())
+	if reflect.DeepEqual(oldEntries, newEntries) {
+		return
+	}
+
+	plName := new.GetName()
+	contains := func(lines []string, line string) bool {
+		for _, l := range lines {
+			if l == line {
+				return true
+			}
+		}
+		return false
+	}
+	entryStep := func(action string, table string, line string) {
+		plan.add(action, "table entry", plName+"/"+table, line, func(dpdki *dpdkinfra.DpdkInfra) error {
+			fn := dpdki.TableEntryAdd
+			if action == "remove" {
+				fn = dpdki.TableEntryDelete
+			}
+			if err := fn(plName, table, line); err != nil {
+				// discard the table changes scheduled for this pipeline
+				if aerr := dpdki.PipelineAbort(plName); aerr != nil {
+					log.Warnf("Abort of pipeline %s table changes failed: %v", plName, aerr)
+				}
+				return err
+			}
+			return nil
+		})
+	}
+
+	// first remove the entries not in the new config and then add the new entries, tables in config order
+	if old.Start != nil {
+		for _, table := range old.Start.Tables {
+			for _, line := range oldEntries[table.Name] {
+				if !contains(newEntries[table.Name], line) {
+					entryStep("remove", table.Name, line)
+				}
+			}
+		}
+	}
+	if new.Start != nil {
+		for _, table := range new.Start.Tables {
+			for _, line := range newEntries[table.Name] {
+				if !contains(oldEntries[table.Name], line) {
+					entryStep("add", table.Name, line)
+				}
+			}
+		}
+	}
+
+	plan.add("commit", "pipeline", plName, "table entries changed", func(dpdki *dpdkinfra.DpdkInfra) error {
+		if err := dpdki.PipelineCommit(plName); err != nil {
+			return err
+		}
+		running.conf.Pipelines = replace(running.conf.Pipelines, new)
+		return nil
+	})
+}
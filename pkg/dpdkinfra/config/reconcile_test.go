@@ -0,0 +1,217 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"github.com/stretchr/testify/assert"
+)
+
+// config with a pktmbuf, a device with an ethdev and a tap using the pktmbuf, a ring and two pipelines. The pipelines
+// have the base path set as in the running config.
+func reconcileTestConfig() *Config {
+	c := &Config{
+		Base:     &config.Base{},
+		Pktmbufs: PktmbufsConfig{{Name: "MEMPOOL0", PoolSize: 1024, CacheSize: 32}},
+		Devices:  DevicesConfig{"net_tap0,iface=tap0"},
+		Interfaces: InterfacesConfig{
+			{Name: "LINK0", Type: portmngr.PortTypeEthdev, Params: &PMDParams{
+				PortName: "net_tap0",
+				Rx:       &PMDRxParams{NQueues: 1, QueueSize: 128, PktMbuf: "MEMPOOL0"},
+				Tx:       &PMDTxParams{NQueues: 1, QueueSize: 128},
+			}},
+			{Name: "TAP0", Type: portmngr.PortTypeTap, Params: &TapParams{Rx: &TapRxParams{Mtu: 1500, PktMbuf: "MEMPOOL0"}}},
+			{Name: "RING0", Type: portmngr.PortTypeRing, Params: &RingParams{Size: 1024}},
+		},
+		Pipelines: PipelinesConfig{
+			{
+				Name:        "PIPE0",
+				BasePath:    "/config",
+				Spec:        "pipe0.spec",
+				InputPorts:  []*InPortConfig{{IfaceName: "LINK0", Bsz: 32}},
+				OutputPorts: []*OutPortConfig{{IfaceName: "RING0", Bsz: 32}},
+				Start:       &StartConfig{Tables: []TableConfig{{Name: "fwd", Data: []string{"match 0x1 action drop"}}}},
+			},
+			{
+				Name:        "PIPE1",
+				BasePath:    "/config",
+				Spec:        "pipe1.spec",
+				InputPorts:  []*InPortConfig{{IfaceName: "RING0", Bsz: 32}},
+				OutputPorts: []*OutPortConfig{{IfaceName: "TAP0", Bsz: 32}},
+			},
+		},
+	}
+	c.SetBasePath("/config")
+	return c
+}
+
+// the plan step strings
+func planSteps(plan *Plan) []string {
+	var steps []string
+	for _, step := range plan.Steps {
+		steps = append(steps, step.String())
+	}
+	return steps
+}
+
+func TestDiffEqual(t *testing.T) {
+	plan := diff(reconcileTestConfig(), reconcileTestConfig())
+	assert.True(t, plan.Empty())
+	assert.Equal(t, "no changes", plan.String())
+
+	// the default buffer size is equal to no buffer size
+	old, new := reconcileTestConfig(), reconcileTestConfig()
+	new.Pktmbufs[0].BufferSize = pktmbuf.RteMbufDefaultBufSize
+	assert.True(t, diff(old, new).Empty())
+	assert.Zero(t, old.Pktmbufs[0].BufferSize)
+}
+
+func TestDiffCreateAll(t *testing.T) {
+	old := &Config{Base: &config.Base{}}
+	new := reconcileTestConfig()
+	plan := diff(old, new)
+	assert.Equal(t, []string{
+		"create pktmbuf MEMPOOL0 (added)",
+		"attach device net_tap0 (added)",
+		"create interface LINK0 (added)",
+		"create interface TAP0 (added)",
+		"create interface RING0 (added)",
+		"create pipeline PIPE0 (added)",
+		"create pipeline PIPE1 (added)",
+	}, planSteps(plan))
+	assert.Contains(t, plan.String(), "  1. create pktmbuf MEMPOOL0 (added)\n  2. attach device net_tap0 (added)\n")
+}
+
+func TestDiffRemove(t *testing.T) {
+	new := reconcileTestConfig()
+	new.Interfaces = new.Interfaces[:2]
+	new.Pipelines = nil
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (removed)",
+		"delete pipeline PIPE1 (removed)",
+		"delete interface RING0 (removed)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+}
+
+func TestDiffDependencies(t *testing.T) {
+	// a changed pktmbuf recreates the interfaces using it and the pipelines bound to them, the ring is kept
+	new := reconcileTestConfig()
+	new.Pktmbufs[0].PoolSize = 2048
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (interface LINK0 recreated)",
+		"delete pipeline PIPE1 (interface TAP0 recreated)",
+		"delete interface LINK0 (pktmbuf MEMPOOL0 recreated)",
+		"delete interface TAP0 (pktmbuf MEMPOOL0 recreated)",
+		"delete pktmbuf MEMPOOL0 (changed)",
+		"create pktmbuf MEMPOOL0 (changed)",
+		"create interface LINK0 (pktmbuf MEMPOOL0 recreated)",
+		"create interface TAP0 (pktmbuf MEMPOOL0 recreated)",
+		"create pipeline PIPE0 (interface LINK0 recreated)",
+		"create pipeline PIPE1 (interface TAP0 recreated)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+
+	// a changed device recreates the ethdev using it
+	new = reconcileTestConfig()
+	new.Devices[0] = "net_tap0,iface=tap1"
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (interface LINK0 recreated)",
+		"delete interface LINK0 (device net_tap0 recreated)",
+		"detach device net_tap0 (changed)",
+		"attach device net_tap0 (changed)",
+		"create interface LINK0 (device net_tap0 recreated)",
+		"create pipeline PIPE0 (interface LINK0 recreated)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+
+	// a changed ring recreates the pipelines bound to it
+	new = reconcileTestConfig()
+	new.Interfaces[2].Params = &RingParams{Size: 2048}
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (interface RING0 recreated)",
+		"delete pipeline PIPE1 (interface RING0 recreated)",
+		"delete interface RING0 (changed)",
+		"create interface RING0 (changed)",
+		"create pipeline PIPE0 (interface RING0 recreated)",
+		"create pipeline PIPE1 (interface RING0 recreated)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+}
+
+func TestDiffPipelines(t *testing.T) {
+	new := reconcileTestConfig()
+	new.Pipelines[0].OutputPorts[0] = &OutPortConfig{IfaceName: "RING0", Bsz: 16}
+	new.Pipelines[1].Disabled = true
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (port bindings changed)",
+		"delete pipeline PIPE1 (changed)",
+		"create pipeline PIPE0 (port bindings changed)",
+		"create pipeline PIPE1 (changed)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+}
+
+func TestDiffTableEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acl.txt"), []byte("match 0x10 action drop\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// only changed table entries change the entries of the running pipeline
+	new := reconcileTestConfig()
+	new.Pipelines[0].Start = &StartConfig{Tables: []TableConfig{
+		{Name: "fwd", Data: []string{"match   0x1 action drop", "match 0x2 action drop"}},
+		{Name: "acl", File: filepath.Join(dir, "acl.txt")},
+	}}
+	new.Pipelines[1].Start = &StartConfig{Tables: []TableConfig{{Name: "fwd"}}}
+	assert.Equal(t, []string{
+		"add table entry PIPE0/fwd (match 0x2 action drop)",
+		"add table entry PIPE0/acl (match 0x10 action drop)",
+		"commit pipeline PIPE0 (table entries changed)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+
+	new = reconcileTestConfig()
+	new.Pipelines[0].Start = nil
+	assert.Equal(t, []string{
+		"remove table entry PIPE0/fwd (match 0x1 action drop)",
+		"commit pipeline PIPE0 (table entries changed)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+
+	// other start settings recreate the pipeline
+	new = reconcileTestConfig()
+	new.Pipelines[0].Start.Registers = []RegisterConfig{{Name: "reg", Index: 1, Value: 2}}
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (changed)",
+		"create pipeline PIPE0 (changed)",
+	}, planSteps(diff(reconcileTestConfig(), new)))
+}
+
+func TestDiffKeepsNewConfig(t *testing.T) {
+	new := reconcileTestConfig()
+	new.Pipelines[0].BasePath = ""
+	new.Pipelines[1].BasePath = "/pipelines"
+	old := reconcileTestConfig()
+	old.Pipelines[1].BasePath = "/pipelines"
+
+	// the pipeline spec files are relative to the base path of the new config
+	assert.True(t, diff(old, new).Empty())
+	assert.Equal(t, "", new.Pipelines[0].BasePath)
+	assert.Zero(t, new.Pktmbufs[0].BufferSize)
+
+	old.Pipelines[0].BasePath = "/other"
+	assert.Equal(t, []string{
+		"delete pipeline PIPE0 (changed)",
+		"create pipeline PIPE0 (changed)",
+	}, planSteps(diff(old, new)))
+	assert.Equal(t, "", new.Pipelines[0].BasePath)
+}
+
+func TestRingParamsDependencies(t *testing.T) {
+	var deps InterfaceDependencies = &RingParams{Size: 1024}
+	pktmbufs, devices := deps.Dependencies()
+	assert.Empty(t, pktmbufs)
+	assert.Empty(t, devices)
+}
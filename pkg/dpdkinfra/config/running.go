@@ -0,0 +1,114 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/stolsma/go-p4pack/pkg/config"
+)
+
+// the configuration of the resources created by Apply and Reconcile. It is updated after every successfully created
+// or deleted resource and is the base of the difference computed by Diff.
+var running = struct {
+	sync.Mutex
+	conf *Config
+}{conf: Create()}
+
+// Running returns a copy of the configuration of the resources created by Apply and Reconcile, resources created in
+// another way (i.e. through the CLI) are not included.
+func Running() *Config {
+	running.Lock()
+	defer running.Unlock()
+	return running.conf.copy()
+}
+
+// copy the config lists, the list items are shared
+func (c *Config) copy() *Config {
+	cc := &Config{
+		Base:       &config.Base{},
+		Eal:        c.Eal,
+		Pktmbufs:   append(PktmbufsConfig(nil), c.Pktmbufs...),
+		Devices:    append(DevicesConfig(nil), c.Devices...),
+		Interfaces: append(InterfacesConfig(nil), c.Interfaces...),
+		Pipelines:  append(PipelinesConfig(nil), c.Pipelines...),
+	}
+	if c.Base != nil {
+		cc.SetBasePath(c.GetBasePath())
+	}
+	return cc
+}
+
+type named interface {
+	GetName() string
+}
+
+// find the item with the given name
+func find[T named](list []T, name string) (item T, ok bool) {
+	for _, item := range list {
+		if item.GetName() == name {
+			return item, true
+		}
+	}
+	return item, false
+}
+
+// replace the item with the same name or append the item if the name isn't in the list yet
+func replace[T named](list []T, item T) []T {
+	for i := range list {
+		if list[i].GetName() == item.GetName() {
+			list[i] = item
+			return list
+		}
+	}
+	return append(list, item)
+}
+
+// remove the item with the given name
+func remove[T named](list []T, name string) []T {
+	for i := range list {
+		if list[i].GetName() == name {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// deviceName returns the device name of a DPDK device argument string, i.e. "virtio_user0" for
+// "virtio_user0,path=/dev/vhost-net,queues=1"
+func deviceName(devArgs string) string {
+	return strings.TrimSpace(strings.SplitN(devArgs, ",", 2)[0])
+}
+
+// the device argument string of the device with the given name
+func findDevice(devices DevicesConfig, name string) (string, bool) {
+	for _, devArgs := range devices {
+		if deviceName(devArgs) == name {
+			return devArgs, true
+		}
+	}
+	return "", false
+}
+
+// replace the device with the same name or append the device
+func replaceDevice(devices DevicesConfig, devArgs string) DevicesConfig {
+	for i := range devices {
+		if deviceName(devices[i]) == deviceName(devArgs) {
+			devices[i] = devArgs
+			return devices
+		}
+	}
+	return append(devices, devArgs)
+}
+
+// remove the device with the given name
+func removeDevice(devices DevicesConfig, name string) DevicesConfig {
+	for i := range devices {
+		if deviceName(devices[i]) == name {
+			return append(devices[:i:i], devices[i+1:]...)
+		}
+	}
+	return devices
+}
@@ -0,0 +1,369 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/pipemngr"
+)
+
+// StartConfig is the initial state of the objects of a pipeline, set after the pipeline is enabled
+type StartConfig struct {
+	Tables        []TableConfig        `json:"tables,omitempty"`
+	Learners      []LearnerConfig      `json:"learners,omitempty"`
+	Selectors     []SelectorConfig     `json:"selectors,omitempty"`
+	Registers     []RegisterConfig     `json:"registers,omitempty"`
+	MeterProfiles []MeterProfileConfig `json:"meterprofiles,omitempty"`
+	Meters        []MeterConfig        `json:"meters,omitempty"`
+}
+
+type TableConfig struct {
+	Name    string   `json:"name"`
+	Default string   `json:"default,omitempty"` // default entry, i.e. "action drop"
+	File    string   `json:"file,omitempty"`    // table entries file, relative to the pipeline base path or absolute
+	Data    []string `json:"data,omitempty"`    // table entries, added after the entries of the file
+
+	fileLines []pipemngr.TableEntryLine // the entries of the file as loaded or compared, the file is read once
+}
+
+type LearnerConfig struct {
+	Name     string   `json:"name"`
+	Default  string   `json:"default,omitempty"`  // default entry, i.e. "action drop"
+	Timeouts []uint32 `json:"timeouts,omitempty"` // key timeouts in seconds, in the order of the spec file timeouts
+}
+
+type SelectorConfig struct {
+	Name   string                `json:"name"`
+	Groups []SelectorGroupConfig `json:"groups"`
+}
+
+type SelectorGroupConfig struct {
+	ID      uint32                 `json:"id"`
+	Members []SelectorMemberConfig `json:"members"`
+}
+
+type SelectorMemberConfig struct {
+	ID     uint32 `json:"id"`
+	Weight uint32 `json:"weight,omitempty"` // 1 when not set
+}
+
+type RegisterConfig struct {
+	Name  string `json:"name"`
+	Index uint32 `json:"index"`
+	Value uint64 `json:"value"`
+}
+
+// MeterProfileConfig is a trTCM (RFC 2698) meter profile
+type MeterProfileConfig struct {
+	Name string `json:"name"`
+	CIR  uint64 `json:"cir"` // committed information rate in bytes per second
+	PIR  uint64 `json:"pir"` // peak information rate in bytes per second
+	CBS  uint64 `json:"cbs"` // committed burst size in bytes
+	PBS  uint64 `json:"pbs"` // peak burst size in bytes
+}
+
+type MeterConfig struct {
+	Name    string `json:"name"`
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to,omitempty"` // last meter index, From when not set
+	Profile string `json:"profile"`
+}
+
+func (mc *MeterConfig) GetTo() uint32 {
+	if mc.To < mc.From {
+		return mc.From
+	}
+	return mc.To
+}
+
+// the path of the table entries file, relative file names are relative to the given base path
+func (tc *TableConfig) path(basePath string) string {
+	if filepath.IsAbs(tc.File) {
+		return tc.File
+	}
+	return filepath.Join(basePath, tc.File)
+}
+
+// the entries of the table entries file, read once so the entries of an applied config don't change with the file
+func (tc *TableConfig) fileEntries(basePath string) ([]pipemngr.TableEntryLine, error) {
+	if tc.File == "" || tc.fileLines != nil {
+		return tc.fileLines, nil
+	}
+
+	lines, err := pipemngr.TableEntriesRead(tc.path(basePath))
+	if err != nil {
+		return nil, err
+	}
+	tc.fileLines = lines
+	return lines, nil
+}
+
+// copy of the start config without the table entries, nil if nothing is left
+func (sc *StartConfig) withoutEntries() *StartConfig {
+	if sc == nil {
+		return nil
+	}
+	c := *sc
+	c.Tables = nil
+	for _, table := range sc.Tables {
+		if table.Default != "" {
+			c.Tables = append(c.Tables, TableConfig{Name: table.Name, Default: table.Default})
+		}
+	}
+	if c.Tables == nil && c.Learners == nil && c.Selectors == nil && c.Registers == nil && c.MeterProfiles == nil &&
+		c.Meters == nil {
+		return nil
+	}
+	return &c
+}
+
+// validate the start config of a pipeline, the table files are searched relative to the given base path
+func (sc *StartConfig) validate(path string, basePath string, errs *validation.Errors) {
+	for i := range sc.Tables {
+		table := &sc.Tables[i]
+		tpath := validation.Path(path, "tables", i)
+		if table.Name == "" {
+			errs.Addf(validation.Path(tpath, "name"), "table name missing")
+		}
+		if table.File != "" {
+			if _, err := os.Stat(table.path(basePath)); err != nil {
+				errs.Add(validation.Path(tpath, "file"), err)
+			}
+		}
+	}
+
+	for i, learner := range sc.Learners {
+		if learner.Name == "" {
+			errs.Addf(validation.Path(path, "learners", i, "name"), "learner table name missing")
+		}
+	}
+
+	for i, selector := range sc.Selectors {
+		spath := validation.Path(path, "selectors", i)
+		if selector.Name == "" {
+			errs.Addf(validation.Path(spath, "name"), "selector table name missing")
+		}
+		ids := make(map[uint32]bool)
+		for j, group := range selector.Groups {
+			if ids[group.ID] {
+				errs.Addf(validation.Path(spath, "groups", j, "id"), "duplicate group %d", group.ID)
+			}
+			ids[group.ID] = true
+		}
+	}
+
+	for i, register := range sc.Registers {
+		if register.Name == "" {
+			errs.Addf(validation.Path(path, "registers", i, "name"), "register array name missing")
+		}
+	}
+
+	profiles := make(map[string]bool)
+	for i, mp := range sc.MeterProfiles {
+		mpath := validation.Path(path, "meterprofiles", i)
+		switch {
+		case mp.Name == "":
+			errs.Addf(validation.Path(mpath, "name"), "meter profile name missing")
+		case profiles[mp.Name]:
+			errs.Addf(validation.Path(mpath, "name"), "duplicate meter profile %s", mp.Name)
+		}
+		profiles[mp.Name] = true
+		if mp.CIR == 0 || mp.PIR == 0 || mp.CBS == 0 || mp.PBS == 0 || mp.PIR < mp.CIR {
+			errs.Addf(mpath, "cir, pir, cbs and pbs must be larger than 0 and pir must be at least cir")
+		}
+	}
+
+	for i, meter := range sc.Meters {
+		mpath := validation.Path(path, "meters", i)
+		if meter.Name == "" {
+			errs.Addf(validation.Path(mpath, "name"), "meter array name missing")
+		}
+		if meter.To != 0 && meter.To < meter.From {
+			errs.Addf(validation.Path(mpath, "to"), "to (%d) is lower than from (%d)", meter.To, meter.From)
+		}
+		if !profiles[meter.Profile] {
+			errs.Addf(validation.Path(mpath, "profile"), "unknown meter profile %q", meter.Profile)
+		}
+	}
+}
+
+// apply the start config to the given enabled pipeline. The table entries, default entries and selector group
+// members are committed at once, all of them are discarded when one of them fails. The meter profiles, learner
+// timeouts, selector groups, registers and meters are set directly by DPDK and are not part of that transaction. They
+// are set before the commit and undone when the start config fails, except for the learner timeouts.
+func (sc *StartConfig) apply(dpdki *dpdkinfra.DpdkInfra, pipeName string, basePath string) error {
+	undo := &startUndo{dpdki: dpdki, pipeName: pipeName}
+
+	for _, mp := range sc.MeterProfiles {
+		if err := dpdki.MeterProfileAdd(pipeName, mp.Name, mp.CIR, mp.PIR, mp.CBS, mp.PBS); err != nil {
+			undo.run()
+			return fmt.Errorf("meter profile %s: %v", mp.Name, err)
+		}
+		name := mp.Name
+		undo.add(func() error {
+			return dpdki.MeterProfileDelete(pipeName, name)
+		}, false)
+	}
+
+	if err := sc.schedule(dpdki, pipeName, basePath, undo); err != nil {
+		undo.run()
+		return err
+	}
+
+	for _, register := range sc.Registers {
+		name, index := register.Name, register.Index
+		value, err := dpdki.RegisterRead(pipeName, name, index)
+		if err == nil {
+			err = dpdki.RegisterWrite(pipeName, name, index, register.Value)
+		}
+		if err != nil {
+			undo.run()
+			return fmt.Errorf("register %s[%d]: %v", name, index, err)
+		}
+		undo.add(func() error {
+			return dpdki.RegisterWrite(pipeName, name, index, value)
+		}, false)
+	}
+
+	for _, meter := range sc.Meters {
+		name, first, last := meter.Name, meter.From, meter.GetTo()
+		undo.add(func() error {
+			return dpdki.MeterReset(pipeName, name, first, last)
+		}, false)
+		if err := dpdki.MeterSet(pipeName, name, first, last, meter.Profile); err != nil {
+			undo.run()
+			return fmt.Errorf("meter %s: %v", name, err)
+		}
+	}
+
+	if err := dpdki.PipelineCommit(pipeName); err != nil {
+		undo.run()
+		return fmt.Errorf("commit: %v", err)
+	}
+
+	return nil
+}
+
+// the changes of a start config done directly by DPDK, undone in reverse order when the start config fails
+type startUndo struct {
+	dpdki    *dpdkinfra.DpdkInfra
+	pipeName string
+	steps    []func() error
+	commit   bool // the undo steps schedule changes that need to be committed
+}
+
+// add an undo step, scheduled is true when the step schedules a change for the next commit
+func (u *startUndo) add(step func() error, scheduled bool) {
+	u.steps = append(u.steps, step)
+	u.commit = u.commit || scheduled
+}
+
+// discard the scheduled changes of the start config and undo the direct changes
+func (u *startUndo) run() {
+	if err := u.dpdki.PipelineAbort(u.pipeName); err != nil {
+		log.Warnf("Abort of pipeline %s start changes failed: %v", u.pipeName, err)
+	}
+
+	for i := len(u.steps) - 1; i >= 0; i-- {
+		if err := u.steps[i](); err != nil {
+			log.Warnf("Undo of pipeline %s start change failed: %v", u.pipeName, err)
+		}
+	}
+
+	if u.commit {
+		if err := u.dpdki.PipelineCommit(u.pipeName); err != nil {
+			log.Warnf("Commit of pipeline %s start undo changes failed: %v", u.pipeName, err)
+		}
+	}
+}
+
+// schedule the table, learner and selector changes for the next commit
+func (sc *StartConfig) schedule(dpdki *dpdkinfra.DpdkInfra, pipeName string, basePath string, undo *startUndo) error {
+	for i := range sc.Tables {
+		table := &sc.Tables[i]
+		if table.File != "" {
+			lines, err := dpdki.TableEntriesLoad(pipeName, table.Name, table.path(basePath))
+			if err != nil {
+				return fmt.Errorf("table %s: %v", table.Name, err)
+			}
+			table.fileLines = lines
+		}
+		for i, line := range table.Data {
+			if err := dpdki.TableEntryAdd(pipeName, table.Name, line); err != nil {
+				return fmt.Errorf("table %s %s: %v", table.Name, validation.Path("data", i), err)
+			}
+		}
+		if table.Default != "" {
+			if err := dpdki.TableDefaultEntryAdd(pipeName, table.Name, table.Default); err != nil {
+				return fmt.Errorf("table %s default: %v", table.Name, err)
+			}
+		}
+	}
+
+	for _, learner := range sc.Learners {
+		if learner.Default != "" {
+			if err := dpdki.LearnerDefaultEntryAdd(pipeName, learner.Name, learner.Default); err != nil {
+				return fmt.Errorf("learner %s default: %v", learner.Name, err)
+			}
+		}
+		for id, timeout := range learner.Timeouts {
+			if err := dpdki.LearnerTimeoutSet(pipeName, learner.Name, uint32(id), timeout); err != nil {
+				return fmt.Errorf("learner %s timeout %d: %v", learner.Name, id, err)
+			}
+		}
+	}
+
+	for _, selector := range sc.Selectors {
+		if err := selector.schedule(dpdki, pipeName, undo); err != nil {
+			return fmt.Errorf("selector %s: %v", selector.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// add the groups in group ID order and schedule their members. DPDK hands out the lowest free group ID, so groups are
+// added until the configured group ID is reached and skipped group IDs are empty groups. The added groups, also the
+// empty ones, are deleted by the undo steps.
+func (sc *SelectorConfig) schedule(dpdki *dpdkinfra.DpdkInfra, pipeName string, undo *startUndo) error {
+	groups := append([]SelectorGroupConfig(nil), sc.Groups...)
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	for _, group := range groups {
+		for {
+			id, err := dpdki.SelectorGroupAdd(pipeName, sc.Name)
+			if err != nil {
+				return fmt.Errorf("group %d: %v", group.ID, err)
+			}
+			name := sc.Name
+			undo.add(func() error {
+				return dpdki.SelectorGroupDelete(pipeName, name, id)
+			}, true)
+			if id == group.ID {
+				break
+			}
+			if id > group.ID {
+				return fmt.Errorf("group %d: group ID already in use", group.ID)
+			}
+		}
+
+		for _, member := range group.Members {
+			weight := member.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			if err := dpdki.SelectorGroupMemberAdd(pipeName, sc.Name, group.ID, member.ID, weight); err != nil {
+				return fmt.Errorf("group %d member %d: %v", group.ID, member.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
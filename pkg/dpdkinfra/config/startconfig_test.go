@@ -0,0 +1,169 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/pipemngr"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTableFile(t *testing.T, dir string, name string, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeTableFile(t, dir, "fwd.txt", "match 0x1 action fwd port 1\n")
+
+	sc := &StartConfig{
+		Tables: []TableConfig{
+			{Name: "fwd", File: "fwd.txt"},
+			{Name: "fwd", File: filepath.Join(dir, "fwd.txt")},
+			{File: "missing.txt"},
+		},
+		Learners: []LearnerConfig{{Name: "learn"}, {}},
+		Selectors: []SelectorConfig{
+			{Name: "sel", Groups: []SelectorGroupConfig{{ID: 0}, {ID: 2}, {ID: 0}}},
+			{},
+		},
+		Registers: []RegisterConfig{{Name: "reg"}, {}},
+		MeterProfiles: []MeterProfileConfig{
+			{Name: "gold", CIR: 100, PIR: 200, CBS: 10, PBS: 20},
+			{Name: "gold", CIR: 100, PIR: 200, CBS: 10, PBS: 20},
+			{CIR: 200, PIR: 100, CBS: 10, PBS: 20},
+		},
+		Meters: []MeterConfig{
+			{Name: "meter", From: 0, To: 10, Profile: "gold"},
+			{From: 5, To: 4, Profile: "silver"},
+		},
+	}
+
+	var errs validation.Errors
+	sc.validate("start", dir, &errs)
+
+	var paths []string
+	for _, err := range errs.List() {
+		paths = append(paths, err.Path)
+	}
+	assert.Equal(t, []string{
+		"start.tables[2].name",
+		"start.tables[2].file",
+		"start.learners[1].name",
+		"start.selectors[0].groups[2].id",
+		"start.selectors[1].name",
+		"start.registers[1].name",
+		"start.meterprofiles[1].name",
+		"start.meterprofiles[2].name",
+		"start.meterprofiles[2]",
+		"start.meters[1].name",
+		"start.meters[1].to",
+		"start.meters[1].profile",
+	}, paths)
+	assert.ErrorIs(t, errs.List()[1], os.ErrNotExist)
+	assert.EqualError(t, errs.List()[3], "start.selectors[0].groups[2].id: duplicate group 0")
+	assert.EqualError(t, errs.List()[6], "start.meterprofiles[1].name: duplicate meter profile gold")
+	assert.EqualError(t, errs.List()[11], `start.meters[1].profile: unknown meter profile "silver"`)
+}
+
+func TestTableConfigFileEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeTableFile(t, dir, "fwd.txt", "# comment\n\nmatch 0x1   action fwd port 1\n; comment\n  match 0x2 action drop\n")
+
+	tc := &TableConfig{Name: "fwd", File: "fwd.txt"}
+	assert.Equal(t, filepath.Join(dir, "fwd.txt"), tc.path(dir))
+	lines, err := tc.fileEntries(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []pipemngr.TableEntryLine{
+		{Line: 3, Text: "match 0x1 action fwd port 1"},
+		{Line: 5, Text: "match 0x2 action drop"},
+	}, lines)
+
+	// the file is read once, a changed file doesn't change the entries of the table config
+	writeTableFile(t, dir, "fwd.txt", "match 0x3 action drop\n")
+	lines, err = tc.fileEntries(dir)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 2)
+
+	// absolute file names are not relative to the base path
+	abs := &TableConfig{Name: "fwd", File: filepath.Join(dir, "fwd.txt")}
+	assert.Equal(t, filepath.Join(dir, "fwd.txt"), abs.path("/other"))
+	lines, err = abs.fileEntries("/other")
+	assert.NoError(t, err)
+	assert.Equal(t, []pipemngr.TableEntryLine{{Line: 1, Text: "match 0x3 action drop"}}, lines)
+
+	// an empty file has no entries and is also read once
+	writeTableFile(t, dir, "empty.txt", "")
+	empty := &TableConfig{Name: "fwd", File: "empty.txt"}
+	lines, err = empty.fileEntries(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+	writeTableFile(t, dir, "empty.txt", "match 0x3 action drop\n")
+	lines, _ = empty.fileEntries(dir)
+	assert.Empty(t, lines)
+
+	missing := &TableConfig{Name: "fwd", File: "missing.txt"}
+	_, err = missing.fileEntries(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	none := &TableConfig{Name: "fwd"}
+	lines, err = none.fileEntries(dir)
+	assert.NoError(t, err)
+	assert.Nil(t, lines)
+}
+
+func TestStartConfigEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeTableFile(t, dir, "fwd.txt", "match 0x1 action fwd port 1\n# comment\n")
+
+	sc := &StartConfig{Tables: []TableConfig{
+		{Name: "fwd", File: "fwd.txt", Data: []string{"match   0x2 action drop", "; comment", ""}},
+		{Name: "acl", Data: []string{"match 0x3 action drop"}},
+		{Name: "missing", File: "missing.txt"},
+		{Name: "default", Default: "action drop"},
+	}}
+	assert.Equal(t, map[string][]string{
+		"fwd": {"match 0x1 action fwd port 1", "match 0x2 action drop"},
+		"acl": {"match 0x3 action drop"},
+	}, sc.entries(dir))
+
+	var nilConfig *StartConfig
+	assert.Empty(t, nilConfig.entries(dir))
+}
+
+func TestStartConfigWithoutEntries(t *testing.T) {
+	var nilConfig *StartConfig
+	assert.Nil(t, nilConfig.withoutEntries())
+
+	// only table entries
+	sc := &StartConfig{Tables: []TableConfig{{Name: "fwd", File: "fwd.txt", Data: []string{"match 0x1 action drop"}}}}
+	assert.Nil(t, sc.withoutEntries())
+
+	// the default entries and the other objects are kept
+	sc = &StartConfig{
+		Tables: []TableConfig{
+			{Name: "fwd", File: "fwd.txt"},
+			{Name: "acl", Default: "action drop", Data: []string{"match 0x1 action drop"}},
+		},
+		Registers: []RegisterConfig{{Name: "reg", Index: 1, Value: 2}},
+	}
+	assert.Equal(t, &StartConfig{
+		Tables:    []TableConfig{{Name: "acl", Default: "action drop"}},
+		Registers: []RegisterConfig{{Name: "reg", Index: 1, Value: 2}},
+	}, sc.withoutEntries())
+	assert.Len(t, sc.Tables, 2)
+}
+
+func TestMeterConfigGetTo(t *testing.T) {
+	assert.Equal(t, uint32(5), (&MeterConfig{From: 5}).GetTo())
+	assert.Equal(t, uint32(5), (&MeterConfig{From: 5, To: 3}).GetTo())
+	assert.Equal(t, uint32(8), (&MeterConfig{From: 5, To: 8}).GetTo())
+}
@@ -5,11 +5,14 @@ package dpdkinfra
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/pipemngr"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/store"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pdump"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
 	"github.com/stolsma/go-p4pack/pkg/logging"
 )
@@ -30,6 +33,9 @@ type DpdkInfra struct {
 	*portmngr.PortMngr
 	*pipemngr.PipeMngr
 	PktmbufStore *store.Store[*pktmbuf.Pktmbuf]
+	captures     captures
+	pktmbufMon   pktmbufMonitor
+	pktmbufAuto  autoPktmbufs
 }
 
 // return a pointer to the (initialized) dpdkinfra singleton
@@ -65,10 +71,20 @@ func (di *DpdkInfra) init(dpdkArgs []string) error {
 	// create store and initialize PortMngr and PipeMngr
 	log.Info("Create Pktmbuf store...")
 	di.PktmbufStore = store.NewStore[*pktmbuf.Pktmbuf]()
+	di.initPktmbufMonitor()
 
 	log.Info("Initialize PortMngr...")
 	di.PortMngr = &portmngr.PortMngr{}
-	di.PortMngr.Init()
+	if err := di.PortMngr.Init(); err != nil {
+		return err
+	}
+
+	di.captures.sessions = make(map[uint32]*CaptureSession)
+
+	// let secondary processes (i.e. monitoring tools) capture the packets of the ethdev ports
+	if err := pdump.Init(); err != nil {
+		log.Warnf("Packet capture for secondary processes not available: %v", err)
+	}
 
 	log.Info("Initialize PipeMngr...")
 	di.PipeMngr = &pipemngr.PipeMngr{}
@@ -81,8 +97,11 @@ func (di *DpdkInfra) init(dpdkArgs []string) error {
 
 // empty & remove stores and cleanup initialized managers
 func (di *DpdkInfra) Cleanup() error {
+	di.captureCleanup()
+	pdump.Uninit()
 	di.PipeMngr.Cleanup()
 	di.PortMngr.Cleanup()
+	di.cleanupPktmbufMonitor()
 	di.PktmbufStore.Clear()
 	return dpdkswx.Runtime.Stop()
 }
@@ -108,3 +127,131 @@ func (di *DpdkInfra) PktmbufCreate(
 	log.Infof("pktmbuf %s created", name)
 	return &pm, nil
 }
+
+// PktmbufDelete deletes the pktmbuf with the given name, for an automatically placed pktmbuf the pktmbufs on all NUMA
+// nodes are deleted. A pktmbuf with mbufs in use (i.e. by the queues of a port) can't be deleted.
+func (di *DpdkInfra) PktmbufDelete(name string) error {
+	names := []string{name}
+	di.pktmbufAuto.mu.Lock()
+	pools, auto := di.pktmbufAuto.pools[name]
+	if auto {
+		names = names[:0]
+		for _, poolName := range pools {
+			names = append(names, poolName)
+		}
+	}
+	di.pktmbufAuto.mu.Unlock()
+
+	// check all pktmbufs before anything is deleted
+	var pms []*pktmbuf.Pktmbuf
+	for _, n := range names {
+		pm := di.PktmbufStore.Get(n)
+		if pm == nil {
+			return fmt.Errorf("pktmbuf %s doesn't exist", n)
+		}
+		if stats := pm.Stats(); stats.InUse > 0 {
+			return fmt.Errorf("pktmbuf %s has %d mbufs in use", n, stats.InUse)
+		}
+		pms = append(pms, pm)
+	}
+
+	for _, pm := range pms {
+		di.PktmbufSetAlarm(pm.Name(), 0)
+		if err := pm.Free(); err != nil {
+			return err
+		}
+	}
+
+	if auto {
+		di.pktmbufAuto.mu.Lock()
+		delete(di.pktmbufAuto.pools, name)
+		di.pktmbufAuto.mu.Unlock()
+	}
+	log.Infof("pktmbuf %s deleted", name)
+	return nil
+}
+
+// PipelineDelete stops the captures on the pipeline ports, releases the port queues bound to the pipeline and frees
+// the (disabled or enabled) pipeline with the given name.
+func (di *DpdkInfra) PipelineDelete(plName string) error {
+	pl := di.PipelineStore.Get(plName)
+	if pl == nil {
+		return fmt.Errorf("pipeline %s doesn't exist", plName)
+	}
+
+	di.captureStopPipeline(pl)
+	if err := pl.Free(); err != nil {
+		return err
+	}
+
+	// release the port queues bound to the pipeline
+	di.IteratePorts(func(name string, port portmngr.PortType) error {
+		port.IterateRxQueues(func(index uint16, q device.Queue) error {
+			if q.Pipeline() == plName {
+				port.SetRxQueue(index, "", device.NotBound, 0)
+			}
+			return nil
+		})
+		port.IterateTxQueues(func(index uint16, q device.Queue) error {
+			if q.Pipeline() == plName {
+				port.SetTxQueue(index, "", device.NotBound, 0)
+			}
+			return nil
+		})
+		return nil
+	})
+
+	log.Infof("pipeline %s deleted", plName)
+	return nil
+}
+
+// EthdevReconfigureQueues reconfigures the number and size of the rx and tx queues of the given ethdev port. Enabled
+// pipelines with ports bound to queues of the ethdev port are disabled during the reconfiguration and enabled again on
+// the same thread afterwards, also when the reconfiguration fails and the previous queues are restored.
+func (di *DpdkInfra) EthdevReconfigureQueues(name string, nRxQ uint16, rxQSize uint32, nTxQ uint16, txQSize uint32) error {
+	e := di.EthdevStore.Get(name)
+	if e == nil {
+		return fmt.Errorf("ethdev %s doesn't exists", name)
+	}
+
+	// collect all enabled pipelines using this port
+	var pipelines = make(map[string]uint)
+	collect := func(index uint16, q device.Queue) error {
+		if q.PipelinePort() == device.NotBound {
+			return nil
+		}
+		if pl := di.PipelineStore.Get(q.Pipeline()); pl != nil && pl.IsEnabled() {
+			pipelines[pl.GetName()] = pl.GetThreadID()
+		}
+		return nil
+	}
+	e.IterateRxQueues(collect)
+	e.IterateTxQueues(collect)
+
+	// disable these pipelines
+	var err error
+	disabled := make(map[string]uint)
+	for plName, threadID := range pipelines {
+		if err = di.PipelineDisable(plName); err != nil {
+			break
+		}
+		disabled[plName] = threadID
+		log.Infof("pipeline %s disabled for reconfiguration of ethdev %s", plName, name)
+	}
+
+	if err == nil {
+		err = e.ReconfigureQueues(nRxQ, rxQSize, nTxQ, txQSize)
+	}
+
+	// and enable the disabled pipelines again, also when disabling or reconfiguration went wrong
+	for plName, threadID := range disabled {
+		if plErr := di.PipelineEnable(plName, threadID); plErr != nil {
+			log.Errorf("pipeline %s enable after reconfiguration of ethdev %s err: %v", plName, name, plErr)
+			if err == nil {
+				err = plErr
+			}
+		}
+	}
+
+	return err
+}
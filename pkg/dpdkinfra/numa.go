@@ -0,0 +1,176 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package dpdkinfra
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/placement"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+)
+
+// per NUMA node pktmbufs created for one automatically placed pktmbuf name
+type autoPktmbufs struct {
+	mu    sync.Mutex
+	pools map[string]map[int]string // pktmbuf name -> NUMA node -> created pktmbuf name
+}
+
+// PktmbufCreateAuto creates a pktmbuf (named <name>_<NUMA node>) on every NUMA node. Interfaces referring to the given
+// name get the pktmbuf on the NUMA node of their device, see PktmbufForNuma.
+func (di *DpdkInfra) PktmbufCreateAuto(
+	name string, bufferSize uint, poolSize uint32, cacheSize uint32,
+) ([]*pktmbuf.Pktmbuf, error) {
+	if di.PktmbufStore.Contains(name) {
+		return nil, fmt.Errorf("pktmbuf mempool %s exists", name)
+	}
+
+	nodes := eal.NumaNodes()
+	if len(nodes) == 0 {
+		nodes = []int{0}
+	}
+
+	var created []*pktmbuf.Pktmbuf
+	pools := make(map[int]string)
+	for _, numa := range nodes {
+		pm, err := di.PktmbufCreate(fmt.Sprintf("%s_%d", name, numa), bufferSize, poolSize, cacheSize, numa)
+		if err != nil {
+			for _, pm := range created {
+				pm.Free()
+			}
+			return nil, err
+		}
+		created = append(created, pm)
+		pools[numa] = pm.Name()
+	}
+
+	di.pktmbufAuto.mu.Lock()
+	defer di.pktmbufAuto.mu.Unlock()
+	if di.pktmbufAuto.pools == nil {
+		di.pktmbufAuto.pools = make(map[string]map[int]string)
+	}
+	di.pktmbufAuto.pools[name] = pools
+
+	log.Infof("pktmbuf %s created on NUMA nodes %v", name, nodes)
+	return created, nil
+}
+
+// PktmbufForNuma returns the pktmbuf with the given name to use for a device on the given NUMA node (-1 if the device
+// isn't connected to a specific node). For an automatically placed pktmbuf the pktmbuf on the same NUMA node is
+// returned, for other pktmbufs a warning is logged when the pktmbuf is on another NUMA node than the device.
+func (di *DpdkInfra) PktmbufForNuma(name string, numa int) (*pktmbuf.Pktmbuf, error) {
+	di.pktmbufAuto.mu.Lock()
+	pools, auto := di.pktmbufAuto.pools[name]
+	di.pktmbufAuto.mu.Unlock()
+
+	if auto {
+		poolName, ok := pools[numa]
+		if !ok {
+			// use the pktmbuf on the lowest NUMA node
+			var nodes []int
+			for n := range pools {
+				nodes = append(nodes, n)
+			}
+			sort.Ints(nodes)
+			poolName = pools[nodes[0]]
+			if numa != placement.NumaAny {
+				log.Warnf("pktmbuf %s has no mempool on NUMA node %d, using %s", name, numa, poolName)
+			}
+		}
+		name = poolName
+	}
+
+	pm := di.PktmbufStore.Get(name)
+	if pm == nil {
+		return nil, fmt.Errorf("mempool %s not found", name)
+	}
+	if numa != placement.NumaAny && pm.NumaNode() != placement.NumaAny && pm.NumaNode() != numa {
+		log.Warnf("pktmbuf %s is on NUMA node %d but used for a device on NUMA node %d", name, pm.NumaNode(), numa)
+	}
+	return pm, nil
+}
+
+// PktmbufAutoName returns the automatically placed pktmbuf name the pktmbuf with the given (created) name is part of,
+// false if the pktmbuf is not created by PktmbufCreateAuto
+func (di *DpdkInfra) PktmbufAutoName(name string) (string, bool) {
+	di.pktmbufAuto.mu.Lock()
+	defer di.pktmbufAuto.mu.Unlock()
+
+	for autoName, pools := range di.pktmbufAuto.pools {
+		for _, poolName := range pools {
+			if poolName == name {
+				return autoName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// the NUMA node of the created port with the given name, NumaAny if the port isn't connected to a specific node
+func (di *DpdkInfra) portNumaNode(name string) int {
+	if e := di.EthdevStore.Get(name); e != nil {
+		return e.NumaNode()
+	}
+	return placement.NumaAny
+}
+
+// PipelineNumaNode returns the NUMA node for a pipeline serving the given ports, i.e. the NUMA node most of the ports
+// are connected to. Node 0 is returned if none of the ports is connected to a specific NUMA node.
+func (di *DpdkInfra) PipelineNumaNode(plName string, ports []string) int {
+	var numas []int
+	for _, port := range ports {
+		numas = append(numas, di.portNumaNode(port))
+	}
+
+	numa, mixed := placement.Numa(numas)
+	if mixed {
+		log.Warnf("pipeline %s serves ports on multiple NUMA nodes %v, using node %d", plName, numas, numa)
+	}
+	if numa == placement.NumaAny {
+		numa = 0
+	}
+	return numa
+}
+
+// PipelineThread returns the worker thread (lcore) on the given NUMA node with the least enabled pipelines. If there
+// is no worker thread on the NUMA node the least loaded worker thread of all nodes is returned.
+func (di *DpdkInfra) PipelineThread(numa int) (uint, error) {
+	lcores := make(map[uint]int)
+	for _, lcore := range eal.GetLcoresWorkers() {
+		lcores[lcore] = eal.LcoreNumaNode(lcore)
+	}
+	planner := placement.NewPlanner(lcores)
+	di.PipelineStore.Iterate(func(key string, pl *pipeline.Pipeline) error {
+		if pl.IsEnabled() {
+			planner.Reserve(pl.GetThreadID())
+		}
+		return nil
+	})
+
+	threadID, matched, err := planner.Thread(numa)
+	if err != nil {
+		return 0, err
+	}
+	if !matched {
+		log.Warnf("no worker thread on NUMA node %d, using thread %d on NUMA node %d", numa, threadID,
+			eal.LcoreNumaNode(threadID))
+	}
+	return threadID, nil
+}
+
+// CheckPipelinePlacement logs a warning for every port of the pipeline and for the pipeline thread that are on another
+// NUMA node than the pipeline
+func (di *DpdkInfra) CheckPipelinePlacement(plName string, numa int, threadID uint, ports []string) {
+	for _, port := range ports {
+		if portNuma := di.portNumaNode(port); portNuma != placement.NumaAny && portNuma != numa {
+			log.Warnf("pipeline %s on NUMA node %d serves port %s on NUMA node %d", plName, numa, port, portNuma)
+		}
+	}
+	if lcoreNuma := eal.LcoreNumaNode(threadID); lcoreNuma != numa {
+		log.Warnf("pipeline %s on NUMA node %d runs on thread %d on NUMA node %d", plName, numa, threadID, lcoreNuma)
+	}
+}
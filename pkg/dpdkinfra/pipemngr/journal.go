@@ -0,0 +1,109 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pipemngr
+
+import (
+	"strings"
+)
+
+// TableEntries are the committed entries of a pipeline table, in order of addition
+type TableEntries struct {
+	Table   string
+	Default string // default entry, empty if not changed
+	Entries []string
+}
+
+type tableOp struct {
+	table string
+	line  string
+	add   bool
+	dflt  bool // default entry
+}
+
+// the spec file and table entries of a pipeline as given to the PipeMngr. The DPDK table entries can't be read back in
+// the format they are added with, so this journal is used to get the running state of a pipeline.
+type journal struct {
+	spec    string
+	tables  []*TableEntries // committed entries
+	pending []tableOp       // entry changes since the last commit or abort
+}
+
+// the match part of a table entry line, entries with the same match are the same entry
+func entryKey(line string) string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if f == "action" {
+			return strings.Join(fields[:i], " ")
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func (j *journal) schedule(table string, line string, add bool) {
+	j.pending = append(j.pending, tableOp{table: table, line: TableEntryNormalize(line), add: add})
+}
+
+func (j *journal) scheduleDefault(table string, line string) {
+	j.pending = append(j.pending, tableOp{table: table, line: TableEntryNormalize(line), dflt: true})
+}
+
+func (j *journal) abort() {
+	j.pending = nil
+}
+
+// apply the pending changes to the committed entries
+func (j *journal) commit() {
+	for _, op := range j.pending {
+		var te *TableEntries
+		for _, t := range j.tables {
+			if t.Table == op.table {
+				te = t
+				break
+			}
+		}
+		if te == nil {
+			if !op.add && !op.dflt {
+				continue
+			}
+			te = &TableEntries{Table: op.table}
+			j.tables = append(j.tables, te)
+		}
+		if op.dflt {
+			te.Default = op.line
+			continue
+		}
+
+		index := -1
+		for i, e := range te.Entries {
+			if entryKey(e) == entryKey(op.line) {
+				index = i
+				break
+			}
+		}
+		switch {
+		case op.add && index >= 0:
+			te.Entries[index] = op.line
+		case op.add:
+			te.Entries = append(te.Entries, op.line)
+		case index >= 0:
+			te.Entries = append(te.Entries[:index:index], te.Entries[index+1:]...)
+		}
+	}
+	j.pending = nil
+}
+
+// copy of the committed entries
+func (j *journal) entries() []TableEntries {
+	var result []TableEntries
+	for _, t := range j.tables {
+		if len(t.Entries) > 0 || t.Default != "" {
+			result = append(result, TableEntries{
+				Table:   t.Table,
+				Default: t.Default,
+				Entries: append([]string(nil), t.Entries...),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pipemngr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryKey(t *testing.T) {
+	assert.Equal(t, "match 0x1", entryKey("match   0x1 action fwd port 1"))
+	assert.Equal(t, "match 0x1 0x2", entryKey("match 0x1 0x2"))
+	assert.Equal(t, "", entryKey("action drop"))
+}
+
+func TestJournalCommit(t *testing.T) {
+	j := &journal{}
+	j.schedule("fwd", "match 0x1 action fwd port 1", true)
+	j.schedule("fwd", "match  0x2 action drop", true)
+	j.schedule("acl", "match 0x3 action drop", true)
+
+	// nothing is committed yet
+	assert.Nil(t, j.entries())
+
+	j.commit()
+	assert.Nil(t, j.pending)
+	assert.Equal(t, []TableEntries{
+		{Table: "fwd", Entries: []string{"match 0x1 action fwd port 1", "match 0x2 action drop"}},
+		{Table: "acl", Entries: []string{"match 0x3 action drop"}},
+	}, j.entries())
+
+	// an added entry with the same match replaces the entry, deleting uses the match only
+	j.schedule("fwd", "match 0x1 action fwd port 2", true)
+	j.schedule("fwd", "match 0x2", false)
+	j.schedule("acl", "match 0x3 action drop", false)
+	j.schedule("unknown", "match 0x4", false)
+	j.commit()
+	assert.Equal(t, []TableEntries{
+		{Table: "fwd", Entries: []string{"match 0x1 action fwd port 2"}},
+	}, j.entries())
+}
+
+func TestJournalAbort(t *testing.T) {
+	j := &journal{}
+	j.schedule("fwd", "match 0x1 action drop", true)
+	j.commit()
+
+	j.schedule("fwd", "match 0x1", false)
+	j.schedule("fwd", "match 0x2 action drop", true)
+	j.scheduleDefault("fwd", "action drop")
+	j.abort()
+	assert.Nil(t, j.pending)
+
+	// an aborted change isn't applied by a later commit
+	j.commit()
+	assert.Equal(t, []TableEntries{{Table: "fwd", Entries: []string{"match 0x1 action drop"}}}, j.entries())
+}
+
+func TestJournalDefault(t *testing.T) {
+	j := &journal{}
+	j.scheduleDefault("fwd", "action  drop")
+	j.commit()
+	assert.Equal(t, []TableEntries{{Table: "fwd", Default: "action drop"}}, j.entries())
+
+	j.scheduleDefault("fwd", "action fwd port 1")
+	j.schedule("fwd", "match 0x1 action drop", true)
+	j.commit()
+	assert.Equal(t, []TableEntries{
+		{Table: "fwd", Default: "action fwd port 1", Entries: []string{"match 0x1 action drop"}},
+	}, j.entries())
+
+	// the default entry is kept when all entries are deleted
+	j.schedule("fwd", "match 0x1", false)
+	j.commit()
+	assert.Equal(t, []TableEntries{{Table: "fwd", Default: "action fwd port 1"}}, j.entries())
+}
+
+func TestJournalEntriesCopy(t *testing.T) {
+	j := &journal{}
+	j.schedule("fwd", "match 0x1 action drop", true)
+	j.commit()
+
+	entries := j.entries()
+	entries[0].Entries[0] = "match 0x2 action drop"
+	assert.Equal(t, []TableEntries{{Table: "fwd", Entries: []string{"match 0x1 action drop"}}}, j.entries())
+
+	// tables without entries are left out
+	j.schedule("fwd", "match 0x1", false)
+	j.commit()
+	assert.Nil(t, j.entries())
+}
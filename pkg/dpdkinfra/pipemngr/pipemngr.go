@@ -4,7 +4,12 @@
 package pipemngr
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/store"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
@@ -22,12 +27,16 @@ func init() {
 
 type PipeMngr struct {
 	PipelineStore *store.Store[*pipeline.Pipeline]
+
+	mu       sync.Mutex
+	journals map[string]*journal // spec file and table entries per pipeline name
 }
 
 // Initialize the non system intrusive dpdkinfra singleton parts (i.e. excluding the dpdkswx runtime parts!)
 func (pm *PipeMngr) Init() error {
 	// create stores
 	pm.PipelineStore = store.NewStore[*pipeline.Pipeline]()
+	pm.journals = make(map[string]*journal)
 
 	return nil
 }
@@ -44,15 +53,50 @@ func (pm *PipeMngr) PipelineCreate(plName string, numaNode int) (*pipeline.Pipel
 	if err := pl.Init(plName, numaNode, func() {
 		log.Infof("Remove pipeline %s from store", plName)
 		pm.PipelineStore.Delete(plName)
+		pm.mu.Lock()
+		delete(pm.journals, plName)
+		pm.mu.Unlock()
 	}); err != nil {
 		return nil, err
 	}
 
 	// add node to list
 	pm.PipelineStore.Set(plName, &pl)
+	pm.mu.Lock()
+	pm.journals[plName] = &journal{}
+	pm.mu.Unlock()
 	return &pl, nil
 }
 
+// update the journal of the given pipeline
+func (pm *PipeMngr) updateJournal(plName string, fn func(j *journal)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if j := pm.journals[plName]; j != nil {
+		fn(j)
+	}
+}
+
+// PipelineSpec returns the spec file the given pipeline is build with, empty if not build
+func (pm *PipeMngr) PipelineSpec(plName string) string {
+	var spec string
+	pm.updateJournal(plName, func(j *journal) {
+		spec = j.spec
+	})
+	return spec
+}
+
+// PipelineTableEntries returns the committed table entries of the given pipeline added with TableEntryAdd and not
+// deleted with TableEntryDelete, per table in order of addition. The default entry of a table is the last committed
+// TableDefaultEntryAdd line.
+func (pm *PipeMngr) PipelineTableEntries(plName string) []TableEntries {
+	var entries []TableEntries
+	pm.updateJournal(plName, func(j *journal) {
+		entries = j.entries()
+	})
+	return entries
+}
+
 func (pm *PipeMngr) PipelineBuild(plName string, specfile string) error {
 	pipeline := pm.PipelineStore.Get(plName)
 	if pipeline == nil {
@@ -64,7 +108,14 @@ func (pm *PipeMngr) PipelineBuild(plName string, specfile string) error {
 		return errors.New("number of receive ports in this pipeline is 0 or not a power of 2")
 	}
 
-	return pipeline.BuildFromSpec(specfile)
+	if err := pipeline.BuildFromSpec(specfile); err != nil {
+		return err
+	}
+
+	pm.updateJournal(plName, func(j *journal) {
+		j.spec = specfile
+	})
+	return nil
 }
 
 func (pm *PipeMngr) PipelineCommit(plName string) error {
@@ -73,16 +124,35 @@ func (pm *PipeMngr) PipelineCommit(plName string) error {
 		return errors.New("pipeline doesn't exists")
 	}
 
-	return pl.Commit(pipeline.CommitAbortOnFail)
+	err := pl.Commit(pipeline.CommitAbortOnFail)
+	pm.updateJournal(plName, func(j *journal) {
+		if err != nil {
+			j.abort()
+		} else {
+			j.commit()
+		}
+	})
+	return err
 }
 
 func (pm *PipeMngr) PipelineEnable(plName string, threadID uint) error {
 	pl := pm.PipelineStore.Get(plName)
+	if pl == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	if !pl.IsBuild() {
+		return errors.New("pipeline is not build")
+	}
 	return pl.SetEnabled(threadID)
 }
 
 func (pm *PipeMngr) PipelineDisable(plName string) error {
 	pl := pm.PipelineStore.Get(plName)
+	if pl == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
 	return pl.SetDisabled()
 }
 
@@ -94,11 +164,313 @@ func (pm *PipeMngr) TableEntryAdd(plName string, tableName string, line string)
 
 	tableEntry := pipeline.TableEntryRead(tableName, line)
 	if tableEntry == nil {
+		return entryReadErr(line)
+	}
+
+	if err := pipeline.TableEntryAdd(tableName, tableEntry); err != nil {
+		return err
+	}
+
+	pm.updateJournal(plName, func(j *journal) {
+		j.schedule(tableName, line, true)
+	})
+	return nil
+}
+
+func (pm *PipeMngr) TableEntryDelete(plName string, tableName string, line string) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	tableEntry := pipeline.TableEntryRead(tableName, line)
+	if tableEntry == nil {
+		return entryReadErr(line)
+	}
+
+	if err := pipeline.TableEntryDelete(tableName, tableEntry); err != nil {
+		return err
+	}
+
+	pm.updateJournal(plName, func(j *journal) {
+		j.schedule(tableName, line, false)
+	})
+	return nil
+}
+
+// the error of a table entry line that couldn't be read, nil for empty and comment lines as they are skipped
+func entryReadErr(line string) error {
+	if TableEntryNormalize(line) == "" {
 		return nil
 	}
+	return fmt.Errorf("invalid table entry: %s", line)
+}
 
-	err := pipeline.TableEntryAdd(tableName, tableEntry)
-	return err
+// TableEntryNormalize returns the table entry line with single spaces between the fields, or an empty string for empty
+// and comment lines
+func TableEntryNormalize(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+		return ""
+	}
+	return strings.Join(fields, " ")
+}
+
+// TableEntryLine is a normalized table entry line read from a table entries file
+type TableEntryLine struct {
+	Line int // line number in the file
+	Text string
+}
+
+// TableEntriesRead reads the table entries of the given file, empty and comment lines are skipped
+func TableEntriesRead(file string) ([]TableEntryLine, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []TableEntryLine{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if text := TableEntryNormalize(scanner.Text()); text != "" {
+			lines = append(lines, TableEntryLine{Line: n, Text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// TableEntriesLoad schedules the entries of the given table entries file for the next commit of the pipeline and
+// returns the entries scheduled. The error of a failing entry gives its file name and line number, the entries before
+// it stay scheduled and are returned.
+func (pm *PipeMngr) TableEntriesLoad(plName string, tableName string, file string) ([]TableEntryLine, error) {
+	lines, err := TableEntriesRead(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, line := range lines {
+		if err := pm.TableEntryAdd(plName, tableName, line.Text); err != nil {
+			return lines[:i], fmt.Errorf("%s:%d: %v", file, line.Line, err)
+		}
+	}
+	return lines, nil
+}
+
+// TableDefaultEntryAdd schedules the update of the default entry of a table for the next commit of the pipeline. The
+// line contains the default action, i.e. "action drop".
+func (pm *PipeMngr) TableDefaultEntryAdd(plName string, tableName string, line string) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	tableEntry := pipeline.TableEntryRead(tableName, line)
+	if tableEntry == nil {
+		return fmt.Errorf("invalid default entry: %s", line)
+	}
+
+	if err := pipeline.TableDefaultEntryAdd(tableName, tableEntry); err != nil {
+		return err
+	}
+
+	pm.updateJournal(plName, func(j *journal) {
+		j.scheduleDefault(tableName, line)
+	})
+	return nil
+}
+
+// LearnerDefaultEntryAdd schedules the update of the default entry of a learner table for the next commit of the
+// pipeline. The line contains the default action, i.e. "action drop".
+func (pm *PipeMngr) LearnerDefaultEntryAdd(plName string, learnerName string, line string) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	entry := pipeline.LearnerDefaultEntryRead(learnerName, line)
+	if entry == nil {
+		return fmt.Errorf("invalid default entry: %s", line)
+	}
+
+	return pipeline.LearnerDefaultEntryAdd(learnerName, entry)
+}
+
+// LearnerTimeoutSet sets the key timeout with the given ID of a learner table in seconds, this is done immediately
+func (pm *PipeMngr) LearnerTimeoutSet(plName string, learnerName string, timeoutID uint32, timeout uint32) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	learner := pipeline.GetLearner(learnerName)
+	if learner == nil {
+		return fmt.Errorf("learner table %s doesn't exists", learnerName)
+	}
+
+	return learner.TimeoutSet(timeoutID, timeout)
+}
+
+// SelectorGroupAdd adds a new empty group to a selector table and returns its group ID, this is done immediately
+func (pm *PipeMngr) SelectorGroupAdd(plName string, selectorName string) (uint32, error) {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return 0, errors.New("pipeline doesn't exists")
+	}
+
+	return pipeline.SelectorGroupAdd(selectorName)
+}
+
+// SelectorGroupDelete schedules the deletion of a selector table group for the next commit of the pipeline
+func (pm *PipeMngr) SelectorGroupDelete(plName string, selectorName string, groupID uint32) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	return pipeline.SelectorGroupDelete(selectorName, groupID)
+}
+
+// SelectorGroupMemberAdd schedules the addition of a member with the given weight to a selector table group for the
+// next commit of the pipeline
+func (pm *PipeMngr) SelectorGroupMemberAdd(plName string, selectorName string, groupID uint32, memberID uint32,
+	weight uint32,
+) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	return pipeline.SelectorGroupMemberAdd(selectorName, groupID, memberID, weight)
+}
+
+// RegisterRead reads the value of a register array element
+func (pm *PipeMngr) RegisterRead(plName string, regName string, index uint32) (uint64, error) {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return 0, errors.New("pipeline doesn't exists")
+	}
+
+	register := pipeline.GetRegister(regName)
+	if register == nil {
+		return 0, fmt.Errorf("register array %s doesn't exists", regName)
+	}
+
+	return register.RegisterRead(index)
+}
+
+// RegisterWrite writes the value of a register array element, this is done immediately
+func (pm *PipeMngr) RegisterWrite(plName string, regName string, index uint32, value uint64) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	register := pipeline.GetRegister(regName)
+	if register == nil {
+		return fmt.Errorf("register array %s doesn't exists", regName)
+	}
+
+	return register.RegisterWrite(index, value)
+}
+
+// MeterProfileAdd adds a trTCM meter profile to the pipeline, the rates are in bytes per second and the burst sizes in
+// bytes
+func (pm *PipeMngr) MeterProfileAdd(plName string, profileName string, cir, pir, cbs, pbs uint64) error {
+	pl := pm.PipelineStore.Get(plName)
+	if pl == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	profiles := pl.GetMeterProfiles()
+	if profiles == nil {
+		return errors.New("pipeline is not build")
+	}
+
+	return profiles.Add(pipeline.CreateMeterProfile(profileName, cir, pir, cbs, pbs))
+}
+
+// MeterProfileDelete deletes a meter profile from the pipeline, the profile can't be in use by a meter
+func (pm *PipeMngr) MeterProfileDelete(plName string, profileName string) error {
+	pl := pm.PipelineStore.Get(plName)
+	if pl == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	profiles := pl.GetMeterProfiles()
+	if profiles == nil {
+		return errors.New("pipeline is not build")
+	}
+
+	return profiles.Delete(profileName)
+}
+
+// MeterSet sets the meter array elements from index first up to and including index last to the given meter profile,
+// this is done immediately
+func (pm *PipeMngr) MeterSet(plName string, meterName string, first uint32, last uint32, profileName string) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	meter := pipeline.GetMeter(meterName)
+	if meter == nil {
+		return fmt.Errorf("meter array %s doesn't exists", meterName)
+	}
+
+	for i := first; i <= last; i++ {
+		if err := meter.Set(i, profileName); err != nil {
+			return fmt.Errorf("meter %d: %w", i, err)
+		}
+		if i == last {
+			// prevent overflow when last is the maximum index
+			break
+		}
+	}
+	return nil
+}
+
+// MeterReset resets the meter array elements from index first up to and including index last to the default profile
+// coloring all packets green, this is done immediately
+func (pm *PipeMngr) MeterReset(plName string, meterName string, first uint32, last uint32) error {
+	pipeline := pm.PipelineStore.Get(plName)
+	if pipeline == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	meter := pipeline.GetMeter(meterName)
+	if meter == nil {
+		return fmt.Errorf("meter array %s doesn't exists", meterName)
+	}
+
+	for i := first; i <= last; i++ {
+		if err := meter.Reset(i); err != nil {
+			return fmt.Errorf("meter %d: %w", i, err)
+		}
+		if i == last {
+			// prevent overflow when last is the maximum index
+			break
+		}
+	}
+	return nil
+}
+
+// PipelineAbort discards all table changes scheduled for the next commit of the pipeline
+func (pm *PipeMngr) PipelineAbort(plName string) error {
+	pl := pm.PipelineStore.Get(plName)
+	if pl == nil {
+		return errors.New("pipeline doesn't exists")
+	}
+
+	pl.Abort()
+	pm.updateJournal(plName, func(j *journal) {
+		j.abort()
+	})
+	return nil
 }
 
 var ErrPipelineInfoGet = errors.New("pipeline info couldn't be retrieved")
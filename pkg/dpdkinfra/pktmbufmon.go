@@ -0,0 +1,242 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package dpdkinfra
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+)
+
+const (
+	pktmbufMonitorInterval  = time.Second // interval the pktmbuf mempools are checked
+	pktmbufAlarmHysteresis  = 5           // an alarm is cleared when the usage drops this percentage below the threshold
+	pktmbufSubscriptionSize = 64          // number of events that can be queued per subscriber before events are dropped
+)
+
+// PktmbufEventType is the type of a pktmbuf mempool event
+type PktmbufEventType int
+
+const (
+	PktmbufAlarmRaised  PktmbufEventType = iota + 1 // mempool usage reached the alarm threshold
+	PktmbufAlarmCleared                             // mempool usage dropped below the alarm threshold again
+)
+
+func (t PktmbufEventType) String() string {
+	switch t {
+	case PktmbufAlarmRaised:
+		return "alarm-raised"
+	case PktmbufAlarmCleared:
+		return "alarm-cleared"
+	}
+	return "unknown"
+}
+
+// PktmbufEvent is a pktmbuf mempool alarm event as delivered to subscribers
+type PktmbufEvent struct {
+	Type      PktmbufEventType
+	Time      time.Time
+	Pktmbuf   string
+	Threshold uint // alarm threshold in percent of the mempool size
+	Stats     pktmbuf.Stats
+}
+
+func (e *PktmbufEvent) String() string {
+	return fmt.Sprintf("%s %s pktmbuf %s threshold %d%% %s", e.Time.Format(time.RFC3339), e.Type, e.Pktmbuf, e.Threshold,
+		e.Stats.String())
+}
+
+type pktmbufAlarm struct {
+	threshold uint
+	raised    bool
+}
+
+type pktmbufMonitor struct {
+	mu     sync.Mutex
+	alarms map[string]*pktmbufAlarm
+	subs   map[chan PktmbufEvent]struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// start the periodic pktmbuf mempool check keeping the high watermarks up to date and raising the alarms
+func (di *DpdkInfra) initPktmbufMonitor() {
+	mon := &di.pktmbufMon
+	mon.alarms = make(map[string]*pktmbufAlarm)
+	mon.subs = make(map[chan PktmbufEvent]struct{})
+	mon.done = make(chan struct{})
+
+	mon.wg.Add(1)
+	go func() {
+		defer mon.wg.Done()
+		ticker := time.NewTicker(pktmbufMonitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				di.checkPktmbufs()
+			case <-mon.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop the pktmbuf mempool check and close all subscriptions
+func (di *DpdkInfra) cleanupPktmbufMonitor() {
+	mon := &di.pktmbufMon
+	if mon.done == nil {
+		return
+	}
+
+	close(mon.done)
+	mon.wg.Wait()
+
+	mon.mu.Lock()
+	mon.done = nil
+	for ch := range mon.subs {
+		delete(mon.subs, ch)
+		close(ch)
+	}
+	mon.mu.Unlock()
+}
+
+// update the high watermarks and check the alarm thresholds of all pktmbuf mempools
+func (di *DpdkInfra) checkPktmbufs() {
+	mon := &di.pktmbufMon
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	di.PktmbufStore.Iterate(func(name string, pm *pktmbuf.Pktmbuf) error {
+		stats := pm.Stats()
+		alarm := mon.alarms[name]
+		if alarm == nil || alarm.threshold == 0 {
+			return nil
+		}
+
+		usage := stats.Usage()
+		e := PktmbufEvent{Time: time.Now(), Pktmbuf: name, Threshold: alarm.threshold, Stats: stats}
+		switch {
+		case !alarm.raised && usage >= float64(alarm.threshold):
+			alarm.raised = true
+			e.Type = PktmbufAlarmRaised
+			log.Warnf("pktmbuf %s usage %.1f%% reached alarm threshold %d%% (%s)", name, usage, alarm.threshold,
+				stats.String())
+		case alarm.raised && usage < float64(alarm.threshold)-pktmbufAlarmHysteresis:
+			alarm.raised = false
+			e.Type = PktmbufAlarmCleared
+			log.Infof("pktmbuf %s usage %.1f%% below alarm threshold %d%% again", name, usage, alarm.threshold)
+		default:
+			return nil
+		}
+
+		for ch := range mon.subs {
+			select {
+			case ch <- e:
+			default:
+				log.Debugf("pktmbuf event subscriber queue full, event %s dropped", e.Type)
+			}
+		}
+		return nil
+	})
+}
+
+// PktmbufSetAlarm sets the usage alarm threshold (in percent of the mempool size) of the given pktmbuf. A threshold
+// of 0 disables the alarm.
+func (di *DpdkInfra) PktmbufSetAlarm(name string, threshold uint) error {
+	if !di.PktmbufStore.Contains(name) {
+		return fmt.Errorf("pktmbuf %s doesn't exist", name)
+	}
+	if threshold > 100 {
+		return fmt.Errorf("alarm threshold %d%% is larger than 100%%", threshold)
+	}
+
+	mon := &di.pktmbufMon
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	if threshold == 0 {
+		delete(mon.alarms, name)
+		return nil
+	}
+	mon.alarms[name] = &pktmbufAlarm{threshold: threshold}
+	return nil
+}
+
+// PktmbufAlarm returns the usage alarm threshold of the given pktmbuf and if the alarm is raised
+func (di *DpdkInfra) PktmbufAlarm(name string) (threshold uint, raised bool) {
+	mon := &di.pktmbufMon
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	if alarm := mon.alarms[name]; alarm != nil {
+		return alarm.threshold, alarm.raised
+	}
+	return 0, false
+}
+
+// PktmbufStats returns the utilisation statistics of the requested pktmbuf or all pktmbufs if no name given
+func (di *DpdkInfra) PktmbufStats(name string) (map[string]pktmbuf.Stats, error) {
+	result := make(map[string]pktmbuf.Stats)
+
+	if name != "" {
+		pm := di.PktmbufStore.Get(name)
+		if pm == nil {
+			return result, fmt.Errorf("pktmbuf %s doesn't exist", name)
+		}
+		result[name] = pm.Stats()
+		return result, nil
+	}
+
+	err := di.PktmbufStore.Iterate(func(key string, pm *pktmbuf.Pktmbuf) error {
+		result[key] = pm.Stats()
+		return nil
+	})
+	return result, err
+}
+
+// PktmbufResetHighWater resets the high watermark of the requested pktmbuf or all pktmbufs if no name given
+func (di *DpdkInfra) PktmbufResetHighWater(name string) error {
+	if name != "" {
+		pm := di.PktmbufStore.Get(name)
+		if pm == nil {
+			return fmt.Errorf("pktmbuf %s doesn't exist", name)
+		}
+		pm.ResetHighWater()
+		return nil
+	}
+
+	return di.PktmbufStore.Iterate(func(key string, pm *pktmbuf.Pktmbuf) error {
+		pm.ResetHighWater()
+		return nil
+	})
+}
+
+// PktmbufSubscribe returns a channel on which all pktmbuf alarm events are delivered and a function to cancel the
+// subscription. The channel is closed when the subscription is cancelled or dpdkinfra is cleaned up. Events are
+// dropped if the subscriber doesn't read the channel fast enough.
+func (di *DpdkInfra) PktmbufSubscribe() (<-chan PktmbufEvent, func()) {
+	mon := &di.pktmbufMon
+	ch := make(chan PktmbufEvent, pktmbufSubscriptionSize)
+
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+	if mon.done == nil {
+		close(ch)
+		return ch, func() {}
+	}
+	mon.subs[ch] = struct{}{}
+
+	return ch, func() {
+		mon.mu.Lock()
+		defer mon.mu.Unlock()
+		if _, ok := mon.subs[ch]; ok {
+			delete(mon.subs, ch)
+			close(ch)
+		}
+	}
+}
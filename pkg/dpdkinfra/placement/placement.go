@@ -0,0 +1,95 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package placement selects NUMA nodes and threads (lcores) for pipelines from the NUMA nodes of the ports they serve
+package placement
+
+import (
+	"errors"
+	"sort"
+)
+
+// NumaAny is the NUMA node of resources that are not connected to a specific NUMA node
+const NumaAny = -1
+
+// Planner keeps track of the worker threads (lcores), their NUMA node and the number of pipelines assigned to them
+type Planner struct {
+	lcores map[uint]int  // worker lcore -> NUMA node
+	load   map[uint]uint // worker lcore -> number of pipelines
+}
+
+// NewPlanner creates a planner for the given worker lcores with their NUMA node
+func NewPlanner(lcores map[uint]int) *Planner {
+	p := &Planner{
+		lcores: make(map[uint]int),
+		load:   make(map[uint]uint),
+	}
+	for lcore, numa := range lcores {
+		p.lcores[lcore] = numa
+	}
+	return p
+}
+
+// Reserve adds a pipeline to the given lcore, i.e. for pipelines already running or explicitly configured
+func (p *Planner) Reserve(lcore uint) {
+	p.load[lcore]++
+}
+
+// LcoreNuma returns the NUMA node of the given worker lcore and false if the lcore is not a known worker lcore
+func (p *Planner) LcoreNuma(lcore uint) (int, bool) {
+	numa, ok := p.lcores[lcore]
+	return numa, ok
+}
+
+// Numa returns the NUMA node most of the given port NUMA nodes are connected to (lowest node on a tie) and true if
+// the ports are connected to more than one NUMA node. Ports not connected to a specific NUMA node are ignored and
+// NumaAny is returned if no port is connected to a specific NUMA node.
+func Numa(portNumas []int) (int, bool) {
+	count := make(map[int]int)
+	for _, numa := range portNumas {
+		if numa != NumaAny {
+			count[numa]++
+		}
+	}
+	if len(count) == 0 {
+		return NumaAny, false
+	}
+
+	best, bestCount := NumaAny, 0
+	for numa, c := range count {
+		if c > bestCount || (c == bestCount && numa < best) {
+			best, bestCount = numa, c
+		}
+	}
+	return best, len(count) > 1
+}
+
+// Thread returns the least loaded worker lcore on the given NUMA node (lowest lcore on a tie) and reserves it. When
+// there is no worker lcore on the NUMA node (or the node is NumaAny) the least loaded worker lcore of all nodes is
+// returned and the boolean result is false.
+func (p *Planner) Thread(numa int) (uint, bool, error) {
+	if len(p.lcores) == 0 {
+		return 0, false, errors.New("no worker lcores available")
+	}
+
+	var all, local []uint
+	for lcore, n := range p.lcores {
+		all = append(all, lcore)
+		if n == numa && numa != NumaAny {
+			local = append(local, lcore)
+		}
+	}
+
+	candidates, matched := local, true
+	if len(candidates) == 0 {
+		candidates, matched = all, numa == NumaAny
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		li, lj := p.load[candidates[i]], p.load[candidates[j]]
+		return li < lj || (li == lj && candidates[i] < candidates[j])
+	})
+
+	lcore := candidates[0]
+	p.Reserve(lcore)
+	return lcore, matched, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package placement
+
+import "testing"
+
+func TestNuma(t *testing.T) {
+	tests := []struct {
+		ports []int
+		numa  int
+		mixed bool
+	}{
+		{nil, NumaAny, false},
+		{[]int{NumaAny, NumaAny}, NumaAny, false},
+		{[]int{1, NumaAny, 1}, 1, false},
+		{[]int{0, 1, 1}, 1, true},
+		{[]int{1, 0}, 0, true},
+	}
+
+	for _, test := range tests {
+		numa, mixed := Numa(test.ports)
+		if numa != test.numa || mixed != test.mixed {
+			t.Fatalf("Numa(%v) = %d, %v; expected %d, %v", test.ports, numa, mixed, test.numa, test.mixed)
+		}
+	}
+}
+
+func TestPlannerThread(t *testing.T) {
+	p := NewPlanner(map[uint]int{1: 0, 2: 0, 3: 1, 4: 1})
+	p.Reserve(1)
+
+	expect := func(numa int, lcore uint, matched bool) {
+		t.Helper()
+		l, m, err := p.Thread(numa)
+		if err != nil || l != lcore || m != matched {
+			t.Fatalf("Thread(%d) = %d, %v, %v; expected %d, %v", numa, l, m, err, lcore, matched)
+		}
+	}
+
+	expect(0, 2, true)       // lcore 1 already has a pipeline
+	expect(0, 1, true)       // both lcores on node 0 have one pipeline, lowest first
+	expect(1, 3, true)       // node 1
+	expect(2, 4, false)      // no lcores on node 2, least loaded of all
+	expect(NumaAny, 2, true) // least loaded of all, lowest first (lcore 1 has two pipelines)
+	if numa, ok := p.LcoreNuma(3); !ok || numa != 1 {
+		t.Fatalf("LcoreNuma(3) = %d, %v", numa, ok)
+	}
+	if _, ok := p.LcoreNuma(0); ok {
+		t.Fatalf("LcoreNuma(0) should not be a worker lcore")
+	}
+
+	if _, _, err := NewPlanner(nil).Thread(0); err == nil {
+		t.Fatalf("Thread without lcores should fail")
+	}
+}
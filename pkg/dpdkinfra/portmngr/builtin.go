@@ -0,0 +1,110 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package portmngr
+
+import (
+	"fmt"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/fd"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ring"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/sourcesink"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/tap"
+)
+
+// Names of the built-in port types
+const (
+	PortTypeEthdev = "ethdev"
+	PortTypeRing   = "ring"
+	PortTypeTap    = "tap"
+	PortTypeSource = "source"
+	PortTypeSink   = "sink"
+	PortTypeFd     = "fd"
+)
+
+// The built-in port types. Ports are freed in reverse order of registration so ethdev ports are freed last.
+func init() {
+	RegisterPortType(PortTypeEthdev, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*ethdev.Params)
+		if !ok {
+			return nil, paramsErr(PortTypeEthdev, params)
+		}
+
+		var e ethdev.Ethdev
+		e.Init(name)
+		if err := e.Initialize(p, clean); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	})
+
+	RegisterPortType(PortTypeRing, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*ring.Params)
+		if !ok {
+			return nil, paramsErr(PortTypeRing, params)
+		}
+
+		var r ring.Ring
+		if err := r.Init(name, p, clean); err != nil {
+			return nil, err
+		}
+		return &r, nil
+	})
+
+	RegisterPortType(PortTypeTap, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*tap.Params)
+		if !ok {
+			return nil, paramsErr(PortTypeTap, params)
+		}
+
+		var t tap.Tap
+		if err := t.Init(name, p, clean); err != nil {
+			return nil, err
+		}
+		return &t, nil
+	})
+
+	RegisterPortType(PortTypeSource, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*sourcesink.SourceParams)
+		if !ok {
+			return nil, paramsErr(PortTypeSource, params)
+		}
+
+		var s sourcesink.Source
+		if err := s.Init(name, p, clean); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	})
+
+	RegisterPortType(PortTypeSink, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*sourcesink.SinkParams)
+		if !ok {
+			return nil, paramsErr(PortTypeSink, params)
+		}
+
+		var s sourcesink.Sink
+		if err := s.Init(name, p, clean); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	})
+
+	RegisterPortType(PortTypeFd, func(name string, params any, clean func()) (PortType, error) {
+		p, ok := params.(*fd.Params)
+		if !ok {
+			return nil, paramsErr(PortTypeFd, params)
+		}
+
+		var f fd.Fd
+		if err := f.Init(name, p, clean); err != nil {
+			return nil, err
+		}
+		return &f, nil
+	})
+}
+
+func paramsErr(portType string, params any) error {
+	return fmt.Errorf("wrong parameter type %T for port type %s", params, portType)
+}
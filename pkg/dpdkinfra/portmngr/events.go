@@ -0,0 +1,264 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package portmngr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/ethdev"
+)
+
+const (
+	eventQueueSize        = 256 // number of raw DPDK events that can be queued before events are dropped
+	subscriptionQueueSize = 64  // number of events that can be queued per subscriber before events are dropped
+)
+
+// EventType is the type of a PortMngr port or device event
+type EventType int
+
+const (
+	EventLinkUp        EventType = iota + 1 // link of an ethdev port changed to up
+	EventLinkDown                           // link of an ethdev port changed to down
+	EventPortRemoved                        // device of an ethdev port is removed (device removal interrupt)
+	EventDeviceAdded                        // device is added to the system (hotplug)
+	EventDeviceRemoved                      // device is removed from the system (hotplug)
+)
+
+var eventTypeNames = map[EventType]string{
+	EventLinkUp:        "link-up",
+	EventLinkDown:      "link-down",
+	EventPortRemoved:   "port-removed",
+	EventDeviceAdded:   "device-added",
+	EventDeviceRemoved: "device-removed",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event is a port or device event as delivered to PortMngr subscribers
+type Event struct {
+	Type       EventType
+	Time       time.Time
+	Port       string // name of the created port, empty if the event is not related to a created port
+	PortID     uint16 // DPDK port id, only valid for link and port removal events
+	Device     string // DPDK device name
+	LinkSpeed  uint32 // link speed in Mbps, only valid for link up events
+	FullDuplex bool   // link duplex mode, only valid for link up events
+}
+
+func (e *Event) String() string {
+	result := fmt.Sprintf("%s %s", e.Time.Format(time.RFC3339), e.Type)
+	if e.Port != "" {
+		result += fmt.Sprintf(" port %s", e.Port)
+	}
+	if e.Device != "" {
+		result += fmt.Sprintf(" device %s", e.Device)
+	}
+	if e.Type == EventLinkUp {
+		duplex := "half-duplex"
+		if e.FullDuplex {
+			duplex = "full-duplex"
+		}
+		result += fmt.Sprintf(" speed %s %s", ethdev.RteEthLinkSpeedToString(e.LinkSpeed), duplex)
+	}
+	return result
+}
+
+// raw DPDK event as received from the DPDK interrupt thread
+type rawEvent struct {
+	ethdev    bool
+	portID    uint16
+	ethEvent  ethdev.EventType
+	devName   string
+	devEvent  eal.DevEventType
+	timestamp time.Time
+}
+
+type events struct {
+	queue  chan rawEvent
+	done   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	devMon bool
+}
+
+// initialize and start the event handling. Failing to start the device (hotplug) monitoring is not fatal because it
+// is not supported on every system.
+func (pm *PortMngr) initEvents() error {
+	ev := &events{
+		queue: make(chan rawEvent, eventQueueSize),
+		done:  make(chan struct{}),
+		subs:  make(map[chan Event]struct{}),
+	}
+	pm.events = ev
+
+	ev.wg.Add(1)
+	go pm.dispatchEvents()
+
+	if err := ethdev.EventCallbackRegister(func(portID uint16, event ethdev.EventType) {
+		ev.push(rawEvent{ethdev: true, portID: portID, ethEvent: event, timestamp: time.Now()})
+	}); err != nil {
+		return err
+	}
+
+	if err := eal.DevEventMonitorStart(func(devName string, event eal.DevEventType) {
+		ev.push(rawEvent{devName: devName, devEvent: event, timestamp: time.Now()})
+	}); err != nil {
+		log.Warnf("device hotplug events not available: %v", err)
+	} else {
+		ev.devMon = true
+	}
+
+	return nil
+}
+
+// stop the event handling and close all subscriptions
+func (pm *PortMngr) cleanupEvents() {
+	ev := pm.events
+	if ev == nil {
+		return
+	}
+
+	if err := ethdev.EventCallbackUnregister(); err != nil {
+		log.Warnf("ethdev event callback unregister error: %v", err)
+	}
+	if ev.devMon {
+		if err := eal.DevEventMonitorStop(); err != nil {
+			log.Warnf("device event monitor stop error: %v", err)
+		}
+	}
+
+	close(ev.done)
+	ev.wg.Wait()
+
+	ev.mu.Lock()
+	for ch := range ev.subs {
+		delete(ev.subs, ch)
+		close(ch)
+	}
+	ev.mu.Unlock()
+	pm.events = nil
+}
+
+// queue a raw event, called from the DPDK interrupt thread so never block
+func (ev *events) push(e rawEvent) {
+	select {
+	case ev.queue <- e:
+	default:
+	}
+}
+
+// handle the raw DPDK events, translate them to PortMngr events and send them to all subscribers
+func (pm *PortMngr) dispatchEvents() {
+	ev := pm.events
+	defer ev.wg.Done()
+
+	for {
+		select {
+		case raw := <-ev.queue:
+			var e Event
+			if raw.ethdev {
+				e = pm.ethdevEvent(raw)
+			} else {
+				e = pm.deviceEvent(raw)
+			}
+			log.Infof("event: %s", e.String())
+			ev.publish(e)
+		case <-ev.done:
+			return
+		}
+	}
+}
+
+// translate a raw ethdev event into a PortMngr event
+func (pm *PortMngr) ethdevEvent(raw rawEvent) Event {
+	e := Event{Time: raw.timestamp, PortID: raw.portID}
+
+	// find the created port using this DPDK port
+	var port *ethdev.Ethdev
+	pm.EthdevStore.Iterate(func(k string, v *ethdev.Ethdev) error {
+		if v.PortID() == raw.portID {
+			port = v
+			e.Port = k
+			e.Device = v.DevName()
+		}
+		return nil
+	})
+
+	switch raw.ethEvent {
+	case ethdev.EventIntrLsc:
+		e.Type = EventLinkDown
+		if port != nil {
+			up, speed, duplex, err := port.LinkGet()
+			if err != nil {
+				log.Warnf("port %s link status read error: %v", e.Port, err)
+			} else if up {
+				e.Type = EventLinkUp
+				e.LinkSpeed = speed
+				e.FullDuplex = duplex
+			}
+		}
+	case ethdev.EventIntrRmv:
+		e.Type = EventPortRemoved
+	}
+
+	return e
+}
+
+// translate a raw device (hotplug) event into a PortMngr event
+func (pm *PortMngr) deviceEvent(raw rawEvent) Event {
+	e := Event{Time: raw.timestamp, Device: raw.devName, Type: EventDeviceAdded}
+	if raw.devEvent == eal.DevEventRemove {
+		e.Type = EventDeviceRemoved
+	}
+
+	return e
+}
+
+// send the event to all subscribers, events are dropped for subscribers that don't keep up
+func (ev *events) publish(e Event) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+
+	for ch := range ev.subs {
+		select {
+		case ch <- e:
+		default:
+			log.Debugf("event subscriber queue full, event %s dropped", e.Type)
+		}
+	}
+}
+
+// Subscribe returns a channel on which all port and device events are delivered and a function to cancel the
+// subscription. The channel is closed when the subscription is cancelled or the PortMngr is cleaned up. Events are
+// dropped if the subscriber doesn't read the channel fast enough.
+func (pm *PortMngr) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriptionQueueSize)
+	ev := pm.events
+	if ev == nil {
+		close(ch)
+		return ch, func() {}
+	}
+
+	ev.mu.Lock()
+	ev.subs[ch] = struct{}{}
+	ev.mu.Unlock()
+
+	return ch, func() {
+		ev.mu.Lock()
+		defer ev.mu.Unlock()
+		if _, ok := ev.subs[ch]; ok {
+			delete(ev.subs, ch)
+			close(ch)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package portmngr
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/store"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
@@ -32,175 +33,178 @@ type PortType interface {
 }
 
 type PortMngr struct {
-	EthdevStore *store.Store[*ethdev.Ethdev]
-	RingStore   *store.Store[*ring.Ring]
-	TapStore    *store.Store[*tap.Tap]
-	SourceStore *store.Store[*sourcesink.Source]
-	SinkStore   *store.Store[*sourcesink.Sink]
+	stores      map[string]*store.Store[PortType]
+	portTypes   []*portTypeDef
+	EthdevStore *PortStore[*ethdev.Ethdev]
+	RingStore   *PortStore[*ring.Ring]
+	TapStore    *PortStore[*tap.Tap]
+	SourceStore *PortStore[*sourcesink.Source]
+	SinkStore   *PortStore[*sourcesink.Sink]
+	events      *events
+
+	mu      sync.Mutex
+	params  map[string]any // creation parameters per port name
+	devices []string       // device argument strings of the attached devices in order of attachment
 }
 
 // Initialize the non system intrusive portmngr singleton parts
 func (pm *PortMngr) Init() error {
-	// create stores
-	pm.EthdevStore = store.NewStore[*ethdev.Ethdev]()
-	pm.RingStore = store.NewStore[*ring.Ring]()
-	pm.TapStore = store.NewStore[*tap.Tap]()
-	pm.SourceStore = store.NewStore[*sourcesink.Source]()
-	pm.SinkStore = store.NewStore[*sourcesink.Sink]()
-
-	return nil
+	// create a store for every registered port type
+	pm.portTypes = registeredPortTypes()
+	pm.stores = make(map[string]*store.Store[PortType])
+	pm.params = make(map[string]any)
+	for _, pt := range pm.portTypes {
+		pm.stores[pt.name] = store.NewStore[PortType]()
+	}
+
+	// typed access to the built-in port type stores
+	pm.EthdevStore = newPortStore[*ethdev.Ethdev](pm, PortTypeEthdev)
+	pm.RingStore = newPortStore[*ring.Ring](pm, PortTypeRing)
+	pm.TapStore = newPortStore[*tap.Tap](pm, PortTypeTap)
+	pm.SourceStore = newPortStore[*sourcesink.Source](pm, PortTypeSource)
+	pm.SinkStore = newPortStore[*sourcesink.Sink](pm, PortTypeSink)
+
+	// start handling link state change and device events
+	return pm.initEvents()
 }
 
 func (pm *PortMngr) Cleanup() {
-	// empty & remove stores
-	pm.SinkStore.Clear()
-	pm.SourceStore.Clear()
-	pm.TapStore.Clear()
-	pm.RingStore.Clear()
-	pm.EthdevStore.Clear()
-}
+	// stop event handling
+	pm.cleanupEvents()
 
-func (pm *PortMngr) GetPort(name string) PortType {
-	if port := pm.TapStore.Get(name); port != nil {
-		return port
+	// empty & remove stores in reverse order of port type registration
+	for i := len(pm.portTypes) - 1; i >= 0; i-- {
+		pm.stores[pm.portTypes[i].name].Clear()
 	}
+}
 
-	if port := pm.EthdevStore.Get(name); port != nil {
-		return port
+// Create creates a port of the given registered port type with the given port type specific parameters and stores it
+// in the portmngr store of that port type
+func (pm *PortMngr) Create(portType string, name string, params any) (PortType, error) {
+	var pt *portTypeDef
+	for _, t := range pm.portTypes {
+		if t.name == portType {
+			pt = t
+			break
+		}
 	}
-
-	if port := pm.RingStore.Get(name); port != nil {
-		return port
+	if pt == nil {
+		return nil, fmt.Errorf("unknown port type %s", portType)
 	}
 
-	if port := pm.SinkStore.Get(name); port != nil {
-		return port
+	if pm.ContainsPort(name) {
+		return nil, errors.New("port with this name exists already")
 	}
 
-	if port := pm.SourceStore.Get(name); port != nil {
-		return port
+	s := pm.stores[portType]
+	port, err := pt.factory(name, params, func() {
+		s.Delete(name)
+		pm.mu.Lock()
+		delete(pm.params, name)
+		pm.mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// add node to list
+	s.Set(name, port)
+	pm.mu.Lock()
+	pm.params[name] = params
+	pm.mu.Unlock()
+	log.Infof("%s %s created", portType, name)
+	return port, nil
 }
 
-func (pm *PortMngr) ContainsPort(name string) bool {
-	return pm.TapStore.Contains(name) ||
-		pm.EthdevStore.Contains(name) ||
-		pm.RingStore.Contains(name) ||
-		pm.SinkStore.Contains(name) ||
-		pm.SourceStore.Contains(name)
+// GetPortType returns the registered port type name of the port with the given name or an empty string if the port
+// doesn't exist
+func (pm *PortMngr) GetPortType(name string) string {
+	for _, pt := range pm.portTypes {
+		if pm.stores[pt.name].Contains(name) {
+			return pt.name
+		}
+	}
+	return ""
 }
 
-// Iterate over the contents of all the device stores
-func (pm *PortMngr) IteratePorts(fn func(key string, value PortType) error) error {
-	// iterate tap store
-	if err := pm.TapStore.Iterate(func(k string, v *tap.Tap) error {
-		return fn(k, v)
-	}); err != nil {
-		return err
+func (pm *PortMngr) GetPort(name string) PortType {
+	for _, pt := range pm.portTypes {
+		if port := pm.stores[pt.name].Get(name); port != nil {
+			return port
+		}
 	}
+	return nil
+}
 
-	// iterate ethdev store
-	if err := pm.EthdevStore.Iterate(func(k string, v *ethdev.Ethdev) error {
-		return fn(k, v)
-	}); err != nil {
-		return err
-	}
+// GetPortParams returns the port type specific parameters the port with the given name is created with or nil if the
+// port doesn't exist. Changes made after creation (i.e. the MTU of an ethdev port) are not included.
+func (pm *PortMngr) GetPortParams(name string) any {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.params[name]
+}
 
-	// iterate ring store
-	if err := pm.RingStore.Iterate(func(k string, v *ring.Ring) error {
-		return fn(k, v)
-	}); err != nil {
-		return err
-	}
+func (pm *PortMngr) ContainsPort(name string) bool {
+	return pm.GetPortType(name) != ""
+}
 
-	// iterate sink store
-	if err := pm.SinkStore.Iterate(func(k string, v *sourcesink.Sink) error {
-		return fn(k, v)
-	}); err != nil {
-		return err
+// PortDelete frees the port with the given name and removes it from its port store. A port with queues bound to a
+// pipeline can't be deleted.
+func (pm *PortMngr) PortDelete(name string) error {
+	port := pm.GetPort(name)
+	if port == nil {
+		return fmt.Errorf("port %s doesn't exist", name)
+	}
+	if port.IsBound() {
+		return fmt.Errorf("port %s is bound to a pipeline", name)
 	}
 
-	// iterate source store
-	if err := pm.SourceStore.Iterate(func(k string, v *sourcesink.Source) error {
-		return fn(k, v)
-	}); err != nil {
+	if err := port.Free(); err != nil {
 		return err
 	}
-
+	log.Infof("port %s deleted", name)
 	return nil
 }
 
-func (pm *PortMngr) SourceCreate(name string, params *sourcesink.SourceParams) (*sourcesink.Source, error) {
-	var t sourcesink.Source
-	if pm.ContainsPort(name) {
-		return nil, errors.New("port with this name exists already")
+// Iterate over the contents of all the port stores
+func (pm *PortMngr) IteratePorts(fn func(key string, value PortType) error) error {
+	for _, pt := range pm.portTypes {
+		if err := pm.stores[pt.name].Iterate(fn); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if err := t.Init(name, params, func() {
-		pm.SourceStore.Delete(name)
-	}); err != nil {
-		return nil, err
+// create a port of a built-in port type and return it with its own type
+func create[T PortType](pm *PortMngr, portType string, name string, params any) (T, error) {
+	var t T
+	port, err := pm.Create(portType, name, params)
+	if err != nil {
+		return t, err
 	}
+	t, _ = port.(T)
+	return t, nil
+}
 
-	// add node to list
-	pm.SourceStore.Set(name, &t)
-	return &t, nil
+// SourceCreate creates a source and stores it in the portmngr source store
+func (pm *PortMngr) SourceCreate(name string, params *sourcesink.SourceParams) (*sourcesink.Source, error) {
+	return create[*sourcesink.Source](pm, PortTypeSource, name, params)
 }
 
+// SinkCreate creates a sink and stores it in the portmngr sink store
 func (pm *PortMngr) SinkCreate(name string, params *sourcesink.SinkParams) (*sourcesink.Sink, error) {
-	var t sourcesink.Sink
-	if pm.ContainsPort(name) {
-		return nil, errors.New("port with this name exists already")
-	}
-
-	if err := t.Init(name, params, func() {
-		pm.SinkStore.Delete(name)
-	}); err != nil {
-		return nil, err
-	}
-
-	// add node to list
-	pm.SinkStore.Set(name, &t)
-	return &t, nil
+	return create[*sourcesink.Sink](pm, PortTypeSink, name, params)
 }
 
+// TapCreate creates a tap and stores it in the portmngr tap store
 func (pm *PortMngr) TapCreate(name string, params *tap.Params) (*tap.Tap, error) {
-	var t tap.Tap
-	if pm.ContainsPort(name) {
-		return nil, errors.New("port with this name exists already")
-	}
-
-	if err := t.Init(name, params, func() {
-		pm.TapStore.Delete(name)
-	}); err != nil {
-		return nil, err
-	}
-
-	// add node to list
-	pm.TapStore.Set(name, &t)
-	return &t, nil
+	return create[*tap.Tap](pm, PortTypeTap, name, params)
 }
 
 // RingCreate creates a ring and stores it in the portmngr ring store
 func (pm *PortMngr) RingCreate(name string, params *ring.Params) (*ring.Ring, error) {
-	var r ring.Ring
-	if pm.ContainsPort(name) {
-		return nil, errors.New("port with this name exists already")
-	}
-
-	// initialize
-	if err := r.Init(name, params, func() {
-		pm.RingStore.Delete(name)
-	}); err != nil {
-		return nil, err
-	}
-
-	// add node to list
-	pm.RingStore.Set(name, &r)
-	log.Infof("ring %s created", name)
-	return &r, nil
+	return create[*ring.Ring](pm, PortTypeRing, name, params)
 }
 
 // Attach (hotplug) the DPDK ethdev device defined by given DPDK device argument string
@@ -217,6 +221,9 @@ func (pm *PortMngr) AttachDevice(device string) (*eal.DevArgs, error) {
 		return nil, err
 	}
 
+	pm.mu.Lock()
+	pm.devices = append(pm.devices, device)
+	pm.mu.Unlock()
 	return &devArgs, nil
 }
 
@@ -246,9 +253,26 @@ func (pm *PortMngr) DetachDevice(device string) (*eal.DevArgs, error) {
 		return nil, err
 	}
 
+	pm.mu.Lock()
+	for i, d := range pm.devices {
+		var da eal.DevArgs
+		if da.Parse(d) == nil && da.Name() == devArgs.Name() {
+			pm.devices = append(pm.devices[:i:i], pm.devices[i+1:]...)
+			break
+		}
+	}
+	pm.mu.Unlock()
 	return &devArgs, nil
 }
 
+// AttachedDevices returns the device argument strings of the devices attached with AttachDevice and not detached yet.
+// Devices given as EAL argument are not included.
+func (pm *PortMngr) AttachedDevices() []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]string(nil), pm.devices...)
+}
+
 // Get all raw DPDK ethdev ports
 func (pm *PortMngr) GetAttachedEthdevPorts() ([]*ethdev.Ethdev, error) {
 	return ethdev.GetAttachedPorts()
@@ -280,23 +304,7 @@ func (pm *PortMngr) GetUnusedEthdevPorts() ([]*ethdev.Ethdev, error) {
 
 // EthdevCreate creates a ethdev and stores it in the portmngr ethdev store
 func (pm *PortMngr) EthdevCreate(name string, params *ethdev.Params) (*ethdev.Ethdev, error) {
-	var e ethdev.Ethdev
-	if pm.ContainsPort(name) {
-		return nil, errors.New("port with this name exists already")
-	}
-
-	// initialize struct, then initialize port
-	e.Init(name)
-	if err := e.Initialize(params, func() {
-		pm.EthdevStore.Delete(name)
-	}); err != nil {
-		return nil, err
-	}
-
-	// add node to list
-	pm.EthdevStore.Set(name, &e)
-	log.Infof("ethdev %s created", name)
-	return &e, nil
+	return create[*ethdev.Ethdev](pm, PortTypeEthdev, name, params)
 }
 
 type EthdevPortFilter uint
@@ -351,6 +359,51 @@ func (pm *PortMngr) LinkDown(name string) error {
 	return port.SetLinkDown()
 }
 
+// get the sink with the given name
+func (pm *PortMngr) getSink(name string) (*sourcesink.Sink, error) {
+	sink := pm.SinkStore.Get(name)
+	if sink == nil {
+		return nil, fmt.Errorf("sink %s doesn't exist", name)
+	}
+	return sink, nil
+}
+
+// SinkPause pauses writing the packets of the given sink to its PCAP file
+func (pm *PortMngr) SinkPause(name string) error {
+	sink, err := pm.getSink(name)
+	if err != nil {
+		return err
+	}
+	return sink.Pause()
+}
+
+// SinkResume resumes writing the packets of the given sink to its PCAP file
+func (pm *PortMngr) SinkResume(name string) error {
+	sink, err := pm.getSink(name)
+	if err != nil {
+		return err
+	}
+	return sink.Resume()
+}
+
+// SinkReopen closes the current PCAP file of the given sink and opens a new file
+func (pm *PortMngr) SinkReopen(name string) error {
+	sink, err := pm.getSink(name)
+	if err != nil {
+		return err
+	}
+	return sink.Reopen()
+}
+
+// SinkStats returns the counters and state of the given sink
+func (pm *PortMngr) SinkStats(name string) (sourcesink.SinkStats, error) {
+	sink, err := pm.getSink(name)
+	if err != nil {
+		return sourcesink.SinkStats{}, err
+	}
+	return sink.Stats(), nil
+}
+
 // returns the port info array of the requested port or all ports if no name given
 func (pm *PortMngr) GetPortInfo(name string) (map[string]map[string]map[string]string, error) {
 	result := make(map[string]map[string]map[string]string)
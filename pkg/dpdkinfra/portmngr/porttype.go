@@ -0,0 +1,94 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package portmngr
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/store"
+)
+
+// PortFactory creates and initializes a port with the given name and port type specific parameters. The given clean
+// function must be called by the port when it is freed, it removes the port from the PortMngr.
+type PortFactory func(name string, params any, clean func()) (PortType, error)
+
+type portTypeDef struct {
+	name    string
+	factory PortFactory
+}
+
+var portTypesMu sync.Mutex
+var portTypes []*portTypeDef
+
+// RegisterPortType makes a port type available to all PortMngr instances initialized afterwards. It is intended to be
+// called from the init function of the package implementing the port type and panics if the port type name is already
+// registered or the factory is nil.
+func RegisterPortType(name string, factory PortFactory) {
+	portTypesMu.Lock()
+	defer portTypesMu.Unlock()
+
+	if factory == nil {
+		panic("portmngr: RegisterPortType factory is nil for port type " + name)
+	}
+	for _, pt := range portTypes {
+		if pt.name == name {
+			panic("portmngr: RegisterPortType called twice for port type " + name)
+		}
+	}
+	portTypes = append(portTypes, &portTypeDef{name: name, factory: factory})
+}
+
+// PortTypes returns the names of the registered port types in order of registration
+func PortTypes() []string {
+	portTypesMu.Lock()
+	defer portTypesMu.Unlock()
+
+	var names []string
+	for _, pt := range portTypes {
+		names = append(names, pt.name)
+	}
+	return names
+}
+
+// return a copy of the registered port type list
+func registeredPortTypes() []*portTypeDef {
+	portTypesMu.Lock()
+	defer portTypesMu.Unlock()
+	return append([]*portTypeDef(nil), portTypes...)
+}
+
+// PortStore gives typed access to the created ports of one registered port type
+type PortStore[T PortType] struct {
+	s *store.Store[PortType]
+}
+
+func newPortStore[T PortType](pm *PortMngr, portType string) *PortStore[T] {
+	s, ok := pm.stores[portType]
+	if !ok {
+		panic(fmt.Sprintf("portmngr: port type %s not registered", portType))
+	}
+	return &PortStore[T]{s: s}
+}
+
+// Get returns the port with the given name or the zero value (nil) if it doesn't exist
+func (ps *PortStore[T]) Get(name string) T {
+	port, _ := ps.s.Get(name).(T)
+	return port
+}
+
+// Contains reports whether a port with the given name exists
+func (ps *PortStore[T]) Contains(name string) bool {
+	return ps.s.Contains(name)
+}
+
+// Iterate calls the given function for all the ports in the store
+func (ps *PortStore[T]) Iterate(fn func(key string, value T) error) error {
+	return ps.s.Iterate(func(key string, value PortType) error {
+		if port, ok := value.(T); ok {
+			return fn(key, port)
+		}
+		return nil
+	})
+}
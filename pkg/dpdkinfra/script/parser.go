@@ -0,0 +1,736 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+)
+
+// the arguments of a script command
+type tokens struct {
+	args []string
+	pos  int
+}
+
+func (t *tokens) more() bool {
+	return t.pos < len(t.args)
+}
+
+func (t *tokens) peek() string {
+	if !t.more() {
+		return ""
+	}
+	return t.args[t.pos]
+}
+
+// the next argument, what is the name of the argument used in the error when there are no more arguments
+func (t *tokens) next(what string) (string, error) {
+	if !t.more() {
+		return "", fmt.Errorf("%s missing", what)
+	}
+	t.pos++
+	return t.args[t.pos-1], nil
+}
+
+// the next argument must be the given keyword
+func (t *tokens) keyword(kw string) error {
+	arg, err := t.next(kw)
+	if err != nil {
+		return err
+	}
+	if arg != kw {
+		return fmt.Errorf("%q expected instead of %q", kw, arg)
+	}
+	return nil
+}
+
+// the next argument as unsigned integer with the given bit size. Like in the DPDK pipeline application hexadecimal
+// (0x prefix) values and the K, M and G (1024 based) suffixes are accepted.
+func (t *tokens) uint(what string, bitSize int) (uint64, error) {
+	arg, err := t.next(what)
+	if err != nil {
+		return 0, err
+	}
+
+	s, mult := arg, uint64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		s, mult = s[:len(s)-1], 1<<10
+	case "M":
+		s, mult = s[:len(s)-1], 1<<20
+	case "G":
+		s, mult = s[:len(s)-1], 1<<30
+	}
+	value, err := strconv.ParseUint(s, 0, bitSize)
+	if err == nil && value*mult>>bitSize != 0 {
+		err = strconv.ErrRange
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a valid number", what, arg)
+	}
+	return value * mult, nil
+}
+
+// the next argument as "on" or "off"
+func (t *tokens) onOff(what string) (bool, error) {
+	arg, err := t.next(what)
+	if err != nil {
+		return false, err
+	}
+	switch arg {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s must be on or off, not %q", what, arg)
+}
+
+// all arguments must be used
+func (t *tokens) done() error {
+	if t.more() {
+		return fmt.Errorf("unexpected argument %q", t.peek())
+	}
+	return nil
+}
+
+// translates the script commands into the chassis configuration of the script
+type parser struct {
+	s         *Script
+	line      int
+	pktmbufs  map[string]bool
+	ifaces    map[string]int // index in the interfaces config
+	pipelines map[string]int // index in the pipelines config
+}
+
+func newParser(s *Script) *parser {
+	return &parser{
+		s:         s,
+		pktmbufs:  make(map[string]bool),
+		ifaces:    make(map[string]int),
+		pipelines: make(map[string]int),
+	}
+}
+
+// the absolute path of a file in the script, relative files are relative to the directory of the script
+func (p *parser) file(name string) (string, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(p.s.dir, name)
+	}
+	return filepath.Abs(name)
+}
+
+// register the current script line as the origin of the configuration item at the given JSON path
+func (p *parser) at(path string, elems ...any) {
+	p.s.lines[validation.Path(path, elems...)] = p.line
+}
+
+func (p *parser) command(args []string) error {
+	t := &tokens{args: args[1:]}
+	switch args[0] {
+	case "mempool":
+		return p.mempool(t)
+	case "link":
+		return p.link(t)
+	case "tap":
+		return p.tap(t)
+	case "ring":
+		return p.ring(t)
+	case "pipeline":
+		return p.pipeline(t)
+	case "thread":
+		return p.thread(t)
+	}
+	return fmt.Errorf("unsupported command %q", args[0])
+}
+
+// mempool <mempool_name> buffer <buffer_size> pool <pool_size> cache <cache_size> cpu <cpu_id>
+func (p *parser) mempool(t *tokens) error {
+	name, err := t.next("mempool name")
+	if err != nil {
+		return err
+	}
+	if p.pktmbufs[name] {
+		return fmt.Errorf("mempool %s already defined", name)
+	}
+
+	pc := &config.PktmbufConfig{Name: name}
+	var value uint64
+	if err = t.keyword("buffer"); err == nil {
+		value, err = t.uint("buffer size", 32)
+		pc.BufferSize = uint(value)
+	}
+	if err == nil {
+		if err = t.keyword("pool"); err == nil {
+			value, err = t.uint("pool size", 32)
+			pc.PoolSize = uint32(value)
+		}
+	}
+	if err == nil {
+		if err = t.keyword("cache"); err == nil {
+			value, err = t.uint("cache size", 32)
+			pc.CacheSize = uint32(value)
+		}
+	}
+	if err == nil {
+		if err = t.keyword("cpu"); err == nil {
+			value, err = t.uint("cpu id", 31)
+			pc.CPUID = config.AutoInt{Value: int(value)}
+		}
+	}
+	if err == nil {
+		err = t.done()
+	}
+	if err != nil {
+		return err
+	}
+
+	p.pktmbufs[name] = true
+	p.at("pktmbufs", len(p.s.conf.Pktmbufs))
+	p.s.conf.Pktmbufs = append(p.s.conf.Pktmbufs, pc)
+	return nil
+}
+
+// add an interface to the configuration
+func (p *parser) addInterface(name string, portType string, params config.InterfaceParams) error {
+	if _, ok := p.ifaces[name]; ok {
+		return fmt.Errorf("interface %s already defined", name)
+	}
+	p.ifaces[name] = len(p.s.conf.Interfaces)
+	p.at("interfaces", len(p.s.conf.Interfaces))
+	p.s.conf.Interfaces = append(p.s.conf.Interfaces, &config.InterfaceConfig{Name: name, Type: portType, Params: params})
+	return nil
+}
+
+// get the defined interface with the given name and type
+func (p *parser) getInterface(name string, portType string) (int, *config.InterfaceConfig, error) {
+	i, ok := p.ifaces[name]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown %s %s", portType, name)
+	}
+	ic := p.s.conf.Interfaces[i]
+	if ic.Type != portType {
+		return 0, nil, fmt.Errorf("%s is not a %s but a %s", name, portType, ic.Type)
+	}
+	return i, ic, nil
+}
+
+// check if the mempool with the given name is defined
+func (p *parser) mempoolName(t *tokens) (string, error) {
+	name, err := t.next("mempool name")
+	if err != nil {
+		return "", err
+	}
+	if !p.pktmbufs[name] {
+		return "", fmt.Errorf("unknown mempool %s", name)
+	}
+	return name, nil
+}
+
+// link <link_name> dev <device_name> rxq <n_queues> <queue_size> <mempool_name> txq <n_queues> <queue_size>
+// promiscuous on | off [rss <qid_0> ... <qid_n>]
+func (p *parser) link(t *tokens) error {
+	name, err := t.next("link name")
+	if err != nil {
+		return err
+	}
+
+	params := &config.PMDParams{Rx: &config.PMDRxParams{}, Tx: &config.PMDTxParams{}}
+	if t.peek() == "port" {
+		return errors.New("links by port id are not supported, use dev <device_name>")
+	}
+	if err = t.keyword("dev"); err != nil {
+		return err
+	}
+	if params.PortName, err = t.next("device name"); err != nil {
+		return err
+	}
+
+	var value uint64
+	if err = t.keyword("rxq"); err != nil {
+		return err
+	}
+	if value, err = t.uint("rx queues", 16); err != nil {
+		return err
+	}
+	params.Rx.NQueues = uint16(value)
+	if value, err = t.uint("rx queue size", 32); err != nil {
+		return err
+	}
+	params.Rx.QueueSize = uint32(value)
+	if params.Rx.PktMbuf, err = p.mempoolName(t); err != nil {
+		return err
+	}
+
+	if err = t.keyword("txq"); err != nil {
+		return err
+	}
+	if value, err = t.uint("tx queues", 16); err != nil {
+		return err
+	}
+	params.Tx.NQueues = uint16(value)
+	if value, err = t.uint("tx queue size", 32); err != nil {
+		return err
+	}
+	params.Tx.QueueSize = uint32(value)
+
+	if err = t.keyword("promiscuous"); err != nil {
+		return err
+	}
+	if params.Rx.Promiscuous, err = t.onOff("promiscuous"); err != nil {
+		return err
+	}
+
+	if t.more() {
+		if err = t.keyword("rss"); err != nil {
+			return err
+		}
+		params.Rx.Rss = &config.RssParams{}
+		for t.more() {
+			if value, err = t.uint("rss queue", 16); err != nil {
+				return err
+			}
+			params.Rx.Rss.Queues = append(params.Rx.Rss.Queues, uint16(value))
+		}
+		if len(params.Rx.Rss.Queues) == 0 {
+			return errors.New("rss queues missing")
+		}
+	}
+
+	return p.addInterface(name, portmngr.PortTypeEthdev, params)
+}
+
+// tap <tap_name>
+func (p *parser) tap(t *tokens) error {
+	name, err := t.next("tap name")
+	if err != nil {
+		return err
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	// the mempool and mtu are given when the tap is used as pipeline input port
+	return p.addInterface(name, portmngr.PortTypeTap, &config.TapParams{})
+}
+
+// ring <ring_name> size <size> numa <numa_node>
+func (p *parser) ring(t *tokens) error {
+	name, err := t.next("ring name")
+	if err != nil {
+		return err
+	}
+
+	params := &config.RingParams{}
+	var value uint64
+	if err = t.keyword("size"); err == nil {
+		value, err = t.uint("ring size", 32)
+		params.Size = uint(value)
+	}
+	if err == nil {
+		if err = t.keyword("numa"); err == nil {
+			value, err = t.uint("numa node", 32)
+			params.NumaNode = uint32(value)
+		}
+	}
+	if err == nil {
+		err = t.done()
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.addInterface(name, portmngr.PortTypeRing, params)
+}
+
+// pipeline <pipeline_name> create <numa_node>
+// pipeline <pipeline_name> port in | out ...
+// pipeline <pipeline_name> build <spec_file>
+// pipeline <pipeline_name> table <table_name> add <file_name>
+// pipeline <pipeline_name> table <table_name> update <file_name> none none
+// pipeline <pipeline_name> commit
+func (p *parser) pipeline(t *tokens) error {
+	name, err := t.next("pipeline name")
+	if err != nil {
+		return err
+	}
+	cmd, err := t.next("pipeline command")
+	if err != nil {
+		return err
+	}
+
+	if cmd == "create" {
+		return p.pipelineCreate(name, t)
+	}
+
+	i, ok := p.pipelines[name]
+	if !ok {
+		return fmt.Errorf("unknown pipeline %s", name)
+	}
+	path := validation.Path("pipelines", i)
+	pc := p.s.conf.Pipelines[i]
+
+	switch cmd {
+	case "port":
+		dir, err := t.next("port direction")
+		if err != nil {
+			return err
+		}
+		switch dir {
+		case "in":
+			return p.pipelinePortIn(path, pc, t)
+		case "out":
+			return p.pipelinePortOut(path, pc, t)
+		}
+		return fmt.Errorf("port direction must be in or out, not %q", dir)
+	case "build":
+		return p.pipelineBuild(path, pc, t)
+	case "table":
+		return p.pipelineTable(path, pc, t)
+	case "commit":
+		// the pipeline is committed when it is created from the configuration
+		return t.done()
+	}
+	return fmt.Errorf("unsupported pipeline command %q", cmd)
+}
+
+func (p *parser) pipelineCreate(name string, t *tokens) error {
+	if _, ok := p.pipelines[name]; ok {
+		return fmt.Errorf("pipeline %s already defined", name)
+	}
+	numaNode, err := t.uint("numa node", 31)
+	if err != nil {
+		return err
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	// the pipeline stays disabled when no thread enable command is given
+	pc := &config.PipelineConfig{
+		Name:     name,
+		NumaNode: config.AutoInt{Value: int(numaNode)},
+		Disabled: true,
+	}
+	p.pipelines[name] = len(p.s.conf.Pipelines)
+	p.at("pipelines", len(p.s.conf.Pipelines))
+	p.s.conf.Pipelines = append(p.s.conf.Pipelines, pc)
+	return nil
+}
+
+// the pipeline port id must be the next port id
+func portID(t *tokens, next int) error {
+	id, err := t.uint("port id", 31)
+	if err != nil {
+		return err
+	}
+	if int(id) != next {
+		return fmt.Errorf("port id %d out of order, port %d expected", id, next)
+	}
+	return nil
+}
+
+// the burst size given with bsz <burst_size>
+func burstSize(t *tokens) (uint, error) {
+	if err := t.keyword("bsz"); err != nil {
+		return 0, err
+	}
+	bsz, err := t.uint("burst size", 32)
+	return uint(bsz), err
+}
+
+// pipeline <pipeline_name> port in <port_id>
+//
+//	link <link_name> rxq <queue_id> bsz <burst_size>
+//	ring <ring_name> bsz <burst_size>
+//	source <mempool_name> <file_name> [loop <n_loops>] [packets <n_pkts_max>]
+//	tap <tap_name> mempool <mempool_name> mtu <mtu> bsz <burst_size>
+func (p *parser) pipelinePortIn(path string, pc *config.PipelineConfig, t *tokens) error {
+	if err := portID(t, len(pc.InputPorts)); err != nil {
+		return err
+	}
+	kind, err := t.next("port type")
+	if err != nil {
+		return err
+	}
+
+	port := &config.InPortConfig{}
+	switch kind {
+	case "link":
+		if port.IfaceName, err = t.next("link name"); err != nil {
+			return err
+		}
+		if _, _, err = p.getInterface(port.IfaceName, portmngr.PortTypeEthdev); err != nil {
+			return err
+		}
+		if err = t.keyword("rxq"); err != nil {
+			return err
+		}
+		value, err := t.uint("rx queue", 16)
+		if err != nil {
+			return err
+		}
+		port.RxQueue = uint16(value)
+		if port.Bsz, err = burstSize(t); err != nil {
+			return err
+		}
+	case "ring":
+		if port.IfaceName, err = t.next("ring name"); err != nil {
+			return err
+		}
+		if _, _, err = p.getInterface(port.IfaceName, portmngr.PortTypeRing); err != nil {
+			return err
+		}
+		if port.Bsz, err = burstSize(t); err != nil {
+			return err
+		}
+	case "source":
+		rx := &config.SourceRxParams{}
+		if rx.PktMbuf, err = p.mempoolName(t); err != nil {
+			return err
+		}
+		if rx.FileName, err = t.next("file name"); err != nil {
+			return err
+		}
+		if rx.FileName, err = p.file(rx.FileName); err != nil {
+			return err
+		}
+		for t.more() && err == nil {
+			var value uint64
+			switch t.peek() {
+			case "loop":
+				t.pos++
+				value, err = t.uint("number of loops", 64)
+				rx.NLoops = value
+			case "packets":
+				t.pos++
+				value, err = t.uint("number of packets", 32)
+				rx.NPktsMax = uint32(value)
+			default:
+				err = t.done()
+			}
+		}
+		if err != nil {
+			return err
+		}
+		// the source is an interface named after the pipeline and port
+		port.IfaceName = fmt.Sprintf("%s_source%d", pc.GetName(), len(pc.InputPorts))
+		if err = p.addInterface(port.IfaceName, portmngr.PortTypeSource, &config.SourceParams{Rx: rx}); err != nil {
+			return err
+		}
+		port.Bsz = 1
+	case "tap":
+		if port.IfaceName, err = t.next("tap name"); err != nil {
+			return err
+		}
+		i, ic, err := p.getInterface(port.IfaceName, portmngr.PortTypeTap)
+		if err != nil {
+			return err
+		}
+		rx := &config.TapRxParams{}
+		if err = t.keyword("mempool"); err != nil {
+			return err
+		}
+		if rx.PktMbuf, err = p.mempoolName(t); err != nil {
+			return err
+		}
+		if err = t.keyword("mtu"); err != nil {
+			return err
+		}
+		value, err := t.uint("mtu", 16)
+		if err != nil {
+			return err
+		}
+		rx.Mtu = int(value)
+		if port.Bsz, err = burstSize(t); err != nil {
+			return err
+		}
+		// the tap interface receives with the mempool and mtu of its input port
+		ic.Params.(*config.TapParams).Rx = rx
+		p.at("interfaces", i, portmngr.PortTypeTap, "rx")
+	default:
+		return fmt.Errorf("unsupported input port type %q", kind)
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	p.at(path, "inputports", len(pc.InputPorts))
+	pc.InputPorts = append(pc.InputPorts, port)
+	return nil
+}
+
+// pipeline <pipeline_name> port out <port_id>
+//
+//	link <link_name> txq <queue_id> bsz <burst_size>
+//	ring <ring_name> bsz <burst_size>
+//	sink <file_name> | none
+//	tap <tap_name> bsz <burst_size>
+func (p *parser) pipelinePortOut(path string, pc *config.PipelineConfig, t *tokens) error {
+	if err := portID(t, len(pc.OutputPorts)); err != nil {
+		return err
+	}
+	kind, err := t.next("port type")
+	if err != nil {
+		return err
+	}
+
+	port := &config.OutPortConfig{}
+	switch kind {
+	case "link":
+		if port.IfaceName, err = t.next("link name"); err != nil {
+			return err
+		}
+		if _, _, err = p.getInterface(port.IfaceName, portmngr.PortTypeEthdev); err != nil {
+			return err
+		}
+		if err = t.keyword("txq"); err != nil {
+			return err
+		}
+		value, err := t.uint("tx queue", 16)
+		if err != nil {
+			return err
+		}
+		port.TxQueue = uint16(value)
+		if port.Bsz, err = burstSize(t); err != nil {
+			return err
+		}
+	case "ring", "tap":
+		if port.IfaceName, err = t.next(kind + " name"); err != nil {
+			return err
+		}
+		if _, _, err = p.getInterface(port.IfaceName, kind); err != nil {
+			return err
+		}
+		if port.Bsz, err = burstSize(t); err != nil {
+			return err
+		}
+	case "sink":
+		tx := &config.SinkTxParams{}
+		if tx.FileName, err = t.next("file name"); err != nil {
+			return err
+		}
+		if tx.FileName == "none" {
+			// packets are dropped when no file is given
+			tx.FileName = ""
+		} else if tx.FileName, err = p.file(tx.FileName); err != nil {
+			return err
+		}
+		// the sink is an interface named after the pipeline and port
+		port.IfaceName = fmt.Sprintf("%s_sink%d", pc.GetName(), len(pc.OutputPorts))
+		if err = p.addInterface(port.IfaceName, portmngr.PortTypeSink, &config.SinkParams{Tx: tx}); err != nil {
+			return err
+		}
+		port.Bsz = 1
+	default:
+		return fmt.Errorf("unsupported output port type %q", kind)
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	p.at(path, "outputports", len(pc.OutputPorts))
+	pc.OutputPorts = append(pc.OutputPorts, port)
+	return nil
+}
+
+func (p *parser) pipelineBuild(path string, pc *config.PipelineConfig, t *tokens) error {
+	spec, err := t.next("spec file")
+	if err != nil {
+		return err
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	// the spec file with an absolute base path
+	if spec, err = p.file(spec); err != nil {
+		return err
+	}
+	pc.BasePath, pc.Spec = filepath.Split(spec)
+	p.at(path, "spec")
+	return nil
+}
+
+func (p *parser) pipelineTable(path string, pc *config.PipelineConfig, t *tokens) error {
+	table, err := t.next("table name")
+	if err != nil {
+		return err
+	}
+	cmd, err := t.next("table command")
+	if err != nil {
+		return err
+	}
+	if cmd != "add" && cmd != "update" {
+		return fmt.Errorf("unsupported table command %q", cmd)
+	}
+	file, err := t.next("file name")
+	if err != nil {
+		return err
+	}
+	if cmd == "update" {
+		// older DPDK versions: update <file_name_add> <file_name_delete> <file_name_default>
+		for _, what := range []string{"delete file name", "default file name"} {
+			name, err := t.next(what)
+			if err != nil {
+				return err
+			}
+			if name != "none" {
+				return fmt.Errorf("only none is supported as %s", what)
+			}
+		}
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	// the file is read when the pipeline is created, every file of a table is a table config of its own
+	if file, err = p.file(file); err != nil {
+		return err
+	}
+	if pc.Start == nil {
+		pc.Start = &config.StartConfig{}
+	}
+	p.at(path, "start", "tables", len(pc.Start.Tables))
+	pc.Start.Tables = append(pc.Start.Tables, config.TableConfig{Name: table, File: file})
+	return nil
+}
+
+// thread <thread_id> pipeline <pipeline_name> enable
+func (p *parser) thread(t *tokens) error {
+	threadID, err := t.uint("thread id", 31)
+	if err != nil {
+		return err
+	}
+	if err = t.keyword("pipeline"); err != nil {
+		return err
+	}
+	name, err := t.next("pipeline name")
+	if err != nil {
+		return err
+	}
+	i, ok := p.pipelines[name]
+	if !ok {
+		return fmt.Errorf("unknown pipeline %s", name)
+	}
+	if err = t.keyword("enable"); err != nil {
+		return err
+	}
+	if err = t.done(); err != nil {
+		return err
+	}
+
+	p.s.conf.Pipelines[i].ThreadID = config.AutoInt{Value: int(threadID)}
+	p.s.conf.Pipelines[i].Disabled = false
+	p.at("pipelines", i, "threadid")
+	return nil
+}
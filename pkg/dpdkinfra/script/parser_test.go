@@ -0,0 +1,189 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/portmngr"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScript = `; comment
+# comment
+mempool MEMPOOL0 buffer 2304 pool 32K cache 256 cpu 0
+
+link LINK0 dev 0000:00:04.0 rxq 2 128 MEMPOOL0 txq 1 512 promiscuous on rss 0 1
+tap TAP0
+ring RING0 size 1024 numa 0
+
+pipeline PIPELINE0 create 0
+pipeline PIPELINE0 port in 0 link LINK0 rxq 1 bsz 32
+pipeline PIPELINE0 port in 1 ring RING0 bsz 16
+pipeline PIPELINE0 port in 2 source MEMPOOL0 pcap/in.pcap loop 2 packets 100
+pipeline PIPELINE0 port in 3 tap TAP0 mempool MEMPOOL0 mtu 1500 bsz 8
+pipeline PIPELINE0 port out 0 link LINK0 txq 0 bsz 32
+pipeline PIPELINE0 port out 1 ring RING0 bsz 16
+pipeline PIPELINE0 port out 2 sink out.pcap
+pipeline PIPELINE0 port out 3 sink none
+pipeline PIPELINE0 port out 4 tap TAP0 bsz 8
+pipeline PIPELINE0 build spec/l2fwd.spec
+pipeline PIPELINE0 table fwd add tables/fwd.txt
+pipeline PIPELINE0 table fwd update tables/fwd2.txt none none
+pipeline PIPELINE0 commit
+
+pipeline PIPELINE1 create 1
+thread 2 pipeline PIPELINE0 enable
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(testScript), "/scripts/test.cli")
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf := s.Config()
+
+	// mempool
+	assert.Equal(t, config.PktmbufsConfig{
+		{Name: "MEMPOOL0", BufferSize: 2304, PoolSize: 32 * 1024, CacheSize: 256, CPUID: config.AutoInt{Value: 0}},
+	}, conf.Pktmbufs)
+
+	// interfaces in script order, the source and sink interfaces are named after the pipeline and port
+	ifaces := map[string]*config.InterfaceConfig{}
+	var names []string
+	for _, ic := range conf.Interfaces {
+		ifaces[ic.Name] = ic
+		names = append(names, ic.Name)
+	}
+	assert.Equal(t, []string{"LINK0", "TAP0", "RING0", "PIPELINE0_source2", "PIPELINE0_sink2", "PIPELINE0_sink3"}, names)
+
+	assert.Equal(t, portmngr.PortTypeEthdev, ifaces["LINK0"].Type)
+	assert.Equal(t, &config.PMDParams{
+		PortName: "0000:00:04.0",
+		Rx: &config.PMDRxParams{NQueues: 2, QueueSize: 128, PktMbuf: "MEMPOOL0", Promiscuous: true,
+			Rss: &config.RssParams{Queues: []uint16{0, 1}}},
+		Tx: &config.PMDTxParams{NQueues: 1, QueueSize: 512},
+	}, ifaces["LINK0"].Params)
+	assert.Equal(t, &config.TapParams{Rx: &config.TapRxParams{Mtu: 1500, PktMbuf: "MEMPOOL0"}}, ifaces["TAP0"].Params)
+	assert.Equal(t, &config.RingParams{Size: 1024, NumaNode: 0}, ifaces["RING0"].Params)
+	assert.Equal(t, &config.SourceParams{Rx: &config.SourceRxParams{
+		FileName: "/scripts/pcap/in.pcap", NLoops: 2, NPktsMax: 100, PktMbuf: "MEMPOOL0",
+	}}, ifaces["PIPELINE0_source2"].Params)
+	assert.Equal(t, &config.SinkParams{Tx: &config.SinkTxParams{FileName: "/scripts/out.pcap"}},
+		ifaces["PIPELINE0_sink2"].Params)
+	assert.Equal(t, &config.SinkParams{Tx: &config.SinkTxParams{}}, ifaces["PIPELINE0_sink3"].Params)
+
+	// pipelines
+	if !assert.Len(t, conf.Pipelines, 2) {
+		return
+	}
+	pc := conf.Pipelines[0]
+	assert.Equal(t, "PIPELINE0", pc.Name)
+	assert.Equal(t, config.AutoInt{Value: 0}, pc.NumaNode)
+	assert.Equal(t, config.AutoInt{Value: 2}, pc.ThreadID)
+	assert.False(t, pc.Disabled)
+	assert.Equal(t, "/scripts/spec/", pc.BasePath)
+	assert.Equal(t, "l2fwd.spec", pc.Spec)
+	assert.Equal(t, []*config.InPortConfig{
+		{IfaceName: "LINK0", RxQueue: 1, Bsz: 32},
+		{IfaceName: "RING0", Bsz: 16},
+		{IfaceName: "PIPELINE0_source2", Bsz: 1},
+		{IfaceName: "TAP0", Bsz: 8},
+	}, pc.InputPorts)
+	assert.Equal(t, []*config.OutPortConfig{
+		{IfaceName: "LINK0", TxQueue: 0, Bsz: 32},
+		{IfaceName: "RING0", Bsz: 16},
+		{IfaceName: "PIPELINE0_sink2", Bsz: 1},
+		{IfaceName: "PIPELINE0_sink3", Bsz: 1},
+		{IfaceName: "TAP0", Bsz: 8},
+	}, pc.OutputPorts)
+
+	// the table files are relative to the script directory and read when the pipeline is created
+	assert.Equal(t, &config.StartConfig{Tables: []config.TableConfig{
+		{Name: "fwd", File: "/scripts/tables/fwd.txt"},
+		{Name: "fwd", File: "/scripts/tables/fwd2.txt"},
+	}}, pc.Start)
+
+	// a pipeline without thread enable command stays disabled
+	assert.Equal(t, "PIPELINE1", conf.Pipelines[1].Name)
+	assert.True(t, conf.Pipelines[1].Disabled)
+
+	// the script lines of the configuration items
+	assert.Equal(t, 3, s.lineOf("pktmbufs[0]"))
+	assert.Equal(t, 5, s.lineOf("interfaces[0].ethdev.rx"))
+	assert.Equal(t, 13, s.lineOf("interfaces[1].tap.rx"))
+	assert.Equal(t, 9, s.lineOf("pipelines[0].name"))
+	assert.Equal(t, 11, s.lineOf("pipelines[0].inputports[1]"))
+	assert.Equal(t, 19, s.lineOf("pipelines[0].spec"))
+	assert.Equal(t, 21, s.lineOf("pipelines[0].start.tables[1].file"))
+	assert.Equal(t, 25, s.lineOf("pipelines[0].threadid"))
+}
+
+func TestParseErrors(t *testing.T) {
+	const header = "mempool MEMPOOL0 buffer 2304 pool 32K cache 256 cpu 0\n" +
+		"link LINK0 dev 0000:00:04.0 rxq 1 128 MEMPOOL0 txq 1 512 promiscuous off\n" +
+		"ring RING0 size 1024 numa 0\n" +
+		"pipeline PIPELINE0 create 0\n"
+
+	tests := []struct {
+		name   string
+		script string
+		line   int
+		err    string
+	}{
+		{"unknown command", "foo bar", 1, `unsupported command "foo"`},
+		{"mempool duplicate", "mempool MEMPOOL0 buffer 1 pool 1 cache 1 cpu 0", 5, "mempool MEMPOOL0 already defined"},
+		{"mempool keyword", "mempool M buffer 1 pool 1 cash 1 cpu 0", 5, `"cache" expected instead of "cash"`},
+		{"mempool number", "mempool M buffer x pool 1 cache 1 cpu 0", 5, `buffer size "x" is not a valid number`},
+		{"mempool range", "mempool M buffer 8G pool 1 cache 1 cpu 0", 5, `buffer size "8G" is not a valid number`},
+		{"mempool argument", "mempool M buffer 1 pool 1 cache 1 cpu 0 x", 5, `unexpected argument "x"`},
+		{"link port id", "link L port 0 rxq 1 128 MEMPOOL0 txq 1 512 promiscuous on", 5, "links by port id"},
+		{"link mempool", "link L dev d rxq 1 128 M1 txq 1 512 promiscuous on", 5, "unknown mempool M1"},
+		{"link promiscuous", "link L dev d rxq 1 128 MEMPOOL0 txq 1 512 promiscuous yes", 5, "must be on or off"},
+		{"link rss", "link L dev d rxq 1 128 MEMPOOL0 txq 1 512 promiscuous on rss", 5, "rss queues missing"},
+		{"link duplicate", "link LINK0 dev d rxq 1 128 MEMPOOL0 txq 1 512 promiscuous on", 5,
+			"interface LINK0 already defined"},
+		{"tap argument", "tap T x", 5, `unexpected argument "x"`},
+		{"ring numa", "ring R size 1024", 5, "numa missing"},
+		{"pipeline unknown", "pipeline P1 build x.spec", 5, "unknown pipeline P1"},
+		{"pipeline duplicate", "pipeline PIPELINE0 create 0", 5, "pipeline PIPELINE0 already defined"},
+		{"pipeline command", "pipeline PIPELINE0 run", 5, `unsupported pipeline command "run"`},
+		{"port direction", "pipeline PIPELINE0 port up 0 ring RING0 bsz 1", 5, "port direction must be in or out"},
+		{"port order", "pipeline PIPELINE0 port in 1 ring RING0 bsz 1", 5, "port id 1 out of order, port 0 expected"},
+		{"port in type", "pipeline PIPELINE0 port in 0 fd 3", 5, `unsupported input port type "fd"`},
+		{"port in interface type", "pipeline PIPELINE0 port in 0 ring LINK0 bsz 1", 5, "LINK0 is not a ring but a ethdev"},
+		{"port in unknown", "pipeline PIPELINE0 port in 0 link LINK1 rxq 0 bsz 1", 5, "unknown ethdev LINK1"},
+		{"port in bsz", "pipeline PIPELINE0 port in 0 ring RING0", 5, "bsz missing"},
+		{"port in source option", "pipeline PIPELINE0 port in 0 source MEMPOOL0 in.pcap times 2", 5,
+			`unexpected argument "times"`},
+		{"port out type", "pipeline PIPELINE0 port out 0 fd 3", 5, `unsupported output port type "fd"`},
+		{"port out txq", "pipeline PIPELINE0 port out 0 link LINK0 rxq 0 bsz 1", 5, `"txq" expected instead of "rxq"`},
+		{"build spec", "pipeline PIPELINE0 build", 5, "spec file missing"},
+		{"table command", "pipeline PIPELINE0 table fwd delete x.txt", 5, `unsupported table command "delete"`},
+		{"table update", "pipeline PIPELINE0 table fwd update a.txt b.txt none", 5,
+			"only none is supported as delete file name"},
+		{"commit argument", "pipeline PIPELINE0 commit now", 5, `unexpected argument "now"`},
+		{"thread pipeline", "thread 1 pipeline P1 enable", 5, "unknown pipeline P1"},
+		{"thread enable", "thread 1 pipeline PIPELINE0 disable", 5, `"enable" expected instead of "disable"`},
+		{"thread id", "thread x pipeline PIPELINE0 enable", 5, `thread id "x" is not a valid number`},
+	}
+
+	for _, test := range tests {
+		script := test.script
+		if test.line > 1 {
+			script = header + script
+		}
+		_, err := Parse(strings.NewReader(script), "test.cli")
+		var serr *Error
+		if !assert.True(t, errors.As(err, &serr), test.name) {
+			continue
+		}
+		assert.Equal(t, "test.cli", serr.File, test.name)
+		assert.Equal(t, test.line, serr.Line, test.name)
+		assert.ErrorContains(t, serr.Err, test.err, test.name)
+	}
+}
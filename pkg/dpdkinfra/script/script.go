@@ -0,0 +1,147 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package script replays the CLI scripts of the DPDK pipeline example application (dpdk/examples/pipeline) on the
+// dpdkinfra module. A script is translated into a dpdkinfra chassis configuration that is validated and applied like
+// a configuration file, so the created resources are part of the running configuration. The script can also be
+// converted into a configuration file.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stolsma/go-p4pack/pkg/dpdkinfra/config"
+	"github.com/stolsma/go-p4pack/pkg/logging"
+)
+
+var log logging.Logger
+
+func init() {
+	// keep the logger up to date, also after new log config
+	logging.Register("dpdkinfra/script", func(logger logging.Logger) {
+		log = logger
+	})
+}
+
+// Error is an error found at a line of a script
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Script is a DPDK pipeline application CLI script translated into a dpdkinfra chassis configuration
+type Script struct {
+	name  string
+	dir   string // the spec, table and pcap files in the script are relative to this directory
+	conf  *config.Config
+	lines map[string]int // script line of the configuration items by JSON path
+}
+
+// Load reads and translates the given script file
+func Load(filename string) (*Script, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f, filename)
+}
+
+// Parse reads and translates a script, name is the file name of the script used in the errors. The spec, table and pcap
+// files in the script are relative to the directory of the script file. A pipeline is only enabled by a thread enable
+// command, pipelines without one are built and committed but stay disabled.
+func Parse(r io.Reader, name string) (*Script, error) {
+	s := &Script{name: name, dir: filepath.Dir(name), conf: config.Create(), lines: make(map[string]int)}
+	p := newParser(s)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 || strings.HasPrefix(args[0], ";") || strings.HasPrefix(args[0], "#") {
+			continue
+		}
+		p.line = line
+		if err := p.command(args); err != nil {
+			return nil, &Error{File: name, Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Config returns the chassis configuration the script is translated into
+func (s *Script) Config() *config.Config {
+	return s.conf
+}
+
+// the script line of the configuration item at the given JSON path or its nearest parent
+func (s *Script) lineOf(path string) int {
+	for path != "" {
+		if line, ok := s.lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+// Validate checks the translated configuration without applying it and returns all errors found with the script line
+// that caused them
+func (s *Script) Validate() error {
+	var errs, lineErrs validation.Errors
+	s.conf.Validate("", &errs)
+	for _, err := range errs.List() {
+		lineErrs.Add(fmt.Sprintf("%s:%d", s.name, s.lineOf(err.Path)), err.Err)
+	}
+	return lineErrs.Err()
+}
+
+// Apply creates the resources of the script in dependency order and adds them to the running configuration. It stops
+// at the first error, the resources created before are not deleted.
+func (s *Script) Apply() error {
+	errorAt := func(path string, err error) error {
+		return &Error{File: s.name, Line: s.lineOf(path), Err: err}
+	}
+
+	for i, pc := range s.conf.Pktmbufs {
+		if err := (config.PktmbufsConfig{pc}).Apply(); err != nil {
+			return errorAt(validation.Path("pktmbufs", i), err)
+		}
+	}
+	for i, ic := range s.conf.Interfaces {
+		if err := (config.InterfacesConfig{ic}).Apply(); err != nil {
+			return errorAt(validation.Path("interfaces", i), err)
+		}
+	}
+	for i, pc := range s.conf.Pipelines {
+		if err := (config.PipelinesConfig{pc}).Apply(s.conf.GetBasePath()); err != nil {
+			return errorAt(validation.Path("pipelines", i), err)
+		}
+	}
+
+	log.Infof("Script %s applied", s.name)
+	return nil
+}
@@ -5,6 +5,7 @@ package device
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 )
@@ -25,9 +26,9 @@ type Type interface {
 	IterateRxQueues(fn func(index uint16, q Queue) error) error
 	IterateTxQueues(fn func(index uint16, q Queue) error) error
 	GetRxQueue(q uint16) (string, int, error)
-	SetRxQueue(q uint16, pl string, portID int) error
+	SetRxQueue(q uint16, pl string, portID int, bsz uint) error
 	GetTxQueue(q uint16) (string, int, error)
-	SetTxQueue(q uint16, pl string, portID int) error
+	SetTxQueue(q uint16, pl string, portID int, bsz uint) error
 	Clean() func()
 	SetClean(fn func())
 	BindToPipelineInputPort(pl *pipeline.Pipeline, portID int, rxq uint16, bsz uint) error
@@ -42,6 +43,7 @@ type Type interface {
 type Queue struct {
 	pipeline     string
 	pipelinePort int
+	bsz          uint // burst size of the pipeline port binding
 }
 
 func (q *Queue) Pipeline() string {
@@ -52,6 +54,10 @@ func (q *Queue) PipelinePort() int {
 	return q.pipelinePort
 }
 
+func (q *Queue) Bsz() uint {
+	return q.bsz
+}
+
 // basic definition all port devices need to "inherit"
 type Device struct {
 	devType  string
@@ -82,13 +88,47 @@ func (d *Device) SetName(name string) {
 func (d *Device) InitializeQueues(nRxQ uint16, nTxQ uint16) {
 	d.nRxQ = nRxQ
 	for i := uint16(0); i < nRxQ; i++ {
-		d.rxQueues = append(d.rxQueues, Queue{"", NotBound})
+		d.rxQueues = append(d.rxQueues, Queue{"", NotBound, 0})
 	}
 
 	d.nTxQ = nTxQ
 	for i := uint16(0); i < nTxQ; i++ {
-		d.txQueues = append(d.txQueues, Queue{"", NotBound})
+		d.txQueues = append(d.txQueues, Queue{"", NotBound, 0})
+	}
+}
+
+// ResizeQueues changes the number of rx and tx queues while keeping the pipeline binding of the remaining queues. An
+// error is returned (and nothing is changed) if a queue to be removed is bound to a pipeline.
+func (d *Device) ResizeQueues(nRxQ uint16, nTxQ uint16) error {
+	for i := nRxQ; i < d.nRxQ; i++ {
+		if d.rxQueues[i].pipelinePort != NotBound {
+			return fmt.Errorf("rx queue %d is bound to pipeline %s", i, d.rxQueues[i].pipeline)
+		}
+	}
+
+	for i := nTxQ; i < d.nTxQ; i++ {
+		if d.txQueues[i].pipelinePort != NotBound {
+			return fmt.Errorf("tx queue %d is bound to pipeline %s", i, d.txQueues[i].pipeline)
+		}
+	}
+
+	d.rxQueues = resizeQueues(d.rxQueues, nRxQ)
+	d.nRxQ = nRxQ
+	d.txQueues = resizeQueues(d.txQueues, nTxQ)
+	d.nTxQ = nTxQ
+
+	return nil
+}
+
+func resizeQueues(queues []Queue, n uint16) []Queue {
+	if int(n) <= len(queues) {
+		return queues[:n]
+	}
+
+	for i := len(queues); i < int(n); i++ {
+		queues = append(queues, Queue{"", NotBound, 0})
 	}
+	return queues
 }
 
 func (d *Device) IterateRxQueues(fn func(index uint16, q Queue) error) error {
@@ -124,12 +164,13 @@ func (d *Device) GetRxQueue(q uint16) (string, int, error) {
 	return d.rxQueues[q].pipeline, d.rxQueues[q].pipelinePort, nil
 }
 
-func (d *Device) SetRxQueue(q uint16, pl string, portID int) error {
+func (d *Device) SetRxQueue(q uint16, pl string, portID int, bsz uint) error {
 	if q >= d.nRxQ {
 		return errors.New("requested queue not available")
 	}
 	d.rxQueues[q].pipeline = pl
 	d.rxQueues[q].pipelinePort = portID
+	d.rxQueues[q].bsz = bsz
 	return nil
 }
 
@@ -140,12 +181,13 @@ func (d *Device) GetTxQueue(q uint16) (string, int, error) {
 	return d.txQueues[q].pipeline, d.txQueues[q].pipelinePort, nil
 }
 
-func (d *Device) SetTxQueue(q uint16, pl string, portID int) error {
+func (d *Device) SetTxQueue(q uint16, pl string, portID int, bsz uint) error {
 	if q >= d.nTxQ {
 		return errors.New("requested queue not available")
 	}
 	d.txQueues[q].pipeline = pl
 	d.txQueues[q].pipelinePort = portID
+	d.txQueues[q].bsz = bsz
 	return nil
 }
 
@@ -85,6 +85,19 @@ func GetLcoreCount() uint {
 	return uint(C.rte_lcore_count())
 }
 
+// Returns the NUMA node (socket id) of the given lcore.
+func LcoreNumaNode(lcoreID uint) int {
+	return int(C.rte_lcore_to_socket_id(C.uint(lcoreID)))
+}
+
+// Returns the NUMA nodes (socket ids) detected by EAL.
+func NumaNodes() (out []int) {
+	for i := C.uint(0); i < C.rte_socket_count(); i++ {
+		out = append(out, int(C.rte_socket_id_by_idx(i)))
+	}
+	return out
+}
+
 func LcoreIsRunning(lcoreID uint) bool {
 	threadState := C.rte_eal_get_lcore_state((C.uint32_t)(lcoreID))
 
@@ -101,11 +114,32 @@ func HasPCI() bool {
 	return int(C.rte_eal_has_pci()) != 0
 }
 
+// DPDK process types
+const (
+	ProcAuto      = C.RTE_PROC_AUTO
+	ProcPrimary   = C.RTE_PROC_PRIMARY
+	ProcSecondary = C.RTE_PROC_SECONDARY
+)
+
 // Returns the current process type.
 func ProcessType() int {
 	return int(C.rte_eal_process_type())
 }
 
+// IsSecondary tells if this process runs as DPDK secondary process attached to a primary process.
+func IsSecondary() bool {
+	return ProcessType() == ProcSecondary
+}
+
+// PrimaryOnly returns common.ErrSecondary when called in a DPDK secondary process, used to guard operations that create
+// or reconfigure shared DPDK resources.
+func PrimaryOnly() error {
+	if IsSecondary() {
+		return common.ErrSecondary
+	}
+	return nil
+}
+
 // Type of generic device
 type RteDevtype uint32
 
@@ -0,0 +1,54 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// the device event API is still experimental in DPDK
+#define ALLOW_EXPERIMENTAL_API
+
+#include <stdlib.h>
+
+#include <rte_common.h>
+#include <rte_dev.h>
+
+#include "event.h"
+#include "_cgo_export.h"
+
+// Called by DPDK from the interrupt thread, keep it short and forward the event to the Go side.
+static void dev_event_callback(const char *device_name, enum rte_dev_event_type event, void *cb_arg __rte_unused) {
+	ealDevEventCallback((char *)device_name, (int)event);
+}
+
+int dev_event_monitor_start(void) {
+	int status;
+
+	// register the callback for the events of all devices
+	status = rte_dev_event_callback_register(NULL, dev_event_callback, NULL);
+	if (status)
+		return status;
+
+	status = rte_dev_event_monitor_start();
+	if (status)
+		goto error;
+
+	// let DPDK handle the failure of hot removed devices (i.e. prevent a SIGBUS on access to removed PCI devices)
+	status = rte_dev_hotplug_handle_enable();
+	if (status) {
+		rte_dev_event_monitor_stop();
+		goto error;
+	}
+
+	return 0;
+
+error:
+	rte_dev_event_callback_unregister(NULL, dev_event_callback, NULL);
+	return status;
+}
+
+int dev_event_monitor_stop(void) {
+	int status;
+
+	rte_dev_hotplug_handle_disable();
+	status = rte_dev_event_monitor_stop();
+	rte_dev_event_callback_unregister(NULL, dev_event_callback, NULL);
+
+	return status;
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package eal
+
+/*
+#include "event.h"
+
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+)
+
+// DevEventType is the type of a device (hotplug) event
+type DevEventType int
+
+// The device event types, equal to enum rte_dev_event_type
+const (
+	DevEventAdd    DevEventType = iota // device being added
+	DevEventRemove                     // device being removed
+)
+
+var devEventTypeNames = map[DevEventType]string{
+	DevEventAdd:    "ADD",
+	DevEventRemove: "REMOVE",
+}
+
+func (t DevEventType) String() string {
+	if name, ok := devEventTypeNames[t]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// DevEventHandler is called with the device name and event type for every device event received. It is called from
+// the DPDK interrupt thread and therefore needs to return as fast as possible!
+type DevEventHandler func(devName string, event DevEventType)
+
+var devEventHandlerMu sync.RWMutex
+var devEventHandler DevEventHandler
+
+// DevEventMonitorStart starts the monitoring of device (hotplug) events and calls the given handler for every device
+// event received. Only one handler can be active at a time.
+func DevEventMonitorStart(fn DevEventHandler) error {
+	devEventHandlerMu.Lock()
+	defer devEventHandlerMu.Unlock()
+
+	if fn == nil {
+		return errors.New("no device event handler given")
+	}
+	if devEventHandler != nil {
+		return errors.New("device event monitor already started")
+	}
+
+	devEventHandler = fn
+	if res := C.dev_event_monitor_start(); res != 0 {
+		devEventHandler = nil
+		return fmt.Errorf("device event monitor start failed (%w)", common.Err(res))
+	}
+
+	return nil
+}
+
+// DevEventMonitorStop stops the monitoring of device (hotplug) events.
+func DevEventMonitorStop() error {
+	// clear the handler first so that a callback running in the interrupt thread doesn't block the stop
+	devEventHandlerMu.Lock()
+	started := devEventHandler != nil
+	devEventHandler = nil
+	devEventHandlerMu.Unlock()
+	if !started {
+		return nil
+	}
+
+	if res := C.dev_event_monitor_stop(); res != 0 {
+		return fmt.Errorf("device event monitor stop failed (%w)", common.Err(res))
+	}
+	return nil
+}
+
+//export ealDevEventCallback
+func ealDevEventCallback(devName *C.char, event C.int) {
+	devEventHandlerMu.RLock()
+	defer devEventHandlerMu.RUnlock()
+
+	if devEventHandler != nil {
+		devEventHandler(C.GoString(devName), DevEventType(event))
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#ifndef _INCLUDE_EVENT_H_
+#define _INCLUDE_EVENT_H_
+
+/**
+ * Device (hotplug) event monitoring. The registered callback forwards the device events of all devices to the Go event
+ * handler.
+ */
+
+int dev_event_monitor_start(void);
+int dev_event_monitor_stop(void);
+
+#endif /* _INCLUDE_EVENT_H_ */
@@ -0,0 +1,102 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// fopencookie and open_memstream are GNU extensions
+#define _GNU_SOURCE
+
+#include <stdio.h>
+#include <stdlib.h>
+
+#include <rte_common.h>
+#include <rte_log.h>
+
+#include "log.h"
+#include "_cgo_export.h"
+
+static FILE *log_stream;
+
+#define LOG_MSG_MAX 1024
+
+// The log message being assembled by the current thread. The stream is unbuffered so the parts of a message are
+// written by the thread logging it, messages of other lcores can't get mixed in.
+static __thread struct {
+	uint32_t logtype;
+	uint32_t level;
+	size_t len;
+	char buf[LOG_MSG_MAX];
+} log_msg;
+
+static void log_msg_forward(void) {
+	if (log_msg.len == 0)
+		return;
+
+	ealLogWrite(log_msg.logtype, log_msg.level, log_msg.buf, (int)log_msg.len);
+	log_msg.len = 0;
+}
+
+// Called by DPDK (rte_vlog) from the thread logging the message, the log type and level of the current message are
+// stored per thread by DPDK. A message is forwarded when it ends with a newline or fills the message buffer.
+static ssize_t log_stream_write(void *cookie __rte_unused, const char *buf, size_t size) {
+	uint32_t logtype = (uint32_t)rte_log_cur_msg_logtype();
+	uint32_t level = (uint32_t)rte_log_cur_msg_loglevel();
+	size_t i;
+
+	// a message without newline is finished by the next message of another log type or level
+	if (log_msg.len > 0 && (log_msg.logtype != logtype || log_msg.level != level))
+		log_msg_forward();
+	log_msg.logtype = logtype;
+	log_msg.level = level;
+
+	for (i = 0; i < size; i++) {
+		log_msg.buf[log_msg.len++] = buf[i];
+		if (buf[i] == '\n' || log_msg.len == LOG_MSG_MAX)
+			log_msg_forward();
+	}
+	return size;
+}
+
+int log_stream_open(void) {
+	cookie_io_functions_t funcs = { .write = log_stream_write };
+
+	if (log_stream != NULL)
+		return 0;
+
+	log_stream = fopencookie(NULL, "w", funcs);
+	if (log_stream == NULL)
+		return -1;
+
+	// no stream buffer shared by the lcores, the messages are assembled per thread in log_stream_write
+	setvbuf(log_stream, NULL, _IONBF, 0);
+	if (rte_openlog_stream(log_stream)) {
+		fclose(log_stream);
+		log_stream = NULL;
+		return -1;
+	}
+
+	// filtering is done with the log type levels
+	rte_log_set_global_level(RTE_LOG_DEBUG);
+	return 0;
+}
+
+void log_stream_close(void) {
+	if (log_stream == NULL)
+		return;
+
+	// back to the default DPDK log stream (stderr/syslog)
+	rte_openlog_stream(NULL);
+	fclose(log_stream);
+	log_stream = NULL;
+}
+
+char *log_types_dump(size_t *len) {
+	char *buf = NULL;
+	FILE *f;
+
+	f = open_memstream(&buf, len);
+	if (f == NULL)
+		return NULL;
+
+	rte_log_dump(f);
+	fclose(f);
+	return buf;
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package eal
+
+/*
+#include <stdlib.h>
+#include <rte_log.h>
+
+#include "log.h"
+
+*/
+import "C"
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/logging"
+)
+
+// LogDomain is the logging domain the DPDK log messages are forwarded to. The messages of a DPDK log type (e.g.
+// pmd.net.virtio) are logged with the dpdk/<log type> logger (e.g. dpdk/pmd.net.virtio).
+const LogDomain = "dpdk"
+
+type logType struct {
+	name   string
+	logger logging.Logger
+}
+
+var logBridge struct {
+	sync.Mutex
+	started bool
+	types   map[uint32]*logType // DPDK log type id -> log type
+}
+
+func init() {
+	// drop the cached loggers and resync the DPDK log type levels after a new log config
+	logging.Register(LogDomain, func(logger logging.Logger) {
+		logBridge.Lock()
+		defer logBridge.Unlock()
+		logBridge.types = nil
+		if logBridge.started {
+			syncLogLevels()
+		}
+	})
+
+	// keep the DPDK log type levels in sync with the levels of the dpdk logger tree (e.g. 'log set level')
+	logging.RegisterLevelHook(LogDomain, func(logger logging.Logger) {
+		logBridge.Lock()
+		defer logBridge.Unlock()
+		if logBridge.started {
+			syncLogLevels()
+		}
+	})
+}
+
+// StartLogBridge redirects the DPDK log output (EAL, PMDs, SWX pipeline etc.) from stderr to the dpdk/<log type>
+// loggers and sets the level of every DPDK log type to the level of its logger. Call it before RteEalInit to also
+// forward the EAL initialization messages. Log levels given as EAL arguments are applied by RteEalInit and are valid
+// until the level of a dpdk logger or the logging configuration is changed.
+func StartLogBridge() error {
+	logBridge.Lock()
+	defer logBridge.Unlock()
+
+	if logBridge.started {
+		return nil
+	}
+	if C.log_stream_open() != 0 {
+		return errors.New("opening the DPDK log stream failed")
+	}
+
+	logBridge.started = true
+	syncLogLevels()
+	return nil
+}
+
+// StopLogBridge sets the DPDK log output back to the default DPDK log stream
+func StopLogBridge() {
+	logBridge.Lock()
+	defer logBridge.Unlock()
+
+	if logBridge.started {
+		C.log_stream_close()
+		logBridge.started = false
+	}
+}
+
+// set the level of all DPDK log types to the level of their logger. logBridge must be locked!
+func syncLogLevels() {
+	loadLogTypes()
+	for id, lt := range logBridge.types {
+		C.rte_log_set_level(C.uint32_t(id), level2RteLevel(lt.logger.GetLevel()))
+	}
+}
+
+// (re)load the registered DPDK log types and create their loggers. logBridge must be locked!
+func loadLogTypes() {
+	var size C.size_t
+	buf := C.log_types_dump(&size)
+	if buf == nil {
+		return
+	}
+	dump := C.GoStringN(buf, C.int(size))
+	C.free(unsafe.Pointer(buf))
+
+	if logBridge.types == nil {
+		logBridge.types = make(map[uint32]*logType)
+	}
+
+	// parse lines like "id 4: lib.eal, level is info"
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	for scanner.Scan() {
+		var id uint32
+		var name string
+		if n, _ := fmt.Sscanf(scanner.Text(), "id %d: %s", &id, &name); n != 2 {
+			continue
+		}
+		name = strings.TrimSuffix(name, ",")
+		if _, ok := logBridge.types[id]; !ok {
+			logBridge.types[id] = &logType{name: name, logger: logging.GetLogger(LogDomain + "/" + name)}
+		}
+	}
+}
+
+// get the logger of the given DPDK log type, log types registered after the last load are loaded on demand
+func logTypeLogger(id uint32) logging.Logger {
+	logBridge.Lock()
+	defer logBridge.Unlock()
+
+	lt, ok := logBridge.types[id]
+	if !ok {
+		loadLogTypes()
+		if lt, ok = logBridge.types[id]; !ok {
+			return logging.GetLogger(LogDomain)
+		}
+	}
+	return lt.logger
+}
+
+func level2RteLevel(level logging.Level) C.uint32_t {
+	switch level {
+	case logging.DebugLevel:
+		return C.RTE_LOG_DEBUG
+	case logging.InfoLevel:
+		return C.RTE_LOG_INFO
+	case logging.WarnLevel:
+		return C.RTE_LOG_WARNING
+	case logging.ErrorLevel:
+		return C.RTE_LOG_ERR
+	default:
+		return C.RTE_LOG_CRIT
+	}
+}
+
+//export ealLogWrite
+func ealLogWrite(logtype C.uint32_t, level C.uint32_t, buf *C.char, size C.int) {
+	msg := strings.TrimRight(C.GoStringN(buf, size), "\n")
+	if msg == "" {
+		return
+	}
+
+	// DPDK emergency, alert and critical messages are logged as error, the Go process shouldn't be stopped by DPDK
+	logger := logTypeLogger(uint32(logtype))
+	switch {
+	case level <= C.RTE_LOG_ERR:
+		logger.Error(msg)
+	case level == C.RTE_LOG_WARNING:
+		logger.Warn(msg)
+	case level <= C.RTE_LOG_INFO:
+		logger.Info(msg)
+	default:
+		logger.Debug(msg)
+	}
+}
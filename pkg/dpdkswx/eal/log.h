@@ -0,0 +1,22 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#ifndef _INCLUDE_LOG_H_
+#define _INCLUDE_LOG_H_
+
+#include <stddef.h>
+
+/**
+ * DPDK log stream. The opened stream forwards all DPDK log messages with their log type and level to the Go side.
+ */
+
+int log_stream_open(void);
+void log_stream_close(void);
+
+/**
+ * Dump the registered DPDK log types (as in rte_log_dump) into a newly allocated buffer. The returned buffer must be
+ * freed by the caller.
+ */
+char *log_types_dump(size_t *len);
+
+#endif /* _INCLUDE_LOG_H_ */
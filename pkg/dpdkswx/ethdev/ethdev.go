@@ -13,16 +13,21 @@ package ethdev
 #include <rte_ethdev.h>
 #include <rte_swx_port_ethdev.h>
 
+#include "event.h"
+
 */
 import "C"
 import (
 	"errors"
 	"fmt"
+	"net"
 	"syscall"
 	"unsafe"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/flow"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
 	"github.com/stolsma/go-p4pack/pkg/logging"
@@ -38,10 +43,6 @@ func init() {
 	})
 }
 
-const RetaConfSize = (EthRssRetaSize512 / EthRetaGroupSize)
-
-type ParamsRss []uint16
-
 type Params struct {
 	PortName string
 	Rx       struct {
@@ -49,14 +50,15 @@ type Params struct {
 		NQueues   uint16
 		QueueSize uint32
 		Mempool   *pktmbuf.Pktmbuf
-		Rss       ParamsRss
+		Rss       *ParamsRss
 	}
 	Tx struct {
 		NQueues   uint16
 		QueueSize uint32
 	}
 
-	Promiscuous bool
+	Promiscuous  bool
+	Allmulticast bool
 }
 
 // Ethdev represents a Ethdev record
@@ -66,8 +68,12 @@ type Ethdev struct {
 	portInfo *DevInfo
 	// from create
 	devName string
-	nRxQ    uint16
-	nTxQ    uint16
+	params  Params // current configuration, used when reconfiguring the port
+	intrLsc bool   // link state change interrupts enabled
+	intrRmv bool   // device removal interrupts enabled
+	// flow rules created on this port
+	flows      []*flow.Flow
+	nextFlowID uint32
 }
 
 // Initialize ethdev struct
@@ -79,7 +85,6 @@ func (ethdev *Ethdev) Init(name string) {
 
 // Configure DPDK Ethdev device. Returns error when something went wrong.
 func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
-	var status C.int
 	var res error
 
 	// TODO add all params to check!
@@ -89,6 +94,10 @@ func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
 		return errors.New("parameter error")
 	}
 
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	// get port id and save to this struct!
 	portID, res := lled.GetPortByName(params.PortName)
 	if res != nil {
@@ -112,45 +121,94 @@ func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
 		}
 	}
 
+	// check requested queue and RSS parameters for this device
+	if res = ethdev.checkQueues(params.Rx.NQueues, params.Tx.NQueues, params.Rx.Rss); res != nil {
+		return res
+	}
+
+	// save the configuration for later reconfiguration of the device
+	ethdev.params = *params
+	ethdev.params.Rx.Mtu = mtu
+	if params.Rx.Rss != nil {
+		ethdev.params.Rx.Rss = params.Rx.Rss.copy()
+	}
+
+	// configure the ethdev device and its queues
+	if res = ethdev.configure(); res != nil {
+		return res
+	}
+
+	// initialize queue setup for pipeline bind use
+	ethdev.InitializeQueues(params.Rx.NQueues, params.Tx.NQueues)
+
+	// start the device and set the link up
+	if res = ethdev.start(); res != nil {
+		return res
+	}
+
+	// Node fill in
+	ethdev.devName, res = portID.Name()
+	if res != nil {
+		return res
+	}
+	ethdev.SetClean(clean)
+
+	return nil
+}
+
+// check the requested number of queues and the RSS configuration against the capabilities of the device
+func (ethdev *Ethdev) checkQueues(nRxQ uint16, nTxQ uint16, rss *ParamsRss) error {
+	portInfo := ethdev.portInfo
+
 	// check maximum number of queues to configure to the max supported queues on device
-	if params.Rx.NQueues > portInfo.MaxRxQueues() || params.Tx.NQueues > portInfo.MaxTxQueues() {
+	if nRxQ > portInfo.MaxRxQueues() || nTxQ > portInfo.MaxTxQueues() {
 		return errors.New("number of Tx or Rx queues to large")
 	}
 
 	// check requested receive RSS parameters for this device
-	rss := params.Rx.Rss
 	if rss != nil {
-		if portInfo.RetaSize() == 0 || portInfo.RetaSize() > EthRssRetaSize512 {
-			return errors.New("ethdev redirection table size (rss) is 0 or too large (>512)")
-		}
+		return ethdev.checkRss(nRxQ, rss)
+	}
 
-		for i := 0; i < len(rss); i++ {
-			if rss[i] >= params.Rx.NQueues {
-				return errors.New("the RSS queue id > maximum requested # of Rx queues")
-			}
+	return nil
+}
+
+// configure the (stopped) ethdev device and its rx and tx queues with the saved configuration parameters
+func (ethdev *Ethdev) configure() error {
+	var status C.int
+	var res error
+	params := &ethdev.params
+	portID := ethdev.port
+
+	// define device rx, tx and rss parameters
+	var conf C.struct_rte_eth_conf
+	conf.rxmode.mq_mode = EthMqRxNone
+	conf.rxmode.mtu = C.uint32_t(params.Rx.Mtu)
+	conf.txmode.mq_mode = EthMqTxNone
+	if params.Rx.Rss != nil {
+		conf.rxmode.mq_mode = EthMqRxRss
+		conf.rx_adv_conf.rss_conf.rss_hf = C.uint64_t(ethdev.rssHf(params.Rx.Rss))
+		if key := ethdev.rssKey(params.Rx.Rss); key != nil {
+			cKey := C.CBytes(key)
+			defer C.free(cKey)
+			conf.rx_adv_conf.rss_conf.rss_key = (*C.uint8_t)(cKey)
+			conf.rx_adv_conf.rss_conf.rss_key_len = C.uint8_t(len(key))
 		}
 	}
 
-	// define device rss parameters
-	var optRss = lled.OptRss(lled.RssConf{})
-	var rxMqMode = EthMqRxNone
-	if rss != nil {
-		rxMqMode = EthMqRxRss
-		optRss = lled.OptRss(lled.RssConf{
-			Hf: (EthRssIP | EthRssTCP | EthRssUDP) & portInfo.FlowTypeRssOffloads(),
-		})
+	// enable link state change and device removal interrupts if supported by the device
+	devFlags := ethdev.portInfo.DeviceFlags()
+	ethdev.intrLsc = devFlags&RteEthDevIntrLsc != 0
+	ethdev.intrRmv = devFlags&RteEthDevIntrRmv != 0
+	C.ethdev_intr_conf_set(&conf, boolToUint32(ethdev.intrLsc), boolToUint32(ethdev.intrRmv)) //nolint:gocritic
+	if !ethdev.intrLsc {
+		log.Infof("PMD %s does not support link state change interrupts", ethdev.Name())
 	}
 
 	// configure the ethdev device
-	res = portID.DevConfigure(params.Rx.NQueues, params.Tx.NQueues,
-		lled.OptLinkSpeeds(0),
-		lled.OptRxMode(lled.RxMode{MqMode: uint(rxMqMode), MTU: uint32(mtu), SplitHdrSize: 0}),
-		lled.OptTxMode(lled.TxMode{MqMode: EthMqTxNone}),
-		optRss,
-		lled.OptLoopbackMode(0),
-	)
+	res = ethdev.devConfigure(params.Rx.NQueues, params.Tx.NQueues, &conf)
 	if res != nil {
-		return common.Err(status)
+		return res
 	}
 
 	// if requested set deviceport to promiscuous mode
@@ -164,6 +222,17 @@ func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
 		}
 	}
 
+	// if requested set deviceport to allmulticast mode
+	if params.Allmulticast {
+		res = ethdev.AllmulticastEnable()
+		if res != nil {
+			if !errors.Is(res, syscall.ENOTSUP) {
+				return res
+			}
+			log.Infof("PMD %s does not support allmulticast mode", ethdev.Name())
+		}
+	}
+
 	// is the ethdev device connected to a specific CPU Socket?
 	cpuID := portID.SocketID()
 	if cpuID == C.SOCKET_ID_ANY {
@@ -195,21 +264,26 @@ func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
 		}
 	}
 
-	// initialize queue setup for pipeline bind use
-	ethdev.InitializeQueues(params.Rx.NQueues, params.Tx.NQueues)
+	return nil
+}
+
+// start the configured ethdev device, setup rss (receive side scaling) and set the link up
+func (ethdev *Ethdev) start() error {
+	portID := ethdev.port
+	rss := ethdev.params.Rx.Rss
 
 	// Device start
-	res = portID.Start()
+	res := portID.Start()
 	if res != nil {
 		return res
 	}
 
-	// configure device rss (receive side scaling) settings
+	// configure device rss (receive side scaling) redirection table
 	if rss != nil {
-		status = (C.int)(rssSetup(portID, portInfo.RetaSize(), rss))
-		if status != 0 {
+		reta := FillReta(rss.queues(ethdev.params.Rx.NQueues), ethdev.portInfo.RetaSize())
+		if res = ethdev.RetaUpdate(reta); res != nil {
 			portID.Stop()
-			return common.Err(status)
+			return res
 		}
 	}
 
@@ -223,13 +297,6 @@ func (ethdev *Ethdev) Initialize(params *Params, clean func()) error {
 		log.Infof("PMD %s does not support SetLinkUp", ethdev.Name())
 	}
 
-	// Node fill in
-	ethdev.devName, res = portID.Name()
-	if res != nil {
-		return res
-	}
-	ethdev.SetClean(clean)
-
 	return nil
 }
 
@@ -241,6 +308,54 @@ func (ethdev *Ethdev) DevName() string {
 	return ethdev.devName
 }
 
+// Params returns a copy of the current configuration of the port, including the changes made after creation
+func (ethdev *Ethdev) Params() Params {
+	p := ethdev.params
+	if p.Rx.Rss != nil {
+		p.Rx.Rss = p.Rx.Rss.copy()
+	}
+	return p
+}
+
+// NumaNode returns the NUMA node the device of this ethdev port is connected to, -1 if not connected to a specific node
+func (ethdev *Ethdev) NumaNode() int {
+	return ethdev.port.SocketID()
+}
+
+// PortNumaNode returns the NUMA node the device of the DPDK port with the given name is connected to, -1 if not
+// connected to a specific node
+func PortNumaNode(portName string) (int, error) {
+	portID, err := lled.GetPortByName(portName)
+	if err != nil {
+		return -1, err
+	}
+	return portID.SocketID(), nil
+}
+
+// GetPortID returns the DPDK port id of the ethdev port with the given name
+func GetPortID(portName string) (uint16, error) {
+	portID, err := lled.GetPortByName(portName)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(portID), nil
+}
+
+// PortID returns the DPDK port id of this ethdev port
+func (ethdev *Ethdev) PortID() uint16 {
+	return uint16(ethdev.port)
+}
+
+// IntrLsc returns true if link state change interrupts are enabled on this port
+func (ethdev *Ethdev) IntrLsc() bool {
+	return ethdev.intrLsc
+}
+
+// IntrRmv returns true if device removal interrupts are enabled on this port
+func (ethdev *Ethdev) IntrRmv() bool {
+	return ethdev.intrRmv
+}
+
 func (ethdev *Ethdev) SamePort(ethdev2 *Ethdev) bool {
 	return ethdev.port == ethdev2.port
 }
@@ -248,6 +363,11 @@ func (ethdev *Ethdev) SamePort(ethdev2 *Ethdev) bool {
 // Free deletes the current Ethdev record and calls the clean callback function given at init
 func (ethdev *Ethdev) Free() error {
 	// Release all resources for this port
+	if len(ethdev.flows) > 0 {
+		if err := ethdev.FlowFlush(); err != nil {
+			log.Warnf("ethdev %s flow rules flush error: %v", ethdev.Name(), err)
+		}
+	}
 	ethdev.port.Stop()
 
 	// call given clean callback function if given during init
@@ -258,31 +378,6 @@ func (ethdev *Ethdev) Free() error {
 	return nil
 }
 
-// TODO Rewrite this function to portId.RssRetaUpdate!
-func rssSetup(portID lled.Port, retaSize uint16, rss ParamsRss) int {
-	var retaConf [RetaConfSize]C.struct_rte_eth_rss_reta_entry64
-	var i uint16
-	var status int
-
-	// RETA setting, ethdev retasize is always a multiple of RTE_ETH_RETA_GROUP_SIZE!
-	for i = 0; i < retaSize; i++ {
-		retaConf[i/EthRetaGroupSize].mask = C.UINT64_MAX
-	}
-
-	for i = 0; i < retaSize; i++ {
-		retaID := (C.uint32_t)(i / EthRetaGroupSize)
-		retaPos := (C.uint32_t)(i % EthRetaGroupSize)
-		rssQsPos := (C.uint32_t)(i % (uint16)(len(rss)))
-
-		retaConf[retaID].reta[retaPos] = (C.uint16_t)(rss[rssQsPos])
-	}
-
-	// portId.RssRetaUpdate(([]ethdev.RssRetaEntry64)(reta_conf), reta_size)
-	// RETA update
-	status = (int)(C.rte_eth_dev_rss_reta_update((C.ushort)(portID), &retaConf[0], (C.ushort)(retaSize)))
-	return status
-}
-
 type SwxPortEthdevParams struct {
 	rxParams  *C.struct_rte_swx_port_ethdev_reader_params
 	txParams  *C.struct_rte_swx_port_ethdev_writer_params
@@ -363,7 +458,7 @@ func (ethdev *Ethdev) BindToPipelineInputPort(pl *pipeline.Pipeline, portID int,
 		return err
 	}
 
-	return ethdev.SetRxQueue(rxq, pl.GetName(), portID)
+	return ethdev.SetRxQueue(rxq, pl.GetName(), portID, bsz)
 }
 
 // bind to given pipeline output port
@@ -384,7 +479,7 @@ func (ethdev *Ethdev) BindToPipelineOutputPort(pl *pipeline.Pipeline, portID int
 		return err
 	}
 
-	return ethdev.SetTxQueue(txq, pl.GetName(), portID)
+	return ethdev.SetTxQueue(txq, pl.GetName(), portID, bsz)
 }
 
 func (ethdev *Ethdev) IsUp() (bool, error) {
@@ -396,6 +491,17 @@ func (ethdev *Ethdev) IsUp() (bool, error) {
 	return linkParams.Status(), nil
 }
 
+// LinkGet returns the link status, speed (in Mbps) and duplex mode of the port without waiting for the link to
+// settle. It is safe to use in (the handling of) link state change events.
+func (ethdev *Ethdev) LinkGet() (up bool, speed uint32, fullDuplex bool, err error) {
+	link, err := ethdev.port.EthLinkGetNowait()
+	if err != nil {
+		return false, 0, false, err
+	}
+
+	return link.Status(), link.Speed(), link.Duplex(), nil
+}
+
 func (ethdev *Ethdev) SetLinkUp() error {
 	err := ethdev.port.SetLinkUp()
 	if err != nil {
@@ -423,6 +529,153 @@ func (ethdev *Ethdev) SetLinkDown() error {
 	return nil
 }
 
+// SetMTU changes the MTU of the ethdev port. The new MTU must be within the MTU range supported by the port.
+func (ethdev *Ethdev) SetMTU(mtu uint16) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if mtu < ethdev.portInfo.MinMTU() || mtu > ethdev.portInfo.MaxMTU() {
+		return fmt.Errorf("requested MTU is smaller than minimum MTU (%d) or larger then maximum MTU (%d) supported for "+
+			"this port", ethdev.portInfo.MinMTU(), ethdev.portInfo.MaxMTU())
+	}
+
+	if err := ethdev.MTUSet(mtu); err != nil {
+		return err
+	}
+
+	ethdev.params.Rx.Mtu = mtu
+	return nil
+}
+
+// SetMACAddr sets the default (primary) MAC address of the ethdev port.
+func (ethdev *Ethdev) SetMACAddr(addr net.HardwareAddr) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	return ethdev.DefaultMACAddrSet(addr)
+}
+
+// AddMACAddr adds a secondary MAC address to the receive filter of the ethdev port.
+func (ethdev *Ethdev) AddMACAddr(addr net.HardwareAddr) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	return ethdev.MACAddrAdd(addr, 0)
+}
+
+// SetPromiscuous enables or disables promiscuous mode on the ethdev port.
+func (ethdev *Ethdev) SetPromiscuous(on bool) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	var err error
+	if on {
+		err = ethdev.port.PromiscEnable()
+	} else {
+		err = ethdev.port.PromiscDisable()
+	}
+	if err != nil {
+		return err
+	}
+
+	ethdev.params.Promiscuous = on
+	return nil
+}
+
+// SetAllmulticast enables or disables the receipt of all multicast packets on the ethdev port.
+func (ethdev *Ethdev) SetAllmulticast(on bool) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	var err error
+	if on {
+		err = ethdev.AllmulticastEnable()
+	} else {
+		err = ethdev.AllmulticastDisable()
+	}
+	if err != nil {
+		return err
+	}
+
+	ethdev.params.Allmulticast = on
+	return nil
+}
+
+// ReconfigureQueues stops the ethdev port, reconfigures the number and size of its receive and transmit queues and
+// restarts the port. Queues bound to a pipeline port keep their binding, so the number of queues can't be reduced below
+// a bound queue. Pipelines using this port should be disabled while the queues are reconfigured. When the device
+// can't be restarted with the new queues it is restarted with the previous queues, an error is only returned when
+// that also fails.
+func (ethdev *Ethdev) ReconfigureQueues(nRxQ uint16, rxQSize uint32, nTxQ uint16, txQSize uint32) error {
+	if nRxQ == 0 || rxQSize == 0 || nTxQ == 0 || txQSize == 0 {
+		return errors.New("parameter error")
+	}
+
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if err := ethdev.checkQueues(nRxQ, nTxQ, ethdev.params.Rx.Rss); err != nil {
+		return err
+	}
+
+	// resize the pipeline queue administration first, this fails when bound queues would be removed
+	prevParams := ethdev.params
+	if err := ethdev.ResizeQueues(nRxQ, nTxQ); err != nil {
+		return err
+	}
+
+	ethdev.params.Rx.NQueues = nRxQ
+	ethdev.params.Rx.QueueSize = rxQSize
+	ethdev.params.Tx.NQueues = nTxQ
+	ethdev.params.Tx.QueueSize = txQSize
+
+	// remove the flow rules, stop, reconfigure and restart the device and create the flow rules again
+	if err := flow.Flush(ethdev.PortID()); err != nil {
+		log.Warnf("ethdev %s flow rules flush error: %v", ethdev.Name(), err)
+	}
+	ethdev.port.Stop()
+	err := ethdev.restart()
+	if err == nil {
+		log.Infof("ethdev %s queues reconfigured (rx: %d/%d tx: %d/%d)", ethdev.Name(), nRxQ, rxQSize, nTxQ, txQSize)
+		return nil
+	}
+
+	// roll back to the previous queue configuration, only an error when that also fails
+	log.Errorf("ethdev %s queue reconfiguration failed, restoring previous queues: %v", ethdev.Name(), err)
+	ethdev.port.Stop()
+	ethdev.params = prevParams
+	if rbErr := ethdev.ResizeQueues(prevParams.Rx.NQueues, prevParams.Tx.NQueues); rbErr != nil {
+		return fmt.Errorf("reconfiguring queues of %s failed: %v, restoring queue administration failed: %w",
+			ethdev.Name(), err, rbErr)
+	}
+	if rbErr := ethdev.restart(); rbErr != nil {
+		return fmt.Errorf("reconfiguring queues of %s failed: %v, restoring previous queues failed: %w",
+			ethdev.Name(), err, rbErr)
+	}
+
+	log.Infof("ethdev %s previous queues restored (rx: %d/%d tx: %d/%d)", ethdev.Name(), prevParams.Rx.NQueues,
+		prevParams.Rx.QueueSize, prevParams.Tx.NQueues, prevParams.Tx.QueueSize)
+	return nil
+}
+
+// configure and start the stopped device with the current parameters and create the flow rules again
+func (ethdev *Ethdev) restart() error {
+	if err := ethdev.configure(); err != nil {
+		return err
+	}
+	if err := ethdev.start(); err != nil {
+		return err
+	}
+	ethdev.flowsRecreate()
+	return nil
+}
+
 func (ethdev *Ethdev) GetPortStats() (map[string]string, error) {
 	var stats lled.Stats
 	info := make(map[string]string)
@@ -469,6 +722,12 @@ func (ethdev *Ethdev) GetPortInfo() (map[string]string, error) {
 	}
 	info["speed"] = RteEthLinkSpeedToString(linkParams.Speed())
 	info["promiscuous"] = PromiscuousModeStr[ethdev.PromiscuousGet()]
+	info["allmulticast"] = PromiscuousModeStr[ethdev.AllmulticastGet()]
+
+	mtu, err := ethdev.MTUGet()
+	if err == nil {
+		info["mtu"] = fmt.Sprintf("%d", mtu)
+	}
 
 	var addr = &lled.MACAddr{}
 	err = ethdev.port.MACAddrGet(addr)
@@ -802,10 +1061,95 @@ func (ethdev *Ethdev) OwnerGet() (*DevOwner, error) {
 
 var PromiscuousModeStr = [2]string{0: "off", 1: "on"}
 
+// devConfigure configures the ethdev device with the given configuration structure. Needed instead of the go-dpdk
+// DevConfigure because go-dpdk doesn't support setting the interrupt configuration.
+func (ethdev *Ethdev) devConfigure(nRxQ uint16, nTxQ uint16, conf *C.struct_rte_eth_conf) error {
+	return common.Err(C.rte_eth_dev_configure(C.uint16_t(ethdev.port), C.uint16_t(nRxQ), C.uint16_t(nTxQ), conf))
+}
+
+func boolToUint32(b bool) C.uint32_t {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func (ethdev *Ethdev) PromiscuousGet() int {
 	return int(C.rte_eth_promiscuous_get(C.ushort(ethdev.port)))
 }
 
+func (ethdev *Ethdev) AllmulticastGet() int {
+	return int(C.rte_eth_allmulticast_get(C.ushort(ethdev.port)))
+}
+
+// Enable the receipt of any multicast frame by the ethdev device.
+func (ethdev *Ethdev) AllmulticastEnable() error {
+	return common.Err(C.rte_eth_allmulticast_enable(C.ushort(ethdev.port)))
+}
+
+// Disable the receipt of all multicast frames by the ethdev device.
+func (ethdev *Ethdev) AllmulticastDisable() error {
+	return common.Err(C.rte_eth_allmulticast_disable(C.ushort(ethdev.port)))
+}
+
+// Retrieve the MTU of the ethdev device.
+func (ethdev *Ethdev) MTUGet() (uint16, error) {
+	var mtu C.uint16_t
+
+	err := common.Err(C.rte_eth_dev_get_mtu(C.ushort(ethdev.port), &mtu))
+	return uint16(mtu), err
+}
+
+// Change the MTU of the ethdev device.
+func (ethdev *Ethdev) MTUSet(mtu uint16) error {
+	return common.Err(C.rte_eth_dev_set_mtu(C.ushort(ethdev.port), C.uint16_t(mtu)))
+}
+
+// convert a Go hardware address to a DPDK ethernet address
+func toEtherAddr(addr net.HardwareAddr) (*C.struct_rte_ether_addr, error) {
+	var etherAddr C.struct_rte_ether_addr
+
+	if len(addr) != C.RTE_ETHER_ADDR_LEN {
+		return nil, fmt.Errorf("invalid MAC address: %v", addr)
+	}
+
+	for i := range addr {
+		etherAddr.addr_bytes[i] = C.uint8_t(addr[i])
+	}
+
+	return &etherAddr, nil
+}
+
+// Set the default MAC address of the ethdev device.
+func (ethdev *Ethdev) DefaultMACAddrSet(addr net.HardwareAddr) error {
+	etherAddr, err := toEtherAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	return common.Err(C.rte_eth_dev_default_mac_addr_set(C.ushort(ethdev.port), etherAddr))
+}
+
+// Add a MAC address to the set used for filtering incoming packets in the given VMDq pool of the ethdev device.
+func (ethdev *Ethdev) MACAddrAdd(addr net.HardwareAddr, pool uint32) error {
+	etherAddr, err := toEtherAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	return common.Err(C.rte_eth_dev_mac_addr_add(C.ushort(ethdev.port), etherAddr, C.uint32_t(pool)))
+}
+
+// Remove a MAC address from the set used for filtering incoming packets of the ethdev device.
+func (ethdev *Ethdev) MACAddrRemove(addr net.HardwareAddr) error {
+	etherAddr, err := toEtherAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	return common.Err(C.rte_eth_dev_mac_addr_remove(C.ushort(ethdev.port), etherAddr))
+}
+
 func RteEthDevTxOffloadName(txOffload uint64) string {
 	// no free needed, returned C string is static!
 	cTxOffloadName := C.rte_eth_dev_tx_offload_name(C.uint64_t(txOffload))
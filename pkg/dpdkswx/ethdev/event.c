@@ -0,0 +1,29 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#include <stdint.h>
+
+#include <rte_ethdev.h>
+
+#include "event.h"
+#include "_cgo_export.h"
+
+// Called by DPDK from the interrupt thread, keep it short and forward the event to the Go side.
+static int ethdev_event_callback(uint16_t port_id, enum rte_eth_event_type event, void *cb_arg __rte_unused,
+	void *ret_param __rte_unused) {
+	ethdevEventCallback(port_id, (int)event);
+	return 0;
+}
+
+int ethdev_event_callback_register(enum rte_eth_event_type event) {
+	return rte_eth_dev_callback_register(RTE_ETH_ALL, event, ethdev_event_callback, NULL);
+}
+
+int ethdev_event_callback_unregister(enum rte_eth_event_type event) {
+	return rte_eth_dev_callback_unregister(RTE_ETH_ALL, event, ethdev_event_callback, NULL);
+}
+
+void ethdev_intr_conf_set(struct rte_eth_conf *conf, uint32_t lsc, uint32_t rmv) {
+	conf->intr_conf.lsc = lsc ? 1 : 0;
+	conf->intr_conf.rmv = rmv ? 1 : 0;
+}
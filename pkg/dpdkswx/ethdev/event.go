@@ -0,0 +1,103 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package ethdev
+
+/*
+#include <stdint.h>
+#include <rte_ethdev.h>
+
+#include "event.h"
+
+*/
+import "C"
+import (
+	"errors"
+	"sync"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+)
+
+// EventType is the type of an ethdev port event
+type EventType int
+
+const (
+	EventIntrLsc EventType = C.RTE_ETH_EVENT_INTR_LSC // link status change interrupt event
+	EventIntrRmv EventType = C.RTE_ETH_EVENT_INTR_RMV // device removal interrupt event
+)
+
+var eventTypeNames = map[EventType]string{
+	EventIntrLsc: "INTR_LSC",
+	EventIntrRmv: "INTR_RMV",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// EventHandler is called with the DPDK port id and event type for every ethdev event received. It is called from the
+// DPDK interrupt thread and therefore needs to return as fast as possible!
+type EventHandler func(portID uint16, event EventType)
+
+var eventHandlerMu sync.RWMutex
+var eventHandler EventHandler
+
+// EventCallbackRegister registers the given handler for link status change and device removal events of all ethdev
+// ports. Only one handler can be registered at a time.
+func EventCallbackRegister(fn EventHandler) error {
+	eventHandlerMu.Lock()
+	defer eventHandlerMu.Unlock()
+
+	if fn == nil {
+		return errors.New("no event handler given")
+	}
+	if eventHandler != nil {
+		return errors.New("ethdev event handler already registered")
+	}
+
+	if res := C.ethdev_event_callback_register(C.RTE_ETH_EVENT_INTR_LSC); res < 0 {
+		return common.Err(res)
+	}
+	if res := C.ethdev_event_callback_register(C.RTE_ETH_EVENT_INTR_RMV); res < 0 {
+		C.ethdev_event_callback_unregister(C.RTE_ETH_EVENT_INTR_LSC)
+		return common.Err(res)
+	}
+
+	eventHandler = fn
+	return nil
+}
+
+// EventCallbackUnregister removes the registered ethdev event handler.
+func EventCallbackUnregister() error {
+	// clear the handler first so that a callback running in the interrupt thread doesn't block the unregister
+	eventHandlerMu.Lock()
+	registered := eventHandler != nil
+	eventHandler = nil
+	eventHandlerMu.Unlock()
+	if !registered {
+		return nil
+	}
+
+	res1 := C.ethdev_event_callback_unregister(C.RTE_ETH_EVENT_INTR_LSC)
+	res2 := C.ethdev_event_callback_unregister(C.RTE_ETH_EVENT_INTR_RMV)
+	if res1 < 0 {
+		return common.Err(res1)
+	}
+	if res2 < 0 {
+		return common.Err(res2)
+	}
+	return nil
+}
+
+//export ethdevEventCallback
+func ethdevEventCallback(portID C.uint16_t, event C.int) {
+	eventHandlerMu.RLock()
+	defer eventHandlerMu.RUnlock()
+
+	if eventHandler != nil {
+		eventHandler(uint16(portID), EventType(event))
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#ifndef _INCLUDE_EVENT_H_
+#define _INCLUDE_EVENT_H_
+
+#include <stdint.h>
+#include <rte_ethdev.h>
+
+/**
+ * Ethdev event callbacks. The registered callbacks forward the ethdev events of all ports to the Go event handler.
+ */
+
+int ethdev_event_callback_register(enum rte_eth_event_type event);
+int ethdev_event_callback_unregister(enum rte_eth_event_type event);
+
+/**
+ * Set the link state change (lsc) and device removal (rmv) interrupt configuration bitfields of the given ethdev
+ * configuration structure.
+ */
+void ethdev_intr_conf_set(struct rte_eth_conf *conf, uint32_t lsc, uint32_t rmv);
+
+#endif /* _INCLUDE_EVENT_H_ */
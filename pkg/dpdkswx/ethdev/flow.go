@@ -0,0 +1,104 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package ethdev
+
+import (
+	"fmt"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/flow"
+)
+
+// FlowValidate checks whether the given flow rule can be created on this port
+func (ethdev *Ethdev) FlowValidate(rule *flow.Rule) error {
+	return flow.Validate(ethdev.PortID(), rule)
+}
+
+// FlowCreate creates the given flow rule on this port and returns the created flow
+func (ethdev *Ethdev) FlowCreate(rule *flow.Rule) (*flow.Flow, error) {
+	if err := eal.PrimaryOnly(); err != nil {
+		return nil, err
+	}
+
+	f, err := flow.Create(ethdev.PortID(), ethdev.nextFlowID, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	ethdev.nextFlowID++
+	ethdev.flows = append(ethdev.flows, f)
+	log.Infof("ethdev %s flow %d created: %s", ethdev.Name(), f.ID(), rule.String())
+	return f, nil
+}
+
+// FlowList returns the flow rules created on this port, in order of creation
+func (ethdev *Ethdev) FlowList() []*flow.Flow {
+	return append([]*flow.Flow(nil), ethdev.flows...)
+}
+
+// FlowGet returns the flow rule with the given id
+func (ethdev *Ethdev) FlowGet(id uint32) (*flow.Flow, error) {
+	for _, f := range ethdev.flows {
+		if f.ID() == id {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("flow %d doesn't exist on %s", id, ethdev.Name())
+}
+
+// FlowQuery returns the counters of the flow rule with the given id. The flow rule must have a count action.
+func (ethdev *Ethdev) FlowQuery(id uint32) (*flow.QueryCount, error) {
+	f, err := ethdev.FlowGet(id)
+	if err != nil {
+		return nil, err
+	}
+	return f.QueryCount()
+}
+
+// FlowDestroy destroys the flow rule with the given id
+func (ethdev *Ethdev) FlowDestroy(id uint32) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	for i, f := range ethdev.flows {
+		if f.ID() == id {
+			if err := f.Destroy(); err != nil {
+				return err
+			}
+			ethdev.flows = append(ethdev.flows[:i], ethdev.flows[i+1:]...)
+			log.Infof("ethdev %s flow %d destroyed", ethdev.Name(), id)
+			return nil
+		}
+	}
+	return fmt.Errorf("flow %d doesn't exist on %s", id, ethdev.Name())
+}
+
+// FlowFlush destroys all flow rules on this port
+func (ethdev *Ethdev) FlowFlush() error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if err := flow.Flush(ethdev.PortID()); err != nil {
+		return err
+	}
+
+	ethdev.flows = nil
+	return nil
+}
+
+// recreate the flow rules after the port is reconfigured and started again. Flow rules that can't be created anymore
+// (i.e. because of a changed number of queues) are removed.
+func (ethdev *Ethdev) flowsRecreate() {
+	var flows []*flow.Flow
+	for _, f := range ethdev.flows {
+		if err := f.Recreate(); err != nil {
+			log.Warnf("ethdev %s flow %d (%s) removed: %v", ethdev.Name(), f.ID(), f.Rule().String(), err)
+			continue
+		}
+		flows = append(flows, f)
+	}
+	ethdev.flows = flows
+}
@@ -0,0 +1,465 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package ethdev
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+
+#include <rte_ethdev.h>
+
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+)
+
+// ParamsRss defines the RSS (receive side scaling) configuration of an ethdev port
+type ParamsRss struct {
+	Queues    []uint16 // rx queues used (round robin) to fill the redirection table, all rx queues if empty
+	Key       []byte   // hash key, the default key of the device is used if empty
+	Hf        uint64   // hash functions (EthRss* bits), IP, TCP and UDP hashing is used if 0
+	Symmetric bool     // use a symmetric hash key so both directions of a flow are received on the same queue
+}
+
+func (rss *ParamsRss) copy() *ParamsRss {
+	c := *rss
+	c.Queues = append([]uint16(nil), rss.Queues...)
+	c.Key = append([]byte(nil), rss.Key...)
+	return &c
+}
+
+// returns the rx queues to fill the redirection table with, all rx queues if no queues are given
+func (rss *ParamsRss) queues(nRxQ uint16) []uint16 {
+	if len(rss.Queues) > 0 {
+		return rss.Queues
+	}
+	queues := make([]uint16, nRxQ)
+	for i := range queues {
+		queues[i] = uint16(i)
+	}
+	return queues
+}
+
+// check the requested RSS configuration against the capabilities of the device
+func (ethdev *Ethdev) checkRss(nRxQ uint16, rss *ParamsRss) error {
+	portInfo := ethdev.portInfo
+
+	if portInfo.RetaSize() == 0 || portInfo.RetaSize() > EthRssRetaSize512 {
+		return errors.New("ethdev redirection table size (rss) is 0 or too large (>512)")
+	}
+
+	for _, q := range rss.Queues {
+		if q >= nRxQ {
+			return errors.New("the RSS queue id > maximum requested # of Rx queues")
+		}
+	}
+
+	return ethdev.checkRssHash(rss.Key, rss.Hf, rss.Symmetric)
+}
+
+// check the requested RSS hash configuration against the capabilities of the device
+func (ethdev *Ethdev) checkRssHash(key []byte, hf uint64, symmetric bool) error {
+	portInfo := ethdev.portInfo
+
+	if unsupported := hf &^ portInfo.FlowTypeRssOffloads(); unsupported != 0 {
+		return fmt.Errorf("RSS hash functions not supported by device: %s", RssHfString(unsupported))
+	}
+
+	if len(key) > 0 || symmetric {
+		if symmetric && len(key) > 0 {
+			return errors.New("a RSS hash key can't be given when symmetric hashing is requested")
+		}
+		if portInfo.HashKeySize() == 0 {
+			return errors.New("device doesn't support setting the RSS hash key")
+		}
+		if len(key) > 0 && len(key) != int(portInfo.HashKeySize()) {
+			return fmt.Errorf("RSS hash key length (%d) must be equal to the device hash key size (%d)",
+				len(key), portInfo.HashKeySize())
+		}
+	}
+
+	return nil
+}
+
+// returns the hash functions to use for the given RSS configuration
+func (ethdev *Ethdev) rssHf(rss *ParamsRss) uint64 {
+	if rss.Hf == 0 {
+		return (EthRssIP | EthRssTCP | EthRssUDP) & ethdev.portInfo.FlowTypeRssOffloads()
+	}
+	return rss.Hf
+}
+
+// returns the hash key to use for the given RSS configuration, nil if the device default key is to be used
+func (ethdev *Ethdev) rssKey(rss *ParamsRss) []byte {
+	if rss.Symmetric {
+		return RssSymmetricKey(ethdev.portInfo.HashKeySize())
+	}
+	if len(rss.Key) > 0 {
+		return rss.Key
+	}
+	return nil
+}
+
+// RssSymmetricKey returns a Toeplitz hash key of the given size that results in the same hash for both directions of a
+// flow (i.e. with swapped source and destination addresses and ports).
+func RssSymmetricKey(size uint8) []byte {
+	key := make([]byte, size)
+	for i := range key {
+		if i%2 == 0 {
+			key[i] = 0x6d
+		} else {
+			key[i] = 0x5a
+		}
+	}
+	return key
+}
+
+// FillReta returns a redirection table of the given size filled round robin with the given queues.
+func FillReta(queues []uint16, size uint16) []uint16 {
+	reta := make([]uint16, size)
+	if len(queues) == 0 {
+		return reta
+	}
+
+	for i := range reta {
+		reta[i] = queues[i%len(queues)]
+	}
+	return reta
+}
+
+// RssGet returns the current RSS configuration of the port. The returned redirection table queue list is the list
+// given at creation or with SetRssQueues, use RetaGet to read the actual redirection table of the device.
+func (ethdev *Ethdev) RssGet() (*ParamsRss, error) {
+	if ethdev.params.Rx.Rss == nil {
+		return nil, errors.New("RSS is not configured on this port")
+	}
+
+	key, hf, err := ethdev.RssHashConfGet()
+	if err != nil {
+		return nil, err
+	}
+
+	rss := ethdev.params.Rx.Rss.copy()
+	rss.Key = key
+	rss.Hf = hf
+	return rss, nil
+}
+
+// SetRssHash changes the RSS hash key and hash functions of the port. If the key is empty and symmetric hashing is not
+// requested the current key of the device is kept. If hf is 0, IP, TCP and UDP hashing is used.
+func (ethdev *Ethdev) SetRssHash(key []byte, hf uint64, symmetric bool) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if ethdev.params.Rx.Rss == nil {
+		return errors.New("RSS is not configured on this port")
+	}
+
+	if err := ethdev.checkRssHash(key, hf, symmetric); err != nil {
+		return err
+	}
+
+	rss := ethdev.params.Rx.Rss.copy()
+	switch {
+	case len(key) > 0 || symmetric:
+		rss.Key = key
+	case rss.Symmetric:
+		// keep the symmetric key active in the device as explicit key
+		rss.Key = RssSymmetricKey(ethdev.portInfo.HashKeySize())
+	}
+	rss.Hf = hf
+	rss.Symmetric = symmetric
+
+	if err := ethdev.RssHashUpdate(ethdev.rssKey(rss), ethdev.rssHf(rss)); err != nil {
+		return err
+	}
+
+	ethdev.params.Rx.Rss = rss
+	log.Infof("ethdev %s RSS hash changed to %s (symmetric: %t)", ethdev.Name(), RssHfString(ethdev.rssHf(rss)), symmetric)
+	return nil
+}
+
+// SetRssQueues fills the redirection table of the port round robin with the given queues, with all rx queues if no
+// queues are given.
+func (ethdev *Ethdev) SetRssQueues(queues []uint16) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if ethdev.params.Rx.Rss == nil {
+		return errors.New("RSS is not configured on this port")
+	}
+
+	rss := ethdev.params.Rx.Rss.copy()
+	rss.Queues = queues
+	if err := ethdev.checkRss(ethdev.params.Rx.NQueues, rss); err != nil {
+		return err
+	}
+
+	reta := FillReta(rss.queues(ethdev.params.Rx.NQueues), ethdev.portInfo.RetaSize())
+	if err := ethdev.RetaUpdate(reta); err != nil {
+		return err
+	}
+
+	ethdev.params.Rx.Rss = rss
+	log.Infof("ethdev %s RSS queues changed to %v", ethdev.Name(), queues)
+	return nil
+}
+
+/****************************************************************************************
+ * Everything defined below this line is missing in go-dpdk and needs to be upstreamed! *
+ ****************************************************************************************/
+
+// RSS hash function (offload type) bits
+const (
+	EthRssIPv4            = C.RTE_ETH_RSS_IPV4
+	EthRssFragIPv4        = C.RTE_ETH_RSS_FRAG_IPV4
+	EthRssNonfragIPv4TCP  = C.RTE_ETH_RSS_NONFRAG_IPV4_TCP
+	EthRssNonfragIPv4UDP  = C.RTE_ETH_RSS_NONFRAG_IPV4_UDP
+	EthRssNonfragIPv4SCTP = C.RTE_ETH_RSS_NONFRAG_IPV4_SCTP
+	EthRssNonfragIPv4Oth  = C.RTE_ETH_RSS_NONFRAG_IPV4_OTHER
+	EthRssIPv6            = C.RTE_ETH_RSS_IPV6
+	EthRssFragIPv6        = C.RTE_ETH_RSS_FRAG_IPV6
+	EthRssNonfragIPv6TCP  = C.RTE_ETH_RSS_NONFRAG_IPV6_TCP
+	EthRssNonfragIPv6UDP  = C.RTE_ETH_RSS_NONFRAG_IPV6_UDP
+	EthRssNonfragIPv6SCTP = C.RTE_ETH_RSS_NONFRAG_IPV6_SCTP
+	EthRssNonfragIPv6Oth  = C.RTE_ETH_RSS_NONFRAG_IPV6_OTHER
+	EthRssL2Payload       = C.RTE_ETH_RSS_L2_PAYLOAD
+	EthRssIPv6Ex          = C.RTE_ETH_RSS_IPV6_EX
+	EthRssIPv6TCPEx       = C.RTE_ETH_RSS_IPV6_TCP_EX
+	EthRssIPv6UDPEx       = C.RTE_ETH_RSS_IPV6_UDP_EX
+	EthRssPort            = C.RTE_ETH_RSS_PORT
+	EthRssVxlan           = C.RTE_ETH_RSS_VXLAN
+	EthRssGeneve          = C.RTE_ETH_RSS_GENEVE
+	EthRssNvgre           = C.RTE_ETH_RSS_NVGRE
+	EthRssGtpu            = C.RTE_ETH_RSS_GTPU
+	EthRssEth             = C.RTE_ETH_RSS_ETH
+	EthRssSVlan           = C.RTE_ETH_RSS_S_VLAN
+	EthRssCVlan           = C.RTE_ETH_RSS_C_VLAN
+	EthRssEsp             = C.RTE_ETH_RSS_ESP
+	EthRssAh              = C.RTE_ETH_RSS_AH
+	EthRssL2tpv3          = C.RTE_ETH_RSS_L2TPV3
+	EthRssPfcp            = C.RTE_ETH_RSS_PFCP
+	EthRssPppoe           = C.RTE_ETH_RSS_PPPOE
+	EthRssEcpri           = C.RTE_ETH_RSS_ECPRI
+	EthRssMpls            = C.RTE_ETH_RSS_MPLS
+	EthRssIPv4Chksum      = C.RTE_ETH_RSS_IPV4_CHKSUM
+	EthRssL4Chksum        = C.RTE_ETH_RSS_L4_CHKSUM
+	EthRssL3SrcOnly       = C.RTE_ETH_RSS_L3_SRC_ONLY
+	EthRssL3DstOnly       = C.RTE_ETH_RSS_L3_DST_ONLY
+	EthRssL4SrcOnly       = C.RTE_ETH_RSS_L4_SRC_ONLY
+	EthRssL4DstOnly       = C.RTE_ETH_RSS_L4_DST_ONLY
+	EthRssL2SrcOnly       = C.RTE_ETH_RSS_L2_SRC_ONLY
+	EthRssL2DstOnly       = C.RTE_ETH_RSS_L2_DST_ONLY
+
+	EthRssSCTP   = C.RTE_ETH_RSS_SCTP
+	EthRssTunnel = C.RTE_ETH_RSS_TUNNEL
+	EthRssVlan   = C.RTE_ETH_RSS_VLAN
+)
+
+type rssHfName struct {
+	name string
+	hf   uint64
+}
+
+// names of the single RSS hash function bits, in bit order
+var rssHfNames = []rssHfName{
+	{"ipv4", EthRssIPv4},
+	{"frag-ipv4", EthRssFragIPv4},
+	{"ipv4-tcp", EthRssNonfragIPv4TCP},
+	{"ipv4-udp", EthRssNonfragIPv4UDP},
+	{"ipv4-sctp", EthRssNonfragIPv4SCTP},
+	{"ipv4-other", EthRssNonfragIPv4Oth},
+	{"ipv6", EthRssIPv6},
+	{"frag-ipv6", EthRssFragIPv6},
+	{"ipv6-tcp", EthRssNonfragIPv6TCP},
+	{"ipv6-udp", EthRssNonfragIPv6UDP},
+	{"ipv6-sctp", EthRssNonfragIPv6SCTP},
+	{"ipv6-other", EthRssNonfragIPv6Oth},
+	{"l2-payload", EthRssL2Payload},
+	{"ipv6-ex", EthRssIPv6Ex},
+	{"ipv6-tcp-ex", EthRssIPv6TCPEx},
+	{"ipv6-udp-ex", EthRssIPv6UDPEx},
+	{"port", EthRssPort},
+	{"vxlan", EthRssVxlan},
+	{"geneve", EthRssGeneve},
+	{"nvgre", EthRssNvgre},
+	{"gtpu", EthRssGtpu},
+	{"eth", EthRssEth},
+	{"s-vlan", EthRssSVlan},
+	{"c-vlan", EthRssCVlan},
+	{"esp", EthRssEsp},
+	{"ah", EthRssAh},
+	{"l2tpv3", EthRssL2tpv3},
+	{"pfcp", EthRssPfcp},
+	{"pppoe", EthRssPppoe},
+	{"ecpri", EthRssEcpri},
+	{"mpls", EthRssMpls},
+	{"ipv4-chksum", EthRssIPv4Chksum},
+	{"l4-chksum", EthRssL4Chksum},
+	{"l2-src-only", EthRssL2SrcOnly},
+	{"l2-dst-only", EthRssL2DstOnly},
+	{"l4-dst-only", EthRssL4DstOnly},
+	{"l4-src-only", EthRssL4SrcOnly},
+	{"l3-dst-only", EthRssL3DstOnly},
+	{"l3-src-only", EthRssL3SrcOnly},
+}
+
+// names of often used groups of RSS hash function bits
+var rssHfGroupNames = []rssHfName{
+	{"ip", EthRssIP},
+	{"tcp", EthRssTCP},
+	{"udp", EthRssUDP},
+	{"sctp", EthRssSCTP},
+	{"tunnel", EthRssTunnel},
+	{"vlan", EthRssVlan},
+}
+
+// RssHfNameList returns all RSS hash function and hash function group names accepted by ParseRssHf
+func RssHfNameList() []string {
+	var names []string
+	for _, n := range rssHfGroupNames {
+		names = append(names, n.name)
+	}
+	for _, n := range rssHfNames {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+// ParseRssHf returns the RSS hash function bits of the given hash function and hash function group names
+func ParseRssHf(names []string) (uint64, error) {
+	var hf uint64
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, n := range append(rssHfGroupNames, rssHfNames...) {
+			if n.name == name {
+				hf |= n.hf
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown RSS hash function: %s", name)
+		}
+	}
+
+	return hf, nil
+}
+
+// RssHfString returns the names of the given RSS hash function bits as a space separated string
+func RssHfString(hf uint64) string {
+	var names []string
+	for _, n := range rssHfNames {
+		if hf&n.hf != 0 {
+			names = append(names, n.name)
+			hf &^= n.hf
+		}
+	}
+	if hf != 0 {
+		names = append(names, fmt.Sprintf("0x%x", hf))
+	}
+	return strings.Join(names, " ")
+}
+
+// Size of the hash key in bytes
+func (info *DevInfo) HashKeySize() uint8 {
+	return uint8(info.hash_key_size)
+}
+
+// RssHashConfGet returns the RSS hash key and hash functions currently configured in the device.
+func (ethdev *Ethdev) RssHashConfGet() ([]byte, uint64, error) {
+	var conf C.struct_rte_eth_rss_conf
+
+	keySize := ethdev.portInfo.HashKeySize()
+	if keySize > 0 {
+		conf.rss_key = (*C.uint8_t)(C.malloc(C.size_t(keySize)))
+		defer C.free(unsafe.Pointer(conf.rss_key))
+		conf.rss_key_len = C.uint8_t(keySize)
+	}
+
+	if err := common.Err(C.rte_eth_dev_rss_hash_conf_get(C.uint16_t(ethdev.port), &conf)); err != nil { //nolint:gocritic
+		return nil, 0, err
+	}
+
+	var key []byte
+	if keySize > 0 {
+		key = C.GoBytes(unsafe.Pointer(conf.rss_key), C.int(conf.rss_key_len))
+	}
+	return key, uint64(conf.rss_hf), nil
+}
+
+// RssHashUpdate updates the RSS hash key and hash functions of the device. The key of the device is not changed if the
+// given key is empty.
+func (ethdev *Ethdev) RssHashUpdate(key []byte, hf uint64) error {
+	var conf C.struct_rte_eth_rss_conf
+
+	conf.rss_hf = C.uint64_t(hf)
+	if len(key) > 0 {
+		cKey := C.CBytes(key)
+		defer C.free(cKey)
+		conf.rss_key = (*C.uint8_t)(cKey)
+		conf.rss_key_len = C.uint8_t(len(key))
+	}
+
+	return common.Err(C.rte_eth_dev_rss_hash_update(C.uint16_t(ethdev.port), &conf)) //nolint:gocritic
+}
+
+// RetaGet returns the redirection table (queue per table entry) of the device.
+func (ethdev *Ethdev) RetaGet() ([]uint16, error) {
+	var retaConf [EthRssRetaSize512 / EthRetaGroupSize]C.struct_rte_eth_rss_reta_entry64
+
+	retaSize := ethdev.portInfo.RetaSize()
+	if retaSize == 0 || retaSize > EthRssRetaSize512 {
+		return nil, errors.New("ethdev redirection table size (rss) is 0 or too large (>512)")
+	}
+
+	for i := uint16(0); i < retaSize; i += EthRetaGroupSize {
+		retaConf[i/EthRetaGroupSize].mask = C.UINT64_MAX
+	}
+
+	status := C.rte_eth_dev_rss_reta_query(C.uint16_t(ethdev.port), &retaConf[0], C.uint16_t(retaSize))
+	if status != 0 {
+		return nil, common.Err(status)
+	}
+
+	reta := make([]uint16, retaSize)
+	for i := range reta {
+		reta[i] = uint16(retaConf[i/EthRetaGroupSize].reta[i%EthRetaGroupSize])
+	}
+	return reta, nil
+}
+
+// RetaUpdate sets the redirection table (queue per table entry) of the device. The length of the given table must be
+// equal to the redirection table size of the device.
+func (ethdev *Ethdev) RetaUpdate(reta []uint16) error {
+	var retaConf [EthRssRetaSize512 / EthRetaGroupSize]C.struct_rte_eth_rss_reta_entry64
+
+	retaSize := ethdev.portInfo.RetaSize()
+	if retaSize == 0 || retaSize > EthRssRetaSize512 {
+		return errors.New("ethdev redirection table size (rss) is 0 or too large (>512)")
+	}
+	if len(reta) != int(retaSize) {
+		return fmt.Errorf("redirection table length (%d) must be equal to the device table size (%d)", len(reta), retaSize)
+	}
+
+	// ethdev retasize is always a multiple of RTE_ETH_RETA_GROUP_SIZE!
+	for i, q := range reta {
+		retaConf[i/EthRetaGroupSize].mask = C.UINT64_MAX
+		retaConf[i/EthRetaGroupSize].reta[i%EthRetaGroupSize] = C.uint16_t(q)
+	}
+
+	status := C.rte_eth_dev_rss_reta_update(C.uint16_t(ethdev.port), &retaConf[0], C.uint16_t(retaSize))
+	return common.Err(status)
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package ethdev
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRssQueues(t *testing.T) {
+	// an empty queue list uses all rx queues
+	assert.Equal(t, []uint16{0, 1, 2, 3}, (&ParamsRss{}).queues(4))
+	assert.Equal(t, []uint16{0, 1, 2, 3}, (&ParamsRss{Queues: []uint16{}}).queues(4))
+	assert.Equal(t, []uint16{2, 0}, (&ParamsRss{Queues: []uint16{2, 0}}).queues(4))
+}
+
+func TestFillReta(t *testing.T) {
+	tests := []struct {
+		name   string
+		queues []uint16
+		size   uint16
+		reta   []uint16
+	}{
+		{"no queues", nil, 4, []uint16{0, 0, 0, 0}},
+		{"one queue", []uint16{3}, 4, []uint16{3, 3, 3, 3}},
+		{"round robin", []uint16{0, 1, 2}, 8, []uint16{0, 1, 2, 0, 1, 2, 0, 1}},
+		{"queue order", []uint16{2, 0}, 4, []uint16{2, 0, 2, 0}},
+		{"more queues than entries", []uint16{0, 1, 2, 3}, 2, []uint16{0, 1}},
+		{"no entries", []uint16{0, 1}, 0, []uint16{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.reta, FillReta(test.queues, test.size), test.name)
+	}
+}
+
+func TestParseRssHf(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		hf    uint64
+		err   string
+	}{
+		{"none", nil, 0, ""},
+		{"single", []string{"ipv4-tcp"}, EthRssNonfragIPv4TCP, ""},
+		{"group", []string{"ip"}, EthRssIP, ""},
+		{"combined", []string{"ipv4", "ipv6-udp", "l3-src-only"}, EthRssIPv4 | EthRssNonfragIPv6UDP | EthRssL3SrcOnly, ""},
+		{"case and spaces", []string{" IPv4 ", "TCP"}, EthRssIPv4 | EthRssTCP, ""},
+		{"unknown", []string{"ipv4", "ipv7"}, 0, "unknown RSS hash function: ipv7"},
+		{"empty name", []string{""}, 0, "unknown RSS hash function: "},
+	}
+
+	for _, test := range tests {
+		hf, err := ParseRssHf(test.names)
+		if test.err == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.err, test.name)
+		}
+		assert.Equal(t, test.hf, hf, test.name)
+	}
+}
+
+func TestRssHfString(t *testing.T) {
+	assert.Equal(t, "", RssHfString(0))
+	assert.Equal(t, "ipv4 ipv6-udp", RssHfString(EthRssNonfragIPv6UDP|EthRssIPv4))
+
+	// every name and group round trips through the string
+	for _, name := range RssHfNameList() {
+		hf, err := ParseRssHf([]string{name})
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		parsed, err := ParseRssHf(strings.Fields(RssHfString(hf)))
+		assert.NoError(t, err, name)
+		assert.Equal(t, hf, parsed, name)
+	}
+
+	// unnamed bits are given as hex value
+	assert.Equal(t, "ipv4 0x200000000000", RssHfString(EthRssIPv4|1<<45))
+}
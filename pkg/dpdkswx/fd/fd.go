@@ -0,0 +1,402 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package fd
+
+/*
+#define _GNU_SOURCE
+#include <stdlib.h>
+#include <string.h>
+#include <bsd/string.h>
+#include <errno.h>
+#include <sched.h>
+#include <arpa/inet.h>
+#include <net/if.h>
+#include <linux/if_ether.h>
+#include <linux/if_packet.h>
+#include <sys/ioctl.h>
+#include <sys/socket.h>
+#include <fcntl.h>
+#include <unistd.h>
+
+#include <rte_swx_port_fd.h>
+
+// Create a non blocking AF_PACKET socket bound to the given interface. Returns the socket fd and the MTU of the
+// interface or -1 with errno set.
+int fd_packet_socket(const char *name, int promisc, int *mtu) {
+	struct sockaddr_ll sll;
+	struct packet_mreq mreq;
+	struct ifreq ifr;
+	int fd, ifindex, err;
+	int one = 1;
+
+	ifindex = if_nametoindex(name);
+	if (ifindex == 0)
+		return -1;
+
+	fd = socket(AF_PACKET, SOCK_RAW | SOCK_NONBLOCK | SOCK_CLOEXEC, htons(ETH_P_ALL));
+	if (fd < 0)
+		return fd;
+
+	memset(&sll, 0, sizeof(sll));
+	sll.sll_family = AF_PACKET;
+	sll.sll_protocol = htons(ETH_P_ALL);
+	sll.sll_ifindex = ifindex;
+	if (bind(fd, (struct sockaddr *) &sll, sizeof(sll)) < 0)
+		goto error;
+
+#ifdef PACKET_IGNORE_OUTGOING
+	// don't read back the packets written to this socket
+	if (setsockopt(fd, SOL_PACKET, PACKET_IGNORE_OUTGOING, &one, sizeof(one)) < 0)
+		goto error;
+#endif
+
+	if (promisc) {
+		memset(&mreq, 0, sizeof(mreq));
+		mreq.mr_ifindex = ifindex;
+		mreq.mr_type = PACKET_MR_PROMISC;
+		if (setsockopt(fd, SOL_PACKET, PACKET_ADD_MEMBERSHIP, &mreq, sizeof(mreq)) < 0)
+			goto error;
+	}
+
+	memset(&ifr, 0, sizeof(ifr));
+	strlcpy(ifr.ifr_name, name, IFNAMSIZ);
+	if (ioctl(fd, SIOCGIFMTU, &ifr) < 0)
+		goto error;
+	*mtu = ifr.ifr_mtu;
+
+	return fd;
+
+error:
+	err = errno;
+	close(fd);
+	errno = err;
+	return -1;
+}
+
+// Switch the network namespace of the calling thread to the namespace given by path. Returns an fd of the original
+// network namespace of the thread or -1 with errno set.
+int fd_netns_enter(const char *path) {
+	int orig, ns, err;
+
+	orig = open("/proc/thread-self/ns/net", O_RDONLY | O_CLOEXEC);
+	if (orig < 0)
+		return orig;
+
+	ns = open(path, O_RDONLY | O_CLOEXEC);
+	if (ns < 0 || setns(ns, CLONE_NEWNET) < 0) {
+		err = errno;
+		if (ns >= 0)
+			close(ns);
+		close(orig);
+		errno = err;
+		return -1;
+	}
+
+	close(ns);
+	return orig;
+}
+
+// Switch the network namespace of the calling thread back to the namespace given by the fd returned by fd_netns_enter
+int fd_netns_exit(int orig) {
+	int status, err;
+
+	status = setns(orig, CLONE_NEWNET);
+	err = errno;
+	close(orig);
+	errno = err;
+	return status;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"golang.org/x/sys/unix"
+)
+
+const netnsDir = "/var/run/netns"
+
+type Params struct {
+	// Name of an existing Linux interface (i.e. veth, bridge or macvlan) to bind an AF_PACKET socket to. When empty the
+	// given Fd is used.
+	Interface string
+	// Network namespace of the interface, a name (in /var/run/netns) or a path. When empty the namespace of the process
+	// is used.
+	Netns string
+	// Receive all packets on the interface, not only the packets destined to its MAC address
+	Promiscuous bool
+	// An already opened file descriptor (i.e. a socket or pipe) used when Interface is empty. The port takes ownership of
+	// the file descriptor and closes it when the port is freed.
+	Fd int
+	// Maximum packet size read from the file descriptor. When 0 the MTU of the interface is used.
+	Mtu     int
+	Pktmbuf *pktmbuf.Pktmbuf
+}
+
+// Fd represents a file descriptor device
+type Fd struct {
+	*device.Device
+	fd      C.int
+	iface   string
+	netns   string
+	mtu     int
+	pktmbuf *pktmbuf.Pktmbuf
+}
+
+// Create and initialize an Fd device, either by creating an AF_PACKET socket on an existing interface or by using an
+// already opened file descriptor.
+func (f *Fd) Init(name string, params *Params, clean func()) error {
+	var fd, mtu C.int
+	var err error
+
+	if err = eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
+	if params.Pktmbuf == nil {
+		return errors.New("pktmbuf not given")
+	}
+
+	if params.Interface != "" {
+		if fd, mtu, err = packetSocket(params.Interface, params.Netns, params.Promiscuous); err != nil {
+			return fmt.Errorf("AF_PACKET socket on %s: %w", params.Interface, err)
+		}
+	} else {
+		if params.Fd < 3 {
+			return errors.New("no interface or file descriptor (other than stdin, stdout or stderr) given")
+		}
+		if _, err = unix.FcntlInt(uintptr(params.Fd), unix.F_GETFD, 0); err != nil {
+			return fmt.Errorf("file descriptor %d: %w", params.Fd, err)
+		}
+		if err = unix.SetNonblock(params.Fd, true); err != nil {
+			return fmt.Errorf("file descriptor %d: %w", params.Fd, err)
+		}
+		fd = C.int(params.Fd)
+	}
+
+	// Node fill in
+	f.Device = &device.Device{}
+	f.SetType("FD")
+	f.SetName(name)
+	f.fd = fd
+	f.iface = params.Interface
+	f.netns = params.Netns
+	f.mtu = params.Mtu
+	if f.mtu == 0 {
+		f.mtu = int(mtu)
+	}
+	f.pktmbuf = params.Pktmbuf
+	f.InitializeQueues(1, 1) // initialize queue setup for pipeline bind use
+	f.SetClean(clean)
+
+	if f.mtu <= 0 {
+		f.Free()
+		return errors.New("mtu not given")
+	}
+
+	return nil
+}
+
+// create an AF_PACKET socket on the given interface in the given network namespace
+func packetSocket(iface string, netns string, promisc bool) (C.int, C.int, error) {
+	var mtu C.int
+
+	cname := C.CString(iface)
+	defer C.free(unsafe.Pointer(cname))
+
+	if netns == "" {
+		fd, err := C.fd_packet_socket(cname, C.int(boolToInt(promisc)), &mtu)
+		if fd < 0 {
+			return -1, 0, err
+		}
+		return fd, mtu, nil
+	}
+
+	if filepath.Base(netns) == netns {
+		netns = filepath.Join(netnsDir, netns)
+	}
+	cnetns := C.CString(netns)
+	defer C.free(unsafe.Pointer(cnetns))
+
+	// the network namespace is switched for the current thread only, so don't let the goroutine move
+	runtime.LockOSThread()
+	orig, err := C.fd_netns_enter(cnetns)
+	if orig < 0 {
+		runtime.UnlockOSThread()
+		return -1, 0, fmt.Errorf("network namespace %s: %w", netns, err)
+	}
+
+	fd, sockErr := C.fd_packet_socket(cname, C.int(boolToInt(promisc)), &mtu)
+
+	// a thread that couldn't switch back is tainted and is terminated when the goroutine exits while still locked
+	if status, err := C.fd_netns_exit(orig); status < 0 {
+		if fd >= 0 {
+			C.close(fd)
+		}
+		return -1, 0, fmt.Errorf("network namespace switch back: %w", err)
+	}
+	runtime.UnlockOSThread()
+
+	if fd < 0 {
+		return -1, 0, sockErr
+	}
+	return fd, mtu, nil
+}
+
+func (f *Fd) Type() string {
+	return "FD"
+}
+
+// Fd returns the file descriptor of the device
+func (f *Fd) Fd() C.int {
+	return f.fd
+}
+
+// Free closes the file descriptor and calls the clean callback function given at init
+func (f *Fd) Free() error {
+	var err error
+	if f.fd >= 0 {
+		_, err = C.close(f.fd)
+		f.fd = -1
+	}
+
+	// call given clean callback function if given during init
+	if f.Clean() != nil {
+		f.Clean()()
+	}
+
+	return err
+}
+
+func (f *Fd) GetPortInfo() (map[string]string, error) {
+	info := make(map[string]string)
+	info["fd"] = strconv.Itoa(int(f.fd))
+	info["interface"] = f.iface
+	info["netns"] = f.netns
+	info["mtu"] = strconv.Itoa(f.mtu)
+	info["pktmbuf"] = f.pktmbuf.Name()
+	return info, nil
+}
+
+type SwxPortFdParams struct {
+	rxParams  *C.struct_rte_swx_port_fd_reader_params
+	txParams  *C.struct_rte_swx_port_fd_writer_params
+	paramsSet bool
+	name      string
+
+	fd      C.int
+	mtu     uint
+	mempool *C.struct_rte_mempool
+	bsz     uint
+}
+
+func (e *SwxPortFdParams) PortName() string {
+	return e.name
+}
+
+func (e *SwxPortFdParams) PortType() string {
+	return "fd"
+}
+
+func (e *SwxPortFdParams) GetReaderParams() unsafe.Pointer {
+	e.createCParams()
+	return unsafe.Pointer(e.rxParams)
+}
+
+func (e *SwxPortFdParams) GetWriterParams() unsafe.Pointer {
+	e.createCParams()
+	return unsafe.Pointer(e.txParams)
+}
+
+func (e *SwxPortFdParams) FreeParams() {
+	e.freeCParams()
+}
+
+func (e *SwxPortFdParams) createCParams() {
+	if e.paramsSet {
+		return
+	}
+
+	e.paramsSet = true
+	e.rxParams = &C.struct_rte_swx_port_fd_reader_params{
+		fd:         e.fd,
+		mtu:        (C.uint)(e.mtu),
+		mempool:    e.mempool,
+		burst_size: (C.uint)(e.bsz),
+	}
+	e.txParams = &C.struct_rte_swx_port_fd_writer_params{
+		fd:         e.fd,
+		burst_size: (C.uint)(e.bsz),
+	}
+}
+
+func (e *SwxPortFdParams) freeCParams() {
+	if !e.paramsSet {
+		return
+	}
+
+	e.paramsSet = false
+	e.rxParams = nil
+	e.txParams = nil
+}
+
+// bind to given pipeline input port. An fd device has 1 queue so only queue number 0 is valid.
+func (f *Fd) BindToPipelineInputPort(pl *pipeline.Pipeline, portID int, rxq uint16, bsz uint) error {
+	if _, plp, err := f.GetRxQueue(rxq); err != nil {
+		return err
+	} else if plp != device.NotBound {
+		return errors.New("port already bound")
+	}
+
+	params := &SwxPortFdParams{
+		name:    f.Name(),
+		fd:      f.Fd(),
+		mempool: (*C.struct_rte_mempool)(unsafe.Pointer(f.pktmbuf.Mempool())),
+		mtu:     (uint)(f.mtu),
+		bsz:     bsz,
+	}
+	if err := pl.PortInConfig(portID, params); err != nil {
+		return err
+	}
+
+	return f.SetRxQueue(rxq, pl.GetName(), portID, bsz)
+}
+
+// bind to given pipeline output port. An fd device has 1 queue so only queue number 0 is valid.
+func (f *Fd) BindToPipelineOutputPort(pl *pipeline.Pipeline, portID int, txq uint16, bsz uint) error {
+	if _, plp, err := f.GetTxQueue(txq); err != nil {
+		return err
+	} else if plp != device.NotBound {
+		return errors.New("port already bound")
+	}
+
+	params := &SwxPortFdParams{
+		name: f.Name(),
+		fd:   f.Fd(),
+		bsz:  bsz,
+	}
+	if err := pl.PortOutConfig(portID, params); err != nil {
+		return err
+	}
+
+	return f.SetTxQueue(txq, pl.GetName(), portID, bsz)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
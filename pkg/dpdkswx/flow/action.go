@@ -0,0 +1,58 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+/*
+#include <stdlib.h>
+
+#include <rte_flow.h>
+
+*/
+import "C"
+import (
+	"runtime"
+	"unsafe"
+
+	lflow "github.com/yerden/go-dpdk/ethdev/flow"
+)
+
+// Action types supported by the flow rule parser
+const (
+	ActionTypeQueue = lflow.ActionTypeQueue
+	ActionTypeRss   = lflow.ActionTypeRss
+	ActionTypeDrop  = lflow.ActionTypeDrop
+	ActionTypeMark  = lflow.ActionTypeMark
+	ActionTypeCount = lflow.ActionTypeCount
+)
+
+// actionMark attaches an integer value to packets and sets the RTE_MBUF_F_RX_FDIR and RTE_MBUF_F_RX_FDIR_ID mbuf flags.
+type actionMark struct {
+	id   uint32
+	cptr *C.struct_rte_flow_action_mark
+}
+
+var _ lflow.Action = (*actionMark)(nil)
+
+func (action *actionMark) free() {
+	C.free(unsafe.Pointer(action.cptr))
+}
+
+// Reload implements Action interface.
+func (action *actionMark) Reload() {
+	if action.cptr == nil {
+		action.cptr = (*C.struct_rte_flow_action_mark)(C.calloc(1, C.sizeof_struct_rte_flow_action_mark))
+		runtime.SetFinalizer(action, (*actionMark).free)
+	}
+	action.cptr.id = C.uint32_t(action.id)
+}
+
+// Pointer implements Action interface.
+func (action *actionMark) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(action.cptr)
+}
+
+// Type implements Action interface.
+func (action *actionMark) Type() lflow.ActionType {
+	return ActionTypeMark
+}
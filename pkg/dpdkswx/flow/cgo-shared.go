@@ -0,0 +1,9 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+/*
+#cgo pkg-config: libdpdk
+*/
+import "C"
@@ -0,0 +1,153 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+/*
+#include <stdint.h>
+
+#include <rte_flow.h>
+
+static int flow_query_count(uint16_t port_id, struct rte_flow *flow, uint64_t *hits, uint64_t *bytes, int *hits_set,
+	int *bytes_set, struct rte_flow_error *error) {
+	struct rte_flow_query_count count = { .reset = 0 };
+	struct rte_flow_action action = { .type = RTE_FLOW_ACTION_TYPE_COUNT, .conf = NULL };
+	int status;
+
+	status = rte_flow_query(port_id, flow, &action, &count, error);
+	if (status)
+		return status;
+
+	*hits = count.hits;
+	*bytes = count.bytes;
+	*hits_set = count.hits_set;
+	*bytes_set = count.bytes_set;
+	return 0;
+}
+
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"github.com/stolsma/go-p4pack/pkg/logging"
+	lled "github.com/yerden/go-dpdk/ethdev"
+	lflow "github.com/yerden/go-dpdk/ethdev/flow"
+)
+
+var log logging.Logger
+
+func init() {
+	// keep the logger up to date, also after new log config
+	logging.Register("dpdkswx/flow", func(logger logging.Logger) {
+		log = logger
+	})
+}
+
+// Flow is a flow rule created on an ethdev port
+type Flow struct {
+	id     uint32
+	portID uint16
+	rule   *Rule
+	handle *lflow.Flow
+}
+
+// QueryCount is the result of a count action query
+type QueryCount struct {
+	Hits     uint64
+	Bytes    uint64
+	HitsSet  bool
+	BytesSet bool
+}
+
+// add the verbose flow error information if given by the PMD
+func flowErr(err error, flowErr *lflow.Error) error {
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(flowErr.Unwrap(), lflow.ErrTypeNone) {
+		return fmt.Errorf("%s (%w)", flowErr.Error(), err)
+	}
+	return err
+}
+
+// Validate checks whether the flow rule can be created on the given port
+func Validate(portID uint16, rule *Rule) error {
+	var fErr lflow.Error
+	err := lflow.Validate(lled.Port(portID), &rule.attr, rule.pattern, rule.actions, &fErr)
+	return flowErr(err, &fErr)
+}
+
+// Create creates the flow rule on the given port. The given id is used to identify the flow rule.
+func Create(portID uint16, id uint32, rule *Rule) (*Flow, error) {
+	var fErr lflow.Error
+	handle, err := lflow.Create(lled.Port(portID), &rule.attr, rule.pattern, rule.actions, &fErr)
+	if err != nil {
+		return nil, flowErr(err, &fErr)
+	}
+
+	log.Debugf("flow %d created on port %d: %s", id, portID, rule.String())
+	return &Flow{id: id, portID: portID, rule: rule, handle: handle}, nil
+}
+
+// Flush destroys all flow rules on the given port
+func Flush(portID uint16) error {
+	var fErr lflow.Error
+	return flowErr(lflow.Flush(lled.Port(portID), &fErr), &fErr)
+}
+
+// ID returns the identifier of the flow rule
+func (f *Flow) ID() uint32 {
+	return f.id
+}
+
+// Rule returns the definition of the flow rule
+func (f *Flow) Rule() *Rule {
+	return f.rule
+}
+
+// Destroy destroys the flow rule
+func (f *Flow) Destroy() error {
+	var fErr lflow.Error
+	if err := lflow.Destroy(lled.Port(f.portID), f.handle, &fErr); err != nil {
+		return flowErr(err, &fErr)
+	}
+
+	f.handle = nil
+	log.Debugf("flow %d destroyed on port %d", f.id, f.portID)
+	return nil
+}
+
+// Recreate creates the flow rule again, i.e. after the port is reconfigured and the flow rules are removed by the PMD
+func (f *Flow) Recreate() error {
+	var fErr lflow.Error
+	handle, err := lflow.Create(lled.Port(f.portID), &f.rule.attr, f.rule.pattern, f.rule.actions, &fErr)
+	if err != nil {
+		return flowErr(err, &fErr)
+	}
+
+	f.handle = handle
+	return nil
+}
+
+// QueryCount returns the hit and byte counters of a flow rule with a count action
+func (f *Flow) QueryCount() (*QueryCount, error) {
+	var hits, bytes C.uint64_t
+	var hitsSet, bytesSet C.int
+	var fErr lflow.Error
+
+	if !f.rule.HasCount() {
+		return nil, errors.New("flow rule has no count action")
+	}
+
+	status := C.flow_query_count(C.uint16_t(f.portID), (*C.struct_rte_flow)(unsafe.Pointer(f.handle)),
+		&hits, &bytes, &hitsSet, &bytesSet, (*C.struct_rte_flow_error)(unsafe.Pointer(&fErr)))
+	if status != 0 {
+		return nil, flowErr(common.Err(status), &fErr)
+	}
+
+	return &QueryCount{Hits: uint64(hits), Bytes: uint64(bytes), HitsSet: hitsSet != 0, BytesSet: bytesSet != 0}, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+/*
+#include <stdlib.h>
+#include <string.h>
+
+#include <rte_flow.h>
+
+*/
+import "C"
+import (
+	"runtime"
+	"unsafe"
+
+	lflow "github.com/yerden/go-dpdk/ethdev/flow"
+)
+
+// Protocol header sizes and pattern item types supported by the flow rule parser
+const (
+	ethHdrLen   = 14
+	vlanHdrLen  = 4
+	ipv4HdrLen  = 20
+	ipv6HdrLen  = 40
+	udpHdrLen   = 8
+	tcpHdrLen   = 20
+	vxlanHdrLen = 8
+
+	ItemTypeEth   = lflow.ItemTypeEth
+	ItemTypeVlan  = lflow.ItemTypeVlan
+	ItemTypeIPv4  = lflow.ItemTypeIPv4
+	ItemTypeIPv6  = lflow.ItemTypeIPv6
+	ItemTypeUDP   = lflow.ItemTypeUDP
+	ItemTypeTCP   = lflow.ItemTypeTCP
+	ItemTypeVxlan = lflow.ItemTypeVxlan
+)
+
+// size of the rte_flow_item_* structure per supported item type
+var itemSizes = map[lflow.ItemType]C.size_t{
+	ItemTypeEth:   C.sizeof_struct_rte_flow_item_eth,
+	ItemTypeVlan:  C.sizeof_struct_rte_flow_item_vlan,
+	ItemTypeIPv4:  C.sizeof_struct_rte_flow_item_ipv4,
+	ItemTypeIPv6:  C.sizeof_struct_rte_flow_item_ipv6,
+	ItemTypeUDP:   C.sizeof_struct_rte_flow_item_udp,
+	ItemTypeTCP:   C.sizeof_struct_rte_flow_item_tcp,
+	ItemTypeVxlan: C.sizeof_struct_rte_flow_item_vxlan,
+}
+
+// headerItem is a pattern item spec or mask defined by the raw (network byte order) protocol header. All supported
+// rte_flow_item_* structures start with the protocol header so the header bytes are copied to the start of the
+// (zeroed) C structure.
+type headerItem struct {
+	typ  lflow.ItemType
+	hdr  []byte
+	cptr unsafe.Pointer
+}
+
+var _ lflow.ItemStruct = (*headerItem)(nil)
+
+func newHeaderItem(typ lflow.ItemType, hdr []byte) *headerItem {
+	return &headerItem{typ: typ, hdr: hdr}
+}
+
+func (item *headerItem) free() {
+	C.free(item.cptr)
+}
+
+// Reload implements ItemStruct interface.
+func (item *headerItem) Reload() {
+	size := itemSizes[item.typ]
+	if item.cptr == nil {
+		item.cptr = C.calloc(1, size)
+		runtime.SetFinalizer(item, (*headerItem).free)
+	}
+
+	n := C.size_t(len(item.hdr))
+	if n > size {
+		n = size
+	}
+	if n > 0 {
+		C.memcpy(item.cptr, unsafe.Pointer(&item.hdr[0]), n)
+	}
+}
+
+// Pointer implements ItemStruct interface.
+func (item *headerItem) Pointer() unsafe.Pointer {
+	return item.cptr
+}
+
+// Type implements ItemStruct interface.
+func (item *headerItem) Type() lflow.ItemType {
+	return item.typ
+}
+
+// Mask implements ItemStruct interface. The mask is always given explicitly in the pattern item.
+func (item *headerItem) Mask() unsafe.Pointer {
+	return nil
+}
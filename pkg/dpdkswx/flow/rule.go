@@ -0,0 +1,407 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	lflow "github.com/yerden/go-dpdk/ethdev/flow"
+)
+
+// Rule is a parsed flow rule definition. The textual flow rule format follows the testpmd flow syntax:
+//
+//	[ingress|egress|transfer] [group <n>] [priority <n>]
+//	pattern <item> [<field> <value>]... [/ <item> [<field> <value>]...]... [/ end]
+//	actions <action> [<arg>]... [/ <action> [<arg>]...]... [/ end]
+//
+// Supported items are eth, vlan, ipv4, ipv6, udp, tcp and vxlan, supported actions are queue, rss, drop, mark and count.
+// IPv4 and IPv6 addresses can be given with a prefix length (i.e. 10.0.0.0/8) to match a subnet.
+type Rule struct {
+	attr    lflow.Attr
+	pattern []lflow.Item
+	actions []lflow.Action
+	count   bool
+	text    string
+}
+
+// String returns the textual definition of the rule
+func (r *Rule) String() string {
+	return r.text
+}
+
+// HasCount returns true if the rule has a count action and can be queried
+func (r *Rule) HasCount() bool {
+	return r.count
+}
+
+type fieldKind int
+
+const (
+	fieldUint fieldKind = iota
+	fieldMAC
+	fieldIPv4
+	fieldIPv6
+)
+
+// definition of a matchable field in a protocol header
+type field struct {
+	off  int       // byte offset in the header
+	size int       // size in bytes
+	kind fieldKind // value type
+	mask uint64    // bits of the field (for uint fields smaller than the given byte size), 0 means all bits
+}
+
+type itemDef struct {
+	typ    lflow.ItemType
+	size   int
+	fields map[string]field
+}
+
+// pattern items and their matchable fields supported by the parser
+var itemDefs = map[string]itemDef{
+	"eth": {ItemTypeEth, ethHdrLen, map[string]field{
+		"dst":  {0, 6, fieldMAC, 0},
+		"src":  {6, 6, fieldMAC, 0},
+		"type": {12, 2, fieldUint, 0},
+	}},
+	"vlan": {ItemTypeVlan, vlanHdrLen, map[string]field{
+		"pcp":        {0, 2, fieldUint, 0xe000},
+		"vid":        {0, 2, fieldUint, 0x0fff},
+		"inner_type": {2, 2, fieldUint, 0},
+	}},
+	"ipv4": {ItemTypeIPv4, ipv4HdrLen, map[string]field{
+		"tos":   {1, 1, fieldUint, 0},
+		"ttl":   {8, 1, fieldUint, 0},
+		"proto": {9, 1, fieldUint, 0},
+		"src":   {12, 4, fieldIPv4, 0},
+		"dst":   {16, 4, fieldIPv4, 0},
+	}},
+	"ipv6": {ItemTypeIPv6, ipv6HdrLen, map[string]field{
+		"tc":    {0, 4, fieldUint, 0x0ff00000},
+		"proto": {6, 1, fieldUint, 0},
+		"hop":   {7, 1, fieldUint, 0},
+		"src":   {8, 16, fieldIPv6, 0},
+		"dst":   {24, 16, fieldIPv6, 0},
+	}},
+	"udp": {ItemTypeUDP, udpHdrLen, map[string]field{
+		"src": {0, 2, fieldUint, 0},
+		"dst": {2, 2, fieldUint, 0},
+	}},
+	"tcp": {ItemTypeTCP, tcpHdrLen, map[string]field{
+		"src":   {0, 2, fieldUint, 0},
+		"dst":   {2, 2, fieldUint, 0},
+		"flags": {13, 1, fieldUint, 0},
+	}},
+	"vxlan": {ItemTypeVxlan, vxlanHdrLen, map[string]field{
+		"vni": {4, 3, fieldUint, 0},
+	}},
+}
+
+// ItemNames returns the sorted names of the supported pattern items
+func ItemNames() []string {
+	var names []string
+	for name := range itemDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ItemFieldNames returns the sorted names of the matchable fields of the given pattern item
+func ItemFieldNames(item string) []string {
+	var names []string
+	for name := range itemDefs[item].fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActionNames returns the names of the supported actions
+func ActionNames() []string {
+	return []string{"count", "drop", "mark", "queue", "rss"}
+}
+
+// token reader
+type tokens struct {
+	args []string
+	pos  int
+}
+
+func (t *tokens) more() bool {
+	return t.pos < len(t.args)
+}
+
+func (t *tokens) peek() string {
+	if !t.more() {
+		return ""
+	}
+	return t.args[t.pos]
+}
+
+func (t *tokens) next() string {
+	s := t.peek()
+	t.pos++
+	return s
+}
+
+func (t *tokens) uint(name string, bitSize int) (uint64, error) {
+	if !t.more() {
+		return 0, fmt.Errorf("missing value for %s", name)
+	}
+	s := t.next()
+	v, err := strconv.ParseUint(s, 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("value %s for %s is not a correct integer", s, name)
+	}
+	return v, nil
+}
+
+// ParseRule parses the given flow rule definition (split in words) into a flow rule
+func ParseRule(args []string) (*Rule, error) {
+	r := &Rule{text: strings.Join(args, " ")}
+	t := &tokens{args: args}
+
+	if err := r.parseAttr(t); err != nil {
+		return nil, err
+	}
+	if err := r.parsePattern(t); err != nil {
+		return nil, err
+	}
+	if err := r.parseActions(t); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Rule) parseAttr(t *tokens) error {
+	for t.more() {
+		switch tok := t.next(); tok {
+		case "ingress":
+			r.attr.Ingress = true
+		case "egress":
+			r.attr.Egress = true
+		case "transfer":
+			r.attr.Transfer = true
+		case "group":
+			v, err := t.uint("group", 32)
+			if err != nil {
+				return err
+			}
+			r.attr.Group = uint32(v)
+		case "priority":
+			v, err := t.uint("priority", 32)
+			if err != nil {
+				return err
+			}
+			r.attr.Priority = uint32(v)
+		case "pattern":
+			if !r.attr.Ingress && !r.attr.Egress && !r.attr.Transfer {
+				r.attr.Ingress = true
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown flow rule attribute: %s", tok)
+		}
+	}
+
+	return errors.New("flow rule has no pattern")
+}
+
+func (r *Rule) parsePattern(t *tokens) error {
+	for t.more() {
+		tok := t.next()
+		switch tok {
+		case "/", "end":
+			continue
+		case "actions":
+			return nil
+		}
+
+		def, ok := itemDefs[tok]
+		if !ok {
+			return fmt.Errorf("unknown flow pattern item: %s", tok)
+		}
+
+		spec := make([]byte, def.size)
+		mask := make([]byte, def.size)
+		fieldsSet := false
+		for t.more() && t.peek() != "/" && t.peek() != "end" && t.peek() != "actions" {
+			name := t.next()
+			f, ok := def.fields[name]
+			if !ok {
+				return fmt.Errorf("unknown field %s in flow pattern item %s", name, tok)
+			}
+			if !t.more() {
+				return fmt.Errorf("missing value for %s %s", tok, name)
+			}
+			if err := f.set(spec, mask, t.next()); err != nil {
+				return fmt.Errorf("%s %s: %w", tok, name, err)
+			}
+			fieldsSet = true
+		}
+
+		// an item without fields matches any header of the item type
+		item := lflow.Item{Spec: def.typ}
+		if fieldsSet {
+			item.Spec = newHeaderItem(def.typ, spec)
+			item.Mask = newHeaderItem(def.typ, mask)
+		}
+		r.pattern = append(r.pattern, item)
+	}
+
+	return errors.New("flow rule has no actions")
+}
+
+func (r *Rule) parseActions(t *tokens) error {
+	for t.more() {
+		tok := t.next()
+		switch tok {
+		case "/", "end":
+			continue
+		case "queue":
+			if t.peek() == "index" {
+				t.next()
+			}
+			v, err := t.uint("queue index", 16)
+			if err != nil {
+				return err
+			}
+			r.actions = append(r.actions, &lflow.ActionQueue{Index: uint16(v)})
+		case "rss":
+			action := &lflow.ActionRSS{}
+			if t.peek() == "queues" {
+				t.next()
+			}
+			for t.more() && t.peek() != "/" && t.peek() != "end" {
+				v, err := t.uint("rss queue", 16)
+				if err != nil {
+					return err
+				}
+				action.Queues = append(action.Queues, uint16(v))
+			}
+			r.actions = append(r.actions, action)
+		case "drop":
+			r.actions = append(r.actions, ActionTypeDrop)
+		case "mark":
+			if t.peek() == "id" {
+				t.next()
+			}
+			v, err := t.uint("mark id", 32)
+			if err != nil {
+				return err
+			}
+			r.actions = append(r.actions, &actionMark{id: uint32(v)})
+		case "count":
+			r.actions = append(r.actions, ActionTypeCount)
+			r.count = true
+		default:
+			return fmt.Errorf("unknown flow action: %s", tok)
+		}
+	}
+
+	if len(r.actions) == 0 {
+		return errors.New("flow rule has no actions")
+	}
+	return nil
+}
+
+// set the field value and mask in the given header spec and mask
+func (f field) set(spec []byte, mask []byte, value string) error {
+	switch f.kind {
+	case fieldMAC:
+		mac, err := net.ParseMAC(value)
+		if err != nil || len(mac) != f.size {
+			return fmt.Errorf("%s is not a correct MAC address", value)
+		}
+		copy(spec[f.off:], mac)
+		for i := 0; i < f.size; i++ {
+			mask[f.off+i] = 0xff
+		}
+	case fieldIPv4, fieldIPv6:
+		ip, prefix, err := parseIPPrefix(value, f.size*8)
+		if err != nil {
+			return err
+		}
+		ipMask := net.CIDRMask(prefix, f.size*8)
+		for i := 0; i < f.size; i++ {
+			spec[f.off+i] = ip[i] & ipMask[i]
+			mask[f.off+i] = ipMask[i]
+		}
+	case fieldUint:
+		fieldMask := f.mask
+		if fieldMask == 0 {
+			fieldMask = (1 << (uint(f.size) * 8)) - 1
+		}
+		shift := 0
+		for (fieldMask>>shift)&1 == 0 {
+			shift++
+		}
+
+		v, err := strconv.ParseUint(value, 0, 64)
+		if err != nil || v > fieldMask>>shift {
+			return fmt.Errorf("%s is not a correct value (max %d)", value, fieldMask>>shift)
+		}
+		putUint(spec[f.off:f.off+f.size], getUint(spec[f.off:f.off+f.size])|(v<<shift))
+		putUint(mask[f.off:f.off+f.size], getUint(mask[f.off:f.off+f.size])|fieldMask)
+	}
+
+	return nil
+}
+
+// parse an IP address with optional prefix length, returns the address as byte slice of the given bit length
+func parseIPPrefix(value string, bits int) (net.IP, int, error) {
+	var ip net.IP
+	prefix := bits
+
+	if strings.Contains(value, "/") {
+		var ipNet *net.IPNet
+		var err error
+		ip, ipNet, err = net.ParseCIDR(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s is not a correct IP prefix", value)
+		}
+		ones, size := ipNet.Mask.Size()
+		if size != bits {
+			return nil, 0, fmt.Errorf("%s is not a correct IPv%d prefix", value, map[int]int{32: 4, 128: 6}[bits])
+		}
+		prefix = ones
+	} else {
+		ip = net.ParseIP(value)
+		if ip == nil {
+			return nil, 0, fmt.Errorf("%s is not a correct IP address", value)
+		}
+	}
+
+	if bits == 32 {
+		ip = ip.To4()
+	} else if ip.To4() != nil {
+		ip = nil
+	}
+	if ip == nil {
+		return nil, 0, fmt.Errorf("%s is not a correct IPv%d address", value, map[int]int{32: 4, 128: 6}[bits])
+	}
+
+	return ip, prefix, nil
+}
+
+// big endian unsigned integer of 1 to 8 bytes
+func getUint(b []byte) uint64 {
+	var buf [8]byte
+	copy(buf[8-len(b):], b)
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+func putUint(b []byte, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	copy(b, buf[8-len(b):])
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lflow "github.com/yerden/go-dpdk/ethdev/flow"
+)
+
+// return the spec and mask header bytes of a parsed pattern item
+func itemHeaders(t *testing.T, item lflow.Item) ([]byte, []byte) {
+	spec, ok := item.Spec.(*headerItem)
+	if !assert.True(t, ok, "pattern item spec is not a header item") {
+		return nil, nil
+	}
+	mask, ok := item.Mask.(*headerItem)
+	if !assert.True(t, ok, "pattern item mask is not a header item") {
+		return nil, nil
+	}
+	return spec.hdr, mask.hdr
+}
+
+func TestParseRuleAttributes(t *testing.T) {
+	tests := []struct {
+		rule string
+		attr lflow.Attr
+	}{
+		{"pattern eth / end actions drop / end", lflow.Attr{Ingress: true}},
+		{"ingress pattern eth actions drop", lflow.Attr{Ingress: true}},
+		{"egress pattern eth actions drop", lflow.Attr{Egress: true}},
+		{"transfer pattern eth actions drop", lflow.Attr{Transfer: true}},
+		{"ingress group 1 priority 2 pattern eth actions drop", lflow.Attr{Ingress: true, Group: 1, Priority: 2}},
+		{"group 0x10 pattern eth actions drop", lflow.Attr{Ingress: true, Group: 16}},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRule(strings.Fields(test.rule))
+		if !assert.NoError(t, err, test.rule) {
+			continue
+		}
+		assert.Equal(t, test.attr, r.attr, test.rule)
+		assert.Equal(t, test.rule, r.String(), test.rule)
+	}
+}
+
+func TestParseRulePattern(t *testing.T) {
+	r, err := ParseRule(strings.Fields("pattern eth dst 00:11:22:33:44:55 type 0x0800 / vlan vid 100 pcp 3 / " +
+		"ipv4 src 10.1.2.3/8 dst 192.168.1.1 proto 17 / udp dst 4789 / vxlan vni 42 / tcp / end actions drop"))
+	if !assert.NoError(t, err) || !assert.Len(t, r.pattern, 6) {
+		return
+	}
+
+	// eth
+	spec, mask := itemHeaders(t, r.pattern[0])
+	assert.Equal(t, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0, 0, 0, 0, 0, 0, 0x08, 0x00}, spec)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0xff, 0xff}, mask)
+
+	// vlan, pcp and vid share the TCI field
+	spec, mask = itemHeaders(t, r.pattern[1])
+	assert.Equal(t, []byte{0x60, 0x64, 0, 0}, spec)
+	assert.Equal(t, []byte{0xef, 0xff, 0, 0}, mask)
+
+	// ipv4, the source address is masked with the prefix length
+	spec, mask = itemHeaders(t, r.pattern[2])
+	assert.Equal(t, []byte{10, 0, 0, 0}, spec[12:16])
+	assert.Equal(t, []byte{0xff, 0, 0, 0}, mask[12:16])
+	assert.Equal(t, []byte{192, 168, 1, 1}, spec[16:20])
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff}, mask[16:20])
+	assert.Equal(t, byte(17), spec[9])
+	assert.Equal(t, byte(0xff), mask[9])
+
+	// udp
+	spec, mask = itemHeaders(t, r.pattern[3])
+	assert.Equal(t, []byte{0, 0, 0x12, 0xb5, 0, 0, 0, 0}, spec)
+	assert.Equal(t, []byte{0, 0, 0xff, 0xff, 0, 0, 0, 0}, mask)
+
+	// vxlan
+	spec, mask = itemHeaders(t, r.pattern[4])
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 42, 0}, spec)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0}, mask)
+
+	// an item without fields matches any header of the item type
+	assert.Equal(t, lflow.Item{Spec: ItemTypeTCP}, r.pattern[5])
+}
+
+func TestParseRuleIPv6(t *testing.T) {
+	r, err := ParseRule(strings.Fields("pattern ipv6 dst 2001:db8::/32 tc 0x2e actions queue 1"))
+	if !assert.NoError(t, err) || !assert.Len(t, r.pattern, 1) {
+		return
+	}
+
+	spec, mask := itemHeaders(t, r.pattern[0])
+	assert.Equal(t, []byte{0x02, 0xe0, 0, 0}, spec[0:4])
+	assert.Equal(t, []byte{0x0f, 0xf0, 0, 0}, mask[0:4])
+	assert.Equal(t, []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, spec[24:40])
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, mask[24:40])
+}
+
+func TestParseRuleActions(t *testing.T) {
+	r, err := ParseRule(strings.Fields("pattern eth actions queue index 3 / rss queues 0 1 2 / mark id 7 / count / " +
+		"drop / queue 4 / mark 8 / end"))
+	if !assert.NoError(t, err) || !assert.Len(t, r.actions, 7) {
+		return
+	}
+
+	assert.Equal(t, &lflow.ActionQueue{Index: 3}, r.actions[0])
+	assert.Equal(t, &lflow.ActionRSS{Queues: []uint16{0, 1, 2}}, r.actions[1])
+	assert.Equal(t, &actionMark{id: 7}, r.actions[2])
+	assert.Equal(t, ActionTypeCount, r.actions[3])
+	assert.Equal(t, ActionTypeDrop, r.actions[4])
+	assert.Equal(t, &lflow.ActionQueue{Index: 4}, r.actions[5])
+	assert.Equal(t, &actionMark{id: 8}, r.actions[6])
+	assert.True(t, r.HasCount())
+
+	r, err = ParseRule(strings.Fields("pattern eth actions drop"))
+	if assert.NoError(t, err) {
+		assert.False(t, r.HasCount())
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{"", "flow rule has no pattern"},
+		{"ingress", "flow rule has no pattern"},
+		{"ingres pattern eth actions drop", "unknown flow rule attribute: ingres"},
+		{"group pattern eth actions drop", "value pattern for group is not a correct integer"},
+		{"priority", "missing value for priority"},
+		{"pattern eth", "flow rule has no actions"},
+		{"pattern eth actions", "flow rule has no actions"},
+		{"pattern eth actions / end", "flow rule has no actions"},
+		{"pattern arp actions drop", "unknown flow pattern item: arp"},
+		{"pattern eth vid 1 actions drop", "unknown field vid in flow pattern item eth"},
+		{"pattern eth dst", "missing value for eth dst"},
+		{"pattern eth dst 00:11:22 actions drop", "eth dst: 00:11:22 is not a correct MAC address"},
+		{"pattern eth type 0x10000 actions drop", "eth type: 0x10000 is not a correct value (max 65535)"},
+		{"pattern vlan vid 4096 actions drop", "vlan vid: 4096 is not a correct value (max 4095)"},
+		{"pattern vlan pcp 8 actions drop", "vlan pcp: 8 is not a correct value (max 7)"},
+		{"pattern ipv4 src 10.0.0.256 actions drop", "ipv4 src: 10.0.0.256 is not a correct IP address"},
+		{"pattern ipv4 src 2001:db8::1 actions drop", "ipv4 src: 2001:db8::1 is not a correct IPv4 address"},
+		{"pattern ipv4 src 2001:db8::/32 actions drop", "ipv4 src: 2001:db8::/32 is not a correct IPv4 prefix"},
+		{"pattern ipv4 src 10.0.0.0/33 actions drop", "ipv4 src: 10.0.0.0/33 is not a correct IP prefix"},
+		{"pattern ipv6 dst 10.0.0.1 actions drop", "ipv6 dst: 10.0.0.1 is not a correct IPv6 address"},
+		{"pattern eth actions jump 1", "unknown flow action: jump"},
+		{"pattern eth actions queue", "missing value for queue index"},
+		{"pattern eth actions queue index 65536", "value 65536 for queue index is not a correct integer"},
+		{"pattern eth actions rss queues 0 x", "value x for rss queue is not a correct integer"},
+		{"pattern eth actions mark id -1", "value -1 for mark id is not a correct integer"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRule(strings.Fields(test.rule))
+		assert.EqualError(t, err, test.err, test.rule)
+	}
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, []string{"eth", "ipv4", "ipv6", "tcp", "udp", "vlan", "vxlan"}, ItemNames())
+	assert.Equal(t, []string{"dst", "src", "type"}, ItemFieldNames("eth"))
+	assert.Equal(t, []string{"inner_type", "pcp", "vid"}, ItemFieldNames("vlan"))
+	assert.Nil(t, ItemFieldNames("arp"))
+	assert.Equal(t, []string{"count", "drop", "mark", "queue", "rss"}, ActionNames())
+}
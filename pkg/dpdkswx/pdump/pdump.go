@@ -0,0 +1,191 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pdump captures the packets of the ethdev ports of a DPDK primary process from a DPDK secondary process with
+// the DPDK packet capture framework (librte_pdump)
+package pdump
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include <rte_mbuf.h>
+#include <rte_mempool.h>
+#include <rte_ring.h>
+#include <rte_pdump.h>
+
+// copy maximal len bytes of the (segmented) packet data of the mbuf into buf and return the number of bytes copied
+static uint32_t mbuf_copy(struct rte_mbuf *m, void *buf, uint32_t len) {
+	const void *data;
+
+	if (len > m->pkt_len)
+		len = m->pkt_len;
+	data = rte_pktmbuf_read(m, 0, len, buf);
+	if (data == NULL)
+		return 0;
+	if (data != buf)
+		memcpy(buf, data, len);
+	return len;
+}
+
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
+)
+
+// AllQueues captures the packets of all queues of a port
+const AllQueues = C.RTE_PDUMP_ALL_QUEUES
+
+// Capture directions
+const (
+	Rx = C.RTE_PDUMP_FLAG_RX
+	Tx = C.RTE_PDUMP_FLAG_TX
+)
+
+const (
+	readBurst   = 32
+	maxSnapLen  = 65535
+	mbufBufSize = C.RTE_MBUF_DEFAULT_BUF_SIZE
+)
+
+// Init initializes the packet capture server in the primary process. Secondary processes can only capture the packets
+// of the ports of a primary process that called Init.
+func Init() error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+	return common.Err(C.rte_pdump_init())
+}
+
+// Uninit stops the packet capture server in the primary process
+func Uninit() error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+	return common.Err(C.rte_pdump_uninit())
+}
+
+// Capture receives copies of the packets of an ethdev port of the primary process
+type Capture struct {
+	portID uint16
+	queue  uint16
+	rings  map[int]*C.struct_rte_ring // direction -> ring receiving the packet copies
+	mp     *C.struct_rte_mempool
+	buf    []byte // packet data buffer reused by Read
+}
+
+// NewCapture starts capturing the packets of the given port and queue (or AllQueues) in the given directions (Rx, Tx
+// or both). Size is the number of packets that can be buffered per direction.
+func NewCapture(name string, portID uint16, queue uint16, dirs int, size uint32) (*Capture, error) {
+	if dirs&(Rx|Tx) == 0 {
+		return nil, errors.New("no capture direction given")
+	}
+
+	// ring size must be a power of 2
+	ringSize := uint32(1)
+	for ringSize <= size {
+		ringSize <<= 1
+	}
+
+	c := &Capture{portID: portID, queue: queue, rings: make(map[int]*C.struct_rte_ring)}
+
+	cname := C.CString(name + "_pool")
+	defer C.free(unsafe.Pointer(cname))
+	c.mp = C.rte_pktmbuf_pool_create(cname, C.uint(ringSize*2), 0, 0, mbufBufSize, C.SOCKET_ID_ANY)
+	if c.mp == nil {
+		return nil, fmt.Errorf("capture mempool create error: %w", common.Err())
+	}
+
+	for _, dir := range []int{Rx, Tx} {
+		if dirs&dir == 0 {
+			continue
+		}
+
+		rname := C.CString(fmt.Sprintf("%s_%s", name, dirName(dir)))
+		r := C.rte_ring_create(rname, C.uint(ringSize), C.SOCKET_ID_ANY, C.RING_F_SC_DEQ)
+		C.free(unsafe.Pointer(rname))
+		if r == nil {
+			err := common.Err()
+			c.Free()
+			return nil, fmt.Errorf("capture ring create error: %w", err)
+		}
+		c.rings[dir] = r
+
+		status := C.rte_pdump_enable(C.uint16_t(portID), C.uint16_t(queue), C.uint32_t(dir), r, c.mp, nil)
+		if status != 0 {
+			err := common.Err()
+			c.Free()
+			return nil, fmt.Errorf("enabling %s capture on port %d failed: %w", dirName(dir), portID, err)
+		}
+	}
+
+	return c, nil
+}
+
+func dirName(dir int) string {
+	if dir == Tx {
+		return "tx"
+	}
+	return "rx"
+}
+
+// Read calls the given function for the captured packets currently in the buffers (with a maximum of one burst per
+// direction) and returns the number of packets read. At most snapLen bytes of a packet are given and the data slice
+// is only valid during the function call. The packets are timestamped at the time they are read.
+func (c *Capture) Read(snapLen uint32, fn func(dir int, ts time.Time, origLen uint32, data []byte)) int {
+	var mbufs [readBurst]*C.struct_rte_mbuf
+	if snapLen == 0 {
+		snapLen = maxSnapLen
+	}
+	if uint32(len(c.buf)) < snapLen {
+		c.buf = make([]byte, snapLen)
+	}
+	buf := c.buf
+
+	total := 0
+	for dir, r := range c.rings {
+		n := C.rte_ring_dequeue_burst(r, (*unsafe.Pointer)(unsafe.Pointer(&mbufs[0])), readBurst, nil)
+		ts := time.Now()
+		for _, m := range mbufs[:n] {
+			l := C.mbuf_copy(m, unsafe.Pointer(&buf[0]), C.uint32_t(snapLen))
+			fn(dir, ts, uint32(m.pkt_len), buf[:l])
+			C.rte_pktmbuf_free(m)
+		}
+		total += int(n)
+	}
+
+	return total
+}
+
+// Free stops the capture and frees the capture buffers
+func (c *Capture) Free() {
+	var mbufs [readBurst]*C.struct_rte_mbuf
+
+	for dir, r := range c.rings {
+		C.rte_pdump_disable(C.uint16_t(c.portID), C.uint16_t(c.queue), C.uint32_t(dir))
+
+		// free the packets still in the ring
+		for {
+			n := C.rte_ring_dequeue_burst(r, (*unsafe.Pointer)(unsafe.Pointer(&mbufs[0])), readBurst, nil)
+			if n == 0 {
+				break
+			}
+			for _, m := range mbufs[:n] {
+				C.rte_pktmbuf_free(m)
+			}
+		}
+		C.rte_ring_free(r)
+		delete(c.rings, dir)
+	}
+
+	if c.mp != nil {
+		C.rte_mempool_free(c.mp)
+		c.mp = nil
+	}
+}
@@ -3,7 +3,10 @@
 
 package pipeline
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // represent an action argument description
 type ActionArg struct {
@@ -59,6 +62,16 @@ func (a *Action) GetName() string {
 	return a.name
 }
 
+// returns the action arguments in argument order
+func (a *Action) GetArgs() []*ActionArg {
+	args := make([]*ActionArg, 0, len(a.actionArgs))
+	for _, arg := range a.actionArgs {
+		args = append(args, arg)
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].GetIndex() < args[j].GetIndex() })
+	return args
+}
+
 // represents a store of action records
 type ActionStore map[string]*Action
 
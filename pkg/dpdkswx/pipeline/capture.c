@@ -0,0 +1,582 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#include <stdlib.h>
+#include <string.h>
+#include <errno.h>
+#include <linux/filter.h>
+
+#include <rte_common.h>
+#include <rte_byteorder.h>
+#include <rte_cycles.h>
+#include <rte_mempool.h>
+#include <rte_ring.h>
+#include <rte_pause.h>
+#include <rte_spinlock.h>
+#include <rte_swx_port.h>
+#include <rte_version.h>
+
+// The port functions of a running pipeline can only be replaced through the pipeline internals, the header is
+// installed together with DPDK (see build/dpdk/install.sh). The internals change between DPDK releases, so only the
+// release the capture hooks are written for is accepted.
+#if RTE_VERSION < RTE_VERSION_NUM(22, 7, 0, 0) || RTE_VERSION >= RTE_VERSION_NUM(22, 8, 0, 0)
+#error "the pipeline packet capture (capture.c) only supports the pipeline internals of DPDK 22.07"
+#endif
+
+#include <rte_swx_pipeline_internal.h>
+
+#include "capture.h"
+
+struct capture {
+	struct rte_ring *ring;
+	struct rte_mempool *mp;
+	uint32_t snaplen;
+	struct capture_insn *insns;
+	uint32_t n_insns;
+	uint64_t dropped;
+};
+
+/**
+ * Capture buffer
+ */
+struct capture *capture_create(const char *name, uint32_t n_recs, uint32_t snaplen, int numa_node) {
+	char ring_name[RTE_RING_NAMESIZE];
+	struct capture *cap;
+
+	cap = calloc(1, sizeof(struct capture));
+	if (!cap)
+		return NULL;
+	cap->snaplen = snaplen;
+
+	// multiple pipeline threads can write, only the control plane reads
+	snprintf(ring_name, sizeof(ring_name), "%s_R", name);
+	cap->ring = rte_ring_create(ring_name, rte_align32pow2(n_recs + 1), numa_node, RING_F_SC_DEQ);
+	if (!cap->ring)
+		goto error;
+
+	cap->mp = rte_mempool_create(name, n_recs, sizeof(struct capture_rec) + snaplen, 0, 0, NULL, NULL, NULL, NULL,
+		numa_node, 0);
+	if (!cap->mp)
+		goto error;
+
+	return cap;
+
+error:
+	capture_free(cap);
+	return NULL;
+}
+
+void capture_free(struct capture *cap) {
+	struct capture_rec *recs[64];
+	uint32_t n;
+
+	if (!cap)
+		return;
+
+	if (cap->ring) {
+		while ((n = capture_read(cap, recs, RTE_DIM(recs))) > 0)
+			capture_rec_free(cap, recs, n);
+		rte_ring_free(cap->ring);
+	}
+	rte_mempool_free(cap->mp);
+	free(cap->insns);
+	free(cap);
+}
+
+// Check that the classic BPF program only jumps forward within the program, uses valid scratch memory indexes,
+// doesn't divide by a zero constant and ends with a return
+static int capture_filter_validate(const struct capture_insn *insns, uint32_t n_insns) {
+	uint32_t i;
+
+	if (!n_insns)
+		return -EINVAL;
+
+	for (i = 0; i < n_insns; i++) {
+		const struct capture_insn *insn = &insns[i];
+		uint32_t left = n_insns - i - 1;
+
+		switch (BPF_CLASS(insn->code)) {
+		case BPF_LD:
+		case BPF_LDX:
+			if (BPF_MODE(insn->code) == BPF_MEM && insn->k >= BPF_MEMWORDS)
+				return -EINVAL;
+			break;
+		case BPF_ST:
+		case BPF_STX:
+			if (insn->k >= BPF_MEMWORDS)
+				return -EINVAL;
+			break;
+		case BPF_ALU:
+			if ((BPF_OP(insn->code) == BPF_DIV || BPF_OP(insn->code) == BPF_MOD) &&
+				BPF_SRC(insn->code) == BPF_K && insn->k == 0)
+				return -EINVAL;
+			break;
+		case BPF_JMP:
+			if (BPF_OP(insn->code) == BPF_JA) {
+				if (insn->k >= left)
+					return -EINVAL;
+			} else if (insn->jt >= left || insn->jf >= left) {
+				return -EINVAL;
+			}
+			break;
+		case BPF_RET:
+		case BPF_MISC:
+			break;
+		default:
+			return -EINVAL;
+		}
+	}
+
+	return BPF_CLASS(insns[n_insns - 1].code) == BPF_RET ? 0 : -EINVAL;
+}
+
+// Set the classic BPF filter program of the capture. Must be called before the capture is attached to a port.
+int capture_filter_set(struct capture *cap, const struct capture_insn *insns, uint32_t n_insns) {
+	int status;
+
+	status = capture_filter_validate(insns, n_insns);
+	if (status)
+		return status;
+
+	cap->insns = malloc(n_insns * sizeof(struct capture_insn));
+	if (!cap->insns)
+		return -ENOMEM;
+	memcpy(cap->insns, insns, n_insns * sizeof(struct capture_insn));
+	cap->n_insns = n_insns;
+	return 0;
+}
+
+uint32_t capture_read(struct capture *cap, struct capture_rec **recs, uint32_t n_recs) {
+	return rte_ring_sc_dequeue_burst(cap->ring, (void **)recs, n_recs, NULL);
+}
+
+void capture_rec_free(struct capture *cap, struct capture_rec **recs, uint32_t n_recs) {
+	rte_mempool_put_bulk(cap->mp, (void **)recs, n_recs);
+}
+
+uint64_t capture_dropped(struct capture *cap) {
+	return __atomic_load_n(&cap->dropped, __ATOMIC_RELAXED);
+}
+
+static inline uint32_t capture_load32(const uint8_t *p) {
+	uint32_t v;
+
+	memcpy(&v, p, sizeof(v));
+	return rte_be_to_cpu_32(v);
+}
+
+static inline uint32_t capture_load16(const uint8_t *p) {
+	uint16_t v;
+
+	memcpy(&v, p, sizeof(v));
+	return rte_be_to_cpu_16(v);
+}
+
+// Run the validated classic BPF program on the packet, returns 0 when the packet doesn't match
+static uint32_t capture_filter(const struct capture_insn *pc, const uint8_t *p, uint32_t len) {
+	uint32_t mem[BPF_MEMWORDS] = {0};
+	uint32_t A = 0, X = 0, k;
+
+	for (;; pc++) {
+		switch (pc->code) {
+		case BPF_RET | BPF_K:
+			return pc->k;
+		case BPF_RET | BPF_A:
+			return A;
+
+		case BPF_LD | BPF_W | BPF_ABS:
+		case BPF_LD | BPF_W | BPF_IND:
+			k = pc->k + (BPF_MODE(pc->code) == BPF_IND ? X : 0);
+			if (k < pc->k || k > len || len - k < 4)
+				return 0;
+			A = capture_load32(p + k);
+			break;
+		case BPF_LD | BPF_H | BPF_ABS:
+		case BPF_LD | BPF_H | BPF_IND:
+			k = pc->k + (BPF_MODE(pc->code) == BPF_IND ? X : 0);
+			if (k < pc->k || k > len || len - k < 2)
+				return 0;
+			A = capture_load16(p + k);
+			break;
+		case BPF_LD | BPF_B | BPF_ABS:
+		case BPF_LD | BPF_B | BPF_IND:
+			k = pc->k + (BPF_MODE(pc->code) == BPF_IND ? X : 0);
+			if (k < pc->k || k >= len)
+				return 0;
+			A = p[k];
+			break;
+		case BPF_LD | BPF_W | BPF_LEN:
+			A = len;
+			break;
+		case BPF_LDX | BPF_W | BPF_LEN:
+			X = len;
+			break;
+		case BPF_LD | BPF_IMM:
+			A = pc->k;
+			break;
+		case BPF_LDX | BPF_IMM:
+			X = pc->k;
+			break;
+		case BPF_LD | BPF_MEM:
+			A = mem[pc->k];
+			break;
+		case BPF_LDX | BPF_MEM:
+			X = mem[pc->k];
+			break;
+		case BPF_LDX | BPF_B | BPF_MSH:
+			if (pc->k >= len)
+				return 0;
+			X = (p[pc->k] & 0xf) << 2;
+			break;
+		case BPF_ST:
+			mem[pc->k] = A;
+			break;
+		case BPF_STX:
+			mem[pc->k] = X;
+			break;
+
+		case BPF_JMP | BPF_JA:
+			pc += pc->k;
+			break;
+		case BPF_JMP | BPF_JEQ | BPF_K:
+			pc += (A == pc->k) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JGT | BPF_K:
+			pc += (A > pc->k) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JGE | BPF_K:
+			pc += (A >= pc->k) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JSET | BPF_K:
+			pc += (A & pc->k) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JEQ | BPF_X:
+			pc += (A == X) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JGT | BPF_X:
+			pc += (A > X) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JGE | BPF_X:
+			pc += (A >= X) ? pc->jt : pc->jf;
+			break;
+		case BPF_JMP | BPF_JSET | BPF_X:
+			pc += (A & X) ? pc->jt : pc->jf;
+			break;
+
+		case BPF_ALU | BPF_ADD | BPF_X:
+			A += X;
+			break;
+		case BPF_ALU | BPF_SUB | BPF_X:
+			A -= X;
+			break;
+		case BPF_ALU | BPF_MUL | BPF_X:
+			A *= X;
+			break;
+		case BPF_ALU | BPF_DIV | BPF_X:
+			if (X == 0)
+				return 0;
+			A /= X;
+			break;
+		case BPF_ALU | BPF_MOD | BPF_X:
+			if (X == 0)
+				return 0;
+			A %= X;
+			break;
+		case BPF_ALU | BPF_AND | BPF_X:
+			A &= X;
+			break;
+		case BPF_ALU | BPF_OR | BPF_X:
+			A |= X;
+			break;
+		case BPF_ALU | BPF_XOR | BPF_X:
+			A ^= X;
+			break;
+		case BPF_ALU | BPF_LSH | BPF_X:
+			A = X < 32 ? A << X : 0;
+			break;
+		case BPF_ALU | BPF_RSH | BPF_X:
+			A = X < 32 ? A >> X : 0;
+			break;
+		case BPF_ALU | BPF_ADD | BPF_K:
+			A += pc->k;
+			break;
+		case BPF_ALU | BPF_SUB | BPF_K:
+			A -= pc->k;
+			break;
+		case BPF_ALU | BPF_MUL | BPF_K:
+			A *= pc->k;
+			break;
+		case BPF_ALU | BPF_DIV | BPF_K:
+			A /= pc->k;
+			break;
+		case BPF_ALU | BPF_MOD | BPF_K:
+			A %= pc->k;
+			break;
+		case BPF_ALU | BPF_AND | BPF_K:
+			A &= pc->k;
+			break;
+		case BPF_ALU | BPF_OR | BPF_K:
+			A |= pc->k;
+			break;
+		case BPF_ALU | BPF_XOR | BPF_K:
+			A ^= pc->k;
+			break;
+		case BPF_ALU | BPF_LSH | BPF_K:
+			A = pc->k < 32 ? A << pc->k : 0;
+			break;
+		case BPF_ALU | BPF_RSH | BPF_K:
+			A = pc->k < 32 ? A >> pc->k : 0;
+			break;
+		case BPF_ALU | BPF_NEG:
+			A = -A;
+			break;
+
+		case BPF_MISC | BPF_TAX:
+			X = A;
+			break;
+		case BPF_MISC | BPF_TXA:
+			A = X;
+			break;
+
+		default:
+			return 0;
+		}
+	}
+}
+
+// Called from the pipeline threads
+static void capture_pkt(struct capture *cap, uint32_t if_index, struct rte_swx_pkt *pkt, uint32_t length) {
+	uint8_t *data = &pkt->pkt[pkt->offset];
+	struct capture_rec *rec;
+
+	if (cap->n_insns && !capture_filter(cap->insns, data, length))
+		return;
+
+	if (rte_mempool_get(cap->mp, (void **)&rec) < 0) {
+		__atomic_fetch_add(&cap->dropped, 1, __ATOMIC_RELAXED);
+		return;
+	}
+
+	rec->tsc = rte_rdtsc();
+	rec->if_index = if_index;
+	rec->len = length;
+	rec->caplen = RTE_MIN(length, cap->snaplen);
+	memcpy(rec->data, data, rec->caplen);
+
+	if (rte_ring_mp_enqueue(cap->ring, rec) < 0) {
+		rte_mempool_put(cap->mp, rec);
+		__atomic_fetch_add(&cap->dropped, 1, __ATOMIC_RELAXED);
+	}
+}
+
+/**
+ * Capture hooks. While a capture is attached to a pipeline port the port functions in the pipeline runtime are
+ * replaced by the capture functions below, which find the hook of the port by its port object, call the original port
+ * function and copy the packet to the capture. A hook stays bound to its port object until the pipeline is freed, so
+ * a pipeline thread that still calls a capture function after the detach always finds the hook.
+ */
+#define CAPTURE_HOOKS_MAX 256
+
+struct capture_hook {
+	void *obj;
+	struct capture *cap;
+	uint32_t if_index;
+	uint32_t refs;
+	rte_swx_port_in_pkt_rx_t pkt_rx;
+	rte_swx_port_out_pkt_tx_t pkt_tx;
+	rte_swx_port_out_pkt_fast_clone_tx_t pkt_fast_clone_tx;
+	rte_swx_port_out_pkt_clone_tx_t pkt_clone_tx;
+};
+
+struct capture_hooks {
+	struct capture_hook hook[CAPTURE_HOOKS_MAX];
+	uint32_t n; // highest bound hook + 1
+};
+
+static struct capture_hooks capture_hooks_in;
+static struct capture_hooks capture_hooks_out;
+static rte_spinlock_t capture_hooks_lock = RTE_SPINLOCK_INITIALIZER;
+
+static inline struct capture_hook *capture_hook_find(struct capture_hooks *hooks, void *obj) {
+	uint32_t n = __atomic_load_n(&hooks->n, __ATOMIC_ACQUIRE);
+	uint32_t i;
+
+	for (i = 0; i < n; i++)
+		if (__atomic_load_n(&hooks->hook[i].obj, __ATOMIC_ACQUIRE) == obj)
+			return &hooks->hook[i];
+	return NULL;
+}
+
+// The hook reference count keeps the attached capture from being freed while it is used by a pipeline thread
+static inline void capture_hook_pkt(struct capture_hook *hook, struct rte_swx_pkt *pkt, uint32_t length) {
+	struct capture *cap;
+
+	__atomic_fetch_add(&hook->refs, 1, __ATOMIC_SEQ_CST);
+	cap = __atomic_load_n(&hook->cap, __ATOMIC_SEQ_CST);
+	if (cap)
+		capture_pkt(cap, hook->if_index, pkt, length);
+	__atomic_fetch_sub(&hook->refs, 1, __ATOMIC_SEQ_CST);
+}
+
+static int capture_pkt_rx(void *obj, struct rte_swx_pkt *pkt) {
+	struct capture_hook *hook = capture_hook_find(&capture_hooks_in, obj);
+	int n_pkts;
+
+	n_pkts = hook->pkt_rx(obj, pkt);
+	if (n_pkts)
+		capture_hook_pkt(hook, pkt, pkt->length);
+	return n_pkts;
+}
+
+static void capture_pkt_tx(void *obj, struct rte_swx_pkt *pkt) {
+	struct capture_hook *hook = capture_hook_find(&capture_hooks_out, obj);
+
+	capture_hook_pkt(hook, pkt, pkt->length);
+	hook->pkt_tx(obj, pkt);
+}
+
+static void capture_pkt_fast_clone_tx(void *obj, struct rte_swx_pkt *pkt) {
+	struct capture_hook *hook = capture_hook_find(&capture_hooks_out, obj);
+
+	capture_hook_pkt(hook, pkt, pkt->length);
+	hook->pkt_fast_clone_tx(obj, pkt);
+}
+
+static void capture_pkt_clone_tx(void *obj, struct rte_swx_pkt *pkt, uint32_t truncation_length) {
+	struct capture_hook *hook = capture_hook_find(&capture_hooks_out, obj);
+
+	capture_hook_pkt(hook, pkt, truncation_length ? RTE_MIN(pkt->length, truncation_length) : pkt->length);
+	hook->pkt_clone_tx(obj, pkt, truncation_length);
+}
+
+// find the hook of the port object or bind a free hook to it, called with the hooks lock taken
+static struct capture_hook *capture_hook_get(struct capture_hooks *hooks, void *obj) {
+	struct capture_hook *hook = capture_hook_find(hooks, obj);
+	uint32_t i;
+
+	if (hook)
+		return hook;
+
+	for (i = 0; i < CAPTURE_HOOKS_MAX; i++) {
+		hook = &hooks->hook[i];
+		if (hook->obj)
+			continue;
+
+		__atomic_store_n(&hook->obj, obj, __ATOMIC_RELEASE);
+		if (i >= hooks->n)
+			__atomic_store_n(&hooks->n, i + 1, __ATOMIC_RELEASE);
+		return hook;
+	}
+	return NULL;
+}
+
+int capture_port_attach(struct rte_swx_pipeline *p, int out, uint32_t port_id, struct capture *cap, uint32_t if_index) {
+	struct capture_hook *hook;
+
+	if (!p->build_done || port_id >= (out ? p->n_ports_out : p->n_ports_in))
+		return -EINVAL;
+
+	rte_spinlock_lock(&capture_hooks_lock);
+	if (out) {
+		struct port_out_runtime *port = &p->out[port_id];
+
+		hook = capture_hook_get(&capture_hooks_out, port->obj);
+		if (!hook || hook->cap)
+			goto busy;
+		if (!hook->pkt_tx) {
+			hook->pkt_tx = port->pkt_tx;
+			hook->pkt_fast_clone_tx = port->pkt_fast_clone_tx;
+			hook->pkt_clone_tx = port->pkt_clone_tx;
+		}
+		hook->if_index = if_index;
+		__atomic_store_n(&hook->cap, cap, __ATOMIC_SEQ_CST);
+
+		__atomic_store_n(&port->pkt_tx, capture_pkt_tx, __ATOMIC_RELEASE);
+		if (hook->pkt_fast_clone_tx)
+			__atomic_store_n(&port->pkt_fast_clone_tx, capture_pkt_fast_clone_tx, __ATOMIC_RELEASE);
+		if (hook->pkt_clone_tx)
+			__atomic_store_n(&port->pkt_clone_tx, capture_pkt_clone_tx, __ATOMIC_RELEASE);
+	} else {
+		struct port_in_runtime *port = &p->in[port_id];
+
+		hook = capture_hook_get(&capture_hooks_in, port->obj);
+		if (!hook || hook->cap)
+			goto busy;
+		if (!hook->pkt_rx)
+			hook->pkt_rx = port->pkt_rx;
+		hook->if_index = if_index;
+		__atomic_store_n(&hook->cap, cap, __ATOMIC_SEQ_CST);
+
+		__atomic_store_n(&port->pkt_rx, capture_pkt_rx, __ATOMIC_RELEASE);
+	}
+	rte_spinlock_unlock(&capture_hooks_lock);
+	return 0;
+
+busy:
+	rte_spinlock_unlock(&capture_hooks_lock);
+	return hook ? -EBUSY : -ENOSPC;
+}
+
+// restore the original port functions, detach the capture and wait until no pipeline thread is using it anymore.
+// Called with the hooks lock taken.
+static void capture_hook_detach(struct rte_swx_pipeline *p, int out, uint32_t port_id, struct capture_hook *hook) {
+	if (out) {
+		struct port_out_runtime *port = &p->out[port_id];
+
+		__atomic_store_n(&port->pkt_tx, hook->pkt_tx, __ATOMIC_RELEASE);
+		__atomic_store_n(&port->pkt_fast_clone_tx, hook->pkt_fast_clone_tx, __ATOMIC_RELEASE);
+		__atomic_store_n(&port->pkt_clone_tx, hook->pkt_clone_tx, __ATOMIC_RELEASE);
+	} else {
+		__atomic_store_n(&p->in[port_id].pkt_rx, hook->pkt_rx, __ATOMIC_RELEASE);
+	}
+
+	__atomic_store_n(&hook->cap, NULL, __ATOMIC_SEQ_CST);
+	while (__atomic_load_n(&hook->refs, __ATOMIC_SEQ_CST))
+		rte_pause();
+}
+
+int capture_port_detach(struct rte_swx_pipeline *p, int out, uint32_t port_id) {
+	struct capture_hook *hook;
+	void *obj;
+
+	if (!p->build_done || port_id >= (out ? p->n_ports_out : p->n_ports_in))
+		return -EINVAL;
+	obj = out ? p->out[port_id].obj : p->in[port_id].obj;
+
+	rte_spinlock_lock(&capture_hooks_lock);
+	hook = capture_hook_find(out ? &capture_hooks_out : &capture_hooks_in, obj);
+	if (hook && hook->cap)
+		capture_hook_detach(p, out, port_id, hook);
+	rte_spinlock_unlock(&capture_hooks_lock);
+	return 0;
+}
+
+// Release the hooks of the ports of the pipeline, the pipeline must not run on a pipeline thread anymore
+void capture_hooks_release(struct rte_swx_pipeline *p) {
+	uint32_t i;
+	int out;
+
+	if (!p->build_done)
+		return;
+
+	rte_spinlock_lock(&capture_hooks_lock);
+	for (out = 0; out <= 1; out++) {
+		struct capture_hooks *hooks = out ? &capture_hooks_out : &capture_hooks_in;
+		uint32_t n_ports = out ? p->n_ports_out : p->n_ports_in;
+
+		for (i = 0; i < n_ports; i++) {
+			struct capture_hook *hook = capture_hook_find(hooks, out ? p->out[i].obj : p->in[i].obj);
+
+			if (!hook)
+				continue;
+			if (hook->cap)
+				capture_hook_detach(p, out, i, hook);
+			hook->pkt_rx = NULL;
+			hook->pkt_tx = NULL;
+			hook->pkt_fast_clone_tx = NULL;
+			hook->pkt_clone_tx = NULL;
+			__atomic_store_n(&hook->obj, NULL, __ATOMIC_RELEASE);
+		}
+	}
+	rte_spinlock_unlock(&capture_hooks_lock);
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pipeline
+
+/*
+#include <stdlib.h>
+#include <errno.h>
+#include <rte_cycles.h>
+
+#include "capture.h"
+
+*/
+import "C"
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"golang.org/x/net/bpf"
+)
+
+// Port directions
+const (
+	PortIn  = iota // pipeline input port
+	PortOut        // pipeline output port
+)
+
+const captureReadBurst = 64
+
+// Capture is a buffer receiving copies of the packets of the pipeline ports it is attached to
+type Capture struct {
+	c       *C.struct_capture
+	tscHz   uint64
+	tscRef  uint64
+	timeRef time.Time
+}
+
+// NewCapture creates a capture buffer with room for size packets of maximal snaplen bytes. When the filter (a classic
+// BPF program, i.e. a compiled pcap filter expression) is not empty only the matching packets are captured.
+func NewCapture(name string, size uint32, snaplen uint32, filter []bpf.RawInstruction, numaNode int) (*Capture, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	c := C.capture_create(cname, C.uint32_t(size), C.uint32_t(snaplen), C.int(numaNode))
+	if c == nil {
+		return nil, fmt.Errorf("capture buffer %s create error", name)
+	}
+
+	if len(filter) > 0 {
+		insns := make([]C.struct_capture_insn, len(filter))
+		for i, ins := range filter {
+			insns[i] = C.struct_capture_insn{code: C.uint16_t(ins.Op), jt: C.uint8_t(ins.Jt), jf: C.uint8_t(ins.Jf),
+				k: C.uint32_t(ins.K)}
+		}
+		if status := C.capture_filter_set(c, &insns[0], C.uint32_t(len(insns))); status != 0 {
+			C.capture_free(c)
+			return nil, fmt.Errorf("capture filter error: %w", common.Err(status))
+		}
+	}
+
+	return &Capture{
+		c:       c,
+		tscHz:   uint64(C.rte_get_tsc_hz()),
+		tscRef:  uint64(C.rte_rdtsc()),
+		timeRef: time.Now(),
+	}, nil
+}
+
+// Free the capture buffer, it must be detached from all pipeline ports
+func (c *Capture) Free() {
+	C.capture_free(c.c)
+	c.c = nil
+}
+
+// Dropped returns the number of packets that couldn't be captured because the buffer was full
+func (c *Capture) Dropped() uint64 {
+	return uint64(C.capture_dropped(c.c))
+}
+
+// Read calls the given function for the captured packets currently in the buffer (with a maximum of one burst) and
+// returns the number of packets read. The data slice is only valid during the function call.
+func (c *Capture) Read(fn func(ifIndex uint32, ts time.Time, origLen uint32, data []byte)) int {
+	var recs [captureReadBurst]*C.struct_capture_rec
+
+	n := C.capture_read(c.c, &recs[0], captureReadBurst)
+	for _, rec := range recs[:n] {
+		data := unsafe.Slice((*byte)(unsafe.Pointer(&rec.data)), int(rec.caplen))
+		fn(uint32(rec.if_index), c.time(uint64(rec.tsc)), uint32(rec.len), data)
+	}
+	if n > 0 {
+		C.capture_rec_free(c.c, &recs[0], n)
+	}
+
+	return int(n)
+}
+
+// convert a TSC value to time
+func (c *Capture) time(tsc uint64) time.Time {
+	delta := tsc - c.tscRef
+	ns := (delta/c.tscHz)*uint64(time.Second) + (delta%c.tscHz)*uint64(time.Second)/c.tscHz
+	return c.timeRef.Add(time.Duration(ns))
+}
+
+// pipeline port a capture is attached to
+type capturePort struct {
+	dir    int
+	portID int
+}
+
+// capture attached before the pipeline is build
+type pendingCapture struct {
+	capture *Capture
+	ifIndex uint32
+}
+
+// CaptureAttach starts copying the packets of the given pipeline port into the given capture buffer. The given
+// interface index is stored with every captured packet. The port functions of the pipeline are only replaced by the
+// capture functions while a capture is attached, a capture attached before the pipeline is build is installed when
+// the pipeline is build.
+func (pl *Pipeline) CaptureAttach(dir int, portID int, c *Capture, ifIndex uint32) error {
+	pl.captureMu.Lock()
+	defer pl.captureMu.Unlock()
+
+	if pl.p == nil {
+		return fmt.Errorf("pipeline %s is freed", pl.GetName())
+	}
+	if !pl.build {
+		port := capturePort{dir: dir, portID: portID}
+		if pl.captures[port] != nil {
+			return fmt.Errorf("pipeline %s port %d is already captured", pl.GetName(), portID)
+		}
+		pl.captures[port] = &pendingCapture{capture: c, ifIndex: ifIndex}
+		return nil
+	}
+
+	return pl.captureAttach(dir, portID, c, ifIndex)
+}
+
+func (pl *Pipeline) captureAttach(dir int, portID int, c *Capture, ifIndex uint32) error {
+	status := C.capture_port_attach(pl.p, C.int(dir), C.uint32_t(portID), c.c, C.uint32_t(ifIndex))
+	switch {
+	case status == -C.EBUSY:
+		return fmt.Errorf("pipeline %s port %d is already captured", pl.GetName(), portID)
+	case status == -C.ENOSPC:
+		return fmt.Errorf("no capture hooks left for pipeline %s port %d", pl.GetName(), portID)
+	case status != 0:
+		return fmt.Errorf("pipeline %s port %d capture: %w", pl.GetName(), portID, common.Err(status))
+	}
+	return nil
+}
+
+// install the captures attached before the pipeline was build, called with captureMu locked
+func (pl *Pipeline) captureAttachPending() error {
+	for port, pc := range pl.captures {
+		if err := pl.captureAttach(port.dir, port.portID, pc.capture, pc.ifIndex); err != nil {
+			return err
+		}
+		delete(pl.captures, port)
+	}
+	return nil
+}
+
+// CaptureDetach stops copying the packets of the given pipeline port and restores the port functions. It returns
+// after the pipeline thread stopped using the capture buffer. It can be called from any goroutine, also while the
+// pipeline is build.
+func (pl *Pipeline) CaptureDetach(dir int, portID int) error {
+	pl.captureMu.Lock()
+	defer pl.captureMu.Unlock()
+
+	if pl.p == nil {
+		return fmt.Errorf("pipeline %s is freed", pl.GetName())
+	}
+	if !pl.build {
+		delete(pl.captures, capturePort{dir: dir, portID: portID})
+		return nil
+	}
+
+	if status := C.capture_port_detach(pl.p, C.int(dir), C.uint32_t(portID)); status != 0 {
+		return fmt.Errorf("pipeline %s port %d capture: %w", pl.GetName(), portID, common.Err(status))
+	}
+	return nil
+}
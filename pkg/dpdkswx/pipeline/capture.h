@@ -0,0 +1,50 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+#ifndef _INCLUDE_CAPTURE_H_
+#define _INCLUDE_CAPTURE_H_
+
+#include <stdint.h>
+#include <rte_swx_pipeline.h>
+
+/**
+ * Packet capture buffer. Copies of the (filtered) packets of the pipeline ports it is attached to are stored as
+ * capture records in a ring and read by the control plane.
+ */
+struct capture;
+
+struct capture_rec {
+	uint64_t tsc;
+	uint32_t if_index;
+	uint32_t len;
+	uint32_t caplen;
+	uint32_t reserved;
+	uint8_t data[];
+};
+
+/**
+ * Classic BPF instruction, i.e. compiled from a pcap filter expression
+ */
+struct capture_insn {
+	uint16_t code;
+	uint8_t jt;
+	uint8_t jf;
+	uint32_t k;
+};
+
+struct capture *capture_create(const char *name, uint32_t n_recs, uint32_t snaplen, int numa_node);
+void capture_free(struct capture *cap);
+int capture_filter_set(struct capture *cap, const struct capture_insn *insns, uint32_t n_insns);
+uint32_t capture_read(struct capture *cap, struct capture_rec **recs, uint32_t n_recs);
+void capture_rec_free(struct capture *cap, struct capture_rec **recs, uint32_t n_recs);
+uint64_t capture_dropped(struct capture *cap);
+
+/**
+ * Attach a capture to a port of a build pipeline. The port functions of the pipeline are only replaced by capture
+ * functions while a capture is attached.
+ */
+int capture_port_attach(struct rte_swx_pipeline *p, int out, uint32_t port_id, struct capture *cap, uint32_t if_index);
+int capture_port_detach(struct rte_swx_pipeline *p, int out, uint32_t port_id);
+void capture_hooks_release(struct rte_swx_pipeline *p);
+
+#endif /* _INCLUDE_CAPTURE_H_ */
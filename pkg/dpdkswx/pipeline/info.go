@@ -190,6 +190,13 @@ func (pl *Pipeline) TableInfoGet(tableID uint) (*TableInfo, error) {
 	return &tableInfo, nil
 }
 
+// table match field match types
+const (
+	MatchWildcard = C.RTE_SWX_TABLE_MATCH_WILDCARD // Wildcard match, value/mask
+	MatchLpm      = C.RTE_SWX_TABLE_MATCH_LPM      // Longest prefix match, value/mask with a prefix mask
+	MatchExact    = C.RTE_SWX_TABLE_MATCH_EXACT    // Exact match, value only
+)
+
 // information about table match fields
 type TableMatchFieldInfo C.struct_rte_swx_ctl_table_match_field_info
 
@@ -3,17 +3,28 @@
 
 package pipeline
 
+/*
+#include <stdint.h>
+
+#include <rte_swx_pipeline.h>
+#include <rte_swx_ctl.h>
+*/
+import "C"
 import (
 	"fmt"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
 )
 
 type LearnerTable struct {
-	index                uint   // Index in swx_pipeline learnertable store
-	name                 string // Learner Table name.
-	nMatchFields         uint   // Number of match fields.
-	nActions             uint   // Number of actions.
-	defaultActionIsConst bool   // true => the default action is constant; false => the default action not constant
-	size                 int    // Table size parameter.
+	pipeline             *Pipeline // parent pipeline
+	index                uint      // Index in swx_pipeline learnertable store
+	name                 string    // Learner Table name.
+	nMatchFields         uint      // Number of match fields.
+	nActions             uint      // Number of actions.
+	defaultActionIsConst bool      // true => the default action is constant; false => the default action not constant
+	size                 int       // Table size parameter.
+	nKeyTimeouts         uint32    // Number of key timeouts.
 	matchFields          TableMatchFieldStore
 	actions              TableActionStore
 }
@@ -26,12 +37,14 @@ func (t *LearnerTable) Init(p *Pipeline, index uint) error {
 	}
 
 	// initalize generic table attributes
+	t.pipeline = p
 	t.index = index
 	t.name = learnerInfo.GetName()
 	t.nMatchFields = learnerInfo.GetNMatchFields()
 	t.nActions = learnerInfo.GetNActions()
 	t.defaultActionIsConst = learnerInfo.DefaultActionIsConst()
 	t.size = int(learnerInfo.GetSize())
+	t.nKeyTimeouts = learnerInfo.GetNKeyTimeouts()
 
 	// get all matchfields for this table
 	t.matchFields = CreateTableMatchFieldsStore()
@@ -99,6 +112,33 @@ func (t *LearnerTable) GetSize() int {
 	return t.size
 }
 
+// Number of key timeouts of the learner table
+func (t *LearnerTable) GetNKeyTimeouts() uint32 {
+	return t.nKeyTimeouts
+}
+
+// Learner table key timeout set
+//
+// Set the key timeout with the given timeoutID to the given number of seconds. The timeouts are defined in the
+// pipeline spec file and the timeoutID is the position in that list. Returns nil on success or the following error
+// codes otherwise:
+//
+//	-EINVAL = Invalid argument
+func (t *LearnerTable) TimeoutSet(timeoutID uint32, timeout uint32) error {
+	if timeoutID >= t.nKeyTimeouts {
+		return fmt.Errorf("timeout ID %d out of range, learner table %s has %d timeouts", timeoutID, t.name,
+			t.nKeyTimeouts)
+	}
+
+	result := C.rte_swx_ctl_pipeline_learner_timeout_set(t.pipeline.p, C.uint(t.index), C.uint(timeoutID),
+		C.uint(timeout))
+	if result != 0 {
+		return common.Err(result)
+	}
+
+	return nil
+}
+
 func (t *LearnerTable) GetMatchFields() TableMatchFieldStore {
 	return t.matchFields
 }
@@ -35,6 +35,8 @@ package pipeline
 #include <rte_swx_ctl.h>
 #include <rte_swx_port.h>
 
+#include "capture.h"
+
 int pipeline_build_from_spec(struct rte_swx_pipeline *pipeline, char *specfname) {
 	FILE *spec = NULL;
 	uint32_t err_line;
@@ -60,10 +62,12 @@ int pipeline_build_from_spec(struct rte_swx_pipeline *pipeline, char *specfname)
 import "C"
 import (
 	"errors"
+	"sync"
 	"unsafe"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/swxruntime"
 	"github.com/stolsma/go-p4pack/pkg/logging"
 )
@@ -107,14 +111,21 @@ type Pipeline struct {
 	// TODO mirror slots
 	// TODO mirror sessions
 	// TODO selectors SelectorStore // All the defined selector tables in this pipeline when build
-	learners  LearnerStore  // All the defined learner tables in this pipeline when build
-	registers RegisterStore // All the defined registers in this pipeline when build
-	meters    MeterStore    // All the defined meters in this pipeline when build
-	clean     func()        // The callback function called at clear
+	learners      LearnerStore                    // All the defined learner tables in this pipeline when build
+	registers     RegisterStore                   // All the defined registers in this pipeline when build
+	meters        MeterStore                      // All the defined meters in this pipeline when build
+	meterProfiles *MeterProfileStore              // All the added meter profiles
+	captures      map[capturePort]*pendingCapture // Captures attached before the pipeline is build
+	captureMu     sync.Mutex                      // Guards build, captures and p against the capture sessions
+	clean         func()                          // The callback function called at clear
 }
 
 // Initialize Pipeline. Returns an error if something went wrong.
 func (pl *Pipeline) Init(name string, numaNode int, clean func()) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	var p *C.struct_rte_swx_pipeline
 
 	// Resource create
@@ -139,6 +150,7 @@ func (pl *Pipeline) Init(name string, numaNode int, clean func()) error {
 	pl.enabled = false
 	pl.portsIn = make(swxPorts, MaxPortsIn)
 	pl.portsOut = make(swxPorts, MaxPortsOut)
+	pl.captures = make(map[capturePort]*pendingCapture)
 	pl.clean = clean
 
 	return nil
@@ -155,13 +167,17 @@ func (pl *Pipeline) Free() (err error) {
 				swxruntime.DisablePipeline(pl.GetPipeline())
 			}
 			pl.Ctl.Free()
+			C.capture_hooks_release(pl.p)
 			C.rte_swx_pipeline_free(pl.p)
 			return nil
 		})
 
+		pl.captureMu.Lock()
 		pl.build = false
-		pl.enabled = false
+		pl.captures = make(map[capturePort]*pendingCapture)
 		pl.p = nil
+		pl.captureMu.Unlock()
+		pl.enabled = false
 	}
 
 	if pl.clean != nil {
@@ -183,6 +199,36 @@ func (pl *Pipeline) GetPipeline() unsafe.Pointer {
 	return unsafe.Pointer(pl.p)
 }
 
+// GetTables returns the store of the tables of the pipeline, nil if the pipeline isn't build
+func (pl *Pipeline) GetTables() TableStore {
+	return pl.tables
+}
+
+// GetTable returns the table with the given name or nil if the pipeline has no such table
+func (pl *Pipeline) GetTable(name string) *Table {
+	return pl.tables.FindName(name)
+}
+
+// GetLearner returns the learner table with the given name or nil if the pipeline has no such learner table
+func (pl *Pipeline) GetLearner(name string) *LearnerTable {
+	return pl.learners.FindName(name)
+}
+
+// GetRegister returns the register array with the given name or nil if the pipeline has no such register array
+func (pl *Pipeline) GetRegister(name string) *Register {
+	return pl.registers.FindName(name)
+}
+
+// GetMeter returns the meter array with the given name or nil if the pipeline has no such meter array
+func (pl *Pipeline) GetMeter(name string) *Meter {
+	return pl.meters.FindName(name)
+}
+
+// GetMeterProfiles returns the store of the meter profiles added to the pipeline, nil if the pipeline isn't build
+func (pl *Pipeline) GetMeterProfiles() *MeterProfileStore {
+	return pl.meterProfiles
+}
+
 func (pl *Pipeline) IsBuild() bool {
 	return pl.build
 }
@@ -258,12 +304,15 @@ func (pl *Pipeline) BuildFromSpec(specfile string) error {
 	// retrieve meters
 	pl.meters = CreateMeterStore()
 	pl.meters.CreateFromPipeline(pl)
+	pl.meterProfiles = CreateMeterProfileStore(pl)
 
 	// TODO implement as ENUM state field???
 	// pipeline status is build!
+	pl.captureMu.Lock()
+	defer pl.captureMu.Unlock()
 	pl.build = true
 
-	return nil
+	return pl.captureAttachPending()
 }
 
 // Set pipeline to enabled on given thread
@@ -19,6 +19,20 @@ func (tmf *TableMatchField) GetIndex() uint {
 	return tmf.index
 }
 
+// match type of the field, one of MatchWildcard, MatchLpm or MatchExact
+func (tmf *TableMatchField) GetMatchType() int {
+	return tmf.matchType
+}
+
+// true => the field is a header field; false => the field is a meta-data field
+func (tmf *TableMatchField) GetIsHeader() bool {
+	return tmf.isHeader
+}
+
+func (tmf *TableMatchField) GetNBits() int {
+	return tmf.nBits
+}
+
 // TableMatchFieldsStore represents a store of TableMatchFields records
 type TableMatchFieldStore map[uint]*TableMatchField
 
@@ -74,6 +88,11 @@ func (ta *TableAction) GetActionIndex() uint {
 	return ta.action.GetIndex()
 }
 
+// the arguments of the action in argument order
+func (ta *TableAction) GetActionArgs() []*ActionArg {
+	return ta.action.GetArgs()
+}
+
 func (ta *TableAction) GetActionIsForDefaultEntry() bool {
 	return ta.actionIsForDefaultEntry
 }
@@ -0,0 +1,92 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pktmbuf
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include <rte_mbuf.h>
+#include <rte_mempool.h>
+
+struct mempool_names {
+	char (*names)[RTE_MEMPOOL_NAMESIZE];
+	unsigned int n;
+	unsigned int max;
+};
+
+// returns 1 if the mempool has the private data of a pktmbuf pool
+static int mempool_is_pktmbuf(struct rte_mempool *mp) {
+	return mp->private_data_size >= sizeof(struct rte_pktmbuf_pool_private);
+}
+
+static void mempool_names_cb(struct rte_mempool *mp, void *arg) {
+	struct mempool_names *mn = arg;
+
+	if (!mempool_is_pktmbuf(mp))
+		return;
+	if (mn->n < mn->max)
+		strncpy(mn->names[mn->n], mp->name, RTE_MEMPOOL_NAMESIZE - 1);
+	mn->n++;
+}
+
+// copies max pktmbuf mempool names into names and returns the total number of pktmbuf mempools
+static unsigned int mempool_names(char (*names)[RTE_MEMPOOL_NAMESIZE], unsigned int max) {
+	struct mempool_names mn = { .names = names, .n = 0, .max = max };
+
+	rte_mempool_walk(mempool_names_cb, &mn);
+	return mn.n;
+}
+
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
+	"github.com/yerden/go-dpdk/mempool"
+)
+
+// Lookup searches a mempool created by this or another (primary) DPDK process by its name and returns a Pktmbuf for
+// it. The returned Pktmbuf doesn't own the mempool, i.e. Free doesn't free the mempool. Mempools that aren't pktmbuf
+// pools (i.e. without the pktmbuf pool private data) are not returned.
+func Lookup(name string) (*Pktmbuf, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	mp := C.rte_mempool_lookup(cname)
+	if mp == nil {
+		return nil, common.Err()
+	}
+	if C.mempool_is_pktmbuf(mp) == 0 {
+		return nil, errors.New("not a pktmbuf pool")
+	}
+
+	return &Pktmbuf{
+		name:       name,
+		m:          (*mempool.Mempool)(unsafe.Pointer(mp)),
+		bufferSize: uint(C.rte_pktmbuf_data_room_size(mp)),
+		lookup:     true,
+	}, nil
+}
+
+// Names returns the names of all pktmbuf mempools in the DPDK process (group), other mempools are left out
+func Names() []string {
+	n := C.mempool_names(nil, 0)
+	if n == 0 {
+		return nil
+	}
+
+	names := make([][C.RTE_MEMPOOL_NAMESIZE]C.char, n)
+	n = C.mempool_names(&names[0], n)
+	if int(n) > len(names) {
+		n = C.uint(len(names))
+	}
+
+	result := make([]string, 0, n)
+	for i := range names[:n] {
+		result = append(result, C.GoString(&names[i][0]))
+	}
+	return result
+}
@@ -13,6 +13,7 @@ import (
 	"errors"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/swxruntime"
 	"github.com/stolsma/go-p4pack/pkg/logging"
 	"github.com/yerden/go-dpdk/mempool"
@@ -39,6 +40,8 @@ type Pktmbuf struct {
 	name       string
 	m          *mempool.Mempool
 	bufferSize uint
+	highWater  uint32 // highest number of mbufs in use seen, updated by Stats
+	lookup     bool   // mempool found with Lookup and owned by another process
 	clean      func()
 }
 
@@ -65,6 +68,10 @@ func (pm *Pktmbuf) Init(
 		return errors.New("pool size is 0")
 	}
 
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	// create PktMbufpool on main DPDK Lcore to prevent problems
 	var md *mempool.Mempool
 	err := dpdkswx.Runtime.ExecOnMain(func(*swxruntime.MainCtx) (err error) {
@@ -99,11 +106,20 @@ func (pm *Pktmbuf) Mempool() *mempool.Mempool {
 	return pm.m
 }
 
+// NumaNode returns the NUMA node the mempool memory is allocated on, -1 if not allocated on a specific node
+func (pm *Pktmbuf) NumaNode() int {
+	return int(pm.mempool().socket_id)
+}
+
+func (pm *Pktmbuf) BufferSize() uint {
+	return pm.bufferSize
+}
+
 func (pm *Pktmbuf) Free() error {
-	if pm.m != nil {
+	if pm.m != nil && !pm.lookup {
 		pm.m.Free()
-		pm.m = nil
 	}
+	pm.m = nil
 
 	// call given clean callback function if given during init
 	if pm.clean != nil {
@@ -0,0 +1,107 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pktmbuf
+
+/*
+#include <rte_lcore.h>
+#include <rte_mempool.h>
+
+static unsigned int mempool_cache_count(struct rte_mempool *mp, unsigned int lcore) {
+	if (mp->cache_size == 0 || mp->local_cache == NULL)
+		return 0;
+	return mp->local_cache[lcore].len;
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// CacheStats is the occupancy of the mempool cache of an lcore
+type CacheStats struct {
+	Lcore uint32
+	Count uint32 // number of mbufs in the lcore cache
+}
+
+// Stats are the utilisation statistics of a pktmbuf mempool
+type Stats struct {
+	Size      uint32       // total number of mbufs in the mempool
+	CacheSize uint32       // maximum number of mbufs per lcore cache
+	Avail     uint32       // number of mbufs available (in the mempool and in the lcore caches)
+	InUse     uint32       // number of mbufs in use
+	HighWater uint32       // highest number of mbufs in use seen since creation or last reset
+	Caches    []CacheStats // occupancy of the lcore caches, only for enabled lcores and if caches are used
+}
+
+// Usage returns the percentage of mbufs in use
+func (s *Stats) Usage() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.InUse) * 100 / float64(s.Size)
+}
+
+func (s *Stats) String() string {
+	result := fmt.Sprintf("size: %d, in use: %d (%.1f%%), available: %d, high water: %d", s.Size, s.InUse, s.Usage(),
+		s.Avail, s.HighWater)
+	if s.CacheSize > 0 {
+		result += fmt.Sprintf(", cache size: %d, caches:", s.CacheSize)
+		for _, c := range s.Caches {
+			result += fmt.Sprintf(" %d:%d", c.Lcore, c.Count)
+		}
+	}
+	return result
+}
+
+func (pm *Pktmbuf) mempool() *C.struct_rte_mempool {
+	return (*C.struct_rte_mempool)(unsafe.Pointer(pm.m))
+}
+
+// Stats returns the current utilisation statistics of the mempool and updates the high watermark. The counts are a
+// snapshot and not exact while the mempool is used by other lcores.
+func (pm *Pktmbuf) Stats() Stats {
+	mp := pm.mempool()
+	if mp == nil {
+		return Stats{}
+	}
+
+	stats := Stats{
+		Size:      uint32(mp.size),
+		CacheSize: uint32(mp.cache_size),
+		Avail:     uint32(C.rte_mempool_avail_count(mp)),
+		InUse:     uint32(C.rte_mempool_in_use_count(mp)),
+	}
+
+	// update high watermark
+	for {
+		hw := atomic.LoadUint32(&pm.highWater)
+		if stats.InUse <= hw || atomic.CompareAndSwapUint32(&pm.highWater, hw, stats.InUse) {
+			break
+		}
+	}
+	stats.HighWater = atomic.LoadUint32(&pm.highWater)
+
+	if stats.CacheSize > 0 {
+		for lcore := C.uint(0); lcore < C.RTE_MAX_LCORE; lcore++ {
+			if C.rte_lcore_is_enabled(lcore) == 0 {
+				continue
+			}
+			stats.Caches = append(stats.Caches, CacheStats{
+				Lcore: uint32(lcore),
+				Count: uint32(C.mempool_cache_count(mp, lcore)),
+			})
+		}
+	}
+
+	return stats
+}
+
+// ResetHighWater sets the high watermark to the current number of mbufs in use
+func (pm *Pktmbuf) ResetHighWater() {
+	atomic.StoreUint32(&pm.highWater, 0)
+	pm.Stats()
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/common"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 )
 
@@ -46,6 +47,10 @@ type Ring struct {
 func (r *Ring) Init(name string, params *Params, clean func()) error {
 	const flags = SingleProducer | SingleConsumer
 
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	cname := C.CString(name)
 	defer C.free(unsafe.Pointer(cname))
 
@@ -75,6 +80,21 @@ func (r *Ring) Size() uint {
 	return r.size
 }
 
+// Count returns the number of entries in the ring
+func (r *Ring) Count() uint {
+	return uint(C.rte_ring_count(r.r))
+}
+
+// FreeCount returns the number of free entries in the ring
+func (r *Ring) FreeCount() uint {
+	return uint(C.rte_ring_free_count(r.r))
+}
+
+// Capacity returns the number of entries the ring can hold
+func (r *Ring) Capacity() uint {
+	return uint(C.rte_ring_get_capacity(r.r))
+}
+
 // Free deletes the current Ring record and calls the clean callback function given at init
 func (r *Ring) Free() error {
 	C.rte_ring_free(r.r)
@@ -190,7 +210,7 @@ func (r *Ring) BindToPipelineInputPort(pl *pipeline.Pipeline, portID int, rxq ui
 		return err
 	}
 
-	return r.SetRxQueue(rxq, pl.GetName(), portID)
+	return r.SetRxQueue(rxq, pl.GetName(), portID, bsz)
 }
 
 // bind to given pipeline output port index. A ring has 1 queue so only queue number 0 is valid.
@@ -210,5 +230,5 @@ func (r *Ring) BindToPipelineOutputPort(pl *pipeline.Pipeline, portID int, txq u
 		return err
 	}
 
-	return r.SetTxQueue(txq, pl.GetName(), portID)
+	return r.SetTxQueue(txq, pl.GetName(), portID, bsz)
 }
@@ -12,31 +12,90 @@ package sourcesink
 import "C"
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
+	"github.com/stolsma/go-p4pack/pkg/logging"
+	"github.com/stolsma/go-p4pack/pkg/pcap"
+)
+
+var log logging.Logger
+
+func init() {
+	// keep the logger up to date, also after new log config
+	logging.Register("dpdkswx/sourcesink", func(logger logging.Logger) {
+		log = logger
+	})
+}
+
+const (
+	sinkBufferSize     = 8192                  // number of packets buffered between the pipeline and the file writer
+	sinkDefaultSnapLen = 65535                 // default maximum number of bytes written per packet
+	sinkPollInterval   = 10 * time.Millisecond // interval the buffered packets are written to the file
+	sinkMaxReads       = 64                    // maximum number of buffer reads (bursts) per poll interval
 )
 
 type SinkParams struct {
-	// Name of a valid PCAP file to write the output packets to. When NULL, all the output packets are dropped instead
-	// of being saved to a PCAP file.
+	// File name template of the PCAP files to write the output packets to, see pcap.RotateParams for the supported
+	// verbs. When empty, all the output packets are dropped instead of being saved to a PCAP file.
 	FileName string
+	// Start a new file when the current file would exceed this size in bytes. No size limit when set to 0.
+	MaxSize int64
+	// Start a new file when the current file is open for this duration. No time limit when set to 0.
+	Interval time.Duration
+	// Maximum number of files kept, the oldest file is removed when exceeded. No limit when set to 0.
+	MaxFiles int
+	// Maximum number of bytes written per packet. When 0, it is set to 65535.
+	SnapLen uint32
+}
+
+// SinkStats are the counters and state of a Sink device
+type SinkStats struct {
+	Packets  uint64 // packets written to the PCAP files
+	Bytes    int64  // bytes written to the PCAP files
+	Dropped  uint64 // packets not written because the buffer was full
+	Files    uint64 // number of files opened
+	FileName string // current file
+	Paused   bool
+	Err      error // last write error, writing stops until the sink is reopened
 }
 
 // Sink represents a Sink device
 type Sink struct {
 	*device.Device
-	fileName string
+	params SinkParams
+
+	mu      sync.Mutex
+	pl      *pipeline.Pipeline // pipeline and output port the sink is bound to
+	portID  int
+	capture *pipeline.Capture
+	writer  *pcap.RotatingWriter
+	paused  bool
+	err     error
+	stop    chan struct{}
+	done    chan struct{}
 }
 
 // Create and initialize Sink device
 func (s *Sink) Init(name string, params *SinkParams, clean func()) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	// Node fill in
 	s.Device = &device.Device{}
 	s.SetType("SINK")
 	s.SetName(name)
-	s.fileName = params.FileName
+	s.params = *params
+	if s.params.SnapLen == 0 {
+		s.params.SnapLen = sinkDefaultSnapLen
+	}
 	s.InitializeQueues(0, 1) // initialize queue setup for pipeline bind use
 	s.SetClean(clean)
 
@@ -45,6 +104,8 @@ func (s *Sink) Init(name string, params *SinkParams, clean func()) error {
 
 // Free deletes the current Sink record and calls the clean callback function given at init
 func (s *Sink) Free() error {
+	s.stopWriting()
+
 	// call given clean callback function if given during init
 	if s.Clean() != nil {
 		s.Clean()()
@@ -53,11 +114,208 @@ func (s *Sink) Free() error {
 	return nil
 }
 
+// Pause stops writing packets to the PCAP file, the packets are dropped until the sink is resumed
+func (s *Sink) Pause() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.capture == nil {
+		return errors.New("sink is not bound or doesn't write to a file")
+	}
+	if s.paused {
+		return nil
+	}
+	if err := s.pl.CaptureDetach(pipeline.PortOut, s.portID); err != nil {
+		return err
+	}
+	s.paused = true
+	return nil
+}
+
+// Resume starts writing packets to the PCAP file again
+func (s *Sink) Resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.capture == nil {
+		return errors.New("sink is not bound or doesn't write to a file")
+	}
+	if !s.paused {
+		return nil
+	}
+	if err := s.pl.CaptureAttach(pipeline.PortOut, s.portID, s.capture, 0); err != nil {
+		return err
+	}
+	s.paused = false
+	return nil
+}
+
+// Reopen closes the current PCAP file and opens a new file. A write error is cleared when the new file is opened.
+func (s *Sink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		return errors.New("sink is not bound or doesn't write to a file")
+	}
+	if err := s.writer.Rotate(); err != nil {
+		s.err = err
+		return err
+	}
+	s.err = nil
+	return nil
+}
+
+// Stats returns the counters and state of the sink
+func (s *Sink) Stats() SinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SinkStats{Paused: s.paused, Err: s.err}
+	if s.writer != nil {
+		stats.Packets, stats.Bytes = s.writer.Stats()
+		stats.Files = s.writer.Opened()
+		stats.FileName = s.writer.FileName()
+	}
+	if s.capture != nil {
+		stats.Dropped = s.capture.Dropped()
+	}
+	return stats
+}
+
+func (s *Sink) GetPortInfo() (map[string]string, error) {
+	info := make(map[string]string)
+	info["filename"] = s.params.FileName
+	info["maxsize"] = strconv.FormatInt(s.params.MaxSize, 10)
+	info["interval"] = s.params.Interval.String()
+	info["maxfiles"] = strconv.Itoa(s.params.MaxFiles)
+	info["snaplen"] = strconv.FormatUint(uint64(s.params.SnapLen), 10)
+	return info, nil
+}
+
+func (s *Sink) GetPortStats() (map[string]string, error) {
+	stats := s.Stats()
+	info := make(map[string]string)
+	info["opackets"] = fmt.Sprintf("%-20d", stats.Packets)
+	info["obytes"] = fmt.Sprintf("%-20d", stats.Bytes)
+	info["odropped"] = fmt.Sprintf("%-20d", stats.Dropped)
+	info["files"] = fmt.Sprintf("%-20d", stats.Files)
+	info["file"] = stats.FileName
+	info["paused"] = strconv.FormatBool(stats.Paused)
+	if stats.Err != nil {
+		info["error"] = stats.Err.Error()
+	}
+	return info, nil
+}
+
+// start writing the packets sent to the given pipeline output port to the PCAP file(s), a previous capture (i.e. of a
+// pipeline that is deleted and created again) is stopped first
+func (s *Sink) start(pl *pipeline.Pipeline, portID int) error {
+	s.stopWriting()
+
+	capture, err := pipeline.NewCapture("SNK_"+s.Name(), sinkBufferSize, s.params.SnapLen, nil, -1)
+	if err != nil {
+		return err
+	}
+
+	writer, err := pcap.NewRotatingWriter(pcap.RotateParams{
+		Template: s.params.FileName,
+		MaxSize:  s.params.MaxSize,
+		Interval: s.params.Interval,
+		MaxFiles: s.params.MaxFiles,
+		SnapLen:  s.params.SnapLen,
+		LinkType: pcap.LinkTypeEthernet,
+	})
+	if err != nil {
+		capture.Free()
+		return err
+	}
+
+	if err := pl.CaptureAttach(pipeline.PortOut, portID, capture, 0); err != nil {
+		writer.Close()
+		capture.Free()
+		return err
+	}
+
+	s.mu.Lock()
+	s.pl, s.portID, s.capture, s.writer, s.paused, s.err = pl, portID, capture, writer, false, nil
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+	s.mu.Unlock()
+	return nil
+}
+
+// stop capturing (the pipeline can already be freed, then there is nothing to detach), write the packets still
+// buffered and close the current file. Must be called without holding s.mu, the run goroutine takes it.
+func (s *Sink) stopWriting() {
+	s.mu.Lock()
+	capture, stop, done := s.capture, s.stop, s.done
+	if capture == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.pl.CaptureDetach(pipeline.PortOut, s.portID)
+	s.mu.Unlock()
+
+	close(stop)
+	<-done
+
+	s.mu.Lock()
+	capture.Free()
+	s.pl, s.capture, s.writer = nil, nil, nil
+	s.mu.Unlock()
+}
+
+// write the buffered packets to the PCAP file(s) until stopped
+func (s *Sink) run(stop <-chan struct{}, done chan<- struct{}) {
+	ticker := time.NewTicker(sinkPollInterval)
+	defer ticker.Stop()
+
+	write := func(ifIndex uint32, ts time.Time, origLen uint32, data []byte) {
+		if s.err != nil {
+			return
+		}
+		if s.err = s.writer.WritePacket(ts, origLen, data); s.err != nil {
+			log.Errorf("sink %s write error, packets are dropped until reopened: %v", s.Name(), s.err)
+		}
+	}
+	read := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for i := 0; i < sinkMaxReads; i++ {
+			if s.capture.Read(write) == 0 {
+				break
+			}
+		}
+		if err := s.writer.Flush(); err != nil && s.err == nil {
+			s.err = err
+			log.Errorf("sink %s write error, packets are dropped until reopened: %v", s.Name(), err)
+		}
+	}
+
+	for {
+		select {
+		case <-stop:
+			read()
+			s.mu.Lock()
+			if err := s.writer.Close(); err != nil {
+				log.Errorf("sink %s close error: %v", s.Name(), err)
+			}
+			s.mu.Unlock()
+			close(done)
+			return
+		case <-ticker.C:
+			read()
+		}
+	}
+}
+
 type SwxPortSinkParams struct {
 	txParams  *C.struct_rte_swx_port_sink_params
 	paramsSet bool
 	name      string
-	fileName  string
 }
 
 func (e *SwxPortSinkParams) PortName() string {
@@ -86,9 +344,10 @@ func (e *SwxPortSinkParams) createCParams() {
 		return
 	}
 
+	// the packets are written to file by the sink device itself
 	e.paramsSet = true
 	e.txParams = &C.struct_rte_swx_port_sink_params{
-		file_name: C.CString(e.fileName),
+		file_name: nil,
 	}
 }
 
@@ -98,7 +357,6 @@ func (e *SwxPortSinkParams) freeCParams() {
 	}
 
 	e.paramsSet = false
-	C.free(unsafe.Pointer(e.txParams.file_name))
 	e.txParams = nil
 }
 
@@ -116,12 +374,16 @@ func (s *Sink) BindToPipelineOutputPort(pl *pipeline.Pipeline, portID int, txq u
 	}
 
 	params := &SwxPortSinkParams{
-		name:     s.Name(),
-		fileName: s.fileName,
+		name: s.Name(),
 	}
 	if err := pl.PortOutConfig(portID, params); err != nil {
 		return err
 	}
+	if s.params.FileName != "" {
+		if err := s.start(pl, portID); err != nil {
+			return err
+		}
+	}
 
-	return s.SetTxQueue(txq, pl.GetName(), portID)
+	return s.SetTxQueue(txq, pl.GetName(), portID, bsz)
 }
@@ -12,16 +12,22 @@ package sourcesink
 import "C"
 import (
 	"errors"
+	"os"
+	"time"
 	"unsafe"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
+	"github.com/stolsma/go-p4pack/pkg/pcap"
 )
 
 type SourceParams struct {
 	// Name of a valid PCAP file to read the input packets from.
 	FileName string
+	// Packets to send when no PCAP file name is given. A temporary PCAP file is created with these packets.
+	Packets [][]byte
 	// Number of times to loop through the input PCAP file. Loop infinite times when set to 0.
 	NLoops uint64
 	// Maximum number of packets to read from the PCAP file. When 0, it is internally set to RTE_SWX_PORT_SOURCE_PKTS_MAX.
@@ -35,6 +41,7 @@ type SourceParams struct {
 type Source struct {
 	*device.Device
 	fileName string
+	tempFile bool // the PCAP file is created from the given packets and removed when freed
 	nLoops   uint64
 	nPktsMax uint32
 	pktmbuf  *pktmbuf.Pktmbuf
@@ -42,6 +49,10 @@ type Source struct {
 
 // Create and initialize Source device
 func (s *Source) Init(name string, params *SourceParams, clean func()) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	// Node fill in
 	s.Device = &device.Device{}
 	s.SetType("SOURCE")
@@ -50,14 +61,48 @@ func (s *Source) Init(name string, params *SourceParams, clean func()) error {
 	s.nLoops = params.NLoops
 	s.nPktsMax = params.NPktsMax
 	s.pktmbuf = params.Pktmbuf
+	if s.fileName == "" && len(params.Packets) > 0 {
+		fileName, err := writePackets(name, params.Packets)
+		if err != nil {
+			return err
+		}
+		s.fileName = fileName
+		s.tempFile = true
+	}
 	s.InitializeQueues(1, 0) // initialize queue setup for pipeline bind use
 	s.SetClean(clean)
 
 	return nil
 }
 
+// write the given packets to a temporary PCAP file and return the name of that file
+func writePackets(name string, packets [][]byte) (string, error) {
+	file, err := os.CreateTemp("", "source-"+name+"-*.pcap")
+	if err != nil {
+		return "", err
+	}
+
+	w, err := pcap.NewWriter(file, 0, pcap.LinkTypeEthernet)
+	for i := 0; err == nil && i < len(packets); i++ {
+		err = w.WritePacket(time.Unix(0, int64(i)*int64(time.Microsecond)), uint32(len(packets[i])), packets[i])
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
 // Free deletes the current Sink record and calls the clean callback function given at init
 func (s *Source) Free() error {
+	if s.tempFile {
+		os.Remove(s.fileName)
+	}
+
 	// call given clean callback function if given during init
 	if s.Clean() != nil {
 		s.Clean()()
@@ -140,7 +185,7 @@ func (s *Source) BindToPipelineInputPort(pl *pipeline.Pipeline, portID int, rxq
 		return err
 	}
 
-	return s.SetRxQueue(rxq, pl.GetName(), portID)
+	return s.SetRxQueue(rxq, pl.GetName(), portID, bsz)
 }
 
 // bind to given pipeline output port
@@ -181,16 +181,23 @@ func (rt *Runtime) Start(args []string) (n int, err error) {
 		runtime.LockOSThread()
 		log.Info("swxruntime: lock this go thread to dpdk main core (lockOSThread)")
 
+		// forward the DPDK log output to the dpdk logging domain, also the EAL initialization messages
+		if err := eal.StartLogBridge(); err != nil {
+			log.Warnf("swxruntime: DPDK log output not forwarded: %v", err)
+		}
+
 		// initialize EAL
 		if n, err = eal.RteEalInit(args); err != nil {
 			wg.Done()
 			return
 		}
 
-		// threadLaunch initializes and runs thread_main on all worker lcores.
-		if err = rt.launchWorkers(); err != nil {
-			wg.Done()
-			return
+		// threadLaunch initializes and runs thread_main on all worker lcores. A secondary process doesn't run pipelines.
+		if !eal.IsSecondary() {
+			if err = rt.launchWorkers(); err != nil {
+				wg.Done()
+				return
+			}
 		}
 
 		// create job communication channel for main core listener. MUST be created before calling wg.Done() to prevent
@@ -48,6 +48,7 @@ import (
 	"unsafe"
 
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/device"
+	"github.com/stolsma/go-p4pack/pkg/dpdkswx/eal"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pipeline"
 	"github.com/stolsma/go-p4pack/pkg/dpdkswx/pktmbuf"
 )
@@ -67,6 +68,10 @@ type Tap struct {
 
 // Create Tap interface. Returns a pointer to a Tap structure or nil with error.
 func (tap *Tap) Init(name string, params *Params, clean func()) error {
+	if err := eal.PrimaryOnly(); err != nil {
+		return err
+	}
+
 	// create fd of tap interface
 	cname := C.CString(name)
 	defer C.free(unsafe.Pointer(cname))
@@ -190,7 +195,7 @@ func (tap *Tap) BindToPipelineInputPort(pl *pipeline.Pipeline, portID int, rxq u
 		return err
 	}
 
-	return tap.SetRxQueue(rxq, pl.GetName(), portID)
+	return tap.SetRxQueue(rxq, pl.GetName(), portID, bsz)
 }
 
 // bind to given pipeline output port. A tap has 1 queue so only queue number 0 is valid.
@@ -210,5 +215,5 @@ func (tap *Tap) BindToPipelineOutputPort(pl *pipeline.Pipeline, portID int, txq
 		return err
 	}
 
-	return tap.SetTxQueue(txq, pl.GetName(), portID)
+	return tap.SetTxQueue(txq, pl.GetName(), portID, bsz)
 }
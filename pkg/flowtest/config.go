@@ -9,6 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
 )
 
 type Config struct {
@@ -52,6 +55,55 @@ func (c *Config) Apply() error {
 	return nil
 }
 
+// Validate checks the flowtest configuration without applying it and adds the errors found to errs
+func (c *Config) Validate(path string, errs *validation.Errors) {
+	names := make(map[string]bool)
+	for i, intf := range c.Interfaces {
+		ipath := validation.Path(path, "interfaces", i)
+		switch {
+		case intf.Name == "":
+			errs.Addf(validation.Path(ipath, "name"), "interface name missing")
+		case names[intf.Name]:
+			errs.Addf(validation.Path(ipath, "name"), "duplicate interface %s", intf.Name)
+		}
+		names[intf.Name] = true
+		if len(intf.MAC) != 0 && len(intf.MAC) != 6 {
+			errs.Addf(validation.Path(ipath, "mac"), "MAC address should be 6 bytes, not %d", len(intf.MAC))
+		}
+		if len(intf.IP) != 0 && len(intf.IP) != 4 && len(intf.IP) != 16 {
+			errs.Addf(validation.Path(ipath, "ip"), "IP address should be 4 or 16 bytes, not %d", len(intf.IP))
+		}
+	}
+
+	names = make(map[string]bool)
+	for i, fs := range c.FlowSets {
+		fspath := validation.Path(path, "flowsets", i)
+		switch {
+		case fs.Name == "":
+			errs.Addf(validation.Path(fspath, "name"), "flowset name missing")
+		case names[fs.Name]:
+			errs.Addf(validation.Path(fspath, "name"), "duplicate flowset %s", fs.Name)
+		}
+		names[fs.Name] = true
+
+		for j, flow := range fs.Flows {
+			fpath := validation.Path(fspath, "flows", j)
+			if flow.Source.Interface == "" {
+				errs.Addf(validation.Path(fpath, "source", "interface"), "source interface missing")
+			}
+			if flow.Destination.Interface == "" {
+				errs.Addf(validation.Path(fpath, "destination", "interface"), "destination interface missing")
+			}
+			if flow.Interval < 0 {
+				errs.Addf(validation.Path(fpath, "interval"), "interval can't be negative")
+			}
+			if _, err := flow.Send.ToByteArray(nil); err != nil {
+				errs.Add(validation.Path(fpath, "send"), err)
+			}
+		}
+	}
+}
+
 func (c *Config) GetStart() bool {
 	return c.Start
 }
@@ -136,6 +188,19 @@ func (ha *HexArray) UnmarshalJSON(data []byte) (err error) {
 	return
 }
 
+// MarshalJSON encodes the bytes as one hex string, i.e. ["0x0800"]
+func (ha HexArray) MarshalJSON() ([]byte, error) {
+	if len(ha) == 0 {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal([]string{"0x" + hex.EncodeToString(ha)})
+}
+
+// JSONSchema returns the schema of a list of hex (0x prefix) or decimal number strings
+func (ha *HexArray) JSONSchema() *schema.Schema {
+	return &schema.Schema{Type: "array", Items: &schema.Schema{Type: "string", Pattern: "^(0[xX][0-9a-fA-F]+|[0-9]+)$"}}
+}
+
 // decodes a hex string with 0x prefix or plain number if no 0x prefix, into a byte array
 func decode(input string) ([]byte, error) {
 	if len(input) == 0 {
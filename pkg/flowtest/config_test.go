@@ -5,7 +5,11 @@ package flowtest
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
+
+	"github.com/stolsma/go-p4pack/pkg/config/validation"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDecode(t *testing.T) {
@@ -33,3 +37,48 @@ func TestDecode(t *testing.T) {
 		t.Fatalf("Decoded with wrong hex number (0xJ00)")
 	}
 }
+
+func TestHexArrayJSON(t *testing.T) {
+	data, err := json.Marshal(HexArray{0x08, 0x00, 0xab})
+	assert.NoError(t, err)
+	assert.Equal(t, `["0x0800ab"]`, string(data))
+
+	var ha HexArray
+	assert.NoError(t, json.Unmarshal(data, &ha))
+	assert.Equal(t, HexArray{0x08, 0x00, 0xab}, ha)
+
+	data, err = json.Marshal(HexArray{})
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, string(data))
+}
+
+func TestValidate(t *testing.T) {
+	var c Config
+	err := json.Unmarshal([]byte(`{
+		"interfaces": [
+			{"name": "sw1", "mac": ["0x00", "0x01"]},
+			{"name": "sw1", "ip": ["192", "168", "1", "1"]}
+		],
+		"flowsets": [{
+			"name": "test",
+			"flows": [{
+				"source": {"interface": "sw1"},
+				"send": {"layout": ["dst", "src"], "fields": {"dst": ["0x0102"]}}
+			}]
+		}]
+	}`), &c)
+	assert.NoError(t, err)
+
+	var errs validation.Errors
+	c.Validate("flowtest", &errs)
+	var paths []string
+	for _, e := range errs.List() {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{
+		"flowtest.interfaces[0].mac",
+		"flowtest.interfaces[1].name",
+		"flowtest.flowsets[0].flows[0].destination.interface",
+		"flowtest.flowsets[0].flows[0].send",
+	}, paths)
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flowtest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// PacketTemplate describes a series of packets created from the same Packet layout and fields, with fields that are
+// incremented per packet and checksums that are recalculated per packet
+type PacketTemplate struct {
+	Packet
+	// Number of packets to create, 1 when 0
+	Count int `json:"count"`
+	// Fields incremented per packet
+	Increments map[string]Increment `json:"increments"`
+	// Internet checksum fields (i.e. the IPv4 header checksum) with the layout fields the checksum is calculated over
+	Checksums map[string][]string `json:"checksums"`
+}
+
+// Increment of a field value per packet. The field is handled as a big endian unsigned integer with the size of the
+// field and wraps around at the end of the field size.
+type Increment struct {
+	// Value added to the field per packet, 1 when 0
+	Step uint64 `json:"step"`
+	// Number of different values before starting at the initial field value again, no limit when 0
+	Count uint64 `json:"count"`
+}
+
+// ToByteArrays creates the packets described by the template by using the template fields and the given extra
+// parameters
+func (pt *PacketTemplate) ToByteArrays(param map[string]HexArray) ([][]byte, error) {
+	// find all fields and their position in the packet
+	fields := make(map[string][]byte)
+	offsets := make(map[string]int)
+	var base []byte
+	for _, fname := range pt.Layout {
+		field, ok := pt.Fields[fname]
+		if !ok {
+			field, ok = param[fname]
+			if !ok {
+				return nil, fmt.Errorf("field '%s' not found when creating packet", fname)
+			}
+		}
+		fields[fname] = field
+		offsets[fname] = len(base)
+		base = append(base, field...)
+	}
+
+	for fname := range pt.Increments {
+		if _, ok := fields[fname]; !ok {
+			return nil, fmt.Errorf("increment field '%s' not in packet layout", fname)
+		}
+	}
+	for fname, cover := range pt.Checksums {
+		if len(fields[fname]) != 2 {
+			return nil, fmt.Errorf("checksum field '%s' not in packet layout or not 2 bytes long", fname)
+		}
+		for _, c := range cover {
+			if _, ok := fields[c]; !ok {
+				return nil, fmt.Errorf("checksum '%s' field '%s' not in packet layout", fname, c)
+			}
+		}
+	}
+
+	count := pt.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	packets := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		pkt := append([]byte(nil), base...)
+
+		for fname, inc := range pt.Increments {
+			n := uint64(i)
+			if inc.Count > 0 {
+				n %= inc.Count
+			}
+			step := inc.Step
+			if step == 0 {
+				step = 1
+			}
+			addBigEndian(pkt[offsets[fname]:offsets[fname]+len(fields[fname])], n*step)
+		}
+
+		for fname, cover := range pt.Checksums {
+			sum := pkt[offsets[fname] : offsets[fname]+2]
+			sum[0], sum[1] = 0, 0
+
+			var data []byte
+			for _, c := range cover {
+				data = append(data, pkt[offsets[c]:offsets[c]+len(fields[c])]...)
+			}
+			binary.BigEndian.PutUint16(sum, Checksum(data))
+		}
+
+		packets = append(packets, pkt)
+	}
+
+	return packets, nil
+}
+
+// add the given value to the big endian unsigned integer in the given bytes, wrapping around at the size of the bytes
+func addBigEndian(field []byte, value uint64) {
+	carry := uint64(0)
+	for i := len(field) - 1; i >= 0 && (value > 0 || carry > 0); i-- {
+		sum := uint64(field[i]) + value&0xff + carry
+		field[i] = byte(sum)
+		carry = sum >> 8
+		value >>= 8
+	}
+}
+
+// Checksum returns the internet checksum (RFC 1071) of the given data
+func Checksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
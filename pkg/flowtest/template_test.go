@@ -0,0 +1,83 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package flowtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPacketTemplate(t *testing.T) {
+	var pt PacketTemplate
+	err := json.Unmarshal([]byte(`{
+		"layout": ["version_ihl", "diffserv", "total_len", "identification", "flags_frag_offset", "ttl", "protocol",
+			"hdr_checksum", "src_addr", "dst_addr", "src_port"],
+		"fields": {
+			"version_ihl": ["0x45"],
+			"diffserv": ["0x00"],
+			"total_len": ["0x00", "0x73"],
+			"identification": ["0x00", "0x00"],
+			"flags_frag_offset": ["0x40", "0x00"],
+			"ttl": ["0x40"],
+			"protocol": ["0x11"],
+			"hdr_checksum": ["0x00", "0x00"],
+			"src_addr": ["192", "168", "0", "254"],
+			"dst_addr": ["192", "168", "0", "199"],
+			"src_port": ["0xff", "0xff"]
+		},
+		"count": 4,
+		"increments": {
+			"src_addr": {"step": 1},
+			"src_port": {"step": 2, "count": 2}
+		},
+		"checksums": {
+			"hdr_checksum": ["version_ihl", "diffserv", "total_len", "identification", "flags_frag_offset", "ttl",
+				"protocol", "hdr_checksum", "src_addr", "dst_addr"]
+		}
+	}`), &pt)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	packets, err := pt.ToByteArrays(nil)
+	if err != nil {
+		t.Fatalf("ToByteArrays failed: %v", err)
+	}
+	if len(packets) != 4 {
+		t.Fatalf("Expected 4 packets, got %d", len(packets))
+	}
+
+	expected := []struct {
+		src  []byte
+		port []byte
+	}{
+		{[]byte{192, 168, 0, 254}, []byte{0xff, 0xff}},
+		{[]byte{192, 168, 0, 255}, []byte{0x00, 0x01}},
+		{[]byte{192, 168, 1, 0}, []byte{0xff, 0xff}},
+		{[]byte{192, 168, 1, 1}, []byte{0x00, 0x01}},
+	}
+	for i, pkt := range packets {
+		if !bytes.Equal(pkt[12:16], expected[i].src) || !bytes.Equal(pkt[20:22], expected[i].port) {
+			t.Fatalf("Packet %d has wrong incremented fields: %v", i, pkt)
+		}
+		// the checksum over a header with a correct checksum is 0
+		if sum := Checksum(pkt[:20]); sum != 0 {
+			t.Fatalf("Packet %d has wrong header checksum: %v", i, pkt[10:12])
+		}
+	}
+
+	// a known IPv4 header checksum (192.168.0.1 -> 192.168.0.199)
+	hdr := []byte{0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00, 0x40, 0x11, 0x00, 0x00, 0xc0, 0xa8, 0x00, 0x01,
+		0xc0, 0xa8, 0x00, 0xc7}
+	if sum := Checksum(hdr); sum != 0xb861 {
+		t.Fatalf("Wrong checksum %#04x <> 0xb861", sum)
+	}
+
+	// errors
+	pt.Increments["unknown"] = Increment{}
+	if _, err := pt.ToByteArrays(nil); err == nil {
+		t.Fatalf("ToByteArrays with unknown increment field should fail")
+	}
+}
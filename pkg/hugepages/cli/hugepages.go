@@ -0,0 +1,171 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stolsma/go-p4pack/pkg/cli"
+	"github.com/stolsma/go-p4pack/pkg/hugepages"
+)
+
+func HugepagesCmd(parents ...*cobra.Command) *cobra.Command {
+	var hugepagesCmd = &cobra.Command{
+		Use:   "hugepages",
+		Short: "Base command for all hugepage actions",
+	}
+
+	HugepagesShowCmd(hugepagesCmd)
+	HugepagesMountCmd(hugepagesCmd)
+	HugepagesReserveCmd(hugepagesCmd)
+	return cli.AddCommand(parents, hugepagesCmd)
+}
+
+// complete the NUMA node argument, node "all" is for the system wide counts
+func completeNodeArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	list := []string{"all"}
+	nodes, _ := hugepages.New("").Nodes()
+	for _, node := range nodes {
+		list = append(list, strconv.Itoa(node))
+	}
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No NUMA nodes available for completion!")
+
+	return completions, directive
+}
+
+// complete the hugepage size argument with the supported page sizes
+func completeSizeArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var directive = cobra.ShellCompDirectiveNoFileComp
+
+	var list []string
+	sizes, _ := hugepages.New("").Sizes()
+	for _, size := range sizes {
+		list = append(list, hugepages.FormatSize(size))
+	}
+	completions := cli.FilterCompletions(list, toComplete, &directive, "No hugepage sizes available for completion!")
+
+	return completions, directive
+}
+
+func parseNode(arg string) (int, error) {
+	if arg == "all" {
+		return hugepages.NodeAny, nil
+	}
+	return strconv.Atoi(arg)
+}
+
+func HugepagesShowCmd(parents ...*cobra.Command) *cobra.Command {
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the hugepage counts per NUMA node and the hugetlbfs mounts",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			s := hugepages.New("")
+			info, err := s.Info()
+			if err != nil {
+				cmd.PrintErrf("Hugepages info err: %v\n", err)
+				return
+			}
+
+			cmd.Printf("%-8s %-8s %10s %10s %10s %12s\n", "Node", "Size", "Total", "Free", "Surplus", "Free memory")
+			for _, p := range info {
+				node := "all"
+				if p.Node != hugepages.NodeAny {
+					node = strconv.Itoa(p.Node)
+				}
+				cmd.Printf("%-8s %-8s %10d %10d %10d %11dM\n", node, hugepages.FormatSize(p.Size), p.Total, p.Free,
+					p.Surplus, p.FreeBytes()>>20)
+			}
+
+			mounts, err := s.Mounts()
+			if err != nil {
+				cmd.PrintErrf("Hugetlbfs mounts err: %v\n", err)
+				return
+			}
+			cmd.Println()
+			cmd.Printf("%-30s %-8s\n", "Mount", "Size")
+			for _, m := range mounts {
+				cmd.Printf("%-30s %-8s\n", m.Dir, hugepages.FormatSize(m.PageSize))
+			}
+		},
+	}
+
+	return cli.AddCommand(parents, showCmd)
+}
+
+func HugepagesMountCmd(parents ...*cobra.Command) *cobra.Command {
+	mountCmd := &cobra.Command{
+		Use:   "mount [directory] [size]",
+		Short: "Mount a hugetlbfs with the given page size (default size if not given) if not mounted yet",
+		Args:  cobra.RangeArgs(1, 2),
+		ValidArgsFunction: cli.ValidateArguments(
+			cli.AppendHelp("You must specify the directory to mount the hugetlbfs on"),
+			completeSizeArg,
+			cli.AppendLastHelp(2, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			var size uint64
+			if len(args) > 1 {
+				var err error
+				if size, err = hugepages.ParseSize(args[1]); err != nil {
+					cmd.PrintErrf("Page size err: %v\n", err)
+					return
+				}
+			}
+
+			m, err := hugepages.New("").EnsureMount(args[0], size)
+			if err != nil {
+				cmd.PrintErrf("Mount err: %v\n", err)
+				return
+			}
+
+			cmd.Printf("Hugetlbfs with %s pages mounted on %s\n", hugepages.FormatSize(m.PageSize), m.Dir)
+		},
+	}
+
+	return cli.AddCommand(parents, mountCmd)
+}
+
+func HugepagesReserveCmd(parents ...*cobra.Command) *cobra.Command {
+	reserveCmd := &cobra.Command{
+		Use:   "reserve [node|all] [size] [count]",
+		Short: "Reserve the given number of hugepages of the given size on a NUMA node or system wide",
+		Args:  cobra.ExactArgs(3),
+		ValidArgsFunction: cli.ValidateArguments(
+			completeNodeArg,
+			completeSizeArg,
+			cli.AppendHelp("You must specify the number of hugepages to reserve"),
+			cli.AppendLastHelp(3, "This command does not take any more arguments"),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			node, err := parseNode(args[0])
+			if err != nil {
+				cmd.PrintErrf("NUMA node err: %v\n", err)
+				return
+			}
+			size, err := hugepages.ParseSize(args[1])
+			if err != nil {
+				cmd.PrintErrf("Page size err: %v\n", err)
+				return
+			}
+			count, err := strconv.ParseUint(args[2], 10, 64)
+			if err != nil {
+				cmd.PrintErrf("Page count err: %v\n", err)
+				return
+			}
+
+			if err := hugepages.New("").Reserve(node, size, count); err != nil {
+				cmd.PrintErrf("Reserve err: %v\n", err)
+				return
+			}
+
+			cmd.Printf("%d hugepages of %s reserved on node %s\n", count, hugepages.FormatSize(size), args[0])
+		},
+	}
+
+	return cli.AddCommand(parents, reserveCmd)
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package hugepages
+
+// Config are the hugepages to setup before the DPDK EAL is initialized
+type Config struct {
+	Mount    string         `json:"mount"`    // hugetlbfs directory, mounted if not mounted yet
+	PageSize string         `json:"pagesize"` // page size i.e. "2M" or "1G", the system default page size if not given
+	Pages    map[int]uint64 `json:"pages"`    // number of pages to reserve per NUMA node, -1 is system wide
+	Check    bool           `json:"check"`    // check if the configured mempools fit in the free hugepages
+}
+
+// GetPageSize returns the configured page size in bytes or 0 if not configured
+func (c *Config) GetPageSize() (uint64, error) {
+	if c.PageSize == "" {
+		return 0, nil
+	}
+	return ParseSize(c.PageSize)
+}
+
+// Apply mounts the hugetlbfs and reserves the hugepages on the given system
+func (c *Config) Apply(s *System) error {
+	pageSize, err := c.GetPageSize()
+	if err != nil {
+		return err
+	}
+	if pageSize == 0 {
+		if pageSize, err = s.DefaultSize(); err != nil {
+			return err
+		}
+	}
+
+	if c.Mount != "" {
+		if _, err := s.EnsureMount(c.Mount, pageSize); err != nil {
+			return err
+		}
+	}
+
+	for node, count := range c.Pages {
+		if err := s.Reserve(node, pageSize, count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
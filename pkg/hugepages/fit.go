@@ -0,0 +1,92 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package hugepages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	cacheLine      = 64   // object alignment in a DPDK mempool
+	mbufHeaderSize = 128  // size of struct rte_mbuf
+	objHeaderSize  = 64   // mempool object header and trailer, rounded to a cache line
+	maxLcores      = 128  // RTE_MAX_LCORE, a mempool has a cache for every possible lcore
+	poolOverhead   = 1024 // mempool and ring administration
+)
+
+// MempoolMemory returns an estimate of the hugepage memory in bytes used by a pktmbuf mempool with the given mbuf data
+// buffer size (including headroom), number of mbufs and per lcore cache size.
+func MempoolMemory(bufferSize uint, poolSize uint32, cacheSize uint32) uint64 {
+	objSize := align(mbufHeaderSize+uint64(bufferSize), cacheLine) + objHeaderSize
+
+	// the mbufs are stored in a ring with a power of 2 number of entries
+	ringSize := uint64(1)
+	for ringSize <= uint64(poolSize) {
+		ringSize <<= 1
+	}
+
+	// the lcore caches can hold 1.5 times the cache size
+	caches := maxLcores * align(uint64(cacheSize)*3/2*8+cacheLine, cacheLine)
+
+	return uint64(poolSize)*objSize + ringSize*8 + caches + poolOverhead
+}
+
+func align(size uint64, to uint64) uint64 {
+	return (size + to - 1) / to * to
+}
+
+// Fits checks if the given hugepage memory demands in bytes per NUMA node fit in the free hugepages of the given page
+// size (0 for all page sizes). Demands for NodeAny (or all demands if the system doesn't support NUMA) are checked
+// against the free hugepages of all nodes together.
+func (s *System) Fits(pageSize uint64, demand map[int]uint64) error {
+	info, err := s.Info()
+	if err != nil {
+		return err
+	}
+
+	numa := false
+	free := make(map[int]uint64)
+	var totalFree, totalDemand uint64
+	for _, p := range info {
+		if pageSize != 0 && p.Size != pageSize {
+			continue
+		}
+		numa = numa || p.Node != NodeAny
+		free[p.Node] += p.FreeBytes()
+		totalFree += p.FreeBytes()
+	}
+
+	nodes := make([]int, 0, len(demand))
+	for node, need := range demand {
+		nodes = append(nodes, node)
+		totalDemand += need
+	}
+	sort.Ints(nodes)
+
+	var errs []string
+	if numa {
+		for _, node := range nodes {
+			if node != NodeAny && demand[node] > free[node] {
+				errs = append(errs, fmt.Sprintf("NUMA node %d needs %s hugepage memory but only %s is free", node,
+					formatMB(demand[node]), formatMB(free[node])))
+			}
+		}
+	}
+	if totalDemand > totalFree {
+		errs = append(errs, fmt.Sprintf("%s hugepage memory needed but only %s is free", formatMB(totalDemand),
+			formatMB(totalFree)))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("not enough hugepages: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// format a size in bytes as (rounded up) MB
+func formatMB(size uint64) string {
+	return fmt.Sprintf("%dMB", (size+(1<<20)-1)>>20)
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hugepages discovers, mounts and reserves the hugepages of the system through sysfs and procfs and checks if
+// the hugepage memory needed by DPDK is available before the EAL is initialized
+package hugepages
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// NodeAny is the NUMA node used for the system wide hugepage counts and memory demands not bound to a NUMA node
+const NodeAny = -1
+
+var (
+	pageSizeDir = regexp.MustCompile(`^hugepages-([0-9]+)kB$`)
+	nodeDir     = regexp.MustCompile(`^node([0-9]+)$`)
+)
+
+// System gives access to the hugepage settings of the system below a root directory
+type System struct {
+	root  string
+	mount func(source string, target string, fstype string, flags uintptr, data string) error
+}
+
+// New returns the hugepage settings of the system below the given root directory. An empty root is the root of the
+// running system, another root can be used to work on a copy of the sysfs and procfs trees (i.e. for testing).
+func New(root string) *System {
+	if root == "" {
+		root = "/"
+	}
+	return &System{root: root, mount: syscall.Mount}
+}
+
+// the given absolute system path below the root directory
+func (s *System) path(elem ...string) string {
+	return filepath.Join(append([]string{s.root}, elem...)...)
+}
+
+// Pages are the hugepage counts of one page size on a NUMA node
+type Pages struct {
+	Node    int    // NUMA node, NodeAny if the system has no NUMA nodes
+	Size    uint64 // page size in bytes
+	Total   uint64 // number of reserved pages
+	Free    uint64 // number of reserved pages not in use
+	Surplus uint64 // number of pages allocated above the reserved pages
+}
+
+// FreeBytes returns the hugepage memory not in use in bytes
+func (p *Pages) FreeBytes() uint64 {
+	return p.Free * p.Size
+}
+
+// Sizes returns the hugepage sizes in bytes supported by the system, from small to large
+func (s *System) Sizes() ([]uint64, error) {
+	return s.sizes(s.path("sys/kernel/mm/hugepages"))
+}
+
+// read the page sizes from the hugepages-<size>kB sub directories of the given directory
+func (s *System) sizes(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sizes []uint64
+	for _, e := range entries {
+		m := pageSizeDir.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		kb, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, kb*1024)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return sizes, nil
+}
+
+// DefaultSize returns the default hugepage size of the system in bytes, i.e. the size used for hugetlbfs mounts without
+// pagesize option
+func (s *System) DefaultSize() (uint64, error) {
+	f, err := os.Open(s.path("proc/meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "Hugepagesize:" && fields[2] == "kB" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb * 1024, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no hugepage size in %s", s.path("proc/meminfo"))
+}
+
+// Nodes returns the NUMA nodes of the system, or no nodes if the system doesn't support NUMA
+func (s *System) Nodes() ([]int, error) {
+	entries, err := os.ReadDir(s.path("sys/devices/system/node"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []int
+	for _, e := range entries {
+		if m := nodeDir.FindStringSubmatch(e.Name()); m != nil {
+			node, _ := strconv.Atoi(m[1])
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes, nil
+}
+
+// the directory with the hugepage counts of the given page size on the given NUMA node
+func (s *System) pagesDir(node int, size uint64) string {
+	dir := fmt.Sprintf("hugepages-%dkB", size/1024)
+	if node == NodeAny {
+		return s.path("sys/kernel/mm/hugepages", dir)
+	}
+	return s.path("sys/devices/system/node", fmt.Sprintf("node%d", node), "hugepages", dir)
+}
+
+// Info returns the hugepage counts per NUMA node and page size. If the system doesn't support NUMA the system wide
+// counts are returned with node NodeAny.
+func (s *System) Info() ([]Pages, error) {
+	nodes, err := s.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		nodes = []int{NodeAny}
+	}
+
+	var result []Pages
+	for _, node := range nodes {
+		var sizes []uint64
+		if node == NodeAny {
+			sizes, err = s.Sizes()
+		} else {
+			sizes, err = s.sizes(s.path("sys/devices/system/node", fmt.Sprintf("node%d", node), "hugepages"))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, size := range sizes {
+			p, err := s.pages(node, size)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// read the hugepage counts of the given page size on the given NUMA node
+func (s *System) pages(node int, size uint64) (Pages, error) {
+	dir := s.pagesDir(node, size)
+	p := Pages{Node: node, Size: size}
+
+	var err error
+	if p.Total, err = readCount(filepath.Join(dir, "nr_hugepages")); err != nil {
+		return p, err
+	}
+	if p.Free, err = readCount(filepath.Join(dir, "free_hugepages")); err != nil {
+		return p, err
+	}
+	if p.Surplus, err = readCount(filepath.Join(dir, "surplus_hugepages")); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// Reserve sets the number of reserved hugepages of the given page size on the given NUMA node (or system wide with
+// NodeAny) and returns an error if the kernel couldn't reserve all requested pages.
+func (s *System) Reserve(node int, size uint64, count uint64) error {
+	file := filepath.Join(s.pagesDir(node, size), "nr_hugepages")
+	if err := os.WriteFile(file, []byte(strconv.FormatUint(count, 10)), 0644); err != nil {
+		return fmt.Errorf("reserving %s hugepages failed: %w", FormatSize(size), err)
+	}
+
+	reserved, err := readCount(file)
+	if err != nil {
+		return err
+	}
+	if reserved < count {
+		return fmt.Errorf("only %d of %d %s hugepages reserved on %s", reserved, count, FormatSize(size), nodeName(node))
+	}
+	return nil
+}
+
+// read a single number from the given (sysfs) file
+func readCount(file string) (uint64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func nodeName(node int) string {
+	if node == NodeAny {
+		return "system"
+	}
+	return fmt.Sprintf("NUMA node %d", node)
+}
+
+// ParseSize parses a memory size like "2M", "1G", "2048kB" or "4096" (bytes) into bytes
+func ParseSize(s string) (uint64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "B")
+
+	mult := uint64(1)
+	if str != "" {
+		switch str[len(str)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult != 1 {
+			str = str[:len(str)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// FormatSize formats the given size in bytes into the largest unit (G, M, K) the size is a multiple of
+func FormatSize(size uint64) string {
+	switch {
+	case size == 0:
+		return "0"
+	case size%(1<<30) == 0:
+		return fmt.Sprintf("%dG", size>>30)
+	case size%(1<<20) == 0:
+		return fmt.Sprintf("%dM", size>>20)
+	case size%(1<<10) == 0:
+		return fmt.Sprintf("%dK", size>>10)
+	}
+	return strconv.FormatUint(size, 10)
+}
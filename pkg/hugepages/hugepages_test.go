@@ -0,0 +1,215 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package hugepages
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// create a fake sysfs and procfs tree with two NUMA nodes, 2M and 1G pages
+func fakeSystem(t *testing.T, numa bool) *System {
+	t.Helper()
+	root := t.TempDir()
+
+	write := func(file string, data string) {
+		t.Helper()
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := func(dir string, total, free string) {
+		write(filepath.Join(dir, "nr_hugepages"), total+"\n")
+		write(filepath.Join(dir, "free_hugepages"), free+"\n")
+		write(filepath.Join(dir, "surplus_hugepages"), "0\n")
+	}
+
+	write("proc/meminfo", "MemTotal:       16318412 kB\nHugepagesize:       2048 kB\n")
+	write("proc/mounts", "sysfs /sys sysfs rw 0 0\nnodev /dev/hugepages hugetlbfs rw,relatime 0 0\n"+
+		"nodev /mnt/huge1G hugetlbfs rw,relatime,pagesize=1024M 0 0\n")
+	pages("sys/kernel/mm/hugepages/hugepages-2048kB", "512", "384")
+	pages("sys/kernel/mm/hugepages/hugepages-1048576kB", "2", "2")
+	if numa {
+		pages("sys/devices/system/node/node0/hugepages/hugepages-2048kB", "256", "128")
+		pages("sys/devices/system/node/node0/hugepages/hugepages-1048576kB", "1", "1")
+		pages("sys/devices/system/node/node1/hugepages/hugepages-2048kB", "256", "256")
+		pages("sys/devices/system/node/node1/hugepages/hugepages-1048576kB", "1", "1")
+		write("sys/devices/system/node/online", "0-1\n")
+	}
+
+	s := New(root)
+	s.mount = func(source string, target string, fstype string, flags uintptr, data string) error {
+		mounts, err := os.ReadFile(filepath.Join(root, "proc/mounts"))
+		if err != nil {
+			return err
+		}
+		dir := strings.TrimPrefix(target, root)
+		write("proc/mounts", string(mounts)+source+" "+dir+" "+fstype+" rw,"+data+" 0 0\n")
+		return nil
+	}
+	return s
+}
+
+func TestInfo(t *testing.T) {
+	s := fakeSystem(t, true)
+
+	sizes, err := s.Sizes()
+	if err != nil || !reflect.DeepEqual(sizes, []uint64{2 << 20, 1 << 30}) {
+		t.Fatalf("Sizes() = %v, %v", sizes, err)
+	}
+	if size, err := s.DefaultSize(); err != nil || size != 2<<20 {
+		t.Fatalf("DefaultSize() = %d, %v", size, err)
+	}
+
+	info, err := s.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Pages{
+		{Node: 0, Size: 2 << 20, Total: 256, Free: 128},
+		{Node: 0, Size: 1 << 30, Total: 1, Free: 1},
+		{Node: 1, Size: 2 << 20, Total: 256, Free: 256},
+		{Node: 1, Size: 1 << 30, Total: 1, Free: 1},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Fatalf("Info() = %+v, expected %+v", info, expected)
+	}
+
+	// without NUMA the system wide counts are returned
+	info, err = fakeSystem(t, false).Info()
+	if err != nil || len(info) != 2 || info[0].Node != NodeAny || info[0].Free != 384 {
+		t.Fatalf("Info() without NUMA = %+v, %v", info, err)
+	}
+}
+
+func TestMounts(t *testing.T) {
+	s := fakeSystem(t, true)
+
+	mounts, err := s.Mounts()
+	expected := []Mount{{Dir: "/dev/hugepages", PageSize: 2 << 20}, {Dir: "/mnt/huge1G", PageSize: 1 << 30}}
+	if err != nil || !reflect.DeepEqual(mounts, expected) {
+		t.Fatalf("Mounts() = %+v, %v", mounts, err)
+	}
+
+	// existing mount
+	if _, err := s.EnsureMount("/dev/hugepages/", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EnsureMount("/mnt/huge1G", 2<<20); err == nil {
+		t.Fatal("EnsureMount with other page size should fail")
+	}
+
+	// new mount
+	m, err := s.EnsureMount("/mnt/huge", 2<<20)
+	if err != nil || m.Dir != "/mnt/huge" {
+		t.Fatalf("EnsureMount() = %+v, %v", m, err)
+	}
+	if _, err := os.Stat(s.path("/mnt/huge")); err != nil {
+		t.Fatal(err)
+	}
+	mounts, _ = s.Mounts()
+	if len(mounts) != 3 || mounts[2] != m {
+		t.Fatalf("Mounts() after mount = %+v", mounts)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	s := fakeSystem(t, true)
+
+	if err := s.Reserve(1, 2<<20, 1024); err != nil {
+		t.Fatal(err)
+	}
+	file := s.path("sys/devices/system/node/node1/hugepages/hugepages-2048kB/nr_hugepages")
+	if count, _ := readCount(file); count != 1024 {
+		t.Fatalf("node 1 nr_hugepages = %d", count)
+	}
+	if err := s.Reserve(NodeAny, 1<<30, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reserve(2, 2<<20, 1); err == nil {
+		t.Fatal("Reserve on unknown node should fail")
+	}
+}
+
+func TestFits(t *testing.T) {
+	s := fakeSystem(t, true)
+
+	// node 0: 256M + 1G free, node 1: 512M + 1G free
+	if err := s.Fits(0, map[int]uint64{0: 1 << 30, 1: 1 << 30}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Fits(2<<20, map[int]uint64{0: 300 << 20}); err == nil || !strings.Contains(err.Error(), "NUMA node 0") {
+		t.Fatalf("Fits() = %v, expected NUMA node 0 error", err)
+	}
+	if err := s.Fits(2<<20, map[int]uint64{NodeAny: 700 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Fits(2<<20, map[int]uint64{NodeAny: 800 << 20}); err == nil {
+		t.Fatal("Fits() should fail when the total demand doesn't fit")
+	}
+
+	// without NUMA only the total is checked
+	if err := fakeSystem(t, false).Fits(2<<20, map[int]uint64{0: 700 << 20, 1: 60 << 20}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMempoolMemory(t *testing.T) {
+	// 32768 mbufs of 2304 bytes with 256 lcore cache, at least the mbufs themselves
+	size := MempoolMemory(2304, 32768, 256)
+	if size < 32768*(2304+128) || size > 100<<20 {
+		t.Fatalf("MempoolMemory() = %d", size)
+	}
+	if MempoolMemory(2304, 1024, 0) >= MempoolMemory(2304, 2048, 0) {
+		t.Fatal("MempoolMemory should grow with the pool size")
+	}
+}
+
+func TestParseFormatSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		size uint64
+		out  string
+	}{
+		{"2M", 2 << 20, "2M"},
+		{"1G", 1 << 30, "1G"},
+		{"1024M", 1 << 30, "1G"},
+		{"2048kB", 2 << 20, "2M"},
+		{"4096", 4096, "4K"},
+		{"100", 100, "100"},
+	}
+	for _, test := range tests {
+		size, err := ParseSize(test.in)
+		if err != nil || size != test.size || FormatSize(size) != test.out {
+			t.Fatalf("ParseSize(%q) = %d, %v; FormatSize = %s", test.in, size, err, FormatSize(size))
+		}
+	}
+	if _, err := ParseSize("2X"); err == nil {
+		t.Fatal("ParseSize(2X) should fail")
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	s := fakeSystem(t, true)
+	c := &Config{Mount: "/mnt/huge", PageSize: "2M", Pages: map[int]uint64{0: 300, 1: 300}}
+	if err := c.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := s.Info()
+	if info[0].Total != 300 || info[2].Total != 300 {
+		t.Fatalf("Info() after apply = %+v", info)
+	}
+	mounts, _ := s.Mounts()
+	if len(mounts) != 3 || mounts[2].Dir != "/mnt/huge" {
+		t.Fatalf("Mounts() after apply = %+v", mounts)
+	}
+}
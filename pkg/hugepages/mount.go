@@ -0,0 +1,89 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package hugepages
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mount is a mounted hugetlbfs
+type Mount struct {
+	Dir      string // mount directory
+	PageSize uint64 // page size in bytes
+}
+
+// Mounts returns the mounted hugetlbfs file systems
+func (s *System) Mounts() ([]Mount, error) {
+	f, err := os.Open(s.path("proc/mounts"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []Mount
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// i.e. "nodev /mnt/huge hugetlbfs rw,relatime,pagesize=2M 0 0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "hugetlbfs" {
+			continue
+		}
+
+		m := Mount{Dir: fields[1]}
+		for _, opt := range strings.Split(fields[3], ",") {
+			if strings.HasPrefix(opt, "pagesize=") {
+				if m.PageSize, err = ParseSize(strings.TrimPrefix(opt, "pagesize=")); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if m.PageSize == 0 {
+			if m.PageSize, err = s.DefaultSize(); err != nil {
+				return nil, err
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// EnsureMount checks if a hugetlbfs with the given page size (0 is the default size) is mounted on the given directory
+// and creates the directory and mounts a hugetlbfs if not.
+func (s *System) EnsureMount(dir string, pageSize uint64) (Mount, error) {
+	dir = filepath.Clean(dir)
+	if pageSize == 0 {
+		size, err := s.DefaultSize()
+		if err != nil {
+			return Mount{}, err
+		}
+		pageSize = size
+	}
+
+	mounts, err := s.Mounts()
+	if err != nil {
+		return Mount{}, err
+	}
+	for _, m := range mounts {
+		if filepath.Clean(m.Dir) != dir {
+			continue
+		}
+		if m.PageSize != pageSize {
+			return m, fmt.Errorf("hugetlbfs on %s has page size %s instead of %s", dir, FormatSize(m.PageSize),
+				FormatSize(pageSize))
+		}
+		return m, nil
+	}
+
+	if err := os.MkdirAll(s.path(dir), 0755); err != nil {
+		return Mount{}, err
+	}
+	if err := s.mount("nodev", s.path(dir), "hugetlbfs", 0, "pagesize="+FormatSize(pageSize)); err != nil {
+		return Mount{}, fmt.Errorf("mounting hugetlbfs on %s failed: %w", dir, err)
+	}
+	return Mount{Dir: dir, PageSize: pageSize}, nil
+}
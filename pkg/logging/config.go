@@ -4,6 +4,10 @@
 
 package logging
 
+import (
+	"github.com/stolsma/go-p4pack/pkg/config/schema"
+)
+
 // SinkType is the type of a sink
 type SinkType string
 
@@ -11,6 +15,11 @@ func (t SinkType) String() string {
 	return string(t)
 }
 
+// JSONSchema returns the schema with the supported sink types
+func (t SinkType) JSONSchema() *schema.Schema {
+	return &schema.Schema{Enum: []any{StdoutSinkType, StderrSinkType, FileSinkType, KafkaSinkType}}
+}
+
 const (
 	// StdoutSinkType is the sink type for stdout
 	StdoutSinkType SinkType = "stdout"
@@ -29,6 +38,11 @@ func (e SinkEncoding) String() string {
 	return string(e)
 }
 
+// JSONSchema returns the schema with the supported sink encodings
+func (e SinkEncoding) JSONSchema() *schema.Schema {
+	return &schema.Schema{Enum: []any{ConsoleEncoding, JSONEncoding}}
+}
+
 const (
 	// ConsoleEncoding is an encoding for outputs to the console
 	ConsoleEncoding SinkEncoding = "console"
@@ -249,6 +249,9 @@ func (l *zapLogger) SetLevel(level Level) {
 	for _, child := range l.children {
 		child.setDefaultLevel(level)
 	}
+
+	// inform the domains with a level hook that (possibly) a level of their logger tree changed
+	levelChanged(l)
 }
 
 // GetLoggerDataList gets the domains operational configuration (including loglevel and children data)
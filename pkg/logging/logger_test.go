@@ -6,6 +6,7 @@ package logging
 
 import (
 	"encoding/json"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,6 +23,14 @@ func TestDefaultLogger(t *testing.T) {
 }
 
 func TestLoggerConfig(t *testing.T) {
+	// restore the logging configuration and remove the log file written by the file sink afterwards
+	saved := root
+	t.Cleanup(func() {
+		root = saved
+		reRegister()
+		os.Remove("test.log")
+	})
+
 	// do json config tests
 	jconfig := Config{}
 	err := json.Unmarshal([]byte(jsonConfig), &jconfig)
@@ -269,3 +278,37 @@ sinks:
     file:
       path: test.log
 `
+
+func TestLevelHook(t *testing.T) {
+	// restore the logging configuration of the other tests afterwards
+	saved := root
+	t.Cleanup(func() {
+		root = saved
+		reRegister()
+	})
+	Configure(&Config{})
+
+	var changed []string
+	RegisterLevelHook("hook/a", func(logger Logger) {
+		changed = append(changed, logger.Name())
+	})
+	t.Cleanup(func() {
+		levelRegister.Lock()
+		defer levelRegister.Unlock()
+		delete(levelRegister.hooks, "hook/a")
+	})
+
+	// the domain, its descendants and its ancestors trigger the hook
+	GetLogger("hook/a").SetLevel(DebugLevel)
+	GetLogger("hook/a/b").SetLevel(WarnLevel)
+	GetLogger("hook").SetLevel(ErrorLevel)
+	assert.Equal(t, []string{"hook/a", "hook/a/b", "hook"}, changed)
+
+	// siblings and loggers with only a common name prefix don't trigger the hook
+	GetLogger("hook/b").SetLevel(DebugLevel)
+	GetLogger("hook/ab").SetLevel(DebugLevel)
+	assert.Equal(t, 3, len(changed))
+
+	// the explicit level of the domain is not overruled by the level of its ancestors
+	assert.Equal(t, DebugLevel, GetLogger("hook/a/c").GetLevel())
+}
@@ -3,6 +3,11 @@
 
 package logging
 
+import (
+	"strings"
+	"sync"
+)
+
 type registerFunc func(Logger)
 type register map[string]registerFunc
 
@@ -30,3 +35,36 @@ func reRegister() {
 		fn(GetLogger(domain))
 	}
 }
+
+var levelRegister = struct {
+	sync.Mutex
+	hooks register
+}{hooks: make(register)}
+
+// RegisterLevelHook registers a callback for the logging domain that will be called with the changed logger when the
+// level of the domain logger, one of its descendants or one of its ancestors is changed with SetLevel. This can be used
+// to keep the log level of external (e.g. C library) log sources in sync with the logging domain tree.
+func RegisterLevelHook(domain string, fn registerFunc) {
+	levelRegister.Lock()
+	defer levelRegister.Unlock()
+	levelRegister.hooks[domain] = fn
+}
+
+// Will be called when the level of the given logger changes. The hooks are called without holding the register lock
+// so they can (re)register or change levels themselves.
+func levelChanged(l *zapLogger) {
+	name := l.Name()
+	var hooks []registerFunc
+	levelRegister.Lock()
+	for domain, fn := range levelRegister.hooks {
+		if l == root || name == domain || strings.HasPrefix(name, domain+nameSep) ||
+			strings.HasPrefix(domain, name+nameSep) {
+			hooks = append(hooks, fn)
+		}
+	}
+	levelRegister.Unlock()
+
+	for _, fn := range hooks {
+		fn(l)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package filter compiles pcap filter expressions with libpcap into classic BPF programs
+package filter
+
+/*
+#cgo LDFLAGS: -lpcap
+
+#include <stdlib.h>
+#include <pcap/pcap.h>
+
+int filter_compile(const char *expr, int snaplen, struct bpf_program *prog, char *errbuf, size_t errbuf_size) {
+	pcap_t *pcap;
+	int status;
+
+	pcap = pcap_open_dead(DLT_EN10MB, snaplen);
+	if (!pcap) {
+		snprintf(errbuf, errbuf_size, "out of memory");
+		return -1;
+	}
+
+	status = pcap_compile(pcap, prog, expr, 1, PCAP_NETMASK_UNKNOWN);
+	if (status)
+		snprintf(errbuf, errbuf_size, "%s", pcap_geterr(pcap));
+	pcap_close(pcap);
+	return status;
+}
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/net/bpf"
+)
+
+// Compile compiles the pcap filter expression for Ethernet packets of maximal snaplen bytes
+func Compile(expr string, snaplen uint32) ([]bpf.RawInstruction, error) {
+	var prog C.struct_bpf_program
+	var errbuf [256]C.char
+
+	cexpr := C.CString(expr)
+	defer C.free(unsafe.Pointer(cexpr))
+	if status := C.filter_compile(cexpr, C.int(snaplen), &prog, &errbuf[0], C.size_t(len(errbuf))); status != 0 {
+		return nil, fmt.Errorf("filter %q: %s", expr, C.GoString(&errbuf[0]))
+	}
+	defer C.pcap_freecode(&prog)
+
+	insns := unsafe.Slice(prog.bf_insns, int(prog.bf_len))
+	filter := make([]bpf.RawInstruction, len(insns))
+	for i, ins := range insns {
+		filter[i] = bpf.RawInstruction{Op: uint16(ins.code), Jt: uint8(ins.jt), Jf: uint8(ins.jf), K: uint32(ins.k)}
+	}
+	return filter, nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pcap
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RotateParams are the parameters of a RotatingWriter
+type RotateParams struct {
+	// File name template. The following verbs are replaced when a file is opened: %Y (year), %m (month), %d (day),
+	// %H (hour), %M (minute), %S (second), %N (file sequence number, starting at 0) and %% (a single %). When the
+	// resulting name was already used by this writer "_<sequence number>" is inserted before the file extension.
+	Template string
+	MaxSize  int64         // start a new file when the file would exceed this size in bytes, 0 is no limit
+	Interval time.Duration // start a new file when a packet is written this long after the file was opened, 0 is no limit
+	MaxFiles int           // maximum number of files kept, the oldest file is removed when exceeded, 0 is no limit
+	SnapLen  uint32        // maximum number of bytes written per packet, 0 is no limit
+	LinkType uint32
+}
+
+// RotatingWriter writes packets to a series of pcap files, starting a new file when the size or time limit is reached
+type RotatingWriter struct {
+	params  RotateParams
+	now     func() time.Time
+	file    *os.File
+	buf     *bufio.Writer
+	w       *Writer
+	openAt  time.Time
+	index   uint64   // sequence number of the current file
+	files   []string // names of the files written and still kept, the last is the current file
+	opened  uint64   // number of files opened
+	closed  bool     // closed with Close
+	packets uint64   // total packets written
+	bytes   int64    // total bytes written
+}
+
+// NewRotatingWriter creates a rotating pcap writer and opens the first file
+func NewRotatingWriter(params RotateParams) (*RotatingWriter, error) {
+	return newRotatingWriter(params, time.Now)
+}
+
+func newRotatingWriter(params RotateParams, now func() time.Time) (*RotatingWriter, error) {
+	if params.Template == "" {
+		return nil, errors.New("pcap: no file name template")
+	}
+
+	rw := &RotatingWriter{params: params, now: now}
+	if err := rw.open(); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// WritePacket writes a packet to the current file, first starting a new file when the packet doesn't fit within the
+// size limit or when the time limit of the current file is reached
+func (rw *RotatingWriter) WritePacket(ts time.Time, origLen uint32, data []byte) error {
+	if rw.closed {
+		return errors.New("pcap: writer closed")
+	}
+	if rw.w == nil {
+		return errors.New("pcap: no file open, rotate to retry")
+	}
+
+	if rw.w.Packets() > 0 &&
+		((rw.params.MaxSize > 0 && rw.w.Size()+rw.w.RecordSize(len(data)) > rw.params.MaxSize) ||
+			(rw.params.Interval > 0 && ts.Sub(rw.openAt) >= rw.params.Interval)) {
+		if err := rw.Rotate(); err != nil {
+			return err
+		}
+	}
+
+	size := rw.w.Size()
+	if err := rw.w.WritePacket(ts, origLen, data); err != nil {
+		return err
+	}
+	rw.packets++
+	rw.bytes += rw.w.Size() - size
+	return nil
+}
+
+// Rotate closes the current file and opens the next file. When opening a file failed before, Rotate retries to open
+// the file.
+func (rw *RotatingWriter) Rotate() error {
+	if rw.closed {
+		return errors.New("pcap: writer closed")
+	}
+	if rw.w == nil {
+		return rw.open()
+	}
+
+	err := rw.close()
+	rw.index++
+	if openErr := rw.open(); err == nil {
+		err = openErr
+	}
+	return err
+}
+
+// Flush writes the buffered packets to the current file
+func (rw *RotatingWriter) Flush() error {
+	if rw.buf == nil {
+		return nil
+	}
+	return rw.buf.Flush()
+}
+
+// Close flushes and closes the current file
+func (rw *RotatingWriter) Close() error {
+	rw.closed = true
+	if rw.w == nil {
+		return nil
+	}
+	return rw.close()
+}
+
+// FileName returns the name of the current file
+func (rw *RotatingWriter) FileName() string {
+	if rw.w == nil || len(rw.files) == 0 {
+		return ""
+	}
+	return rw.files[len(rw.files)-1]
+}
+
+// Files returns the names of the files written and still kept, oldest first
+func (rw *RotatingWriter) Files() []string {
+	return append([]string(nil), rw.files...)
+}
+
+// Opened returns the number of files opened, including the files opened by size and time based rotations
+func (rw *RotatingWriter) Opened() uint64 {
+	return rw.opened
+}
+
+// Stats returns the total number of packets and bytes written to all files
+func (rw *RotatingWriter) Stats() (packets uint64, bytes int64) {
+	return rw.packets, rw.bytes
+}
+
+func (rw *RotatingWriter) open() error {
+	rw.openAt = rw.now()
+	name := rw.fileName(rw.openAt)
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(file)
+	w, err := NewWriter(buf, rw.params.SnapLen, rw.params.LinkType)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rw.file, rw.buf, rw.w = file, buf, w
+	rw.opened++
+	rw.bytes += w.Size()
+
+	// remove the oldest files when there are too many (best effort, a file may be removed by others already)
+	rw.files = append(rw.files, name)
+	for rw.params.MaxFiles > 0 && len(rw.files) > rw.params.MaxFiles {
+		os.Remove(rw.files[0])
+		rw.files = rw.files[1:]
+	}
+	return nil
+}
+
+func (rw *RotatingWriter) close() error {
+	err := rw.buf.Flush()
+	if closeErr := rw.file.Close(); err == nil {
+		err = closeErr
+	}
+	rw.file, rw.buf, rw.w = nil, nil, nil
+	return err
+}
+
+// expand the file name template for the given time and current sequence number
+func (rw *RotatingWriter) fileName(t time.Time) string {
+	var sb strings.Builder
+	tmpl := rw.params.Template
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' || i == len(tmpl)-1 {
+			sb.WriteByte(tmpl[i])
+			continue
+		}
+		i++
+		switch tmpl[i] {
+		case 'Y':
+			sb.WriteString(t.Format("2006"))
+		case 'm':
+			sb.WriteString(t.Format("01"))
+		case 'd':
+			sb.WriteString(t.Format("02"))
+		case 'H':
+			sb.WriteString(t.Format("15"))
+		case 'M':
+			sb.WriteString(t.Format("04"))
+		case 'S':
+			sb.WriteString(t.Format("05"))
+		case 'N':
+			sb.WriteString(strconv.FormatUint(rw.index, 10))
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(tmpl[i])
+		}
+	}
+	name := sb.String()
+
+	// never overwrite a file written by this writer
+	for _, f := range rw.files {
+		if f == name {
+			ext := filepath.Ext(name)
+			return strings.TrimSuffix(name, ext) + "_" + strconv.FormatUint(rw.index, 10) + ext
+		}
+	}
+	return name
+}
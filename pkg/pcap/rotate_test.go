@@ -0,0 +1,156 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pcap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// returns the names of the files in the given directory
+func dirFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRotatingWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	pkt := make([]byte, 100)
+	ts := time.Unix(1000, 0)
+
+	// room for the file header and two packets
+	rw, err := NewRotatingWriter(RotateParams{
+		Template: filepath.Join(dir, "sink-%N.pcap"),
+		MaxSize:  fileHeaderSize + 2*(recordHeaderSize+100),
+		MaxFiles: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewRotatingWriter failed: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := rw.WritePacket(ts, 100, pkt); err != nil {
+			t.Fatalf("WritePacket failed: %v", err)
+		}
+	}
+	if rw.FileName() != filepath.Join(dir, "sink-3.pcap") {
+		t.Fatalf("Wrong current file %s", rw.FileName())
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if files := dirFiles(t, dir); !reflect.DeepEqual(files, []string{"sink-2.pcap", "sink-3.pcap"}) {
+		t.Fatalf("Wrong files kept: %v", files)
+	}
+	info, err := os.Stat(filepath.Join(dir, "sink-2.pcap"))
+	if err != nil || info.Size() != fileHeaderSize+2*(recordHeaderSize+100) {
+		t.Fatalf("Wrong size of full file: %v, %v", info, err)
+	}
+	packets, bytes := rw.Stats()
+	if packets != 7 || bytes != 4*fileHeaderSize+7*(recordHeaderSize+100) {
+		t.Fatalf("Wrong stats: %d packets, %d bytes", packets, bytes)
+	}
+	if err := rw.WritePacket(ts, 100, pkt); err == nil {
+		t.Fatalf("WritePacket on closed writer should fail")
+	}
+}
+
+func TestRotatingWriterInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2022, 10, 5, 13, 4, 5, 0, time.UTC)
+
+	rw, err := newRotatingWriter(RotateParams{
+		Template: filepath.Join(dir, "sink-%Y%m%d-%H%M%S.pcap"),
+		Interval: time.Minute,
+	}, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("newRotatingWriter failed: %v", err)
+	}
+
+	rw.WritePacket(now.Add(30*time.Second), 1, []byte{1})
+	now = now.Add(time.Minute)
+	rw.WritePacket(now, 1, []byte{1})
+
+	// reopen within the same second doesn't overwrite the current file
+	if err := rw.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	rw.Close()
+
+	expected := []string{"sink-20221005-130405.pcap", "sink-20221005-130505.pcap", "sink-20221005-130505_2.pcap"}
+	if files := dirFiles(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Fatalf("Wrong files: %v", files)
+	}
+}
+
+func TestRotatingWriterTemplate(t *testing.T) {
+	rw := &RotatingWriter{params: RotateParams{Template: "a%%b%x%N%"}, index: 3}
+	if name := rw.fileName(time.Now()); name != "a%b%x3%" {
+		t.Fatalf("Wrong template expansion: %s", name)
+	}
+}
+
+func TestRotatingWriterRetryOpen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "capture")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	pkt := make([]byte, 100)
+	ts := time.Unix(1000, 0)
+
+	rw, err := NewRotatingWriter(RotateParams{
+		Template: filepath.Join(dir, "sink-%N.pcap"),
+		MaxSize:  fileHeaderSize + recordHeaderSize + 100,
+	})
+	if err != nil {
+		t.Fatalf("NewRotatingWriter failed: %v", err)
+	}
+	if err := rw.WritePacket(ts, 100, pkt); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+
+	// the next file can't be opened
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if err := rw.WritePacket(ts, 100, pkt); err == nil {
+		t.Fatalf("WritePacket without directory should fail")
+	}
+	if err := rw.WritePacket(ts, 100, pkt); err == nil {
+		t.Fatalf("WritePacket without open file should fail")
+	}
+	if err := rw.Rotate(); err == nil {
+		t.Fatalf("Rotate without directory should fail")
+	}
+
+	// rotate recovers when the file can be opened again
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if err := rw.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if err := rw.WritePacket(ts, 100, pkt); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	if rw.Opened() != 2 {
+		t.Fatalf("Wrong number of opened files: %d", rw.Opened())
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := rw.Rotate(); err == nil {
+		t.Fatalf("Rotate on closed writer should fail")
+	}
+}
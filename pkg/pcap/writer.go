@@ -0,0 +1,97 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pcap writes captured packets in the classic libpcap file format with nanosecond timestamps
+// (https://www.ietf.org/archive/id/draft-gharris-opsawg-pcap-01.html)
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	magicNanoseconds uint32 = 0xa1b23c4d
+	versionMajor     uint16 = 2
+	versionMinor     uint16 = 4
+	fileHeaderSize          = 24
+	recordHeaderSize        = 16
+)
+
+// LinkTypeEthernet is the link type of Ethernet interfaces
+const LinkTypeEthernet uint32 = 1
+
+var order = binary.LittleEndian
+
+// Writer writes a pcap file header and packet records to an io.Writer
+type Writer struct {
+	w       io.Writer
+	snapLen uint32
+	size    int64
+	packets uint64
+	hdr     [recordHeaderSize]byte
+}
+
+// NewWriter creates a pcap writer and writes the file header. Packets longer than snapLen bytes are truncated, a
+// snapLen of 0 means no limit.
+func NewWriter(w io.Writer, snapLen uint32, linkType uint32) (*Writer, error) {
+	pw := &Writer{w: w, snapLen: snapLen}
+	if snapLen == 0 {
+		pw.snapLen = 0xffffffff
+	}
+
+	var hdr [fileHeaderSize]byte
+	order.PutUint32(hdr[0:], magicNanoseconds)
+	order.PutUint16(hdr[4:], versionMajor)
+	order.PutUint16(hdr[6:], versionMinor)
+	order.PutUint32(hdr[16:], pw.snapLen)
+	order.PutUint32(hdr[20:], linkType)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	pw.size = fileHeaderSize
+
+	return pw, nil
+}
+
+// WritePacket writes a packet record with the (possibly truncated) packet data. origLen is the length of the packet
+// on the wire.
+func (pw *Writer) WritePacket(ts time.Time, origLen uint32, data []byte) error {
+	if uint64(len(data)) > uint64(pw.snapLen) {
+		data = data[:pw.snapLen]
+	}
+
+	order.PutUint32(pw.hdr[0:], uint32(ts.Unix()))
+	order.PutUint32(pw.hdr[4:], uint32(ts.Nanosecond()))
+	order.PutUint32(pw.hdr[8:], uint32(len(data)))
+	order.PutUint32(pw.hdr[12:], origLen)
+	if _, err := pw.w.Write(pw.hdr[:]); err != nil {
+		return err
+	}
+	if _, err := pw.w.Write(data); err != nil {
+		return err
+	}
+
+	pw.size += recordHeaderSize + int64(len(data))
+	pw.packets++
+	return nil
+}
+
+// Size returns the number of bytes written
+func (pw *Writer) Size() int64 {
+	return pw.size
+}
+
+// Packets returns the number of packets written
+func (pw *Writer) Packets() uint64 {
+	return pw.packets
+}
+
+// RecordSize returns the number of bytes a packet with the given captured length takes in the file
+func (pw *Writer) RecordSize(capLen int) int64 {
+	if uint64(capLen) > uint64(pw.snapLen) {
+		capLen = int(pw.snapLen)
+	}
+	return recordHeaderSize + int64(capLen)
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, 4, LinkTypeEthernet)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	ts := time.Unix(1000, 123456789)
+	pkt := []byte{1, 2, 3, 4, 5}
+	if err := w.WritePacket(ts, 60, pkt); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	if err := w.WritePacket(ts, 2, pkt[:2]); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+
+	if w.Size() != int64(buf.Len()) || w.Packets() != 2 {
+		t.Fatalf("Size %d <> written bytes %d or packets %d <> 2", w.Size(), buf.Len(), w.Packets())
+	}
+	if w.Size() != fileHeaderSize+w.RecordSize(len(pkt))+w.RecordSize(2) {
+		t.Fatalf("RecordSize doesn't match the written records")
+	}
+
+	le := binary.LittleEndian
+	data := buf.Bytes()
+	if le.Uint32(data[0:]) != magicNanoseconds || le.Uint16(data[4:]) != versionMajor ||
+		le.Uint16(data[6:]) != versionMinor || le.Uint32(data[16:]) != 4 || le.Uint32(data[20:]) != LinkTypeEthernet {
+		t.Fatalf("Wrong file header: %v", data[:fileHeaderSize])
+	}
+
+	// first packet is truncated to the snaplen
+	rec := data[fileHeaderSize:]
+	if le.Uint32(rec[0:]) != 1000 || le.Uint32(rec[4:]) != 123456789 || le.Uint32(rec[8:]) != 4 ||
+		le.Uint32(rec[12:]) != 60 {
+		t.Fatalf("Wrong record header: %v", rec[:recordHeaderSize])
+	}
+	if !bytes.Equal(rec[recordHeaderSize:recordHeaderSize+4], pkt[:4]) {
+		t.Fatalf("Wrong packet data: %v", rec[recordHeaderSize:recordHeaderSize+4])
+	}
+	rec = rec[recordHeaderSize+4:]
+	if le.Uint32(rec[8:]) != 2 || le.Uint32(rec[12:]) != 2 || len(rec) != recordHeaderSize+2 {
+		t.Fatalf("Wrong second record: %v", rec)
+	}
+}
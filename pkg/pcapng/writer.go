@@ -0,0 +1,193 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pcapng writes captured packets in the pcapng file format (https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-00.html)
+package pcapng
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Block types
+const (
+	blockSectionHeader     uint32 = 0x0a0d0d0a
+	blockInterfaceDesc     uint32 = 0x00000001
+	blockEnhancedPacket    uint32 = 0x00000006
+	byteOrderMagic         uint32 = 0x1a2b3c4d
+	versionMajor           uint16 = 1
+	versionMinor           uint16 = 0
+	optEndOfOpt            uint16 = 0
+	optComment             uint16 = 1
+	optShbUserAppl         uint16 = 4
+	optIfName              uint16 = 2
+	optIfDescription       uint16 = 3
+	optIfTsResol           uint16 = 9
+	optEpbFlags            uint16 = 2
+	tsResolNanoseconds     uint8  = 9
+	blockHeaderTrailerSize        = 12
+)
+
+// LinkTypeEthernet is the link type of Ethernet interfaces
+const LinkTypeEthernet uint16 = 1
+
+// Direction of a captured packet
+type Direction uint8
+
+const (
+	DirectionUnknown  Direction = 0
+	DirectionInbound  Direction = 1
+	DirectionOutbound Direction = 2
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionInbound:
+		return "rx"
+	case DirectionOutbound:
+		return "tx"
+	}
+	return "unknown"
+}
+
+// Interface describes an interface packets are captured on
+type Interface struct {
+	Name        string
+	Description string
+	LinkType    uint16
+	SnapLen     uint32 // 0 means no limit
+}
+
+// Writer writes a pcapng section with interfaces and packets to an io.Writer. All blocks are written in little endian
+// byte order with nanosecond timestamps.
+type Writer struct {
+	w       io.Writer
+	size    int64
+	nIfaces uint32
+	buf     []byte
+}
+
+var order = binary.LittleEndian
+
+// NewWriter creates a pcapng writer and writes the section header block. The given application name and comment are
+// added to the section header when not empty.
+func NewWriter(w io.Writer, appName string, comment string) (*Writer, error) {
+	pw := &Writer{w: w}
+
+	body := make([]byte, 16)
+	order.PutUint32(body[0:], byteOrderMagic)
+	order.PutUint16(body[4:], versionMajor)
+	order.PutUint16(body[6:], versionMinor)
+	order.PutUint64(body[8:], 0xffffffffffffffff) // section length not specified
+	body = appendStringOption(body, optShbUserAppl, appName)
+	body = appendStringOption(body, optComment, comment)
+	body = appendEndOption(body)
+
+	if err := pw.writeBlock(blockSectionHeader, body); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// AddInterface writes an interface description block and returns the interface id to use when writing packets
+// captured on this interface
+func (pw *Writer) AddInterface(iface Interface) (uint32, error) {
+	body := make([]byte, 8)
+	order.PutUint16(body[0:], iface.LinkType)
+	order.PutUint32(body[4:], iface.SnapLen)
+	body = appendStringOption(body, optIfName, iface.Name)
+	body = appendStringOption(body, optIfDescription, iface.Description)
+	body = appendOption(body, optIfTsResol, []byte{tsResolNanoseconds})
+	body = appendEndOption(body)
+
+	if err := pw.writeBlock(blockInterfaceDesc, body); err != nil {
+		return 0, err
+	}
+
+	id := pw.nIfaces
+	pw.nIfaces++
+	return id, nil
+}
+
+// WritePacket writes an enhanced packet block with the (possibly truncated) packet data. origLen is the length of the
+// packet on the wire.
+func (pw *Writer) WritePacket(ifaceID uint32, ts time.Time, origLen uint32, data []byte, dir Direction) error {
+	if ifaceID >= pw.nIfaces {
+		return errors.New("pcapng: unknown interface id")
+	}
+
+	ns := uint64(ts.UnixNano())
+	body := pw.buf[:0]
+	body = append(body, make([]byte, 20)...)
+	order.PutUint32(body[0:], ifaceID)
+	order.PutUint32(body[4:], uint32(ns>>32))
+	order.PutUint32(body[8:], uint32(ns))
+	order.PutUint32(body[12:], uint32(len(data)))
+	order.PutUint32(body[16:], origLen)
+	body = append(body, data...)
+	body = appendPadding(body)
+	if dir != DirectionUnknown {
+		var flags [4]byte
+		order.PutUint32(flags[:], uint32(dir))
+		body = appendOption(body, optEpbFlags, flags[:])
+		body = appendEndOption(body)
+	}
+	pw.buf = body
+
+	return pw.writeBlock(blockEnhancedPacket, body)
+}
+
+// Size returns the number of bytes written
+func (pw *Writer) Size() int64 {
+	return pw.size
+}
+
+// write a block with the given type and body, the body must be 32 bit aligned
+func (pw *Writer) writeBlock(blockType uint32, body []byte) error {
+	var hdr [8]byte
+	var trailer [4]byte
+	length := uint32(len(body) + blockHeaderTrailerSize)
+
+	order.PutUint32(hdr[0:], blockType)
+	order.PutUint32(hdr[4:], length)
+	order.PutUint32(trailer[0:], length)
+
+	for _, b := range [][]byte{hdr[:], body, trailer[:]} {
+		n, err := pw.w.Write(b)
+		pw.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	var hdr [4]byte
+	order.PutUint16(hdr[0:], code)
+	order.PutUint16(hdr[2:], uint16(len(value)))
+	b = append(b, hdr[:]...)
+	b = append(b, value...)
+	return appendPadding(b)
+}
+
+func appendStringOption(b []byte, code uint16, value string) []byte {
+	if value == "" {
+		return b
+	}
+	return appendOption(b, code, []byte(value))
+}
+
+func appendEndOption(b []byte) []byte {
+	return appendOption(b, optEndOfOpt, nil)
+}
+
+// pad to a 32 bit boundary
+func appendPadding(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
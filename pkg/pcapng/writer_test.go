@@ -0,0 +1,142 @@
+// Copyright 2022 - Sander Tolsma. All rights reserved
+// SPDX-License-Identifier: Apache-2.0
+
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+// split a pcapng byte stream into blocks and check the block framing
+func readBlocks(t *testing.T, data []byte) []block {
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < blockHeaderTrailerSize {
+			t.Fatalf("Truncated block header: %v", data)
+		}
+		typ := binary.LittleEndian.Uint32(data[0:])
+		length := binary.LittleEndian.Uint32(data[4:])
+		if length%4 != 0 || int(length) > len(data) {
+			t.Fatalf("Wrong block length %d (%d bytes left)", length, len(data))
+		}
+		if trailer := binary.LittleEndian.Uint32(data[length-4:]); trailer != length {
+			t.Fatalf("Block trailer length %d <> block length %d", trailer, length)
+		}
+		blocks = append(blocks, block{typ, data[8 : length-4]})
+		data = data[length:]
+	}
+	return blocks
+}
+
+// return the options of a block body starting at the given offset
+func readOptions(t *testing.T, body []byte) map[uint16][]byte {
+	opts := make(map[uint16][]byte)
+	for len(body) >= 4 {
+		code := binary.LittleEndian.Uint16(body[0:])
+		length := int(binary.LittleEndian.Uint16(body[2:]))
+		if code == optEndOfOpt {
+			return opts
+		}
+		padded := (length + 3) &^ 3
+		if 4+padded > len(body) {
+			t.Fatalf("Truncated option %d", code)
+		}
+		opts[code] = body[4 : 4+length]
+		body = body[4+padded:]
+	}
+	t.Fatalf("No end of options found")
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "go-p4pack", "")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	id0, err := w.AddInterface(Interface{Name: "sw1", Description: "PIPELINE0:in:0", LinkType: LinkTypeEthernet})
+	if err != nil || id0 != 0 {
+		t.Fatalf("AddInterface returned %d, %v", id0, err)
+	}
+	id1, err := w.AddInterface(Interface{Name: "sw2", LinkType: LinkTypeEthernet, SnapLen: 64})
+	if err != nil || id1 != 1 {
+		t.Fatalf("AddInterface returned %d, %v", id1, err)
+	}
+
+	ts := time.Unix(1000, 123456789)
+	pkt := []byte{1, 2, 3, 4, 5}
+	if err := w.WritePacket(id1, ts, 60, pkt, DirectionOutbound); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	if err := w.WritePacket(id0, ts, 4, pkt[:4], DirectionUnknown); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	if err := w.WritePacket(2, ts, 4, pkt[:4], DirectionInbound); err == nil {
+		t.Fatalf("WritePacket with unknown interface id should fail")
+	}
+
+	if w.Size() != int64(buf.Len()) {
+		t.Fatalf("Size %d <> written bytes %d", w.Size(), buf.Len())
+	}
+
+	blocks := readBlocks(t, buf.Bytes())
+	if len(blocks) != 5 {
+		t.Fatalf("Expected 5 blocks, got %d", len(blocks))
+	}
+
+	// section header
+	shb := blocks[0]
+	if shb.typ != blockSectionHeader || binary.LittleEndian.Uint32(shb.body) != byteOrderMagic {
+		t.Fatalf("Wrong section header block: %x", shb)
+	}
+	if appl := readOptions(t, shb.body[16:])[optShbUserAppl]; string(appl) != "go-p4pack" {
+		t.Fatalf("Wrong user application option: %q", appl)
+	}
+
+	// interfaces
+	idb := blocks[1]
+	if idb.typ != blockInterfaceDesc || binary.LittleEndian.Uint16(idb.body) != LinkTypeEthernet {
+		t.Fatalf("Wrong interface description block: %x", idb)
+	}
+	opts := readOptions(t, idb.body[8:])
+	if string(opts[optIfName]) != "sw1" || string(opts[optIfDescription]) != "PIPELINE0:in:0" {
+		t.Fatalf("Wrong interface options: %q", opts)
+	}
+	if !bytes.Equal(opts[optIfTsResol], []byte{tsResolNanoseconds}) {
+		t.Fatalf("Wrong timestamp resolution option: %v", opts[optIfTsResol])
+	}
+	if snaplen := binary.LittleEndian.Uint32(blocks[2].body[4:]); snaplen != 64 {
+		t.Fatalf("Wrong snaplen %d", snaplen)
+	}
+
+	// packets
+	epb := blocks[3]
+	if epb.typ != blockEnhancedPacket {
+		t.Fatalf("Wrong enhanced packet block type: %x", epb.typ)
+	}
+	le := binary.LittleEndian
+	ns := uint64(le.Uint32(epb.body[4:]))<<32 | uint64(le.Uint32(epb.body[8:]))
+	if le.Uint32(epb.body[0:]) != id1 || ns != uint64(ts.UnixNano()) ||
+		le.Uint32(epb.body[12:]) != 5 || le.Uint32(epb.body[16:]) != 60 {
+		t.Fatalf("Wrong enhanced packet block header: %v", epb.body[:20])
+	}
+	if !bytes.Equal(epb.body[20:25], pkt) {
+		t.Fatalf("Wrong packet data: %v", epb.body[20:25])
+	}
+	if flags := readOptions(t, epb.body[28:])[optEpbFlags]; le.Uint32(flags) != uint32(DirectionOutbound) {
+		t.Fatalf("Wrong packet flags option: %v", flags)
+	}
+	if len(blocks[4].body) != 24 {
+		t.Fatalf("Packet without direction should have no options, body length %d", len(blocks[4].body))
+	}
+}
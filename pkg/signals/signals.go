@@ -29,3 +29,23 @@ func RegisterSignalHandlers() <-chan struct{} {
 
 	return stopCh
 }
+
+// RegisterReloadHandler registers a signal handler for SIGHUP. The returned channel receives a value for every SIGHUP
+// received, signals received while a previous reload request is still pending are merged into that request.
+func RegisterReloadHandler() <-chan struct{} {
+	notifyCh := make(chan os.Signal, 1)
+	reloadCh := make(chan struct{}, 1)
+
+	go func() {
+		for range notifyCh {
+			select {
+			case reloadCh <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	signal.Notify(notifyCh, syscall.SIGHUP)
+
+	return reloadCh
+}